@@ -0,0 +1,206 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"github.com/bwmarrin/discordgo"
+	"sort"
+	"strconv"
+	"time"
+)
+
+// BackupHandler struct
+type BackupHandler struct {
+	conf     *Config
+	db       *DBHandler
+	registry *CommandRegistry
+	router   *CommandRouter
+	logger   *Logger
+	backups  *Backups
+}
+
+// Init function
+func (h *BackupHandler) Init() {
+	h.backups = &Backups{db: h.db, conf: h.conf}
+	h.RegisterCommands()
+}
+
+// RegisterCommands function
+func (h *BackupHandler) RegisterCommands() (err error) {
+
+	h.registry.Register("backup", "Manage database backups", "create | list | restore <id> | export json")
+	h.router.AddRoute("backup", false, h.ParseCommand, "owner")
+	return nil
+
+}
+
+// ParseCommand function
+func (h *BackupHandler) ParseCommand(command []string, user User, s DiscordSession, m *discordgo.MessageCreate) {
+
+	if len(command) < 2 {
+		s.ChannelMessageSend(m.ChannelID, "Expected flag for 'backup' command, see usage for more info")
+		return
+	}
+
+	if command[1] == "create" {
+		backup, err := h.backups.CreateBackup("manual")
+		if err != nil {
+			s.ChannelMessageSend(m.ChannelID, "Could not create backup: "+err.Error())
+			return
+		}
+		s.ChannelMessageSend(m.ChannelID, "Backup created: "+backup.ID+"\n"+h.FormatCounts(backup))
+		return
+	}
+
+	if command[1] == "list" {
+		formatted, err := h.ListBackups()
+		if err != nil {
+			s.ChannelMessageSend(m.ChannelID, "Could not list backups: "+err.Error())
+			return
+		}
+		s.ChannelMessageSend(m.ChannelID, formatted)
+		return
+	}
+
+	if command[1] == "restore" {
+		if len(command) < 3 {
+			s.ChannelMessageSend(m.ChannelID, "Command expects an argument - the backup ID to restore")
+			return
+		}
+		h.Restore(command[2], s, m)
+		return
+	}
+
+	if command[1] == "export" {
+		if len(command) < 3 || command[2] != "json" {
+			s.ChannelMessageSend(m.ChannelID, "Expected export format, currently only 'json' is supported")
+			return
+		}
+		h.ExportJSON(s, m)
+		return
+	}
+
+	s.ChannelMessageSend(m.ChannelID, "Unrecognized flag for 'backup' command: "+command[1])
+}
+
+// ListBackups function
+func (h *BackupHandler) ListBackups() (formatted string, err error) {
+
+	backups, err := h.backups.GetAllBackups()
+	if err != nil {
+		return "", err
+	}
+
+	if len(backups) == 0 {
+		return "No backups found in " + h.backups.BackupDir(), nil
+	}
+
+	formatted = "```\n"
+	for _, backup := range backups {
+		formatted = formatted + backup.ID + " Schema:v" + strconv.Itoa(backup.SchemaVersion) +
+			" Users:" + strconv.Itoa(backup.Counts["Users"]) + " Rooms:" + strconv.Itoa(backup.Counts["Rooms"]) + "\n"
+	}
+	formatted = formatted + "\n```\n"
+	return formatted, nil
+}
+
+// Restore function
+func (h *BackupHandler) Restore(backupID string, s DiscordSession, m *discordgo.MessageCreate) {
+
+	backup, err := h.backups.GetBackupByID(backupID)
+	if err != nil {
+		s.ChannelMessageSend(m.ChannelID, "Could not read backup "+backupID+": "+err.Error())
+		return
+	}
+
+	// Migrations only run at startup, so a backup from another schema version has to go through -import instead
+	version, err := h.db.GetSchemaVersion()
+	if err != nil {
+		s.ChannelMessageSend(m.ChannelID, "Could not read schema version: "+err.Error())
+		return
+	}
+	if backup.SchemaVersion != version {
+		s.ChannelMessageSend(m.ChannelID, "Backup "+backup.ID+" is from schema v"+strconv.Itoa(backup.SchemaVersion)+
+			" but the database is at v"+strconv.Itoa(version)+", restart the bot with -import to restore it")
+		return
+	}
+
+	// Always take a fresh backup first so a bad restore can be undone
+	safety, err := h.backups.CreateBackup("prerestore")
+	if err != nil {
+		s.ChannelMessageSend(m.ChannelID, "Could not create pre-restore backup, aborting: "+err.Error())
+		return
+	}
+
+	err = h.backups.RestoreBackup(backup)
+	if err != nil {
+		s.ChannelMessageSend(m.ChannelID, "Restore failed, no changes were made: "+err.Error())
+		return
+	}
+
+	s.ChannelMessageSend(m.ChannelID, "Restored backup "+backup.ID+"\n"+h.FormatCounts(backup)+
+		"The previous state was saved as "+safety.ID)
+}
+
+// ExportJSON function
+func (h *BackupHandler) ExportJSON(s DiscordSession, m *discordgo.MessageCreate) {
+
+	backup, err := h.backups.Snapshot("export")
+	if err != nil {
+		s.ChannelMessageSend(m.ChannelID, "Could not export database: "+err.Error())
+		return
+	}
+
+	data, err := json.MarshalIndent(backup, "", "  ")
+	if err != nil {
+		s.ChannelMessageSend(m.ChannelID, "Could not export database: "+err.Error())
+		return
+	}
+
+	_, err = s.ChannelFileSend(m.ChannelID, backup.ID+".json", bytes.NewReader(data))
+	if err != nil {
+		s.ChannelMessageSend(m.ChannelID, "Could not upload export: "+err.Error())
+		return
+	}
+}
+
+// FormatCounts function
+func (h *BackupHandler) FormatCounts(backup Backup) (formatted string) {
+
+	var buckets []string
+	for bucket := range backup.Counts {
+		buckets = append(buckets, bucket)
+	}
+	sort.Strings(buckets)
+
+	formatted = "```\n"
+	for _, bucket := range buckets {
+		formatted = formatted + bucket + ": " + strconv.Itoa(backup.Counts[bucket]) + "\n"
+	}
+	formatted = formatted + "```\n"
+	return formatted
+}
+
+// ScheduledBackups function
+func (h *BackupHandler) ScheduledBackups(ctx context.Context) error {
+
+	// Only run every X hours
+	for SleepContext(ctx, h.conf.MainConfig.BackupInterval*time.Hour) {
+		Heartbeat(ctx)
+
+		backup, err := h.backups.CreateBackup("auto")
+		if err != nil {
+			h.logger.Error(BOTLOG, LogFields{Command: "backup"}, "Error running scheduled backup: "+err.Error())
+			continue
+		}
+
+		removed, err := h.backups.PruneBackups("auto", h.conf.MainConfig.BackupRetention)
+		if err != nil {
+			h.logger.Error(BOTLOG, LogFields{Command: "backup"}, "Error pruning old backups: "+err.Error())
+		}
+		h.logger.Info(BOTLOG, LogFields{Command: "backup"}, "Scheduled backup created: "+backup.ID+" ("+
+			strconv.Itoa(removed)+" old backup(s) removed)")
+	}
+	return nil
+}
@@ -0,0 +1,397 @@
+package main
+
+/*
+Backups are point-in-time JSON snapshots of the game buckets.
+
+Every bucket is read inside a single read-only transaction so the snapshot is consistent, and the result is written
+out as one JSON document. The same document is what "backup export json" hands out, and what the -import flag and
+"backup restore" read back in.
+
+*/
+
+import (
+	"encoding/json"
+	"errors"
+	"github.com/asdine/storm"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Backups struct
+type Backups struct {
+	db          *DBHandler
+	conf        *Config
+	querylocker sync.RWMutex
+}
+
+// Backup struct
+type Backup struct {
+	ID            string
+	Label         string
+	Created       time.Time
+	SchemaVersion int
+	Counts        map[string]int
+	Buckets       map[string]json.RawMessage
+}
+
+// backupBucket struct
+// record is a pointer to an empty value of the type stored in the bucket
+type backupBucket struct {
+	name   string
+	record interface{}
+}
+
+// backupBuckets is the list of everything a backup covers
+// Wallets isn't here, nothing writes to it since the wallet setup in FirstTimeSetup was commented out. Give it a
+// record type and list it if wallets come back.
+var backupBuckets = []backupBucket{
+	{"Users", &User{}},
+	{"Rooms", &Room{}},
+	{"Guilds", &GuildRecord{}},
+	{"Events", &Event{}},
+	{"Transfers", &Transfer{}},
+	{"Notifications", &Notification{}},
+	{"ChannelNotifications", &ChannelNotification{}},
+	{"Commands", &CommandRecord{}},
+	{"Channels", &ChannelRecord{}},
+	{"Migrations", &MigrationRecord{}},
+	{"Callbacks", &WatchUser{}},
+	{"Zones", &Zone{}},
+	{"Parties", &Party{}},
+	{"TravelJournal", &TravelJournalEntry{}},
+}
+
+// BackupDir function
+func (h *Backups) BackupDir() string {
+	if h.conf.MainConfig.BackupDir == "" {
+		return "backups"
+	}
+	return h.conf.MainConfig.BackupDir
+}
+
+// CreateBackup function
+func (h *Backups) CreateBackup(label string) (backup Backup, err error) {
+	h.querylocker.Lock()
+	defer h.querylocker.Unlock()
+
+	backup, err = h.Snapshot(label)
+	if err != nil {
+		return backup, err
+	}
+
+	// IDs only go down to the second, so another backup with the same label in the same second gets a suffix
+	// rather than replacing the first
+	id := backup.ID
+	for i := 2; ; i++ {
+		_, err = os.Stat(h.BackupPath(backup.ID))
+		if os.IsNotExist(err) {
+			break
+		}
+		if err != nil {
+			return backup, err
+		}
+		backup.ID = id + "-" + strconv.Itoa(i)
+	}
+
+	err = h.WriteBackup(backup)
+	if err != nil {
+		return backup, err
+	}
+	return backup, nil
+}
+
+// Snapshot function
+// Reads every bucket inside one read-only transaction
+func (h *Backups) Snapshot(label string) (backup Backup, err error) {
+
+	created := time.Now().UTC()
+	backup = Backup{ID: created.Format("20060102-150405") + "-" + label, Label: label, Created: created,
+		Counts: make(map[string]int), Buckets: make(map[string]json.RawMessage)}
+
+	backup.SchemaVersion, err = h.db.GetSchemaVersion()
+	if err != nil {
+		return backup, err
+	}
+
+	tx, err := h.db.rawdb.Begin(false)
+	if err != nil {
+		return backup, err
+	}
+	defer tx.Rollback()
+
+	for _, bucket := range backupBuckets {
+		records := reflect.New(reflect.SliceOf(reflect.TypeOf(bucket.record).Elem()))
+		err = tx.From(bucket.name).All(records.Interface())
+		if err != nil && err != storm.ErrNotFound {
+			return backup, errors.New("Could not read " + bucket.name + ": " + err.Error())
+		}
+
+		data, err := json.Marshal(records.Interface())
+		if err != nil {
+			return backup, err
+		}
+		backup.Buckets[bucket.name] = data
+		backup.Counts[bucket.name] = records.Elem().Len()
+	}
+
+	return backup, nil
+}
+
+// WriteBackup function
+func (h *Backups) WriteBackup(backup Backup) (err error) {
+
+	err = os.MkdirAll(h.BackupDir(), os.FileMode(0700))
+	if err != nil {
+		return err
+	}
+
+	data, err := json.MarshalIndent(backup, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	// Write to a temp file first so a crash never leaves a half written backup behind
+	path := h.BackupPath(backup.ID)
+	err = ioutil.WriteFile(path+".tmp", data, os.FileMode(0600))
+	if err != nil {
+		return err
+	}
+	return os.Rename(path+".tmp", path)
+}
+
+// BackupPath function
+func (h *Backups) BackupPath(backupID string) string {
+	return filepath.Join(h.BackupDir(), backupID+".json")
+}
+
+// ReadBackup function
+func (h *Backups) ReadBackup(path string) (backup Backup, err error) {
+
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return backup, err
+	}
+
+	err = json.Unmarshal(data, &backup)
+	if err != nil {
+		return backup, errors.New("Invalid backup file: " + err.Error())
+	}
+	return backup, nil
+}
+
+// GetBackupByID function
+func (h *Backups) GetBackupByID(backupID string) (backup Backup, err error) {
+	h.querylocker.RLock()
+	defer h.querylocker.RUnlock()
+
+	// Don't let an ID walk out of our backup directory
+	if backupID == "" || strings.ContainsAny(backupID, `/\`) || strings.Contains(backupID, "..") {
+		return backup, errors.New("Invalid backup ID")
+	}
+
+	backup, err = h.ReadBackup(h.BackupPath(backupID))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return backup, errors.New("No record found")
+		}
+		return backup, err
+	}
+	return backup, nil
+}
+
+// GetAllBackups function
+// Returns our backups oldest first, the bucket data is left out to keep this cheap
+func (h *Backups) GetAllBackups() (backuplist []Backup, err error) {
+	h.querylocker.RLock()
+	defer h.querylocker.RUnlock()
+
+	files, err := ioutil.ReadDir(h.BackupDir())
+	if err != nil {
+		if os.IsNotExist(err) {
+			return backuplist, nil
+		}
+		return backuplist, err
+	}
+
+	for _, file := range files {
+		if file.IsDir() || !strings.HasSuffix(file.Name(), ".json") {
+			continue
+		}
+		backup, err := h.ReadBackup(filepath.Join(h.BackupDir(), file.Name()))
+		if err != nil {
+			continue
+		}
+		backup.Buckets = nil
+		backuplist = append(backuplist, backup)
+	}
+
+	sort.Slice(backuplist, func(i, j int) bool { return backuplist[i].Created.Before(backuplist[j].Created) })
+	return backuplist, nil
+}
+
+// RemoveBackupByID function
+func (h *Backups) RemoveBackupByID(backupID string) (err error) {
+
+	_, err = h.GetBackupByID(backupID)
+	if err != nil {
+		return err
+	}
+
+	h.querylocker.Lock()
+	defer h.querylocker.Unlock()
+	return os.Remove(h.BackupPath(backupID))
+}
+
+// PruneBackups function
+// Keeps only the newest retain backups with the given label, other labels are left alone
+func (h *Backups) PruneBackups(label string, retain int) (removed int, err error) {
+
+	if retain < 1 {
+		return 0, nil
+	}
+
+	backups, err := h.GetAllBackups()
+	if err != nil {
+		return 0, err
+	}
+
+	var labelled []Backup
+	for _, backup := range backups {
+		if backup.Label == label {
+			labelled = append(labelled, backup)
+		}
+	}
+
+	for len(labelled) > retain {
+		err = h.RemoveBackupByID(labelled[0].ID)
+		if err != nil {
+			return removed, err
+		}
+		labelled = labelled[1:]
+		removed++
+	}
+	return removed, nil
+}
+
+// RestoreBackup function
+// Replaces the contents of every bucket in the backup inside a single transaction, so a failed restore leaves the
+// database untouched
+func (h *Backups) RestoreBackup(backup Backup) (err error) {
+	h.querylocker.Lock()
+	defer h.querylocker.Unlock()
+
+	tx, err := h.db.rawdb.Begin(true)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	for _, bucket := range backupBuckets {
+		data, ok := backup.Buckets[bucket.name]
+		if !ok {
+			// Older backups may not have every bucket, leave those as they are
+			continue
+		}
+
+		if bucket.name == "Rooms" {
+			data, err = upgradeLegacyRooms(data)
+			if err != nil {
+				return errors.New("Could not restore Rooms: " + err.Error())
+			}
+		}
+
+		err = restoreBucket(tx.From(bucket.name), bucket.record, data)
+		if err != nil {
+			return errors.New("Could not restore " + bucket.name + ": " + err.Error())
+		}
+	}
+
+	return tx.Commit()
+}
+
+// restoreBucket function
+func restoreBucket(node storm.Node, record interface{}, data json.RawMessage) (err error) {
+
+	recordtype := reflect.TypeOf(record).Elem()
+
+	// Clear out whatever is there now
+	existing := reflect.New(reflect.SliceOf(recordtype))
+	err = node.All(existing.Interface())
+	if err != nil && err != storm.ErrNotFound {
+		return err
+	}
+	for i := 0; i < existing.Elem().Len(); i++ {
+		err = node.DeleteStruct(existing.Elem().Index(i).Addr().Interface())
+		if err != nil {
+			return err
+		}
+	}
+
+	restored := reflect.New(reflect.SliceOf(recordtype))
+	err = json.Unmarshal(data, restored.Interface())
+	if err != nil {
+		return err
+	}
+	for i := 0; i < restored.Elem().Len(); i++ {
+		err = node.Save(restored.Elem().Index(i).Addr().Interface())
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// upgradeLegacyRooms function
+// Exports from before exits still have the old direction fields, which Room can no longer hold. Those are turned
+// into exits here, since they would otherwise be dropped before the exits migration ever saw them.
+func upgradeLegacyRooms(data json.RawMessage) (upgraded json.RawMessage, err error) {
+
+	var records []json.RawMessage
+	err = json.Unmarshal(data, &records)
+	if err != nil {
+		return data, err
+	}
+
+	rooms := make([]Room, len(records))
+	for i, record := range records {
+		err = json.Unmarshal(record, &rooms[i])
+		if err != nil {
+			return data, err
+		}
+
+		var legacy legacyRoomDirections
+		err = json.Unmarshal(record, &legacy)
+		if err != nil {
+			return data, err
+		}
+		err = addLegacyExits(&rooms[i], legacy)
+		if err != nil {
+			return data, err
+		}
+	}
+
+	return json.Marshal(rooms)
+}
+
+// ImportBackupFile function
+// Rebuilds the database from a json export, used by the -import startup flag
+func (h *Backups) ImportBackupFile(path string) (backup Backup, err error) {
+
+	backup, err = h.ReadBackup(path)
+	if err != nil {
+		return backup, err
+	}
+
+	err = h.RestoreBackup(backup)
+	if err != nil {
+		return backup, err
+	}
+	return backup, nil
+}
@@ -0,0 +1,43 @@
+package main
+
+import (
+	"testing"
+)
+
+func TestCreateBackup(t *testing.T) {
+
+	w := newTestWorld(t)
+	w.conf.MainConfig.BackupDir = t.TempDir()
+	w.addPlayer(t, "1", "Alice", w.lobby)
+	backups := &Backups{db: w.db, conf: w.conf}
+
+	// Back to back backups land in the same second, none of them may replace another
+	ids := make(map[string]bool)
+	for i := 0; i < 3; i++ {
+		backup, err := backups.CreateBackup("manual")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if ids[backup.ID] {
+			t.Fatalf("backup ID %s was handed out twice", backup.ID)
+		}
+		ids[backup.ID] = true
+	}
+
+	backuplist, err := backups.GetAllBackups()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(backuplist) != 3 {
+		t.Fatalf("%d backups on disk, want 3", len(backuplist))
+	}
+	for _, listed := range backuplist {
+		backup, err := backups.GetBackupByID(listed.ID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !ids[backup.ID] || backup.Label != "manual" || backup.Counts["Users"] != 1 || backup.Counts["Rooms"] != 1 {
+			t.Errorf("backup %s = %+v", listed.ID, backup.Counts)
+		}
+	}
+}
@@ -1,25 +1,87 @@
 package main
 
+/*
+Callbacks let a handler wait for a user's next message in a channel (ie answering a registration prompt).
+
+Callback functions are registered by name when a handler starts, and a watch only stores that name along with the
+user, channel and arguments. Watches are saved in the "Callbacks" bucket so a prompt that was waiting when the bot
+restarted picks up where it left off, and every watch expires after a TTL, optionally telling the user it timed out.
+
+*/
+
 import (
-	"container/list"
+	"context"
+	"errors"
+	"github.com/asdine/storm"
 	"github.com/bwmarrin/discordgo"
-	"reflect"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
 )
 
+// CallbackFunc type
+type CallbackFunc func(args string, s DiscordSession, m *discordgo.MessageCreate)
+
 // CallbackHandler struct
 type CallbackHandler struct {
-	WatchList list.List
-	dg        *discordgo.Session
-	logger    *Logger
+	conf     *Config
+	db       *DBHandler
+	dg       *discordgo.Session
+	logger   *Logger
+	registry *CommandRegistry
+	router   *CommandRouter
+
+	callbacks map[string]registeredCallback
+	watches   map[string][]WatchUser // Keyed by user and channel
+	locker    sync.RWMutex
+}
+
+// registeredCallback struct
+type registeredCallback struct {
+	callback       CallbackFunc
+	timeoutmessage string
 }
 
 // WatchUser struct
 type WatchUser struct {
-	User      string
-	ChannelID string
-	MessageID string
-	Handler   func(string, *discordgo.Session, *discordgo.MessageCreate)
-	Args      string
+	ID             string `storm:"id"`
+	Key            string `storm:"index"` // User and channel, see watchKey
+	User           string
+	ChannelID      string
+	MessageID      string
+	Callback       string
+	Args           string
+	Created        time.Time
+	Expires        time.Time
+	TimeoutMessage string
+}
+
+// Init function
+// Loads any watches that were waiting when the bot last stopped
+func (c *CallbackHandler) Init() (err error) {
+	c.locker.Lock()
+	defer c.locker.Unlock()
+
+	c.callbacks = make(map[string]registeredCallback)
+	c.watches = make(map[string][]WatchUser)
+
+	var watchlist []WatchUser
+	db := c.db.rawdb.From("Callbacks")
+	err = db.All(&watchlist)
+	if err != nil && err != storm.ErrNotFound {
+		return err
+	}
+
+	for _, watch := range watchlist {
+		c.watches[watch.Key] = append(c.watches[watch.Key], watch)
+	}
+	return nil
+}
+
+// watchKey function
+func watchKey(userID string, channelID string) string {
+	return userID + ":" + channelID
 }
 
 // AddHandler function
@@ -29,66 +91,192 @@ func (c *CallbackHandler) AddHandler(h interface{}) {
 	c.dg.AddHandlerOnce(h)
 }
 
+// RegisterCallback function
+// Names are what get stored in the database, so they must not change between releases or pending watches are lost
+func (c *CallbackHandler) RegisterCallback(name string, callback CallbackFunc, timeoutmessage string) (err error) {
+	c.locker.Lock()
+	defer c.locker.Unlock()
+
+	if _, exists := c.callbacks[name]; exists {
+		return errors.New("Callback already registered: " + name)
+	}
+	c.callbacks[name] = registeredCallback{callback: callback, timeoutmessage: timeoutmessage}
+	return nil
+}
+
 // Watch function
-func (c *CallbackHandler) Watch(Handler func(string, *discordgo.Session, *discordgo.MessageCreate),
-	MessageID string, Args string, s *discordgo.Session, m *discordgo.MessageCreate) {
+// Waits for the author's next message in this channel, using the default TTL and the callback's timeout message
+func (c *CallbackHandler) Watch(callback string, MessageID string, Args string, s DiscordSession,
+	m *discordgo.MessageCreate) (err error) {
+
+	c.locker.RLock()
+	registered, ok := c.callbacks[callback]
+	c.locker.RUnlock()
+	if !ok {
+		return errors.New("Unknown callback: " + callback)
+	}
+
+	return c.WatchWithTimeout(callback, MessageID, Args, c.conf.CallbackTTL(), registered.timeoutmessage, s, m)
+}
+
+// WatchWithTimeout function
+func (c *CallbackHandler) WatchWithTimeout(callback string, MessageID string, Args string, ttl time.Duration,
+	timeoutmessage string, s DiscordSession, m *discordgo.MessageCreate) (err error) {
+
+	c.locker.Lock()
+	defer c.locker.Unlock()
+
+	if _, ok := c.callbacks[callback]; !ok {
+		return errors.New("Unknown callback: " + callback)
+	}
+
+	watch := WatchUser{ID: GetUUIDv2(), Key: watchKey(m.Author.ID, m.ChannelID), User: m.Author.ID,
+		ChannelID: m.ChannelID, MessageID: MessageID, Callback: callback, Args: Args, Created: time.Now(),
+		Expires: time.Now().Add(ttl), TimeoutMessage: timeoutmessage}
 
-	item := WatchUser{User: m.Author.ID, ChannelID: m.ChannelID, MessageID: MessageID, Handler: Handler, Args: Args}
-	c.WatchList.PushBack(item)
+	db := c.db.rawdb.From("Callbacks")
+	err = db.Save(&watch)
+	if err != nil {
+		return err
+	}
 
+	c.watches[watch.Key] = append(c.watches[watch.Key], watch)
+	return nil
 }
 
 // UnWatch function
 func (c *CallbackHandler) UnWatch(User string, ChannelID string, MessageID string) {
+	c.locker.Lock()
+	defer c.locker.Unlock()
+
+	key := watchKey(User, ChannelID)
+	for _, watch := range c.watches[key] {
+		if watch.MessageID == MessageID {
+			c.removeWatch(watch)
+		}
+	}
+}
+
+// CancelWatches function
+// Removes every watch for a user in a channel, returns how many were removed
+func (c *CallbackHandler) CancelWatches(User string, ChannelID string) (count int) {
+	c.locker.Lock()
+	defer c.locker.Unlock()
 
-	// Clear usermanager element by iterating
-	var next *list.Element
-	for e := c.WatchList.Front(); e != nil; e = next {
-		next = e.Next()
+	key := watchKey(User, ChannelID)
+	for _, watch := range c.watches[key] {
+		c.removeWatch(watch)
+		count++
+	}
+	return count
+}
 
-		r := reflect.ValueOf(e.Value)
-		user := reflect.Indirect(r).FieldByName("User")
-		channel := reflect.Indirect(r).FieldByName("ChannelID")
-		messageid := reflect.Indirect(r).FieldByName("MessageID")
+// removeWatch function
+// Must be called with the locker held
+func (c *CallbackHandler) removeWatch(watch WatchUser) {
 
-		if user.String() == User && channel.String() == ChannelID && messageid.String() == MessageID {
-			c.WatchList.Remove(e)
+	var remaining []WatchUser
+	for _, existing := range c.watches[watch.Key] {
+		if existing.ID != watch.ID {
+			remaining = append(remaining, existing)
 		}
 	}
+	if len(remaining) == 0 {
+		delete(c.watches, watch.Key)
+	} else {
+		c.watches[watch.Key] = remaining
+	}
+
+	db := c.db.rawdb.From("Callbacks")
+	err := db.DeleteStruct(&watch)
+	if err != nil && err != storm.ErrNotFound {
+		c.logger.Error(BOTLOG, LogFields{User: watch.User}, "Could not remove callback "+watch.ID+": "+err.Error())
+	}
 }
 
 // Read function
 func (c *CallbackHandler) Read(s *discordgo.Session, m *discordgo.MessageCreate) {
+	c.Dispatch(s, m)
+}
 
-	var next *list.Element
-	for e := c.WatchList.Front(); e != nil; e = next {
-
-		r := reflect.ValueOf(e.Value)
-		user := reflect.Indirect(r).FieldByName("User")
-		channelid := reflect.Indirect(r).FieldByName("ChannelID")
+// Dispatch function
+// Runs every watch the author has in this channel, each watch only ever fires once
+func (c *CallbackHandler) Dispatch(s DiscordSession, m *discordgo.MessageCreate) {
 
-		if m.Author.ID == user.String() && m.ChannelID == channelid.String() {
+	// The cancel command is handled by the router, it should never be taken as an answer
+	if strings.EqualFold(strings.TrimSpace(m.Content), c.conf.CommandPrefix()+"cancel") {
+		return
+	}
 
-			// We get the handler interface from our "Handler" field
-			handler := reflect.Indirect(r).FieldByName("Handler")
+	c.locker.Lock()
+	watches := c.watches[watchKey(m.Author.ID, m.ChannelID)]
+	var ready []WatchUser
+	for _, watch := range watches {
+		// Removed before running, so a callback that watches again for the next step isn't caught up in this
+		c.removeWatch(watch)
+		if time.Now().Before(watch.Expires) {
+			ready = append(ready, watch)
+		}
+	}
+	callbacks := c.callbacks
+	c.locker.Unlock()
 
-			// We get our argument list from the Args field
-			arglist := reflect.Indirect(r).FieldByName("Args")
-			command := arglist.String()
+	for _, watch := range ready {
+		registered, ok := callbacks[watch.Callback]
+		if !ok {
+			c.logger.Warn(BOTLOG, LogFields{User: watch.User}, "Dropping watch for unknown callback: "+watch.Callback)
+			continue
+		}
+		registered.callback(watch.Args, s, m)
+	}
+}
 
-			// We now type the interface to the handler type
-			//v := reflect.ValueOf(handler)
-			rargs := make([]reflect.Value, 3)
+// ExpireWatches function
+// Runs through the lifecycle manager, removing expired watches and letting their users know
+func (c *CallbackHandler) ExpireWatches(ctx context.Context) error {
 
-			//var sizeofargs = len(rargs)
-			rargs[0] = reflect.ValueOf(command)
-			rargs[1] = reflect.ValueOf(s)
-			rargs[2] = reflect.ValueOf(m)
+	for SleepContext(ctx, time.Second*30) {
+		Heartbeat(ctx)
 
-			handler.Call(rargs)
+		c.locker.Lock()
+		var expired []WatchUser
+		for _, watches := range c.watches {
+			for _, watch := range watches {
+				if time.Now().After(watch.Expires) {
+					expired = append(expired, watch)
+				}
+			}
+		}
+		for _, watch := range expired {
+			c.removeWatch(watch)
+		}
+		c.locker.Unlock()
 
-			messageid := reflect.Indirect(r).FieldByName("MessageID").String()
-			c.UnWatch(m.Author.ID, m.ChannelID, messageid)
+		for _, watch := range expired {
+			if watch.TimeoutMessage != "" {
+				c.dg.ChannelMessageSend(watch.ChannelID, "<@"+watch.User+"> "+watch.TimeoutMessage)
+			}
 		}
 	}
+	return nil
+}
+
+// RegisterCommands function
+// The registry is created by the command handler, so this is called separately once it exists
+func (c *CallbackHandler) RegisterCommands() (err error) {
+
+	c.registry.Register("cancel", "Cancel any prompts waiting on your answer in this channel", "")
+	return c.router.AddRoute("cancel", false, c.ReadCancel)
+
+}
+
+// ReadCancel function
+func (c *CallbackHandler) ReadCancel(command []string, user User, s DiscordSession, m *discordgo.MessageCreate) {
+
+	count := c.CancelWatches(m.Author.ID, m.ChannelID)
+	if count == 0 {
+		s.ChannelMessageSend(m.ChannelID, "You have nothing waiting to be cancelled in this channel.")
+		return
+	}
+	s.ChannelMessageSend(m.ChannelID, "Cancelled "+strconv.Itoa(count)+" pending prompt(s).")
 }
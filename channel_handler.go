@@ -11,9 +11,10 @@ type ChannelHandler struct {
 	db        *DBHandler
 	conf      *Config
 	registry  *CommandRegistry
+	router    *CommandRouter
 	channeldb *ChannelDB
 	user      *UserHandler
-	logchan   chan string
+	logger    *Logger
 }
 
 // Init function
@@ -22,33 +23,13 @@ func (h *ChannelHandler) Init() {
 	h.channeldb = new(ChannelDB)
 	h.channeldb.db = h.db
 
-}
-
-// Read function
-func (h *ChannelHandler) Read(s *discordgo.Session, m *discordgo.MessageCreate) {
-
-	// Check for safety
-	if !SafeInput(s, m, h.conf) {
-		return
-	}
-
-	user, err := h.user.GetUser(m.Author.ID, s, m.ChannelID)
-	if err != nil {
-		return
-	}
-	if !user.CheckRole("admin") {
-		return
-	}
-
-	command, message := CleanCommand(m.Content, h.conf)
-
-	if command == "channel" {
-		h.ReadCommand(message, s, m)
-	}
+	h.router.AddRoute("channel", false, h.ReadCommand, "admin")
 }
 
 // ReadCommand function
-func (h *ChannelHandler) ReadCommand(message []string, s *discordgo.Session, m *discordgo.MessageCreate) {
+func (h *ChannelHandler) ReadCommand(input []string, user User, s DiscordSession, m *discordgo.MessageCreate) {
+
+	_, message := SplitPayload(input)
 
 	if len(message) < 1 {
 		s.ChannelMessageSend(m.ChannelID, "<channel> requires an argument")
@@ -85,7 +66,7 @@ func (h *ChannelHandler) ReadCommand(message []string, s *discordgo.Session, m *
 }
 
 // FlushChannel function
-func (h *ChannelHandler) FlushChannel(payload []string, s *discordgo.Session, m *discordgo.MessageCreate) {
+func (h *ChannelHandler) FlushChannel(payload []string, s DiscordSession, m *discordgo.MessageCreate) {
 
 	var strcount string
 	var channelID string
@@ -133,7 +114,7 @@ func (h *ChannelHandler) FlushChannel(payload []string, s *discordgo.Session, m
 }
 
 // Info function
-func (h *ChannelHandler) Info(payload []string, s *discordgo.Session, m *discordgo.MessageCreate) {
+func (h *ChannelHandler) Info(payload []string, s DiscordSession, m *discordgo.MessageCreate) {
 
 	var channelid string
 	var formattedoutput string
@@ -204,7 +185,7 @@ func (h *ChannelHandler) Info(payload []string, s *discordgo.Session, m *discord
 }
 
 // Set function
-func (h *ChannelHandler) Set(payload []string, s *discordgo.Session, m *discordgo.MessageCreate) {
+func (h *ChannelHandler) Set(payload []string, s DiscordSession, m *discordgo.MessageCreate) {
 
 	var channelid string
 	if len(payload) < 1 {
@@ -376,7 +357,7 @@ func (h *ChannelHandler) Set(payload []string, s *discordgo.Session, m *discordg
 }
 
 // Unset function
-func (h *ChannelHandler) Unset(payload []string, s *discordgo.Session, m *discordgo.MessageCreate) {
+func (h *ChannelHandler) Unset(payload []string, s DiscordSession, m *discordgo.MessageCreate) {
 
 	if len(payload) < 1 {
 		s.ChannelMessageSend(m.ChannelID, "<unset> requires an argument")
@@ -481,7 +462,7 @@ func (h *ChannelHandler) GetMusicRoomChannel() (channelid string, err error) {
 }
 
 // ReadGroup function
-func (h *ChannelHandler) ReadGroup(payload []string, s *discordgo.Session, m *discordgo.MessageCreate) {
+func (h *ChannelHandler) ReadGroup(payload []string, s DiscordSession, m *discordgo.MessageCreate) {
 
 	if len(payload) < 1 {
 		s.ChannelMessageSend(m.ChannelID, "<group> requires an argument")
@@ -505,7 +486,7 @@ func (h *ChannelHandler) ReadGroup(payload []string, s *discordgo.Session, m *di
 }
 
 // RemoveGroup function
-func (h *ChannelHandler) RemoveGroup(payload []string, s *discordgo.Session, m *discordgo.MessageCreate) {
+func (h *ChannelHandler) RemoveGroup(payload []string, s DiscordSession, m *discordgo.MessageCreate) {
 
 	if len(payload) < 2 {
 		s.ChannelMessageSend(m.ChannelID, "<remove> requires two arguments")
@@ -534,7 +515,7 @@ func (h *ChannelHandler) RemoveGroup(payload []string, s *discordgo.Session, m *
 }
 
 // AddGroup function
-func (h *ChannelHandler) AddGroup(payload []string, s *discordgo.Session, m *discordgo.MessageCreate) {
+func (h *ChannelHandler) AddGroup(payload []string, s DiscordSession, m *discordgo.MessageCreate) {
 
 	if len(payload) < 2 {
 		s.ChannelMessageSend(m.ChannelID, "<add> requires two arguments")
@@ -20,10 +20,11 @@ type CommandHandler struct {
 	db       *DBHandler
 	perm     *PermissionsHandler
 	registry *CommandRegistry
-	dg       *discordgo.Session
+	router   *CommandRouter
+	dg       DiscordSession
 	user     *UserHandler
 	ch       *ChannelHandler
-	logchan  chan string
+	logger   *Logger
 }
 
 // Init function
@@ -35,7 +36,7 @@ func (h *CommandHandler) Init(channelhandler *ChannelHandler) {
 	// Setup our command registry interface
 	h.registry = new(CommandRegistry)
 	h.registry.conf = h.conf
-	pagecount := h.conf.MainConfig.PerPageCount
+	pagecount := h.conf.PageCount()
 	if pagecount < 2 {
 		count := strconv.Itoa(pagecount)
 		fmt.Println("Invalid Config Parameter Setting: [main]:per_page_count must be 2 or higher - Found " + count)
@@ -44,34 +45,15 @@ func (h *CommandHandler) Init(channelhandler *ChannelHandler) {
 	h.registry.db = h.db
 	h.registry.user = h.user
 
-}
-
-// Read function
-func (h *CommandHandler) Read(s *discordgo.Session, m *discordgo.MessageCreate) {
-
-	// Check for safety
-	if !SafeInput(s, m, h.conf) {
-		return
-	}
-
-	user, err := h.user.GetUser(m.Author.ID, s, m.ChannelID)
-	if err != nil {
-		return
-	}
-	if !user.CheckRole("admin") {
-		return
-	}
-
-	command, message := CleanCommand(m.Content, h.conf)
-
-	if command == "command" {
-		h.ReadCommand(message, s, m)
-
-	}
+	// The router can only check permissions once the registry exists
+	h.router.registry = h.registry
+	h.router.AddRoute("command", false, h.ReadCommand, "admin")
 }
 
 // ReadCommand function
-func (h *CommandHandler) ReadCommand(message []string, s *discordgo.Session, m *discordgo.MessageCreate) {
+func (h *CommandHandler) ReadCommand(input []string, user User, s DiscordSession, m *discordgo.MessageCreate) {
+
+	_, message := SplitPayload(input)
 
 	if len(message) < 1 {
 		s.ChannelMessageSend(m.ChannelID, "<command> requires an argument")
@@ -159,7 +141,7 @@ func (h *CommandHandler) ReadCommand(message []string, s *discordgo.Session, m *
 }
 
 // ReadList function
-func (h *CommandHandler) ReadList(message []string, s *discordgo.Session, m *discordgo.MessageCreate) {
+func (h *CommandHandler) ReadList(message []string, s DiscordSession, m *discordgo.MessageCreate) {
 
 	// list
 	if len(message) < 2 {
@@ -205,7 +187,7 @@ func (h *CommandHandler) ReadList(message []string, s *discordgo.Session, m *dis
 }
 
 // ReadGroups function
-func (h *CommandHandler) ReadGroups(message []string, s *discordgo.Session, m *discordgo.MessageCreate) {
+func (h *CommandHandler) ReadGroups(message []string, s DiscordSession, m *discordgo.MessageCreate) {
 	command := message[0]
 	payload := RemoveStringFromSlice(message, command)
 
@@ -273,7 +255,7 @@ func (h *CommandHandler) ReadGroups(message []string, s *discordgo.Session, m *d
 }
 
 // ReadRoles function
-func (h *CommandHandler) ReadRoles(message []string, s *discordgo.Session, m *discordgo.MessageCreate) {
+func (h *CommandHandler) ReadRoles(message []string, s DiscordSession, m *discordgo.MessageCreate) {
 	command := message[0]
 	payload := RemoveStringFromSlice(message, command)
 
@@ -368,7 +350,7 @@ func (h *CommandHandler) ReadRoles(message []string, s *discordgo.Session, m *di
 }
 
 // ReadUsers function
-func (h *CommandHandler) ReadUsers(message []string, s *discordgo.Session, m *discordgo.MessageCreate) {
+func (h *CommandHandler) ReadUsers(message []string, s DiscordSession, m *discordgo.MessageCreate) {
 	command := message[0]
 	payload := RemoveStringFromSlice(message, command)
 
@@ -450,7 +432,7 @@ func (h *CommandHandler) ReadUsers(message []string, s *discordgo.Session, m *di
 }
 
 // ReadChannels function
-func (h *CommandHandler) ReadChannels(message []string, s *discordgo.Session, m *discordgo.MessageCreate) {
+func (h *CommandHandler) ReadChannels(message []string, s DiscordSession, m *discordgo.MessageCreate) {
 	command := message[0]
 	payload := RemoveStringFromSlice(message, command)
 
@@ -557,7 +539,7 @@ func (h *CommandHandler) ReadChannels(message []string, s *discordgo.Session, m
 }
 
 // EnableCommand function
-func (h *CommandHandler) EnableCommand(message []string, s *discordgo.Session, m *discordgo.MessageCreate) {
+func (h *CommandHandler) EnableCommand(message []string, s DiscordSession, m *discordgo.MessageCreate) {
 
 	if len(message) == 1 {
 		err := h.registry.AddChannel(message[0], m.ChannelID)
@@ -595,7 +577,7 @@ func (h *CommandHandler) EnableCommand(message []string, s *discordgo.Session, m
 }
 
 // DisableCommand function
-func (h *CommandHandler) DisableCommand(message []string, s *discordgo.Session, m *discordgo.MessageCreate) {
+func (h *CommandHandler) DisableCommand(message []string, s DiscordSession, m *discordgo.MessageCreate) {
 
 	if len(message) == 1 {
 		err := h.registry.RemoveChannel(message[0], m.ChannelID)
@@ -634,7 +616,7 @@ func (h *CommandHandler) DisableCommand(message []string, s *discordgo.Session,
 }
 
 // DisplayUsage function
-func (h *CommandHandler) DisplayUsage(message []string, s *discordgo.Session, m *discordgo.MessageCreate) {
+func (h *CommandHandler) DisplayUsage(message []string, s DiscordSession, m *discordgo.MessageCreate) {
 
 	command, err := h.registry.GetCommand(message[0])
 	if err != nil {
@@ -652,7 +634,7 @@ func (h *CommandHandler) DisplayUsage(message []string, s *discordgo.Session, m
 }
 
 // DisplayDescription function
-func (h *CommandHandler) DisplayDescription(message []string, s *discordgo.Session, m *discordgo.MessageCreate) {
+func (h *CommandHandler) DisplayDescription(message []string, s DiscordSession, m *discordgo.MessageCreate) {
 
 	command, err := h.registry.GetCommand(message[0])
 	if err != nil {
@@ -670,7 +652,7 @@ func (h *CommandHandler) DisplayDescription(message []string, s *discordgo.Sessi
 }
 
 // ListCommands function
-func (h *CommandHandler) ListCommands(channelid string, page int, s *discordgo.Session, m *discordgo.MessageCreate) {
+func (h *CommandHandler) ListCommands(channelid string, page int, s DiscordSession, m *discordgo.MessageCreate) {
 
 	if channelid == "" {
 		channelid = m.ChannelID
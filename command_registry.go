@@ -292,7 +292,7 @@ func (h *CommandRegistry) CheckGroup(command string, group string) bool {
 }
 
 // CheckUserGroups function
-func (h *CommandRegistry) CheckUserGroups(command string, user User, s *discordgo.Session, m *discordgo.MessageCreate) bool {
+func (h *CommandRegistry) CheckUserGroups(command string, user User, s DiscordSession, m *discordgo.MessageCreate) bool {
 
 	groups, err := h.GetGroups(command)
 	if err != nil {
@@ -316,7 +316,7 @@ func (h *CommandRegistry) CheckUserGroups(command string, user User, s *discordg
 }
 
 // CheckUserRoles function
-func (h *CommandRegistry) CheckUserRoles(command string, user User, s *discordgo.Session, m *discordgo.MessageCreate) bool {
+func (h *CommandRegistry) CheckUserRoles(command string, user User, s DiscordSession, m *discordgo.MessageCreate) bool {
 
 	roles, err := h.GetRoles(command)
 	if err != nil {
@@ -407,7 +407,7 @@ func (h *CommandRegistry) CheckUser(command string, user string) bool {
 }
 
 // CheckPermission function
-func (h *CommandRegistry) CheckPermission(command string, user User, s *discordgo.Session, m *discordgo.MessageCreate) bool {
+func (h *CommandRegistry) CheckPermission(command string, user User, s DiscordSession, m *discordgo.MessageCreate) bool {
 
 	userpermission := false
 	if h.CheckUser(command, user.ID) {
@@ -505,8 +505,8 @@ func (h *CommandRegistry) CommandsForChannel(page int, channel string) (records
 		for _, channelID := range record.Channels {
 			if channelID == channel {
 				currentrecordcount = currentrecordcount + 1
-				if currentrecordcount > page*h.conf.MainConfig.PerPageCount {
-					if recordcount < h.conf.MainConfig.PerPageCount {
+				if currentrecordcount > page*h.conf.PageCount() {
+					if recordcount < h.conf.PageCount() {
 						records = append(records, record)
 						recordcount = recordcount + 1
 					}
@@ -563,7 +563,7 @@ func (h *CommandRegistry) CommandsForChannelPageCount(channel string) (pages int
 		return pages, errors.New("not found")
 	}
 
-	pages = (commandCount / h.conf.MainConfig.PerPageCount) + 1
+	pages = (commandCount / h.conf.PageCount()) + 1
 
 	return pages, nil
 }
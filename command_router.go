@@ -0,0 +1,162 @@
+package main
+
+/*
+The command router is the single entry point for prefixed commands.
+
+Handlers no longer watch every message themselves, instead they add a route for each command they own and the
+router takes care of parsing the message, loading the author from the database, and checking permissions once
+before handing the command off.
+
+*/
+
+import (
+	"errors"
+	"github.com/bwmarrin/discordgo"
+	"strings"
+	"sync"
+)
+
+// CommandCallback type
+// command[0] is the command name with the prefix removed, user is the already loaded author record
+type CommandCallback func(command []string, user User, s DiscordSession, m *discordgo.MessageCreate)
+
+// CommandRoute struct
+type CommandRoute struct {
+	Command  string
+	Roles    []string // Internal roles the user must hold, all of them are required
+	Registry bool     // Whether the CommandRegistry channel/group/user permissions apply
+	Callback CommandCallback
+}
+
+// CommandRouter struct
+type CommandRouter struct {
+	conf      *Config
+	registry  *CommandRegistry
+	user      *UserHandler
+	lifecycle *Lifecycle
+	metrics   *Metrics
+
+	routes      map[string]CommandRoute
+	order       []string
+	routelocker sync.RWMutex
+}
+
+// AddRoute function
+// Only one route may own a command, so dispatch never depends on the order handlers were added in
+func (h *CommandRouter) AddRoute(command string, registry bool, callback CommandCallback, roles ...string) (err error) {
+	h.routelocker.Lock()
+	defer h.routelocker.Unlock()
+
+	command = strings.ToLower(command)
+	if h.routes == nil {
+		h.routes = make(map[string]CommandRoute)
+	}
+
+	if _, exists := h.routes[command]; exists {
+		return errors.New("Route already exists for command: " + command)
+	}
+
+	h.routes[command] = CommandRoute{Command: command, Roles: roles, Registry: registry, Callback: callback}
+	h.order = append(h.order, command)
+	return nil
+}
+
+// RemoveRoute function
+func (h *CommandRouter) RemoveRoute(command string) {
+	h.routelocker.Lock()
+	defer h.routelocker.Unlock()
+
+	command = strings.ToLower(command)
+	delete(h.routes, command)
+	h.order = RemoveStringFromSlice(h.order, command)
+}
+
+// GetRoute function
+func (h *CommandRouter) GetRoute(command string) (route CommandRoute, err error) {
+	h.routelocker.RLock()
+	defer h.routelocker.RUnlock()
+
+	route, exists := h.routes[strings.ToLower(command)]
+	if !exists {
+		return route, errors.New("No route found")
+	}
+	return route, nil
+}
+
+// Routes function
+// Returns the routed command names in the order they were added
+func (h *CommandRouter) Routes() (commands []string) {
+	h.routelocker.RLock()
+	defer h.routelocker.RUnlock()
+
+	commands = make([]string, len(h.order))
+	copy(commands, h.order)
+	return commands
+}
+
+// Read function
+func (h *CommandRouter) Read(s *discordgo.Session, m *discordgo.MessageCreate) {
+
+	if !SafeInput(s, m, h.conf) {
+		return
+	}
+
+	h.Dispatch(s, m)
+}
+
+// Dispatch function
+// Parses a message once and runs the matching route, returns false if nothing was run
+func (h *CommandRouter) Dispatch(s DiscordSession, m *discordgo.MessageCreate) (dispatched bool) {
+
+	cp := h.conf.CommandPrefix()
+	if !strings.HasPrefix(m.Content, cp) {
+		return false
+	}
+
+	command := strings.Fields(strings.TrimPrefix(m.Content, cp))
+	if len(command) < 1 {
+		return false
+	}
+	command[0] = strings.ToLower(command[0])
+
+	route, err := h.GetRoute(command[0])
+	if err != nil {
+		s.ChannelMessageSend(m.ChannelID, ":question: Unknown command: "+cp+command[0]+
+			" - see "+cp+"help for a list of commands.")
+		return false
+	}
+
+	// Commands can write to the database and change roles, so they have to finish before we shut down
+	if h.lifecycle != nil {
+		done, ok := h.lifecycle.Track()
+		if !ok {
+			s.ChannelMessageSend(m.ChannelID, ":warning: The bot is shutting down, please try again shortly.")
+			return false
+		}
+		defer done()
+	}
+
+	// This also creates the user record if it doesn't exist yet
+	user, err := h.user.GetUser(m.Author.ID, s, m.ChannelID)
+	if err != nil {
+		return false
+	}
+
+	for _, role := range route.Roles {
+		if !user.CheckRole(role) {
+			return false
+		}
+	}
+
+	if route.Registry {
+		if !h.registry.CheckPermission(route.Command, user, s, m) {
+			return false
+		}
+	}
+
+	if h.metrics != nil {
+		h.metrics.CommandDispatched(route.Command)
+	}
+	route.Callback(command, user, s, m)
+	return true
+}
@@ -1,8 +1,11 @@
 package main
 
 import (
+	"errors"
 	"fmt"
 	"github.com/BurntSushi/toml"
+	"strings"
+	"sync"
 	"time"
 )
 
@@ -24,10 +27,38 @@ type mainConfig struct {
 	CP             string        `toml:"default_command_prefix"`
 	Playing        string        `toml:"default_now_playing"`
 	Notifications  time.Duration `toml:"notifications_update_timeout"`
+	CallbackTTL    time.Duration `toml:"callback_timeout"` // In minutes, how long a prompt waits for an answer
+	TravelPace     time.Duration `toml:"auto_travel_pace"` // In seconds, the pause between steps of "travel to"
 	PerPageCount   int           `toml:"per_page_count"`
 	LuaTimeout     int           `toml:"lua_timeout"`
 	Profiler       bool          `toml:"enable_profiler"`
+	HTTPListen     string        `toml:"http_listen"` // ie 127.0.0.1:8080, empty disables the http server
 	DBFile         string        `toml:"dbfilename"`
+
+	// Movement, see stamina.go. The cooldown and regeneration interval are in seconds
+	TravelCooldown       time.Duration `toml:"travel_cooldown"`
+	TravelCost           int64         `toml:"travel_stamina_cost"` // Used by exits that don't set their own cost, 0 is free
+	StaminaMax           int64         `toml:"stamina_max"`
+	StaminaRegen         int64         `toml:"stamina_regen"` // Points regained every stamina_regen_interval, 0 is none
+	StaminaRegenInterval time.Duration `toml:"stamina_regen_interval"`
+	RecallCooldown       time.Duration `toml:"recall_cooldown"` // In minutes
+
+	// How long to wait (in seconds) for workers and in-flight commands to finish when shutting down
+	ShutdownTimeout time.Duration `toml:"shutdown_timeout"`
+
+	// Backups, the interval is in hours and 0 disables scheduled backups
+	BackupDir       string        `toml:"backup_dir"`
+	BackupInterval  time.Duration `toml:"backup_interval"`
+	BackupRetention int           `toml:"backup_retention"`
+
+	// Logging, levels are debug, info, warn or error
+	LogLevel        string `toml:"log_level"`
+	LogFile         string `toml:"log_file"`
+	LogFileLevel    string `toml:"log_file_level"`
+	LogFileMaxSize  int    `toml:"log_file_max_size"` // In megabytes
+	LogFileBackups  int    `toml:"log_file_backups"`
+	DiscordLogLevel string `toml:"discord_log_level"`
+	LogBufferSize   int    `toml:"log_buffer_size"`
 }
 
 // bankConfig struct
@@ -43,14 +74,338 @@ type bankConfig struct {
 	BankMenuSlogan         string `toml:"bank_menu_slogan"`
 }
 
+// configlocker guards the settings that can be swapped out by a reload, anything read from more than one goroutine
+// after startup should go through the accessors below instead of reading MainConfig directly
+var configlocker sync.RWMutex
+
 // ReadConfig function
 func ReadConfig(path string) (config Config, err error) {
 
 	var conf Config
+	conf.PresetDefaults()
 	if _, err := toml.DecodeFile(path, &conf); err != nil {
 		fmt.Println(err)
 		return conf, err
 	}
 
+	conf.ApplyDefaults()
+	err = conf.Validate()
+	if err != nil {
+		return conf, err
+	}
+
 	return conf, nil
 }
+
+// PresetDefaults function
+// Defaults for settings where 0 is a real choice (free travel, no regeneration). These are set before the file is
+// decoded so only a missing key gets the default, ApplyDefaults can't tell 0 apart from unset.
+func (c *Config) PresetDefaults() {
+	c.MainConfig.TravelCost = 1
+	c.MainConfig.StaminaRegen = 1
+}
+
+// ApplyDefaults function
+func (c *Config) ApplyDefaults() {
+
+	if c.MainConfig.BotName == "" {
+		c.MainConfig.BotName = "Aetheral"
+	}
+	if c.MainConfig.CP == "" {
+		c.MainConfig.CP = "~"
+	}
+	if c.MainConfig.PerPageCount == 0 {
+		c.MainConfig.PerPageCount = 10
+	}
+	if c.MainConfig.Notifications == 0 {
+		c.MainConfig.Notifications = 1
+	}
+	if c.MainConfig.LuaTimeout == 0 {
+		c.MainConfig.LuaTimeout = 5
+	}
+	if c.MainConfig.CallbackTTL == 0 {
+		c.MainConfig.CallbackTTL = 10
+	}
+	if c.MainConfig.TravelPace == 0 {
+		c.MainConfig.TravelPace = 3
+	}
+	if c.MainConfig.TravelCooldown == 0 {
+		c.MainConfig.TravelCooldown = 2
+	}
+	if c.MainConfig.StaminaMax == 0 {
+		c.MainConfig.StaminaMax = 100
+	}
+	if c.MainConfig.StaminaRegenInterval == 0 {
+		c.MainConfig.StaminaRegenInterval = 30
+	}
+	if c.MainConfig.RecallCooldown == 0 {
+		c.MainConfig.RecallCooldown = 60
+	}
+	if c.MainConfig.ShutdownTimeout == 0 {
+		c.MainConfig.ShutdownTimeout = 30
+	}
+	if c.MainConfig.BackupDir == "" {
+		c.MainConfig.BackupDir = "backups"
+	}
+	if c.MainConfig.BackupInterval > 0 && c.MainConfig.BackupRetention == 0 {
+		c.MainConfig.BackupRetention = 7
+	}
+}
+
+// Validate function
+// Checks everything at once so a bad config file can be fixed in one pass
+func (c *Config) Validate() error {
+
+	var problems []string
+
+	required := []struct {
+		key   string
+		value string
+	}{
+		{"bot_token", c.MainConfig.Token},
+		{"cluster_owner_id", c.MainConfig.ClusterOwnerID},
+		{"central_Server_id", c.MainConfig.CentralGuildID},
+		{"lobby_channel_id", c.MainConfig.LobbyChannelID},
+		{"dbfilename", c.MainConfig.DBFile},
+	}
+	for _, setting := range required {
+		if strings.TrimSpace(setting.value) == "" {
+			problems = append(problems, setting.key+" is required")
+		}
+	}
+
+	if strings.ContainsAny(c.MainConfig.CP, " \t\n") {
+		problems = append(problems, "default_command_prefix cannot contain whitespace")
+	}
+	if c.MainConfig.PerPageCount < 1 {
+		problems = append(problems, "per_page_count must be at least 1")
+	}
+	if c.MainConfig.Notifications < 1 {
+		problems = append(problems, "notifications_update_timeout must be at least 1 minute")
+	}
+	if c.MainConfig.LuaTimeout < 1 {
+		problems = append(problems, "lua_timeout must be at least 1")
+	}
+	if c.MainConfig.CallbackTTL < 1 {
+		problems = append(problems, "callback_timeout must be at least 1 minute")
+	}
+	if c.MainConfig.TravelPace < 1 {
+		problems = append(problems, "auto_travel_pace must be at least 1 second")
+	}
+	if c.MainConfig.TravelCooldown < 1 {
+		problems = append(problems, "travel_cooldown must be at least 1 second")
+	}
+	if c.MainConfig.TravelCost < 0 {
+		problems = append(problems, "travel_stamina_cost cannot be negative (0 makes travel free)")
+	}
+	if c.MainConfig.StaminaMax < 1 {
+		problems = append(problems, "stamina_max must be at least 1")
+	}
+	if c.MainConfig.StaminaRegen < 0 {
+		problems = append(problems, "stamina_regen cannot be negative (0 turns regeneration off)")
+	}
+	if c.MainConfig.StaminaRegenInterval < 1 {
+		problems = append(problems, "stamina_regen_interval must be at least 1 second")
+	}
+	if c.MainConfig.RecallCooldown < 1 {
+		problems = append(problems, "recall_cooldown must be at least 1 minute")
+	}
+	if c.MainConfig.ShutdownTimeout < 0 {
+		problems = append(problems, "shutdown_timeout cannot be negative")
+	}
+	if c.MainConfig.BackupInterval < 0 {
+		problems = append(problems, "backup_interval cannot be negative")
+	}
+	if c.MainConfig.BackupRetention < 0 {
+		problems = append(problems, "backup_retention cannot be negative")
+	}
+	if c.MainConfig.LogFileMaxSize < 0 {
+		problems = append(problems, "log_file_max_size cannot be negative")
+	}
+	if c.MainConfig.LogFileBackups < 0 {
+		problems = append(problems, "log_file_backups cannot be negative")
+	}
+	if c.MainConfig.LogBufferSize < 0 {
+		problems = append(problems, "log_buffer_size cannot be negative")
+	}
+
+	levels := []struct {
+		key   string
+		value string
+	}{
+		{"log_level", c.MainConfig.LogLevel},
+		{"log_file_level", c.MainConfig.LogFileLevel},
+		{"discord_log_level", c.MainConfig.DiscordLogLevel},
+	}
+	for _, setting := range levels {
+		if _, err := ParseLogLevel(setting.value); err != nil {
+			problems = append(problems, setting.key+": "+err.Error())
+		}
+	}
+
+	if len(problems) > 0 {
+		return errors.New("Invalid config:\n - " + strings.Join(problems, "\n - "))
+	}
+	return nil
+}
+
+// Reload function
+// Reads the config file again and swaps in the settings that are safe to change while running. Anything else that
+// differs is returned in ignored, those need a restart to take effect.
+func (c *Config) Reload(path string) (changed []string, ignored []string, err error) {
+
+	conf, err := ReadConfig(path)
+	if err != nil {
+		return changed, ignored, err
+	}
+
+	structural := []struct {
+		key      string
+		old, new interface{}
+	}{
+		{"bot_token", c.MainConfig.Token, conf.MainConfig.Token},
+		{"bot_name", c.MainConfig.BotName, conf.MainConfig.BotName},
+		{"cluster_owner_id", c.MainConfig.ClusterOwnerID, conf.MainConfig.ClusterOwnerID},
+		{"central_Server_id", c.MainConfig.CentralGuildID, conf.MainConfig.CentralGuildID},
+		{"lobby_channel_id", c.MainConfig.LobbyChannelID, conf.MainConfig.LobbyChannelID},
+		{"dbfilename", c.MainConfig.DBFile, conf.MainConfig.DBFile},
+		{"enable_profiler", c.MainConfig.Profiler, conf.MainConfig.Profiler},
+		{"http_listen", c.MainConfig.HTTPListen, conf.MainConfig.HTTPListen},
+		{"shutdown_timeout", c.MainConfig.ShutdownTimeout, conf.MainConfig.ShutdownTimeout},
+		{"lua_timeout", c.MainConfig.LuaTimeout, conf.MainConfig.LuaTimeout},
+		{"backup_dir", c.MainConfig.BackupDir, conf.MainConfig.BackupDir},
+		{"backup_interval", c.MainConfig.BackupInterval, conf.MainConfig.BackupInterval},
+		{"backup_retention", c.MainConfig.BackupRetention, conf.MainConfig.BackupRetention},
+		{"log_level", c.MainConfig.LogLevel, conf.MainConfig.LogLevel},
+		{"log_file", c.MainConfig.LogFile, conf.MainConfig.LogFile},
+		{"log_file_level", c.MainConfig.LogFileLevel, conf.MainConfig.LogFileLevel},
+		{"log_file_max_size", c.MainConfig.LogFileMaxSize, conf.MainConfig.LogFileMaxSize},
+		{"log_file_backups", c.MainConfig.LogFileBackups, conf.MainConfig.LogFileBackups},
+		{"discord_log_level", c.MainConfig.DiscordLogLevel, conf.MainConfig.DiscordLogLevel},
+		{"log_buffer_size", c.MainConfig.LogBufferSize, conf.MainConfig.LogBufferSize},
+		{"bank", c.BankConfig, conf.BankConfig},
+	}
+	for _, setting := range structural {
+		if setting.old != setting.new {
+			ignored = append(ignored, setting.key)
+		}
+	}
+
+	configlocker.Lock()
+	defer configlocker.Unlock()
+
+	if c.MainConfig.CP != conf.MainConfig.CP {
+		c.MainConfig.CP = conf.MainConfig.CP
+		changed = append(changed, "default_command_prefix")
+	}
+	if c.MainConfig.Playing != conf.MainConfig.Playing {
+		c.MainConfig.Playing = conf.MainConfig.Playing
+		changed = append(changed, "default_now_playing")
+	}
+	if c.MainConfig.PerPageCount != conf.MainConfig.PerPageCount {
+		c.MainConfig.PerPageCount = conf.MainConfig.PerPageCount
+		changed = append(changed, "per_page_count")
+	}
+	if c.MainConfig.Notifications != conf.MainConfig.Notifications {
+		c.MainConfig.Notifications = conf.MainConfig.Notifications
+		changed = append(changed, "notifications_update_timeout")
+	}
+	if c.MainConfig.CallbackTTL != conf.MainConfig.CallbackTTL {
+		c.MainConfig.CallbackTTL = conf.MainConfig.CallbackTTL
+		changed = append(changed, "callback_timeout")
+	}
+	if c.MainConfig.TravelPace != conf.MainConfig.TravelPace {
+		c.MainConfig.TravelPace = conf.MainConfig.TravelPace
+		changed = append(changed, "auto_travel_pace")
+	}
+	if c.MainConfig.TravelCooldown != conf.MainConfig.TravelCooldown {
+		c.MainConfig.TravelCooldown = conf.MainConfig.TravelCooldown
+		changed = append(changed, "travel_cooldown")
+	}
+	if c.MainConfig.TravelCost != conf.MainConfig.TravelCost {
+		c.MainConfig.TravelCost = conf.MainConfig.TravelCost
+		changed = append(changed, "travel_stamina_cost")
+	}
+	if c.MainConfig.StaminaMax != conf.MainConfig.StaminaMax {
+		c.MainConfig.StaminaMax = conf.MainConfig.StaminaMax
+		changed = append(changed, "stamina_max")
+	}
+	if c.MainConfig.StaminaRegen != conf.MainConfig.StaminaRegen {
+		c.MainConfig.StaminaRegen = conf.MainConfig.StaminaRegen
+		changed = append(changed, "stamina_regen")
+	}
+	if c.MainConfig.StaminaRegenInterval != conf.MainConfig.StaminaRegenInterval {
+		c.MainConfig.StaminaRegenInterval = conf.MainConfig.StaminaRegenInterval
+		changed = append(changed, "stamina_regen_interval")
+	}
+	if c.MainConfig.RecallCooldown != conf.MainConfig.RecallCooldown {
+		c.MainConfig.RecallCooldown = conf.MainConfig.RecallCooldown
+		changed = append(changed, "recall_cooldown")
+	}
+
+	return changed, ignored, nil
+}
+
+// CommandPrefix function
+func (c *Config) CommandPrefix() string {
+	configlocker.RLock()
+	defer configlocker.RUnlock()
+	return c.MainConfig.CP
+}
+
+// NowPlaying function
+func (c *Config) NowPlaying() string {
+	configlocker.RLock()
+	defer configlocker.RUnlock()
+	return c.MainConfig.Playing
+}
+
+// PageCount function
+func (c *Config) PageCount() int {
+	configlocker.RLock()
+	defer configlocker.RUnlock()
+	return c.MainConfig.PerPageCount
+}
+
+// NotificationInterval function
+func (c *Config) NotificationInterval() time.Duration {
+	configlocker.RLock()
+	defer configlocker.RUnlock()
+	return c.MainConfig.Notifications * time.Minute
+}
+
+// CallbackTTL function
+func (c *Config) CallbackTTL() time.Duration {
+	configlocker.RLock()
+	defer configlocker.RUnlock()
+	return c.MainConfig.CallbackTTL * time.Minute
+}
+
+// TravelPace function
+func (c *Config) TravelPace() time.Duration {
+	configlocker.RLock()
+	defer configlocker.RUnlock()
+	return c.MainConfig.TravelPace * time.Second
+}
+
+// TravelCooldown function
+func (c *Config) TravelCooldown() time.Duration {
+	configlocker.RLock()
+	defer configlocker.RUnlock()
+	return c.MainConfig.TravelCooldown * time.Second
+}
+
+// Stamina function
+func (c *Config) Stamina() StaminaSettings {
+	configlocker.RLock()
+	defer configlocker.RUnlock()
+	return StaminaSettings{Max: c.MainConfig.StaminaMax, Regen: c.MainConfig.StaminaRegen,
+		Interval: c.MainConfig.StaminaRegenInterval * time.Second, TravelCost: c.MainConfig.TravelCost}
+}
+
+// RecallCooldown function
+func (c *Config) RecallCooldown() time.Duration {
+	configlocker.RLock()
+	defer configlocker.RUnlock()
+	return c.MainConfig.RecallCooldown * time.Minute
+}
@@ -0,0 +1,111 @@
+package main
+
+import (
+	"context"
+	"github.com/bwmarrin/discordgo"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+)
+
+// ConfigHandler struct
+type ConfigHandler struct {
+	conf     *Config
+	path     string
+	registry *CommandRegistry
+	router   *CommandRouter
+	logger   *Logger
+	dg       DiscordSession
+}
+
+// Init function
+func (h *ConfigHandler) Init() {
+	h.RegisterCommands()
+}
+
+// RegisterCommands function
+func (h *ConfigHandler) RegisterCommands() (err error) {
+
+	h.registry.Register("config", "Manage the bot configuration", "reload")
+	h.router.AddRoute("config", false, h.ParseCommand, "owner")
+	return nil
+
+}
+
+// ParseCommand function
+func (h *ConfigHandler) ParseCommand(command []string, user User, s DiscordSession, m *discordgo.MessageCreate) {
+
+	if len(command) < 2 {
+		s.ChannelMessageSend(m.ChannelID, "Expected flag for 'config' command, see usage for more info")
+		return
+	}
+
+	if command[1] == "reload" {
+		changed, ignored, err := h.ReloadConfig(s)
+		if err != nil {
+			s.ChannelMessageSend(m.ChannelID, "Config was not reloaded: "+err.Error())
+			return
+		}
+		s.ChannelMessageSend(m.ChannelID, h.FormatReload(changed, ignored))
+		return
+	}
+
+	s.ChannelMessageSend(m.ChannelID, "Unrecognized flag for 'config' command: "+command[1])
+}
+
+// ReloadConfig function
+func (h *ConfigHandler) ReloadConfig(s DiscordSession) (changed []string, ignored []string, err error) {
+
+	changed, ignored, err = h.conf.Reload(h.path)
+	if err != nil {
+		h.logger.Error(BOTLOG, LogFields{Command: "config"}, "Config reload failed: "+err.Error())
+		return changed, ignored, err
+	}
+
+	for _, setting := range changed {
+		if setting == "default_now_playing" {
+			err = s.UpdateStatus(0, h.conf.NowPlaying())
+			if err != nil {
+				h.logger.Warn(BOTLOG, LogFields{Command: "config"}, "Could not update now playing: "+err.Error())
+			}
+		}
+	}
+
+	h.logger.Info(BOTLOG, LogFields{Command: "config"}, "Config reloaded, changed: "+strings.Join(changed, ", ")+
+		" | needs restart: "+strings.Join(ignored, ", "))
+	return changed, ignored, nil
+}
+
+// FormatReload function
+func (h *ConfigHandler) FormatReload(changed []string, ignored []string) (formatted string) {
+
+	if len(changed) == 0 {
+		formatted = "Config reloaded, nothing changed."
+	} else {
+		formatted = "Config reloaded, updated: " + strings.Join(changed, ", ")
+	}
+	if len(ignored) > 0 {
+		formatted = formatted + "\nThese settings changed but need a restart to take effect: " + strings.Join(ignored, ", ")
+	}
+	return formatted
+}
+
+// WatchSignals function
+// Reloads the config every time a SIGHUP comes in
+func (h *ConfigHandler) WatchSignals(ctx context.Context) error {
+
+	hup := make(chan os.Signal, 1)
+	signal.Notify(hup, syscall.SIGHUP)
+	defer signal.Stop(hup)
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-hup:
+			Heartbeat(ctx)
+			h.ReloadConfig(h.dg)
+		}
+	}
+}
@@ -12,7 +12,7 @@ type DBHandler struct {
 }
 
 // FirstTimeSetup function
-func (h *DBHandler) FirstTimeSetup() error {
+func (h *DBHandler) FirstTimeSetup(dryrun bool) error {
 
 	//	usermanager.ID = h.conf.DiscordConfig.AdminID
 
@@ -48,6 +48,12 @@ func (h *DBHandler) FirstTimeSetup() error {
 			}
 		}
 	*/
+
+	// Bring the schema up to date before anything else touches the database
+	err := h.Migrate(dryrun)
+	if err != nil {
+		return err
+	}
 	return nil
 }
 
@@ -0,0 +1,359 @@
+package main
+
+/*
+The discord queue serializes role and channel mutations per guild.
+
+Each guild gets its own worker (started through the lifecycle manager) so a large sync in one guild never holds up
+travel in another. Within a guild higher priority actions always go first, so players moving between rooms are not
+stuck behind a bulk sync. Actions that fail with a 429 or a 5xx are retried with an exponential backoff.
+
+Pending actions are de-duplicated by key: adding and then removing the same role cancels out, queuing the same
+role change twice runs it once, and a newer permission overwrite for the same channel and target replaces the one
+that hasn't run yet.
+
+*/
+
+import (
+	"context"
+	"errors"
+	"github.com/bwmarrin/discordgo"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// QueueLow const for queue priorities
+const (
+	QueueLow = iota
+	QueueNormal
+	QueueHigh
+)
+
+// QueueAction struct
+type QueueAction struct {
+	GuildID  string
+	Kind     string
+	Key      string // Pending actions with the same key are de-duplicated, empty means never
+	Priority int
+	Run      func() error
+
+	queued  time.Time
+	waiters []chan error
+}
+
+// guildQueue struct
+type guildQueue struct {
+	guildID string
+	pending []*QueueAction
+	running *QueueAction
+	wake    chan bool
+
+	processed int
+	failed    int
+	retried   int
+	collapsed int
+	lasterror string
+}
+
+// GuildQueueStatus struct
+type GuildQueueStatus struct {
+	GuildID   string
+	Pending   [3]int // Indexed by priority
+	Running   string
+	Oldest    time.Duration // How long the oldest pending action has been waiting
+	Processed int
+	Failed    int
+	Retried   int
+	Collapsed int
+	LastError string
+}
+
+// DiscordQueue struct
+type DiscordQueue struct {
+	lifecycle *Lifecycle
+	logger    *Logger
+	metrics   *Metrics
+
+	MaxRetries int
+	Spacing    time.Duration // Minimum time between two actions in the same guild
+
+	guilds      map[string]*guildQueue
+	queuelocker sync.Mutex
+}
+
+// Init function
+func (h *DiscordQueue) Init() {
+	h.guilds = make(map[string]*guildQueue)
+	if h.MaxRetries == 0 {
+		h.MaxRetries = 5
+	}
+	if h.Spacing == 0 {
+		h.Spacing = time.Millisecond * 250
+	}
+}
+
+// RoleAdd function
+func (h *DiscordQueue) RoleAdd(s DiscordSession, guildID string, userID string, roleID string, priority int) <-chan error {
+	return h.Enqueue(&QueueAction{GuildID: guildID, Kind: "role-add", Key: "role:" + userID + ":" + roleID,
+		Priority: priority, Run: func() error { return s.GuildMemberRoleAdd(guildID, userID, roleID) }})
+}
+
+// RoleRemove function
+func (h *DiscordQueue) RoleRemove(s DiscordSession, guildID string, userID string, roleID string, priority int) <-chan error {
+	return h.Enqueue(&QueueAction{GuildID: guildID, Kind: "role-remove", Key: "role:" + userID + ":" + roleID,
+		Priority: priority, Run: func() error { return s.GuildMemberRoleRemove(guildID, userID, roleID) }})
+}
+
+// PermissionSet function
+func (h *DiscordQueue) PermissionSet(s DiscordSession, guildID string, channelID string, targetID string,
+	targetType string, allow int, deny int, priority int) <-chan error {
+	return h.Enqueue(&QueueAction{GuildID: guildID, Kind: "permission-set", Key: "permission:" + channelID + ":" + targetID,
+		Priority: priority, Run: func() error { return s.ChannelPermissionSet(channelID, targetID, targetType, allow, deny) }})
+}
+
+// ChannelEdit function
+// Edits are never de-duplicated since two edits may change different fields
+func (h *DiscordQueue) ChannelEdit(s DiscordSession, guildID string, channelID string, data *discordgo.ChannelEdit,
+	priority int) <-chan error {
+	return h.Enqueue(&QueueAction{GuildID: guildID, Kind: "channel-edit", Priority: priority,
+		Run: func() error {
+			_, err := s.ChannelEditComplex(channelID, data)
+			return err
+		}})
+}
+
+// Enqueue function
+// Returns a channel that receives the result of the action once it has run (or been collapsed into another one)
+func (h *DiscordQueue) Enqueue(action *QueueAction) <-chan error {
+	result := make(chan error, 1)
+	action.waiters = []chan error{result}
+	action.queued = time.Now()
+
+	err := validatePriority(action.Priority)
+	if err != nil {
+		result <- err
+		return result
+	}
+
+	h.queuelocker.Lock()
+	defer h.queuelocker.Unlock()
+
+	queue, err := h.getGuildQueue(action.GuildID)
+	if err != nil {
+		result <- err
+		return result
+	}
+
+	if action.Key != "" {
+		for i, existing := range queue.pending {
+			if existing.Key != action.Key {
+				continue
+			}
+
+			if existing.Kind != action.Kind && (existing.Kind == "role-add" || existing.Kind == "role-remove") {
+				// An add and a remove of the same role cancel each other out
+				queue.pending = append(queue.pending[:i], queue.pending[i+1:]...)
+				queue.collapsed = queue.collapsed + 2
+				existing.waiters = append(existing.waiters, action.waiters...)
+				notifyWaiters(existing.waiters, nil)
+				return result
+			}
+
+			// Otherwise the newest version of the action wins, and everyone waiting gets its result
+			existing.Kind = action.Kind
+			existing.Run = action.Run
+			existing.waiters = append(existing.waiters, action.waiters...)
+			if action.Priority > existing.Priority {
+				existing.Priority = action.Priority
+			}
+			queue.collapsed++
+			return result
+		}
+	}
+
+	queue.pending = append(queue.pending, action)
+	select {
+	case queue.wake <- true:
+	default:
+	}
+	return result
+}
+
+// Report function
+// For actions nobody waits on, logs a failure along with what the caller was doing
+func (h *DiscordQueue) Report(result <-chan error, fields LogFields, message string) {
+	go func() {
+		err := <-result
+		if err != nil && h.logger != nil {
+			h.logger.Warn(BOTLOG, fields, message+": "+err.Error())
+		}
+	}()
+}
+
+// getGuildQueue function
+// Must be called with queuelocker held, starts the worker for a guild the first time it is seen
+func (h *DiscordQueue) getGuildQueue(guildID string) (queue *guildQueue, err error) {
+	queue, ok := h.guilds[guildID]
+	if ok {
+		return queue, nil
+	}
+
+	queue = &guildQueue{guildID: guildID, wake: make(chan bool, 1)}
+	err = h.lifecycle.Start("queue:"+guildID, func(ctx context.Context) error {
+		return h.runGuildQueue(ctx, queue)
+	})
+	if err != nil {
+		return queue, err
+	}
+	h.guilds[guildID] = queue
+	return queue, nil
+}
+
+// runGuildQueue function
+func (h *DiscordQueue) runGuildQueue(ctx context.Context, queue *guildQueue) error {
+
+	for {
+		action := h.nextAction(queue)
+		if action == nil {
+			select {
+			case <-ctx.Done():
+				return nil
+			case <-queue.wake:
+				continue
+			}
+		}
+
+		Heartbeat(ctx)
+		err := h.runAction(ctx, queue, action)
+
+		h.queuelocker.Lock()
+		queue.running = nil
+		queue.processed++
+		if err != nil {
+			queue.failed++
+			queue.lasterror = action.Kind + ": " + err.Error()
+		}
+		h.queuelocker.Unlock()
+
+		notifyWaiters(action.waiters, err)
+		if err != nil {
+			h.logger.Warn(BOTLOG, LogFields{Guild: queue.guildID}, "Queued "+action.Kind+" failed: "+err.Error())
+		}
+
+		// Once we're shutting down we still drain what's pending, just without the spacing
+		if ctx.Err() == nil {
+			SleepContext(ctx, h.Spacing)
+		}
+	}
+}
+
+// nextAction function
+// Pops the oldest of the highest priority pending actions
+func (h *DiscordQueue) nextAction(queue *guildQueue) (action *QueueAction) {
+	h.queuelocker.Lock()
+	defer h.queuelocker.Unlock()
+
+	index := -1
+	for i, pending := range queue.pending {
+		if index < 0 || pending.Priority > queue.pending[index].Priority {
+			index = i
+		}
+	}
+	if index < 0 {
+		return nil
+	}
+
+	action = queue.pending[index]
+	queue.pending = append(queue.pending[:index], queue.pending[index+1:]...)
+	queue.running = action
+	return action
+}
+
+// runAction function
+func (h *DiscordQueue) runAction(ctx context.Context, queue *guildQueue, action *QueueAction) (err error) {
+
+	for attempt := 0; ; attempt++ {
+		err = action.Run()
+		if err != nil && h.metrics != nil {
+			h.metrics.DiscordAPIError()
+		}
+		if err == nil || !isRetryableDiscordError(err) || attempt >= h.MaxRetries {
+			return err
+		}
+
+		h.queuelocker.Lock()
+		queue.retried++
+		h.queuelocker.Unlock()
+
+		// 1s, 2s, 4s ... capped at 30s
+		backoff := time.Second << uint(attempt)
+		if backoff > time.Second*30 {
+			backoff = time.Second * 30
+		}
+		if !SleepContext(ctx, backoff) {
+			return err
+		}
+	}
+}
+
+// isRetryableDiscordError function
+func isRetryableDiscordError(err error) bool {
+	resterr, ok := err.(*discordgo.RESTError)
+	if !ok || resterr.Response == nil {
+		return false
+	}
+	return resterr.Response.StatusCode == 429 || resterr.Response.StatusCode >= 500
+}
+
+// notifyWaiters function
+func notifyWaiters(waiters []chan error, err error) {
+	for _, waiter := range waiters {
+		waiter <- err
+	}
+}
+
+// Status function
+func (h *DiscordQueue) Status() (statuses []GuildQueueStatus) {
+	h.queuelocker.Lock()
+	defer h.queuelocker.Unlock()
+
+	for _, queue := range h.guilds {
+		status := GuildQueueStatus{GuildID: queue.guildID, Processed: queue.processed, Failed: queue.failed,
+			Retried: queue.retried, Collapsed: queue.collapsed, LastError: queue.lasterror}
+		for _, action := range queue.pending {
+			status.Pending[action.Priority]++
+			if wait := time.Since(action.queued); wait > status.Oldest {
+				status.Oldest = wait
+			}
+		}
+		if queue.running != nil {
+			status.Running = queue.running.Kind
+		}
+		statuses = append(statuses, status)
+	}
+
+	sort.Slice(statuses, func(i, j int) bool { return statuses[i].GuildID < statuses[j].GuildID })
+	return statuses
+}
+
+// Backlog function
+// Returns the number of actions waiting in a guild
+func (h *DiscordQueue) Backlog(guildID string) (count int) {
+	h.queuelocker.Lock()
+	defer h.queuelocker.Unlock()
+
+	queue, ok := h.guilds[guildID]
+	if !ok {
+		return 0
+	}
+	return len(queue.pending)
+}
+
+// validatePriority function
+func validatePriority(priority int) error {
+	if priority < QueueLow || priority > QueueHigh {
+		return errors.New("Invalid queue priority: " + strconv.Itoa(priority))
+	}
+	return nil
+}
@@ -2,6 +2,7 @@ package main
 
 import (
 	"errors"
+	"github.com/asdine/storm"
 	"sync"
 )
 
@@ -16,7 +17,7 @@ type Event struct {
 	ID string `json:"id"`
 
 	ChannelID    string `json:"rooms"`
-	UserAttached string `json:"AttachedUserId"` // The ID of a user if the event is tied to one through a cycle count
+	UserAttached string `json:"AttachedUserId" storm:"index"` // The ID of a user if the event is tied to one through a cycle count
 
 	Type      string   `json:"type"`
 	TypeFlags []string `json:"typeflags"`
@@ -74,25 +75,24 @@ func (h *EventsDB) RemoveEventByID(EventID string) (err error) {
 
 // GetEventByID function
 func (h *EventsDB) GetEventByID(EventID string) (Event Event, err error) {
+	h.querylocker.RLock()
+	defer h.querylocker.RUnlock()
 
-	Events, err := h.GetAllEvents()
+	db := h.db.rawdb.From("Events")
+	err = db.One("ID", EventID, &Event)
 	if err != nil {
-		return Event, err
-	}
-
-	for _, record := range Events {
-
-		if EventID == record.ID {
-			return record, nil
+		if err == storm.ErrNotFound {
+			return Event, errors.New("No record found")
 		}
+		return Event, err
 	}
-	return Event, errors.New("No record found")
+	return Event, nil
 }
 
 // GetAllEvents function
 func (h *EventsDB) GetAllEvents() (Eventlist []Event, err error) {
-	h.querylocker.Lock()
-	defer h.querylocker.Unlock()
+	h.querylocker.RLock()
+	defer h.querylocker.RUnlock()
 
 	db := h.db.rawdb.From("Events")
 	err = db.All(&Eventlist)
@@ -105,18 +105,16 @@ func (h *EventsDB) GetAllEvents() (Eventlist []Event, err error) {
 
 // GetEventByAttached function
 func (h *EventsDB) GetEventByAttached(EventID string, UserID string) (Event Event, err error) {
+	h.querylocker.RLock()
+	defer h.querylocker.RUnlock()
 
-	Events, err := h.GetAllEvents()
+	db := h.db.rawdb.From("Events")
+	err = db.One("UserAttached", EventID+"-"+UserID, &Event)
 	if err != nil {
-		return Event, err
-	}
-
-	searchstring := EventID + "-" + UserID
-	for _, record := range Events {
-
-		if searchstring == record.UserAttached {
-			return record, nil
+		if err == storm.ErrNotFound {
+			return Event, errors.New("No record found")
 		}
+		return Event, err
 	}
-	return Event, errors.New("No record found")
+	return Event, nil
 }
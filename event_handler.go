@@ -17,13 +17,15 @@ import (
 type EventHandler struct {
 	conf     *Config
 	registry *CommandRegistry
+	router   *CommandRouter
 	callback *CallbackHandler
 	db       *DBHandler
 	user     *UserHandler
 
 	WatchList list.List
-	dg        *discordgo.Session
+	dg        DiscordSession
 	logger    *Logger
+	metrics   *Metrics
 
 	eventsdb *EventsDB
 	parser   *EventParser
@@ -56,44 +58,11 @@ func (h *EventHandler) Init() (err error) {
 func (h *EventHandler) RegisterCommand() {
 	h.registry.Register("events", "Manage events", "add|remove|list|info|enabled|disable|listenabled")
 	h.registry.AddGroup("events", "builder")
-}
-
-// Read function
-func (h *EventHandler) Read(s *discordgo.Session, m *discordgo.MessageCreate) {
-	cp := h.conf.MainConfig.CP
-
-	if !SafeInput(s, m, h.conf) {
-		return
-	}
-
-	user, err := h.db.GetUser(m.Author.ID)
-	if err != nil {
-		//fmt.Println("Error finding usermanager")
-		return
-	}
-
-	if strings.HasPrefix(m.Content, cp+"events") {
-		if h.registry.CheckPermission("events", user, s, m) {
-
-			command := strings.Fields(m.Content)
-
-			// Grab our sender ID to verify if this usermanager has permission to use this command
-			db := h.db.rawdb.From("Users")
-			var user User
-			err := db.One("ID", m.Author.ID, &user)
-			if err != nil {
-				fmt.Println("error retrieving usermanager:" + m.Author.ID)
-			}
-
-			if user.CheckRole("builder") {
-				h.ParseCommand(command, s, m)
-			}
-		}
-	}
+	h.router.AddRoute("events", true, h.ParseCommand, "builder")
 }
 
 // ParseCommand function
-func (h *EventHandler) ParseCommand(input []string, s *discordgo.Session, m *discordgo.MessageCreate) {
+func (h *EventHandler) ParseCommand(input []string, user User, s DiscordSession, m *discordgo.MessageCreate) {
 	argument, payload := GetArgumentAndFlags(input)
 
 	if argument == "add" {
@@ -195,7 +164,7 @@ func (h *EventHandler) ParseCommand(input []string, s *discordgo.Session, m *dis
 }
 
 // EnableEvent function
-func (h *EventHandler) EnableEvent(eventID, userID string, channelID string, s *discordgo.Session) (err error) {
+func (h *EventHandler) EnableEvent(eventID, userID string, channelID string, s DiscordSession) (err error) {
 	user, err := h.user.GetUser(userID, s, channelID)
 	if err != nil {
 		return err
@@ -229,7 +198,7 @@ func (h *EventHandler) EventToScript(eventID string) (script string, err error)
 }
 
 // DisableEvent function
-func (h *EventHandler) DisableEvent(eventID string, userID string, s *discordgo.Session) (err error) {
+func (h *EventHandler) DisableEvent(eventID string, userID string, s DiscordSession) (err error) {
 	event, err := h.eventsdb.GetEventByID(eventID)
 	if err != nil {
 		return err
@@ -249,7 +218,7 @@ func (h *EventHandler) DisableEvent(eventID string, userID string, s *discordgo.
 }
 
 // RemoveEvent function
-func (h *EventHandler) RemoveEvent(eventID string, userID string, s *discordgo.Session, channelID string) (err error) {
+func (h *EventHandler) RemoveEvent(eventID string, userID string, s DiscordSession, channelID string) (err error) {
 	event, err := h.eventsdb.GetEventByID(eventID)
 	if err != nil {
 		return err
@@ -289,7 +258,7 @@ func (h *EventHandler) ListEvents() (formatted string, err error) {
 }
 
 // RegisterEvent function
-func (h *EventHandler) RegisterEvent(payload string, s *discordgo.Session, m *discordgo.MessageCreate) (eventID string, err error) {
+func (h *EventHandler) RegisterEvent(payload string, s DiscordSession, m *discordgo.MessageCreate) (eventID string, err error) {
 	payload = strings.TrimPrefix(payload, "~events add ") // This all will need to be updated later, this is just
 	payload = strings.TrimPrefix(payload, "\n")           // A lazy way of cleaning the command
 	payload = strings.TrimPrefix(payload, "```")
@@ -421,6 +390,7 @@ func (h *EventHandler) ReadEvents(s *discordgo.Session, m *discordgo.MessageCrea
 			rargs[1] = reflect.ValueOf(s)
 			rargs[2] = reflect.ValueOf(m)
 
+			h.metrics.EventTriggered()
 			go handler.Call(rargs)
 			//handlerid := reflect.Indirect(r).FieldByName("HandlerID").String()
 			//c.UnWatchEvent(m.ChannelID, handlerid)
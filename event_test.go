@@ -0,0 +1,63 @@
+package main
+
+import (
+	"strconv"
+	"testing"
+)
+
+// seedEvents function
+// Saves count events in one transaction, event i is attached to user i
+func seedEvents(b *testing.B, db *DBHandler, count int) *EventsDB {
+	b.Helper()
+
+	tx, err := db.rawdb.Begin(true)
+	if err != nil {
+		b.Fatal(err)
+	}
+	defer tx.Rollback()
+
+	bucket := tx.From("Events")
+	for i := 0; i < count; i++ {
+		event := Event{ID: strconv.Itoa(100000 + i), UserAttached: "event-" + strconv.Itoa(100000+i),
+			Type: "message"}
+		err = bucket.Save(&event)
+		if err != nil {
+			b.Fatal(err)
+		}
+	}
+	err = tx.Commit()
+	if err != nil {
+		b.Fatal(err)
+	}
+	return &EventsDB{db: db}
+}
+
+func BenchmarkGetEventByID(b *testing.B) {
+	for _, size := range benchmarkSizes {
+		b.Run(strconv.Itoa(size), func(b *testing.B) {
+			events := seedEvents(b, newBenchmarkDB(b), size)
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				_, err := events.GetEventByID(strconv.Itoa(100000 + i%size))
+				if err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
+
+func BenchmarkGetEventByAttached(b *testing.B) {
+	for _, size := range benchmarkSizes {
+		b.Run(strconv.Itoa(size), func(b *testing.B) {
+			events := seedEvents(b, newBenchmarkDB(b), size)
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				_, err := events.GetEventByAttached("event", strconv.Itoa(100000+i%size))
+				if err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
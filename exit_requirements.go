@@ -0,0 +1,137 @@
+package main
+
+/*
+Exit requirements lock an exit until a player meets all of them.
+
+	item       <itemID>             - the item is in User.ItemsMap
+	flag       <quest flag>         - the flag is in User.QuestFlags
+	attribute  <attribute> <minimum> - ie attribute strength 14
+	race       <race>
+	class      <class>              - either the primary or secondary class
+	role       <role>               - an internal role such as builder, or a role ID
+
+When a player doesn't meet an exit's requirements they are shown its locked message, so the refusal can be written to
+fit the room ("The gate is barred from the other side").
+
+*/
+
+import (
+	"errors"
+	"strconv"
+	"strings"
+)
+
+// ExitRequirement struct
+type ExitRequirement struct {
+	Type    string
+	Value   string
+	Minimum int // Only used by attribute requirements
+}
+
+// requirementTypes lists the requirement types builders can use
+var requirementTypes = []string{"item", "flag", "attribute", "race", "class", "role"}
+
+// userAttributes maps attribute names to the user field they check
+var userAttributes = map[string]func(user User) int{
+	"strength":     func(user User) int { return user.Strength },
+	"dexterity":    func(user User) int { return user.Dexterity },
+	"constitution": func(user User) int { return user.Constitution },
+	"intelligence": func(user User) int { return user.Intelligence },
+	"wisdom":       func(user User) int { return user.Wisdom },
+	"charisma":     func(user User) int { return user.Charisma },
+}
+
+// ParseExitRequirement function
+// Takes the arguments after "add", ie ["attribute", "strength", "14"]
+func ParseExitRequirement(args []string) (requirement ExitRequirement, err error) {
+
+	if len(args) < 2 {
+		return requirement, errors.New("A requirement needs a type and a value: " + strings.Join(requirementTypes, ", "))
+	}
+
+	requirement.Type = strings.ToLower(args[0])
+	switch requirement.Type {
+
+	case "item", "flag", "role":
+		requirement.Value = args[1]
+
+	case "race", "class":
+		requirement.Value = strings.ToLower(strings.Join(args[1:], " "))
+
+	case "attribute":
+		if len(args) < 3 {
+			return requirement, errors.New("attribute requirements need an attribute and a minimum, ie attribute strength 14")
+		}
+		requirement.Value = strings.ToLower(args[1])
+		if _, ok := userAttributes[requirement.Value]; !ok {
+			return requirement, errors.New("Unrecognized attribute: " + args[1])
+		}
+		requirement.Minimum, err = strconv.Atoi(args[2])
+		if err != nil {
+			return requirement, errors.New("Invalid attribute minimum (must be integer)")
+		}
+
+	default:
+		return requirement, errors.New("Unrecognized requirement type: " + args[0] + " (expected one of " +
+			strings.Join(requirementTypes, ", ") + ")")
+	}
+
+	return requirement, nil
+}
+
+// String function
+func (r ExitRequirement) String() string {
+	if r.Type == "attribute" {
+		return r.Type + " " + r.Value + " " + strconv.Itoa(r.Minimum)
+	}
+	return r.Type + " " + r.Value
+}
+
+// Check function
+func (r ExitRequirement) Check(user User) bool {
+
+	switch r.Type {
+
+	case "item":
+		return StringInSlice(r.Value, user.ItemsMap)
+
+	case "flag":
+		return StringInSlice(r.Value, user.QuestFlags)
+
+	case "attribute":
+		attribute, ok := userAttributes[r.Value]
+		return ok && attribute(user) >= r.Minimum
+
+	case "race":
+		return strings.EqualFold(user.Race, r.Value)
+
+	case "class":
+		return strings.EqualFold(user.Class, r.Value) || strings.EqualFold(user.SecondaryClass, r.Value)
+
+	case "role":
+		return user.CheckRole(r.Value)
+
+	default:
+		// Better to lock an exit we don't understand than to let everyone through
+		return false
+	}
+}
+
+// CanUse function
+// Returns the requirements the user doesn't meet, an exit can be used when there are none
+func (e *Exit) CanUse(user User) (unmet []ExitRequirement) {
+	for _, requirement := range e.Requirements {
+		if !requirement.Check(user) {
+			unmet = append(unmet, requirement)
+		}
+	}
+	return unmet
+}
+
+// LockedText function
+func (e *Exit) LockedText() string {
+	if e.LockedMessage != "" {
+		return e.LockedMessage
+	}
+	return "You are unable to travel " + e.Name + " right now."
+}
@@ -0,0 +1,212 @@
+package main
+
+/*
+Exits link one room to another.
+
+An exit has a name players travel by (ie "north" or "enter tavern"), optional aliases, the room it leads to, and the
+name of the exit in that room leading back (if there is one). The departure and arrival phrasing is what the rooms see
+when someone uses it, ie "Bob has left traveling north" and "Bob has arrived from the south".
+
+The ten compass and vertical directions are just exits with well known reverses and phrasing, see standardDirections.
+
+*/
+
+import (
+	"errors"
+	"strings"
+)
+
+// Exit struct
+type Exit struct {
+	Name        string
+	Aliases     []string
+	TargetID    string
+	ReverseName string // The exit in the target room that leads back here, empty for a one way exit
+
+	// Deprecated: moved into Requirements by migration 3, only kept so older records can be read
+	ItemIDs []string
+
+	Requirements  []ExitRequirement // See exit_requirements.go
+	LockedMessage string            // Shown when someone who doesn't meet the requirements tries to use the exit
+
+	Departure string // Follows "<user> has left", ie "traveling north"
+	Arrival   string // Follows "<user> has arrived", ie "from the south"
+
+	Cost int // Stamina used to travel through the exit, 0 uses the configured travel_stamina_cost
+
+	HiddenDC    int    // 0 for an exit anyone can see, see hidden_exits.go
+	HiddenSkill string // The skill search rolls against HiddenDC, perception or survival
+}
+
+// standardDirection struct
+type standardDirection struct {
+	name    string
+	reverse string
+	aliases []string
+	arrival string // How someone arriving from this side of a room is described
+}
+
+// standardDirections are listed in the order they should be displayed
+var standardDirections = []standardDirection{
+	{name: "north", reverse: "south", aliases: []string{"n"}, arrival: "from the north"},
+	{name: "northeast", reverse: "southwest", aliases: []string{"ne"}, arrival: "from the northeast"},
+	{name: "east", reverse: "west", aliases: []string{"e"}, arrival: "from the east"},
+	{name: "southeast", reverse: "northwest", aliases: []string{"se"}, arrival: "from the southeast"},
+	{name: "south", reverse: "north", aliases: []string{"s"}, arrival: "from the south"},
+	{name: "southwest", reverse: "northeast", aliases: []string{"sw"}, arrival: "from the southwest"},
+	{name: "west", reverse: "east", aliases: []string{"w"}, arrival: "from the west"},
+	{name: "northwest", reverse: "southeast", aliases: []string{"nw"}, arrival: "from the northwest"},
+	{name: "up", reverse: "down", aliases: []string{"u"}, arrival: "from above"},
+	{name: "down", reverse: "up", aliases: []string{"d"}, arrival: "from below"},
+}
+
+// GetStandardDirection function
+func GetStandardDirection(name string) (direction standardDirection, found bool) {
+	name = strings.ToLower(name)
+	for _, direction := range standardDirections {
+		if direction.name == name {
+			return direction, true
+		}
+	}
+	return direction, false
+}
+
+// CleanExitName function
+// Exit names are matched case insensitively with any extra whitespace collapsed
+func CleanExitName(name string) string {
+	return strings.ToLower(strings.Join(strings.Fields(name), " "))
+}
+
+// NewExit function
+// Fills in the aliases and phrasing for an exit from fromroom, reverse may be empty for a one way exit
+func NewExit(name string, targetID string, reverse string, fromroom Room) (exit Exit) {
+
+	exit = Exit{Name: CleanExitName(name), TargetID: targetID, ReverseName: CleanExitName(reverse)}
+
+	direction, standard := GetStandardDirection(exit.Name)
+	if standard {
+		exit.Aliases = append(exit.Aliases, direction.aliases...)
+		exit.Departure = "traveling " + exit.Name
+	} else {
+		exit.Departure = "through " + exit.Name
+	}
+
+	// Arrivals are described from the point of view of the room being arrived in, so by where they came from
+	if reverse, ok := GetStandardDirection(exit.ReverseName); ok {
+		exit.Arrival = reverse.arrival
+	} else if fromroom.Name != "" {
+		exit.Arrival = "from " + fromroom.Name
+	}
+	return exit
+}
+
+// Matches function
+func (e *Exit) Matches(name string) bool {
+	name = CleanExitName(name)
+	if e.Name == name {
+		return true
+	}
+	for _, alias := range e.Aliases {
+		if alias == name {
+			return true
+		}
+	}
+	return false
+}
+
+// DepartureMessage function
+func (e *Exit) DepartureMessage(username string) string {
+	if e.Departure == "" {
+		return username + " has left."
+	}
+	return username + " has left " + e.Departure + "."
+}
+
+// ArrivalMessage function
+func (e *Exit) ArrivalMessage(mention string) string {
+	return FormatArrival(mention, "arrived", e.Arrival)
+}
+
+// GetExit function
+func (r *Room) GetExit(name string) (exit Exit, found bool) {
+	for _, exit := range r.Exits {
+		if exit.Matches(name) {
+			return exit, true
+		}
+	}
+	return exit, false
+}
+
+// AddExit function
+// Fails if the exit's name or one of its aliases is already used by another exit in this room
+func (r *Room) AddExit(exit Exit) (err error) {
+	if exit.Name == "" {
+		return errors.New("Exit name cannot be empty")
+	}
+	if exit.TargetID == "" {
+		return errors.New("Exit " + exit.Name + " has no target room")
+	}
+
+	for _, name := range append([]string{exit.Name}, exit.Aliases...) {
+		existing, found := r.GetExit(name)
+		if found {
+			return errors.New("Exit " + name + " is already used by " + existing.Name + " leading to " + existing.TargetID)
+		}
+	}
+
+	r.Exits = append(r.Exits, exit)
+	return nil
+}
+
+// UpdateExit function
+// Replaces the exit with the same name
+func (r *Room) UpdateExit(exit Exit) (err error) {
+	for i, existing := range r.Exits {
+		if existing.Name == exit.Name {
+			r.Exits[i] = exit
+			return nil
+		}
+	}
+	return errors.New("No exit named " + exit.Name)
+}
+
+// RemoveExit function
+func (r *Room) RemoveExit(name string) (removed Exit, found bool) {
+	for i, exit := range r.Exits {
+		if exit.Matches(name) {
+			r.Exits = append(r.Exits[:i], r.Exits[i+1:]...)
+			return exit, true
+		}
+	}
+	return removed, false
+}
+
+// RemoveExitsTo function
+// Removes every exit leading to roomID, returns how many were removed
+func (r *Room) RemoveExitsTo(roomID string) (count int) {
+	var remaining []Exit
+	for _, exit := range r.Exits {
+		if exit.TargetID == roomID {
+			count++
+			continue
+		}
+		remaining = append(remaining, exit)
+	}
+	r.Exits = remaining
+	return count
+}
+
+// FormatArrival function
+// Transfers store the arrival phrasing of the exit that was used, records from before exits only have the direction
+func FormatArrival(mention string, verb string, arrival string) string {
+	if arrival == "" {
+		return mention + " has " + verb + "."
+	}
+	if arrival == "below" || arrival == "above" {
+		return mention + " has " + verb + " from " + arrival + "."
+	}
+	if _, legacy := GetStandardDirection(arrival); legacy {
+		return mention + " has " + verb + " from the " + arrival + "."
+	}
+	return mention + " has " + verb + " " + arrival + "."
+}
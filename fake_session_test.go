@@ -0,0 +1,705 @@
+package main
+
+import (
+	"errors"
+	"github.com/bwmarrin/discordgo"
+	"io"
+	"io/ioutil"
+	"sort"
+	"strconv"
+	"sync"
+)
+
+// FakeSession struct
+// An in-memory discord that implements DiscordSession, it models guilds, channels, roles, members and messages
+// closely enough that travel, transfers, registration and guild syncing can be run end to end without a bot token.
+type FakeSession struct {
+	sync.RWMutex
+
+	BotUser *discordgo.User
+	Status  string
+
+	guilds   map[string]*discordgo.Guild
+	channels map[string]*discordgo.Channel
+	users    map[string]*discordgo.User
+	messages map[string][]*discordgo.Message
+
+	// Keyed by recipient ID so that repeated UserChannelCreate calls return the same DM channel
+	dmchannels map[string]string
+
+	invites map[string]*discordgo.Invite // Keyed by code
+
+	lastid int
+}
+
+// NewFakeSession function
+func NewFakeSession(botID string, botName string) *FakeSession {
+	s := &FakeSession{
+		guilds:     make(map[string]*discordgo.Guild),
+		channels:   make(map[string]*discordgo.Channel),
+		users:      make(map[string]*discordgo.User),
+		messages:   make(map[string][]*discordgo.Message),
+		dmchannels: make(map[string]string),
+		invites:    make(map[string]*discordgo.Invite),
+	}
+	s.BotUser = &discordgo.User{ID: botID, Username: botName, Bot: true}
+	s.users[botID] = s.BotUser
+	return s
+}
+
+// nextID function
+// Returns a new snowflake-ish ID, callers must hold the lock
+func (s *FakeSession) nextID() string {
+	s.lastid++
+	return strconv.Itoa(1000000 + s.lastid)
+}
+
+// AddGuild function
+// Creates a guild along with its @everyone role, which like on discord shares the guild ID
+func (s *FakeSession) AddGuild(guildID string, name string, ownerID string) *discordgo.Guild {
+	s.Lock()
+	defer s.Unlock()
+
+	everyone := &discordgo.Role{ID: guildID, Name: "@everyone", Position: 0}
+	guild := &discordgo.Guild{ID: guildID, Name: name, OwnerID: ownerID, Roles: []*discordgo.Role{everyone}}
+	s.guilds[guildID] = guild
+	return guild
+}
+
+// AddUser function
+func (s *FakeSession) AddUser(userID string, username string) *discordgo.User {
+	s.Lock()
+	defer s.Unlock()
+
+	user := &discordgo.User{ID: userID, Username: username, Discriminator: "0001"}
+	s.users[userID] = user
+	return user
+}
+
+// AddMember function
+// Joins a user to a guild, creating the user if we haven't seen them yet
+func (s *FakeSession) AddMember(guildID string, userID string, username string) (*discordgo.Member, error) {
+	s.Lock()
+	defer s.Unlock()
+
+	guild, ok := s.guilds[guildID]
+	if !ok {
+		return nil, errors.New("Unknown Guild")
+	}
+
+	user, ok := s.users[userID]
+	if !ok {
+		user = &discordgo.User{ID: userID, Username: username, Discriminator: "0001"}
+		s.users[userID] = user
+	}
+
+	for _, member := range guild.Members {
+		if member.User.ID == userID {
+			return member, nil
+		}
+	}
+
+	member := &discordgo.Member{GuildID: guildID, User: user}
+	guild.Members = append(guild.Members, member)
+	return member, nil
+}
+
+// RemoveMember function
+func (s *FakeSession) RemoveMember(guildID string, userID string) error {
+	s.Lock()
+	defer s.Unlock()
+
+	guild, ok := s.guilds[guildID]
+	if !ok {
+		return errors.New("Unknown Guild")
+	}
+
+	for i, member := range guild.Members {
+		if member.User.ID == userID {
+			guild.Members = append(guild.Members[:i], guild.Members[i+1:]...)
+			return nil
+		}
+	}
+	return errors.New("Unknown Member")
+}
+
+// AddChannel function
+// Creates a channel directly, useful for seeding categories before running a handler against the fake
+func (s *FakeSession) AddChannel(guildID string, name string, parentID string) (*discordgo.Channel, error) {
+	s.Lock()
+	defer s.Unlock()
+
+	return s.createChannel(guildID, name, parentID)
+}
+
+// createChannel function
+// Callers must hold the lock
+func (s *FakeSession) createChannel(guildID string, name string, parentID string) (*discordgo.Channel, error) {
+	guild, ok := s.guilds[guildID]
+	if !ok {
+		return nil, errors.New("Unknown Guild")
+	}
+
+	channel := &discordgo.Channel{ID: s.nextID(), GuildID: guildID, Name: name, ParentID: parentID,
+		Position: len(guild.Channels)}
+	guild.Channels = append(guild.Channels, channel)
+	s.channels[channel.ID] = channel
+	return channel, nil
+}
+
+// Messages function
+// Returns every message sent to a channel, oldest first
+func (s *FakeSession) Messages(channelID string) []*discordgo.Message {
+	s.RLock()
+	defer s.RUnlock()
+
+	messages := make([]*discordgo.Message, len(s.messages[channelID]))
+	copy(messages, s.messages[channelID])
+	return messages
+}
+
+// LastMessage function
+func (s *FakeSession) LastMessage(channelID string) string {
+	s.RLock()
+	defer s.RUnlock()
+
+	messages := s.messages[channelID]
+	if len(messages) < 1 {
+		return ""
+	}
+	return messages[len(messages)-1].Content
+}
+
+// DirectMessages function
+// Returns the messages the bot has sent to a user over a private channel
+func (s *FakeSession) DirectMessages(userID string) []*discordgo.Message {
+	s.RLock()
+	channelID, ok := s.dmchannels[userID]
+	s.RUnlock()
+
+	if !ok {
+		return nil
+	}
+	return s.Messages(channelID)
+}
+
+// MemberHasRole function
+func (s *FakeSession) MemberHasRole(guildID string, userID string, roleID string) bool {
+	s.RLock()
+	defer s.RUnlock()
+
+	member, err := s.findMember(guildID, userID)
+	if err != nil {
+		return false
+	}
+	for _, id := range member.Roles {
+		if id == roleID {
+			return true
+		}
+	}
+	return false
+}
+
+// NewMessage function
+// Builds a MessageCreate as if userID had typed content in channelID, for feeding into a handler
+func (s *FakeSession) NewMessage(userID string, channelID string, content string) *discordgo.MessageCreate {
+	s.Lock()
+	defer s.Unlock()
+
+	author, ok := s.users[userID]
+	if !ok {
+		author = &discordgo.User{ID: userID, Username: userID, Discriminator: "0001"}
+		s.users[userID] = author
+	}
+
+	message := &discordgo.Message{ID: s.nextID(), ChannelID: channelID, Content: content, Author: author}
+	return &discordgo.MessageCreate{Message: message}
+}
+
+// findMember function
+// Callers must hold the lock
+func (s *FakeSession) findMember(guildID string, userID string) (*discordgo.Member, error) {
+	guild, ok := s.guilds[guildID]
+	if !ok {
+		return nil, errors.New("Unknown Guild")
+	}
+	for _, member := range guild.Members {
+		if member.User.ID == userID {
+			return member, nil
+		}
+	}
+	return nil, errors.New("Unknown Member")
+}
+
+// findRole function
+// Callers must hold the lock
+func (s *FakeSession) findRole(guildID string, roleID string) (*discordgo.Role, error) {
+	guild, ok := s.guilds[guildID]
+	if !ok {
+		return nil, errors.New("Unknown Guild")
+	}
+	for _, role := range guild.Roles {
+		if role.ID == roleID {
+			return role, nil
+		}
+	}
+	return nil, errors.New("Unknown Role")
+}
+
+// ChannelMessageSend function
+func (s *FakeSession) ChannelMessageSend(channelID string, content string) (*discordgo.Message, error) {
+	s.Lock()
+	defer s.Unlock()
+
+	if _, ok := s.channels[channelID]; !ok {
+		return nil, errors.New("Unknown Channel")
+	}
+
+	message := &discordgo.Message{ID: s.nextID(), ChannelID: channelID, Content: content, Author: s.BotUser}
+	s.messages[channelID] = append(s.messages[channelID], message)
+	return message, nil
+}
+
+// ChannelMessageSendEmbed function
+func (s *FakeSession) ChannelMessageSendEmbed(channelID string, embed *discordgo.MessageEmbed) (*discordgo.Message,
+	error) {
+	s.Lock()
+	defer s.Unlock()
+
+	if _, ok := s.channels[channelID]; !ok {
+		return nil, errors.New("Unknown Channel")
+	}
+
+	message := &discordgo.Message{ID: s.nextID(), ChannelID: channelID, Embeds: []*discordgo.MessageEmbed{embed},
+		Author: s.BotUser}
+	s.messages[channelID] = append(s.messages[channelID], message)
+	return message, nil
+}
+
+// AddInvite function
+// Invites that were never added are treated as expired
+func (s *FakeSession) AddInvite(code string, channelID string) (*discordgo.Invite, error) {
+	s.Lock()
+	defer s.Unlock()
+
+	channel, ok := s.channels[channelID]
+	if !ok {
+		return nil, errors.New("Unknown Channel")
+	}
+
+	invite := &discordgo.Invite{Code: code, Guild: s.guilds[channel.GuildID], Channel: channel}
+	s.invites[code] = invite
+	return invite, nil
+}
+
+// Invite function
+func (s *FakeSession) Invite(inviteID string) (*discordgo.Invite, error) {
+	s.RLock()
+	defer s.RUnlock()
+
+	invite, ok := s.invites[inviteID]
+	if !ok {
+		return nil, errors.New("Unknown Invite")
+	}
+	return invite, nil
+}
+
+// ChannelInviteCreate function
+func (s *FakeSession) ChannelInviteCreate(channelID string, i discordgo.Invite) (*discordgo.Invite, error) {
+	s.Lock()
+	defer s.Unlock()
+
+	channel, ok := s.channels[channelID]
+	if !ok {
+		return nil, errors.New("Unknown Channel")
+	}
+
+	invite := &discordgo.Invite{Code: "invite" + s.nextID(), Guild: s.guilds[channel.GuildID], Channel: channel,
+		MaxAge: i.MaxAge, MaxUses: i.MaxUses, Temporary: i.Temporary}
+	s.invites[invite.Code] = invite
+	return invite, nil
+}
+
+// UpdateStatus function
+func (s *FakeSession) UpdateStatus(idle int, game string) error {
+	s.Lock()
+	defer s.Unlock()
+
+	s.Status = game
+	return nil
+}
+
+// ChannelFileSend function
+// The file contents are read and thrown away, only the attachment name is kept on the message
+func (s *FakeSession) ChannelFileSend(channelID, name string, r io.Reader) (*discordgo.Message, error) {
+	if _, err := io.Copy(ioutil.Discard, r); err != nil {
+		return nil, err
+	}
+
+	s.Lock()
+	defer s.Unlock()
+
+	if _, ok := s.channels[channelID]; !ok {
+		return nil, errors.New("Unknown Channel")
+	}
+
+	message := &discordgo.Message{ID: s.nextID(), ChannelID: channelID, Author: s.BotUser,
+		Attachments: []*discordgo.MessageAttachment{{ID: s.nextID(), Filename: name}}}
+	s.messages[channelID] = append(s.messages[channelID], message)
+	return message, nil
+}
+
+// ChannelMessages function
+// Returns up to limit messages, newest first like the discord API does
+func (s *FakeSession) ChannelMessages(channelID string, limit int, beforeID, afterID, aroundID string) ([]*discordgo.Message, error) {
+	s.RLock()
+	defer s.RUnlock()
+
+	if _, ok := s.channels[channelID]; !ok {
+		return nil, errors.New("Unknown Channel")
+	}
+
+	var messages []*discordgo.Message
+	history := s.messages[channelID]
+	for i := len(history) - 1; i >= 0 && len(messages) < limit; i-- {
+		messages = append(messages, history[i])
+	}
+	return messages, nil
+}
+
+// ChannelMessageDelete function
+func (s *FakeSession) ChannelMessageDelete(channelID, messageID string) error {
+	s.Lock()
+	defer s.Unlock()
+
+	history := s.messages[channelID]
+	for i, message := range history {
+		if message.ID == messageID {
+			s.messages[channelID] = append(history[:i], history[i+1:]...)
+			return nil
+		}
+	}
+	return errors.New("Unknown Message")
+}
+
+// ChannelMessagesBulkDelete function
+func (s *FakeSession) ChannelMessagesBulkDelete(channelID string, messages []string) error {
+	if len(messages) > 100 {
+		return errors.New("Cannot bulk delete more than 100 messages")
+	}
+	for _, messageID := range messages {
+		err := s.ChannelMessageDelete(channelID, messageID)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Channel function
+func (s *FakeSession) Channel(channelID string) (*discordgo.Channel, error) {
+	s.RLock()
+	defer s.RUnlock()
+
+	channel, ok := s.channels[channelID]
+	if !ok {
+		return nil, errors.New("Unknown Channel")
+	}
+	return channel, nil
+}
+
+// ChannelDelete function
+func (s *FakeSession) ChannelDelete(channelID string) (*discordgo.Channel, error) {
+	s.Lock()
+	defer s.Unlock()
+
+	channel, ok := s.channels[channelID]
+	if !ok {
+		return nil, errors.New("Unknown Channel")
+	}
+	delete(s.channels, channelID)
+	delete(s.messages, channelID)
+
+	if guild, ok := s.guilds[channel.GuildID]; ok {
+		for i, guildchannel := range guild.Channels {
+			if guildchannel.ID == channelID {
+				guild.Channels = append(guild.Channels[:i], guild.Channels[i+1:]...)
+				break
+			}
+		}
+	}
+	return channel, nil
+}
+
+// ChannelEditComplex function
+func (s *FakeSession) ChannelEditComplex(channelID string, data *discordgo.ChannelEdit) (*discordgo.Channel, error) {
+	s.Lock()
+	defer s.Unlock()
+
+	channel, ok := s.channels[channelID]
+	if !ok {
+		return nil, errors.New("Unknown Channel")
+	}
+
+	if data.Name != "" {
+		channel.Name = data.Name
+	}
+	if data.Topic != "" {
+		channel.Topic = data.Topic
+	}
+	if data.ParentID != "" {
+		channel.ParentID = data.ParentID
+	}
+	if data.PermissionOverwrites != nil {
+		channel.PermissionOverwrites = data.PermissionOverwrites
+	}
+	channel.NSFW = data.NSFW
+	channel.Position = data.Position
+	return channel, nil
+}
+
+// ChannelPermissionSet function
+func (s *FakeSession) ChannelPermissionSet(channelID, targetID, targetType string, allow, deny int) error {
+	s.Lock()
+	defer s.Unlock()
+
+	channel, ok := s.channels[channelID]
+	if !ok {
+		return errors.New("Unknown Channel")
+	}
+
+	for _, overwrite := range channel.PermissionOverwrites {
+		if overwrite.ID == targetID {
+			overwrite.Type = targetType
+			overwrite.Allow = allow
+			overwrite.Deny = deny
+			return nil
+		}
+	}
+	channel.PermissionOverwrites = append(channel.PermissionOverwrites,
+		&discordgo.PermissionOverwrite{ID: targetID, Type: targetType, Allow: allow, Deny: deny})
+	return nil
+}
+
+// UserChannelCreate function
+func (s *FakeSession) UserChannelCreate(recipientID string) (*discordgo.Channel, error) {
+	s.Lock()
+	defer s.Unlock()
+
+	recipient, ok := s.users[recipientID]
+	if !ok {
+		return nil, errors.New("Unknown User")
+	}
+
+	if channelID, ok := s.dmchannels[recipientID]; ok {
+		return s.channels[channelID], nil
+	}
+
+	channel := &discordgo.Channel{ID: s.nextID(), Recipients: []*discordgo.User{recipient}}
+	s.channels[channel.ID] = channel
+	s.dmchannels[recipientID] = channel.ID
+	return channel, nil
+}
+
+// Guild function
+func (s *FakeSession) Guild(guildID string) (*discordgo.Guild, error) {
+	s.RLock()
+	defer s.RUnlock()
+
+	guild, ok := s.guilds[guildID]
+	if !ok {
+		return nil, errors.New("Unknown Guild")
+	}
+	return guild, nil
+}
+
+// GuildChannels function
+func (s *FakeSession) GuildChannels(guildID string) ([]*discordgo.Channel, error) {
+	s.RLock()
+	defer s.RUnlock()
+
+	guild, ok := s.guilds[guildID]
+	if !ok {
+		return nil, errors.New("Unknown Guild")
+	}
+
+	channels := make([]*discordgo.Channel, len(guild.Channels))
+	copy(channels, guild.Channels)
+	return channels, nil
+}
+
+// GuildChannelCreate function
+func (s *FakeSession) GuildChannelCreate(guildID, name, ctype string) (*discordgo.Channel, error) {
+	s.Lock()
+	defer s.Unlock()
+
+	return s.createChannel(guildID, name, "")
+}
+
+// User function
+func (s *FakeSession) User(userID string) (*discordgo.User, error) {
+	s.RLock()
+	defer s.RUnlock()
+
+	user, ok := s.users[userID]
+	if !ok {
+		return nil, errors.New("Unknown User")
+	}
+	return user, nil
+}
+
+// GuildMember function
+func (s *FakeSession) GuildMember(guildID, userID string) (*discordgo.Member, error) {
+	s.RLock()
+	defer s.RUnlock()
+
+	return s.findMember(guildID, userID)
+}
+
+// GuildMemberRoleAdd function
+func (s *FakeSession) GuildMemberRoleAdd(guildID, userID, roleID string) error {
+	s.Lock()
+	defer s.Unlock()
+
+	member, err := s.findMember(guildID, userID)
+	if err != nil {
+		return err
+	}
+	_, err = s.findRole(guildID, roleID)
+	if err != nil {
+		return err
+	}
+
+	for _, id := range member.Roles {
+		if id == roleID {
+			return nil
+		}
+	}
+	member.Roles = append(member.Roles, roleID)
+	return nil
+}
+
+// GuildMemberRoleRemove function
+func (s *FakeSession) GuildMemberRoleRemove(guildID, userID, roleID string) error {
+	s.Lock()
+	defer s.Unlock()
+
+	member, err := s.findMember(guildID, userID)
+	if err != nil {
+		return err
+	}
+	_, err = s.findRole(guildID, roleID)
+	if err != nil {
+		return err
+	}
+
+	member.Roles = RemoveStringFromSlice(member.Roles, roleID)
+	return nil
+}
+
+// GuildRoles function
+func (s *FakeSession) GuildRoles(guildID string) ([]*discordgo.Role, error) {
+	s.RLock()
+	defer s.RUnlock()
+
+	guild, ok := s.guilds[guildID]
+	if !ok {
+		return nil, errors.New("Unknown Guild")
+	}
+
+	roles := make([]*discordgo.Role, len(guild.Roles))
+	copy(roles, guild.Roles)
+	return roles, nil
+}
+
+// GuildRoleCreate function
+func (s *FakeSession) GuildRoleCreate(guildID string) (*discordgo.Role, error) {
+	s.Lock()
+	defer s.Unlock()
+
+	guild, ok := s.guilds[guildID]
+	if !ok {
+		return nil, errors.New("Unknown Guild")
+	}
+
+	// Discord caps a guild at 250 roles
+	if len(guild.Roles) >= 250 {
+		return nil, errors.New("Maximum number of guild roles reached (250)")
+	}
+
+	role := &discordgo.Role{ID: s.nextID(), Name: "new role", Position: 1}
+	for _, existing := range guild.Roles {
+		if existing.ID != guildID {
+			existing.Position++
+		}
+	}
+	guild.Roles = append(guild.Roles, role)
+	return role, nil
+}
+
+// GuildRoleEdit function
+func (s *FakeSession) GuildRoleEdit(guildID, roleID, name string, color int, hoist bool, perm int, mention bool) (*discordgo.Role, error) {
+	s.Lock()
+	defer s.Unlock()
+
+	role, err := s.findRole(guildID, roleID)
+	if err != nil {
+		return nil, err
+	}
+
+	role.Name = name
+	role.Color = color
+	role.Hoist = hoist
+	role.Permissions = perm
+	role.Mentionable = mention
+	return role, nil
+}
+
+// GuildRoleDelete function
+func (s *FakeSession) GuildRoleDelete(guildID, roleID string) error {
+	s.Lock()
+	defer s.Unlock()
+
+	guild, ok := s.guilds[guildID]
+	if !ok {
+		return errors.New("Unknown Guild")
+	}
+
+	for i, role := range guild.Roles {
+		if role.ID == roleID {
+			guild.Roles = append(guild.Roles[:i], guild.Roles[i+1:]...)
+			for _, member := range guild.Members {
+				member.Roles = RemoveStringFromSlice(member.Roles, roleID)
+			}
+			return nil
+		}
+	}
+	return errors.New("Unknown Role")
+}
+
+// GuildRoleReorder function
+func (s *FakeSession) GuildRoleReorder(guildID string, roles []*discordgo.Role) ([]*discordgo.Role, error) {
+	s.Lock()
+	defer s.Unlock()
+
+	guild, ok := s.guilds[guildID]
+	if !ok {
+		return nil, errors.New("Unknown Guild")
+	}
+
+	for _, update := range roles {
+		role, err := s.findRole(guildID, update.ID)
+		if err != nil {
+			return nil, err
+		}
+		role.Position = update.Position
+	}
+
+	sort.Slice(guild.Roles, func(i, j int) bool { return guild.Roles[i].Position < guild.Roles[j].Position })
+	ordered := make([]*discordgo.Role, len(guild.Roles))
+	copy(ordered, guild.Roles)
+	return ordered, nil
+}
+
+// Make sure the fake keeps up with the interface
+var _ DiscordSession = (*FakeSession)(nil)
@@ -0,0 +1,259 @@
+package main
+
+/*
+Every room is a channel and (usually) a role in its guild, so how many rooms a guild can hold is set by discord's
+channel and role limits rather than anything of ours.
+
+When a guild is full new rooms go to its overflow guild, which is another guild in the cluster with space. The first
+time a guild overflows, a pair of gate rooms is created (one in each guild) that transfer travelers between the two,
+and the full guild remembers which guild it overflows into. Builders can then link the new rooms to the gate on the
+overflow side like any other room. An overflow guild that fills up overflows in turn.
+
+*/
+
+import (
+	"errors"
+	"github.com/bwmarrin/discordgo"
+	"strconv"
+	"strings"
+)
+
+// Discord limits
+const (
+	DiscordMaxChannels = 500
+	DiscordMaxRoles    = 250
+)
+
+// guildCapacityReserve is how many channels and roles are kept free in each guild, so there is always space for the
+// gate rooms an overflow needs
+const guildCapacityReserve = 4
+
+// GuildCapacity struct
+type GuildCapacity struct {
+	GuildID  string
+	Rooms    int
+	Channels int
+	Roles    int
+}
+
+// GetGuildCapacity function
+func (h *RoomsHandler) GetGuildCapacity(guildID string, s DiscordSession) (capacity GuildCapacity, err error) {
+
+	capacity.GuildID = guildID
+
+	rooms, err := h.rooms.GetRoomsByGuildID(guildID)
+	if err != nil {
+		return capacity, err
+	}
+	capacity.Rooms = len(rooms)
+
+	channels, err := s.GuildChannels(guildID)
+	if err != nil {
+		return capacity, err
+	}
+	capacity.Channels = len(channels)
+
+	roles, err := s.GuildRoles(guildID)
+	if err != nil {
+		return capacity, err
+	}
+	capacity.Roles = len(roles)
+
+	return capacity, nil
+}
+
+// RoomsLeft function
+// Each room takes a channel and a role
+func (c GuildCapacity) RoomsLeft() int {
+	channels := DiscordMaxChannels - guildCapacityReserve - c.Channels
+	roles := DiscordMaxRoles - guildCapacityReserve - c.Roles
+	if roles < channels {
+		return roles
+	}
+	return channels
+}
+
+// String function
+func (c GuildCapacity) String() string {
+	return strconv.Itoa(c.Rooms) + " rooms, " + strconv.Itoa(c.Channels) + "/" + strconv.Itoa(DiscordMaxChannels) +
+		" channels, " + strconv.Itoa(c.Roles) + "/" + strconv.Itoa(DiscordMaxRoles) + " roles"
+}
+
+// PlaceOverflowRoom function
+// Follows the overflow chain from a full guild to the first guild with space, picking and linking a new overflow
+// guild if the chain runs out. Returns the guild the room should be created in.
+func (h *RoomsHandler) PlaceOverflowRoom(guildID string, parentname string, s DiscordSession) (placedID string,
+	err error) {
+
+	visited := make(map[string]bool)
+	current := guildID
+	for {
+		capacity, err := h.GetGuildCapacity(current, s)
+		if err != nil {
+			return "", err
+		}
+		if capacity.RoomsLeft() > 0 {
+			return current, h.EnsureCategory(current, parentname, s)
+		}
+		visited[current] = true
+
+		record, err := h.guilds.GetGuildByID(current)
+		if err != nil {
+			return "", errors.New("Guild " + current + " is full and not registered with the cluster: " + err.Error())
+		}
+
+		next := record.OverflowGuildID
+		if next == "" || visited[next] {
+			next, err = h.PickOverflowGuild(visited, s)
+			if err != nil {
+				return "", err
+			}
+			err = h.LinkOverflowGuild(current, next, s)
+			if err != nil {
+				return "", err
+			}
+		}
+		current = next
+	}
+}
+
+// FindRoomByName function
+// Looks for a room in guildID, then in the guilds it overflows into
+func (h *RoomsHandler) FindRoomByName(name string, guildID string) (room Room, err error) {
+
+	visited := make(map[string]bool)
+	current := guildID
+	for {
+		room, err = h.rooms.GetRoomByName(name, current)
+		if err == nil {
+			return room, nil
+		}
+		visited[current] = true
+
+		record, guilderr := h.guilds.GetGuildByID(current)
+		if guilderr != nil || record.OverflowGuildID == "" || visited[record.OverflowGuildID] {
+			return room, err
+		}
+		current = record.OverflowGuildID
+	}
+}
+
+// PickOverflowGuild function
+// Chooses the registered guild with the most space, skipping any in exclude
+func (h *RoomsHandler) PickOverflowGuild(exclude map[string]bool, s DiscordSession) (guildID string, err error) {
+
+	guilds, err := h.guilds.GetAllGuilds()
+	if err != nil {
+		return "", err
+	}
+
+	best := 0
+	for _, guild := range guilds {
+		if exclude[guild.ID] {
+			continue
+		}
+		capacity, err := h.GetGuildCapacity(guild.ID, s)
+		if err != nil {
+			continue
+		}
+		if capacity.RoomsLeft() > best {
+			best = capacity.RoomsLeft()
+			guildID = guild.ID
+		}
+	}
+
+	if guildID == "" {
+		return "", errors.New("Every guild in the cluster is full, add another guild to the cluster with " +
+			h.conf.CommandPrefix() + "room setupserver and try again")
+	}
+	return guildID, nil
+}
+
+// LinkOverflowGuild function
+// Creates a gate room in each guild that transfers to the other, and records overflowID as fullID's overflow guild.
+// The gates use the space kept free by guildCapacityReserve in the full guild.
+func (h *RoomsHandler) LinkOverflowGuild(fullID string, overflowID string, s DiscordSession) (err error) {
+
+	full, err := h.guilds.GetGuildByID(fullID)
+	if err != nil {
+		return err
+	}
+	overflow, err := h.guilds.GetGuildByID(overflowID)
+	if err != nil {
+		return err
+	}
+
+	err = h.EnsureCategory(fullID, "The Aether", s)
+	if err != nil {
+		return err
+	}
+	err = h.EnsureCategory(overflowID, "The Aether", s)
+	if err != nil {
+		return err
+	}
+
+	// Neither gate can point at the other until both exist
+	landing, err := h.createRoom(s, gateName(full), overflowID, "The Aether", "", "", 0, false)
+	if err != nil {
+		return errors.New("Could not create overflow gate in " + overflow.Name + ": " + err.Error())
+	}
+	landinginvite, err := s.ChannelInviteCreate(landing.ID, discordgo.Invite{})
+	if err != nil {
+		return err
+	}
+
+	gate, err := h.createRoom(s, gateName(overflow), fullID, "The Aether", "https://discord.gg/"+landinginvite.Code,
+		landing.ID, 0, false)
+	if err != nil {
+		return errors.New("Could not create overflow gate in " + full.Name + ": " + err.Error())
+	}
+	gateinvite, err := s.ChannelInviteCreate(gate.ID, discordgo.Invite{})
+	if err != nil {
+		return err
+	}
+
+	landingrecord, err := h.rooms.GetRoomByID(landing.ID)
+	if err != nil {
+		return err
+	}
+	landingrecord.GuildTransferInvite = "https://discord.gg/" + gateinvite.Code
+	landingrecord.TransferRoomID = gate.ID
+	err = h.rooms.SaveRoomToDB(landingrecord)
+	if err != nil {
+		return err
+	}
+	err = h.perm.ApplyTravelRolePerms(landingrecord.ID, overflowID, s)
+	if err != nil {
+		return err
+	}
+
+	full.OverflowGuildID = overflowID
+	return h.guilds.SaveGuildToDB(full)
+}
+
+// gateName function
+func gateName(guild GuildRecord) string {
+	name := strings.ToLower(strings.Join(strings.Fields(guild.Name), "-"))
+	if name == "" {
+		name = guild.ID
+	}
+	return "gate-" + name
+}
+
+// EnsureCategory function
+// Creates a category in the guild if there isn't a channel with that name already
+func (h *RoomsHandler) EnsureCategory(guildID string, name string, s DiscordSession) (err error) {
+
+	channels, err := s.GuildChannels(guildID)
+	if err != nil {
+		return err
+	}
+	for _, channel := range channels {
+		if channel.Name == name {
+			return nil
+		}
+	}
+
+	_, err = s.GuildChannelCreate(guildID, name, "category")
+	return err
+}
@@ -2,7 +2,7 @@ package main
 
 import (
 	"errors"
-	"github.com/bwmarrin/discordgo"
+	"github.com/asdine/storm"
 	"strings"
 	"sync"
 )
@@ -32,6 +32,8 @@ type GuildRecord struct {
 	ModeratorID string
 	BuilderID   string
 	EveryoneID  string
+
+	OverflowGuildID string // Where new rooms go once this guild is full, see guild_capacity.go
 }
 
 // SaveGuildToDB function
@@ -72,42 +74,40 @@ func (h *GuildsManager) RemoveGuildByID(guildID string) (err error) {
 
 // GetGuildByID function
 func (h *GuildsManager) GetGuildByID(guildID string) (guild GuildRecord, err error) {
+	h.querylocker.RLock()
+	defer h.querylocker.RUnlock()
 
-	guilds, err := h.GetAllGuilds()
+	db := h.db.rawdb.From("Guilds")
+	err = db.One("ID", guildID, &guild)
 	if err != nil {
-		return guild, err
-	}
-
-	for _, i := range guilds {
-		if i.ID == guildID {
-			return i, nil
+		if err == storm.ErrNotFound {
+			return guild, errors.New("No guild record found")
 		}
+		return guild, err
 	}
 
-	return guild, errors.New("No guild record found")
+	return guild, nil
 }
 
 // GetGuildByName function
 func (h *GuildsManager) GetGuildByName(guildname string, guildID string) (guild GuildRecord, err error) {
 
-	guilds, err := h.GetAllGuilds()
+	guild, err = h.GetGuildByID(guildID)
 	if err != nil {
 		return guild, err
 	}
 
-	for _, i := range guilds {
-		if i.Name == guildname && i.ID == guildID {
-			return i, nil
-		}
+	if guild.Name != guildname {
+		return GuildRecord{}, errors.New("No guild record found")
 	}
 
-	return guild, errors.New("No guild record found")
+	return guild, nil
 }
 
 // GetAllGuilds function
 func (h *GuildsManager) GetAllGuilds() (guildlist []GuildRecord, err error) {
-	h.querylocker.Lock()
-	defer h.querylocker.Unlock()
+	h.querylocker.RLock()
+	defer h.querylocker.RUnlock()
 
 	db := h.db.rawdb.From("Guilds")
 	err = db.All(&guildlist)
@@ -161,21 +161,15 @@ func (h *GuildsManager) RemoveRoleFromGuild(guildID string, roleID string) (err
 // IsGuildRegistered function
 func (h *GuildsManager) IsGuildRegistered(guildID string) (valid bool) {
 
-	guildlist, err := h.GetAllGuilds()
+	_, err := h.GetGuildByID(guildID)
 	if err != nil {
 		return false
 	}
-
-	for _, guild := range guildlist {
-		if guild.ID == guildID {
-			return true
-		}
-	}
-	return false
+	return true
 }
 
 // IsGuildIDValid function
-func (h *GuildsManager) IsGuildIDValid(guildID string, s *discordgo.Session) (valid bool) {
+func (h *GuildsManager) IsGuildIDValid(guildID string, s DiscordSession) (valid bool) {
 
 	_, err := s.Guild(guildID)
 	if err != nil {
@@ -326,7 +320,7 @@ func (h *GuildsManager) GetGuildEveryoneID(guildID string) (builderID string, er
 }
 
 // GetGuildDiscordAdminID function
-func (h *GuildsManager) GetGuildDiscordAdminID(guildID string, s *discordgo.Session) (adminID string, err error) {
+func (h *GuildsManager) GetGuildDiscordAdminID(guildID string, s DiscordSession) (adminID string, err error) {
 	adminID, err = getRoleIDByName(s, guildID, "Admin")
 	if err != nil {
 		return "", err
@@ -335,7 +329,7 @@ func (h *GuildsManager) GetGuildDiscordAdminID(guildID string, s *discordgo.Sess
 }
 
 // GetGuildDiscordModeratorID function
-func (h *GuildsManager) GetGuildDiscordModeratorID(guildID string, s *discordgo.Session) (moderatorID string, err error) {
+func (h *GuildsManager) GetGuildDiscordModeratorID(guildID string, s DiscordSession) (moderatorID string, err error) {
 	moderatorID, err = getRoleIDByName(s, guildID, "Moderator")
 	if err != nil {
 		return "", err
@@ -344,7 +338,7 @@ func (h *GuildsManager) GetGuildDiscordModeratorID(guildID string, s *discordgo.
 }
 
 // GetGuildDiscordBuilderID function
-func (h *GuildsManager) GetGuildDiscordBuilderID(guildID string, s *discordgo.Session) (builderID string, err error) {
+func (h *GuildsManager) GetGuildDiscordBuilderID(guildID string, s DiscordSession) (builderID string, err error) {
 	builderID, err = getRoleIDByName(s, guildID, "Builder")
 	if err != nil {
 		return "", err
@@ -353,7 +347,7 @@ func (h *GuildsManager) GetGuildDiscordBuilderID(guildID string, s *discordgo.Se
 }
 
 // GetGuildDiscordEveryoneID function
-func (h *GuildsManager) GetGuildDiscordEveryoneID(guildID string, s *discordgo.Session) (everyoneid string, err error) {
+func (h *GuildsManager) GetGuildDiscordEveryoneID(guildID string, s DiscordSession) (everyoneid string, err error) {
 	roles, err := s.GuildRoles(guildID)
 	if err != nil {
 		return "", err
@@ -369,7 +363,7 @@ func (h *GuildsManager) GetGuildDiscordEveryoneID(guildID string, s *discordgo.S
 }
 
 // RegisterGuild function
-func (h *GuildsManager) RegisterGuild(guildID string, s *discordgo.Session) (err error) {
+func (h *GuildsManager) RegisterGuild(guildID string, s DiscordSession) (err error) {
 
 	guildRecord, err := h.GetGuildByID(guildID)
 	if err != nil {
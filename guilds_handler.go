@@ -2,7 +2,6 @@ package main
 
 import (
 	"errors"
-	"fmt"
 	"github.com/bwmarrin/discordgo"
 	"strconv"
 	"strings"
@@ -15,6 +14,7 @@ type GuildsHandler struct {
 	room         *RoomsHandler
 	guildmanager *GuildsManager
 	registry     *CommandRegistry
+	router       *CommandRouter
 	db           *DBHandler
 	conf         *Config
 	perm         *PermissionsHandler
@@ -36,49 +36,13 @@ func (h *GuildsHandler) RegisterCommands() (err error) {
 
 	h.registry.Register("guilds", "Manage rooms for this server", "sync | list | info | add | remove ")
 	err = h.registry.AddGroup("guilds", "admin")
+	h.router.AddRoute("guilds", true, h.ParseCommand, "admin")
 	return err
 
 }
 
-// Read function
-func (h *GuildsHandler) Read(s *discordgo.Session, m *discordgo.MessageCreate) {
-
-	cp := h.conf.MainConfig.CP
-
-	if !SafeInput(s, m, h.conf) {
-		return
-	}
-
-	user, err := h.db.GetUser(m.Author.ID)
-	if err != nil {
-		//fmt.Println("Error finding usermanager")
-		return
-	}
-	if !user.CheckRole("admin") {
-		return
-	}
-	if strings.HasPrefix(m.Content, cp+"guilds") {
-		if h.registry.CheckPermission("guilds", user, s, m) {
-
-			// Grab our sender ID to verify if this usermanager has permission to use this command
-			db := h.db.rawdb.From("Users")
-			var user User
-			err := db.One("ID", m.Author.ID, &user)
-			if err != nil {
-				fmt.Println("error retrieving usermanager:" + m.Author.ID)
-			}
-
-			command := strings.Fields(m.Content)
-
-			if user.CheckRole("admin") {
-				h.ParseCommand(command, s, m)
-			}
-		}
-	}
-}
-
 // ParseCommand function
-func (h *GuildsHandler) ParseCommand(command []string, s *discordgo.Session, m *discordgo.MessageCreate) {
+func (h *GuildsHandler) ParseCommand(command []string, user User, s DiscordSession, m *discordgo.MessageCreate) {
 
 	guildID, err := getGuildID(s, m.ChannelID)
 	if err != nil {
@@ -215,8 +179,8 @@ func (h *GuildsHandler) ParseCommand(command []string, s *discordgo.Session, m *
 // SyncCluster function
 // This will sync the entire cluster, roles for every room and permissions for all of them
 // This is a very intensive task so it's important that it not be run all the time
-// There are timers throughout it to try and alleviate some of the strain on the api
-func (h *GuildsHandler) SyncCluster(s *discordgo.Session) (err error) {
+// Role and permission changes go through the discord queue at low priority so player travel isn't held up
+func (h *GuildsHandler) SyncCluster(s DiscordSession) (err error) {
 	h.clustersynclocker.Lock() // Don't let multiple cluster syncs happen at the same time!
 	defer h.clustersynclocker.Unlock()
 
@@ -227,8 +191,6 @@ func (h *GuildsHandler) SyncCluster(s *discordgo.Session) (err error) {
 
 	// Parse through guild list
 	for _, guild := range guilds {
-		// Wait 10 seconds between each guild
-		time.Sleep(time.Duration(time.Second * 10))
 		h.SyncGuild(guild.ID, s)
 	}
 
@@ -239,7 +201,7 @@ func (h *GuildsHandler) SyncCluster(s *discordgo.Session) (err error) {
 // This will resync a specific guild overwriting any settings in the DB for it
 // It will also fix roles for users in that guild
 // It by itself will take a long time to finish
-func (h *GuildsHandler) SyncGuild(guildID string, s *discordgo.Session) (err error) {
+func (h *GuildsHandler) SyncGuild(guildID string, s DiscordSession) (err error) {
 	h.guildsynclocker.Lock() // One guild at a time!
 	defer h.guildsynclocker.Unlock()
 
@@ -275,28 +237,24 @@ func (h *GuildsHandler) SyncGuild(guildID string, s *discordgo.Session) (err err
 	guild.AFKTimeout = discordguild.AfkTimeout
 	guild.Icon = discordguild.Icon
 
-	time.Sleep(time.Duration(time.Second * 2))
 	adminID, err := h.guildmanager.GetGuildDiscordAdminID(guild.ID, s)
 	if err != nil {
 		return err
 	}
 	guild.AdminID = adminID
 
-	time.Sleep(time.Duration(time.Second * 2))
 	builderID, err := h.guildmanager.GetGuildDiscordBuilderID(guild.ID, s)
 	if err != nil {
 		return err
 	}
 	guild.BuilderID = builderID
 
-	time.Sleep(time.Duration(time.Second * 2))
 	moderatorID, err := h.guildmanager.GetGuildDiscordModeratorID(guild.ID, s)
 	if err != nil {
 		return err
 	}
 	guild.ModeratorID = moderatorID
 
-	time.Sleep(time.Duration(time.Second * 2))
 	everyoneID, err := h.guildmanager.GetGuildDiscordEveryoneID(guild.ID, s)
 	if err != nil {
 		return err
@@ -313,7 +271,6 @@ func (h *GuildsHandler) SyncGuild(guildID string, s *discordgo.Session) (err err
 
 		// If the room is in the current guild
 		if room.GuildID == guild.ID {
-			time.Sleep(time.Duration(time.Second * 5))
 			// Once per room we run syncroom which handles default permission assignments
 			err = h.room.SyncRoom(room.ID, s)
 			if err != nil {
@@ -349,6 +306,11 @@ func (h *GuildsHandler) SyncGuild(guildID string, s *discordgo.Session) (err err
 							}
 							// Otherwise if no record exists for the channel, then we update this record and resync
 							if !foundchannel {
+								// The record is keyed by channel ID, so the stale one has to go
+								err = h.room.rooms.RemoveRoomByID(room.ID)
+								if err != nil {
+									return err
+								}
 								room.ID = channel.ID
 								err = h.room.rooms.SaveRoomToDB(room)
 								if err != nil {
@@ -386,6 +348,10 @@ func (h *GuildsHandler) SyncGuild(guildID string, s *discordgo.Session) (err err
 						}
 
 						// Update the room record and save it to the DB
+						err = h.room.rooms.RemoveRoomByID(room.ID)
+						if err != nil {
+							return err
+						}
 						room.ID = createdchannel.ID
 						err = h.room.rooms.SaveRoomToDB(room)
 						if err != nil {
@@ -426,7 +392,6 @@ func (h *GuildsHandler) SyncGuild(guildID string, s *discordgo.Session) (err err
 					for _, room := range discordguild.Channels {
 						// If the user is in the room
 						if room.ID == user.RoomID {
-							time.Sleep(time.Duration(time.Second * 1))
 							err = h.user.RepairUser(user.ID, s, room.ID, user.GuildID)
 							if err != nil {
 								return errors.New("Error Repairing User: " + err.Error())
@@ -462,6 +427,7 @@ func (h *GuildsHandler) GuildInfo(guildID string) (formatted string, err error)
 	output = output + "BuilderID: " + guild.BuilderID + "\n\n"
 	output = output + "User Count: " + strconv.Itoa(len(guild.UserIDs)) + "\n"
 	output = output + "Role Count: " + strconv.Itoa(len(guild.RoleIDs)) + "\n"
+	output = output + "Overflow Guild: " + guild.OverflowGuildID + "\n"
 	output = output + "\n```\n"
 
 	return output, nil
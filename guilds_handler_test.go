@@ -0,0 +1,102 @@
+package main
+
+import (
+	"testing"
+)
+
+func TestSyncGuild(t *testing.T) {
+
+	w := newTestWorld(t)
+	err := w.guilds.RegisterGuild(testCentralGuild, w.s)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Discord has changed since the guild was registered
+	discordguild, _ := w.s.Guild(testCentralGuild)
+	discordguild.Name = "The Aether Reborn"
+
+	// A record whose channel was deleted, and one whose channel was recreated under a new ID
+	w.rooms.rooms.SaveRoomToDB(Room{ID: "5001", Name: "cellar", GuildID: testCentralGuild, Type: "room"})
+	attic, _ := w.s.AddChannel(testCentralGuild, "attic", "")
+	w.rooms.rooms.SaveRoomToDB(Room{ID: "5002", Name: "attic", GuildID: testCentralGuild, Type: "room"})
+
+	// Roles the user record holds but discord has lost
+	alice := w.addPlayer(t, "1", "Alice", w.lobby)
+	spoilersID := w.addRole(t, testCentralGuild, "Spoilers")
+	alice.RoleIDs = append(alice.RoleIDs, spoilersID)
+	w.user.usermanager.SaveUserToDB(alice)
+	w.s.GuildMemberRoleRemove(testCentralGuild, alice.ID, w.lobby.TravelRoleID)
+
+	err = w.guildhandler.SyncGuild(testCentralGuild, w.s)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	guild, err := w.guilds.GetGuildByID(testCentralGuild)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if guild.Name != "The Aether Reborn" {
+		t.Errorf("guild name = %q, want the name from discord", guild.Name)
+	}
+	adminID, _ := getRoleIDByName(w.s, testCentralGuild, "Admin")
+	if guild.AdminID != adminID || guild.EveryoneID != testCentralGuild {
+		t.Errorf("guild admin = %s and everyone = %s, want %s and %s", guild.AdminID, guild.EveryoneID, adminID,
+			testCentralGuild)
+	}
+	if !StringInSlice(spoilersID, guild.RoleIDs) {
+		t.Error("guild record is missing a discord role")
+	}
+	if !StringInSlice(alice.ID, guild.UserIDs) {
+		t.Error("guild record is missing a member")
+	}
+
+	tests := []struct {
+		name    string
+		wantID  string // Empty for a channel the sync had to create
+		oldID   string
+		checked bool
+	}{
+		{name: "lobby", wantID: w.lobby.ID},
+		{name: "attic", wantID: attic.ID, oldID: "5002"},
+		{name: "cellar", oldID: "5001"},
+	}
+	for _, test := range tests {
+		room, err := w.rooms.rooms.GetRoomByName(test.name, testCentralGuild)
+		if err != nil {
+			t.Errorf("%s: %s", test.name, err.Error())
+			continue
+		}
+		if test.wantID != "" && room.ID != test.wantID {
+			t.Errorf("%s record has ID %s, want %s", test.name, room.ID, test.wantID)
+		}
+		if test.oldID != "" {
+			if room.ID == test.oldID {
+				t.Errorf("%s record was not moved to a channel", test.name)
+			}
+			if _, err := w.rooms.rooms.GetRoomByID(test.oldID); err == nil {
+				t.Errorf("%s record is still under its old ID", test.name)
+			}
+		}
+
+		channel, err := w.s.Channel(room.ID)
+		if err != nil {
+			t.Errorf("%s: %s", test.name, err.Error())
+			continue
+		}
+		overwrites := map[string]bool{}
+		for _, overwrite := range channel.PermissionOverwrites {
+			overwrites[overwrite.ID] = true
+		}
+		if !overwrites[adminID] || !overwrites[testCentralGuild] {
+			t.Errorf("%s is missing its admin or everyone permissions", test.name)
+		}
+	}
+
+	for _, roleID := range []string{w.lobby.TravelRoleID, spoilersID} {
+		waitFor(t, "role "+roleID+" to be restored", func() bool {
+			return w.s.MemberHasRole(testCentralGuild, alice.ID, roleID)
+		})
+	}
+}
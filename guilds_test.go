@@ -0,0 +1,63 @@
+package main
+
+import (
+	"strconv"
+	"testing"
+)
+
+// seedGuilds function
+// Saves count guilds in one transaction, guild i is named guild-i
+func seedGuilds(b *testing.B, db *DBHandler, count int) *GuildsManager {
+	b.Helper()
+
+	tx, err := db.rawdb.Begin(true)
+	if err != nil {
+		b.Fatal(err)
+	}
+	defer tx.Rollback()
+
+	bucket := tx.From("Guilds")
+	for i := 0; i < count; i++ {
+		guild := GuildRecord{ID: strconv.Itoa(100000 + i), Name: "guild-" + strconv.Itoa(i)}
+		err = bucket.Save(&guild)
+		if err != nil {
+			b.Fatal(err)
+		}
+	}
+	err = tx.Commit()
+	if err != nil {
+		b.Fatal(err)
+	}
+	return &GuildsManager{db: db}
+}
+
+func BenchmarkGetGuildByID(b *testing.B) {
+	for _, size := range benchmarkSizes {
+		b.Run(strconv.Itoa(size), func(b *testing.B) {
+			guilds := seedGuilds(b, newBenchmarkDB(b), size)
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				_, err := guilds.GetGuildByID(strconv.Itoa(100000 + i%size))
+				if err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
+
+func BenchmarkGetGuildByName(b *testing.B) {
+	for _, size := range benchmarkSizes {
+		b.Run(strconv.Itoa(size), func(b *testing.B) {
+			guilds := seedGuilds(b, newBenchmarkDB(b), size)
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				guild := i % size
+				_, err := guilds.GetGuildByName("guild-"+strconv.Itoa(guild), strconv.Itoa(100000+guild))
+				if err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
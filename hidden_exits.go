@@ -0,0 +1,104 @@
+package main
+
+/*
+Hidden exits can't be seen or traveled through until a player finds them with search.
+
+A builder hides an exit by giving it a DC and the skill to check against (perception unless survival is set). When a
+player searches, they roll a d20 and add their skill, and every hidden exit in the room with a DC at or below the
+total is discovered. Discoveries are stored on the user as "<room ID>/<exit name>" so a found exit stays found.
+
+Builders always see hidden exits in room info and the map exports, it's only players who need to find them.
+
+*/
+
+import (
+	"errors"
+	"strings"
+)
+
+// Skills a hidden exit can be searched for with
+const (
+	HiddenSkillPerception = "perception"
+	HiddenSkillSurvival   = "survival"
+)
+
+// IsHidden function
+func (e *Exit) IsHidden() bool {
+	return e.HiddenDC > 0
+}
+
+// SearchSkill function
+func (e *Exit) SearchSkill() string {
+	if e.HiddenSkill == "" {
+		return HiddenSkillPerception
+	}
+	return e.HiddenSkill
+}
+
+// ParseHiddenSkill function
+func ParseHiddenSkill(skill string) (parsed string, err error) {
+	switch strings.ToLower(skill) {
+	case "", HiddenSkillPerception:
+		return HiddenSkillPerception, nil
+	case HiddenSkillSurvival:
+		return HiddenSkillSurvival, nil
+	}
+	return "", errors.New("Unrecognized skill " + skill + " (expected perception or survival)")
+}
+
+// discoveryKey function
+func discoveryKey(roomID string, exitname string) string {
+	return roomID + "/" + exitname
+}
+
+// HasDiscovered function
+func (u *User) HasDiscovered(roomID string, exit Exit) bool {
+	return StringInSlice(discoveryKey(roomID, exit.Name), u.DiscoveredExits)
+}
+
+// CanSeeExit function
+func (u *User) CanSeeExit(roomID string, exit Exit) bool {
+	return !exit.IsHidden() || u.HasDiscovered(roomID, exit)
+}
+
+// Discover function
+func (u *User) Discover(roomID string, exit Exit) {
+	if !u.HasDiscovered(roomID, exit) {
+		u.DiscoveredExits = append(u.DiscoveredExits, discoveryKey(roomID, exit.Name))
+	}
+}
+
+// SkillBonus function
+func (u *User) SkillBonus(skill string) int {
+	if skill == HiddenSkillSurvival {
+		return int(u.Survival)
+	}
+	return int(u.Perception)
+}
+
+// VisibleExits function
+// The exits in a room the user can see
+func (u *User) VisibleExits(room Room) (exits []Exit) {
+	for _, exit := range room.Exits {
+		if u.CanSeeExit(room.ID, exit) {
+			exits = append(exits, exit)
+		}
+	}
+	return exits
+}
+
+// VisibleTo function
+// A copy of the graph without the hidden exits the user hasn't found, so maps and routes don't give them away
+func (g *WorldGraph) VisibleTo(user User) (graph WorldGraph) {
+
+	graph = WorldGraph{Rooms: g.Rooms, Adjacency: make(map[string][]MapEdge)}
+	for roomID, edges := range g.Adjacency {
+		for _, edge := range edges {
+			if edge.Hidden && !StringInSlice(discoveryKey(edge.From, edge.Name), user.DiscoveredExits) {
+				continue
+			}
+			graph.Adjacency[roomID] = append(graph.Adjacency[roomID], edge)
+		}
+	}
+	return graph
+}
@@ -0,0 +1,301 @@
+package main
+
+/*
+An optional local HTTP server for health checks, metrics and a read-only view of the world.
+
+Nothing here requires authentication, so http_listen should stay bound to localhost (or a private interface) and be
+put behind a proxy if it ever needs to be reachable from elsewhere. Everything is served from Handler(), so the
+endpoints can be driven with httptest without a live discord session.
+
+/api/players lists the players discord reports as online, taken from the presences in our session state, along with
+the room their record places them in.
+
+*/
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"github.com/bwmarrin/discordgo"
+	"net/http"
+	"net/http/pprof"
+	"time"
+)
+
+// HTTPServer struct
+type HTTPServer struct {
+	conf      *Config
+	db        *DBHandler
+	lifecycle *Lifecycle
+	metrics   *Metrics
+	queue     *DiscordQueue
+
+	rooms     *Rooms
+	guilds    *GuildsManager
+	users     *UserManager
+	transfers *Transfers
+
+	// Our session state, where discord keeps the presences of every guild we are in
+	state *discordgo.State
+
+	// Reports whether our discord websocket is connected and ready
+	discordready func() bool
+}
+
+// HealthStatus struct
+type HealthStatus struct {
+	Status   string
+	Discord  string
+	Database string
+	Workers  []WorkerStatus `json:",omitempty"`
+}
+
+// RoomSummary struct
+type RoomSummary struct {
+	ID       string
+	Name     string
+	GuildID  string
+	Type     string
+	Topic    string
+	Players  int
+	ParentID string
+}
+
+// GuildSummary struct
+type GuildSummary struct {
+	ID      string
+	Name    string
+	Rooms   int
+	Backlog int
+}
+
+// PlayerSummary struct
+type PlayerSummary struct {
+	ID      string
+	Name    string
+	RoomID  string
+	GuildID string
+	Status  string
+}
+
+// Handler function
+func (h *HTTPServer) Handler() http.Handler {
+	mux := http.NewServeMux()
+
+	mux.HandleFunc("/healthz", h.Healthz)
+	mux.HandleFunc("/readyz", h.Readyz)
+	mux.HandleFunc("/metrics", h.Metrics)
+	mux.HandleFunc("/api/rooms", h.Rooms)
+	mux.HandleFunc("/api/guilds", h.Guilds)
+	mux.HandleFunc("/api/players", h.Players)
+
+	if h.conf.MainConfig.Profiler {
+		mux.HandleFunc("/debug/pprof/", pprof.Index)
+		mux.HandleFunc("/debug/pprof/cmdline", pprof.Cmdline)
+		mux.HandleFunc("/debug/pprof/profile", pprof.Profile)
+		mux.HandleFunc("/debug/pprof/symbol", pprof.Symbol)
+		mux.HandleFunc("/debug/pprof/trace", pprof.Trace)
+	}
+	return mux
+}
+
+// ListenAddress function
+// Keeps the old behaviour of enable_profiler serving on :8080 when no http_listen is configured
+func (h *HTTPServer) ListenAddress() string {
+	if h.conf.MainConfig.HTTPListen != "" {
+		return h.conf.MainConfig.HTTPListen
+	}
+	if h.conf.MainConfig.Profiler {
+		return ":8080"
+	}
+	return ""
+}
+
+// Serve function
+// Runs until ctx is cancelled, meant to be started through the lifecycle manager
+func (h *HTTPServer) Serve(ctx context.Context) error {
+
+	server := &http.Server{Addr: h.ListenAddress(), Handler: h.Handler(), ReadTimeout: time.Second * 10,
+		WriteTimeout: time.Second * 60}
+
+	failed := make(chan error, 1)
+	go func() {
+		failed <- server.ListenAndServe()
+	}()
+
+	select {
+	case err := <-failed:
+		return err
+	case <-ctx.Done():
+	}
+
+	shutdownctx, cancel := context.WithTimeout(context.Background(), time.Second*5)
+	defer cancel()
+	return server.Shutdown(shutdownctx)
+}
+
+// Health function
+func (h *HTTPServer) Health() (health HealthStatus, ready bool) {
+
+	health = HealthStatus{Status: "ok", Discord: "connected", Database: "ok"}
+	ready = true
+
+	if h.discordready == nil || !h.discordready() {
+		health.Discord = "disconnected"
+		ready = false
+	}
+
+	_, err := h.db.GetSchemaVersion()
+	if err != nil {
+		health.Database = err.Error()
+		ready = false
+	}
+
+	if h.lifecycle != nil {
+		health.Workers = h.lifecycle.Workers()
+		if h.lifecycle.Stopping() {
+			health.Status = "stopping"
+			ready = false
+		}
+	}
+
+	if !ready && health.Status == "ok" {
+		health.Status = "degraded"
+	}
+	return health, ready
+}
+
+// Healthz function
+// Only fails when the database can't be read, a dropped discord connection is reconnected on its own
+func (h *HTTPServer) Healthz(w http.ResponseWriter, r *http.Request) {
+	health, _ := h.Health()
+
+	status := http.StatusOK
+	if health.Database != "ok" {
+		status = http.StatusServiceUnavailable
+	}
+	writeJSON(w, status, health)
+}
+
+// Readyz function
+func (h *HTTPServer) Readyz(w http.ResponseWriter, r *http.Request) {
+	health, ready := h.Health()
+
+	status := http.StatusOK
+	if !ready {
+		status = http.StatusServiceUnavailable
+	}
+	writeJSON(w, status, health)
+}
+
+// Metrics function
+func (h *HTTPServer) Metrics(w http.ResponseWriter, r *http.Request) {
+
+	gauges := make(map[string]int)
+	transfers, err := h.transfers.GetAllTransfers()
+	if err == nil {
+		gauges["aether_pending_transfers"] = len(transfers)
+	}
+	if h.queue != nil {
+		backlog := 0
+		for _, status := range h.queue.Status() {
+			backlog = backlog + status.Pending[QueueHigh] + status.Pending[QueueNormal] + status.Pending[QueueLow]
+		}
+		gauges["aether_discord_queue_backlog"] = backlog
+	}
+
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4")
+	h.metrics.WritePrometheus(w, gauges)
+}
+
+// Rooms function
+func (h *HTTPServer) Rooms(w http.ResponseWriter, r *http.Request) {
+
+	rooms, err := h.rooms.GetAllRooms()
+	if err != nil {
+		writeJSONError(w, err)
+		return
+	}
+
+	summaries := []RoomSummary{}
+	for _, room := range rooms {
+		summaries = append(summaries, RoomSummary{ID: room.ID, Name: room.Name, GuildID: room.GuildID, Type: room.Type,
+			Topic: room.Topic, Players: len(room.UserIDs), ParentID: room.ParentID})
+	}
+	writeJSON(w, http.StatusOK, summaries)
+}
+
+// Guilds function
+func (h *HTTPServer) Guilds(w http.ResponseWriter, r *http.Request) {
+
+	guilds, err := h.guilds.GetAllGuilds()
+	if err != nil {
+		writeJSONError(w, err)
+		return
+	}
+
+	summaries := []GuildSummary{}
+	for _, guild := range guilds {
+		summary := GuildSummary{ID: guild.ID, Name: guild.Name}
+		rooms, err := h.rooms.GetRoomsByGuildID(guild.ID)
+		if err == nil {
+			summary.Rooms = len(rooms)
+		}
+		if h.queue != nil {
+			summary.Backlog = h.queue.Backlog(guild.ID)
+		}
+		summaries = append(summaries, summary)
+	}
+	writeJSON(w, http.StatusOK, summaries)
+}
+
+// Players function
+// Lists every registered player that is online in one of our guilds. Guild members without a player record (staff,
+// visitors who haven't registered) aren't listed.
+func (h *HTTPServer) Players(w http.ResponseWriter, r *http.Request) {
+
+	if h.state == nil {
+		writeJSONError(w, errors.New("No discord session state"))
+		return
+	}
+
+	// A user is in the presences of every guild they share with us, but should only be listed once
+	var userIDs []string
+	statuses := make(map[string]discordgo.Status)
+	h.state.RLock()
+	for _, guild := range h.state.Guilds {
+		for _, presence := range guild.Presences {
+			if presence.User == nil || presence.Status == "" || presence.Status == discordgo.StatusOffline {
+				continue
+			}
+			if _, seen := statuses[presence.User.ID]; !seen {
+				userIDs = append(userIDs, presence.User.ID)
+				statuses[presence.User.ID] = presence.Status
+			}
+		}
+	}
+	h.state.RUnlock()
+
+	summaries := []PlayerSummary{}
+	for _, userID := range userIDs {
+		user, err := h.users.GetUserByID(userID)
+		if err != nil || user.RoomID == "" || !user.CheckRole("player") {
+			continue
+		}
+		summaries = append(summaries, PlayerSummary{ID: user.ID, Name: user.Name, RoomID: user.RoomID,
+			GuildID: user.GuildID, Status: string(statuses[userID])})
+	}
+	writeJSON(w, http.StatusOK, summaries)
+}
+
+// writeJSON function
+func writeJSON(w http.ResponseWriter, status int, value interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(value)
+}
+
+// writeJSONError function
+func writeJSONError(w http.ResponseWriter, err error) {
+	writeJSON(w, http.StatusInternalServerError, map[string]string{"Error": err.Error()})
+}
@@ -0,0 +1,247 @@
+package main
+
+import (
+	"encoding/json"
+	"github.com/bwmarrin/discordgo"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+// newTestHTTPServer function
+// A world with a linked lobby and hall, Alice online in the lobby, Bob online but not placed anywhere and Carol
+// offline in the hall
+func newTestHTTPServer(t *testing.T) (*testWorld, *HTTPServer, *bool) {
+	t.Helper()
+
+	w := newTestWorld(t)
+	err := w.guilds.RegisterGuild(testCentralGuild, w.s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	hall := w.addRoom(t, testCentralGuild, "hall")
+	w.link(t, "north", w.lobby, hall)
+	w.addPlayer(t, "1", "Alice", w.lobby)
+	w.user.usermanager.SaveUserToDB(User{ID: "2", Name: "Bob", GuildID: testCentralGuild})
+	w.addPlayer(t, "3", "Carol", hall)
+
+	// Alice shares both guilds with us, so she has a presence in each
+	state := discordgo.NewState()
+	for _, guildID := range []string{testCentralGuild, testOtherGuild} {
+		err = state.GuildAdd(&discordgo.Guild{ID: guildID})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	presences := []struct {
+		guildID string
+		userID  string
+		status  discordgo.Status
+	}{
+		{testCentralGuild, "1", discordgo.StatusOnline},
+		{testOtherGuild, "1", discordgo.StatusOnline},
+		{testCentralGuild, "2", discordgo.StatusOnline},
+		{testCentralGuild, "3", discordgo.StatusOffline},
+	}
+	for _, presence := range presences {
+		err = state.PresenceAdd(presence.guildID, &discordgo.Presence{User: &discordgo.User{ID: presence.userID},
+			Status: presence.status})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	ready := true
+	server := &HTTPServer{conf: w.conf, db: w.db, lifecycle: w.lifecycle, metrics: &Metrics{}, queue: w.queue,
+		rooms: w.rooms.rooms, guilds: w.guilds, users: w.user.usermanager, transfers: w.transfer.transferdb,
+		state: state}
+	server.discordready = func() bool { return ready }
+	return w, server, &ready
+}
+
+func TestHTTPHealth(t *testing.T) {
+
+	tests := []struct {
+		name     string
+		path     string
+		setup    func(w *testWorld, ready *bool)
+		status   int
+		health   string
+		discord  string
+		database string
+	}{
+		{name: "healthy", path: "/healthz", status: http.StatusOK, health: "ok", discord: "connected"},
+		{name: "ready", path: "/readyz", status: http.StatusOK, health: "ok", discord: "connected"},
+		{
+			// A dropped connection is reconnected on its own, so the process is still healthy
+			name:    "healthy while disconnected",
+			path:    "/healthz",
+			setup:   func(w *testWorld, ready *bool) { *ready = false },
+			status:  http.StatusOK,
+			health:  "degraded",
+			discord: "disconnected",
+		},
+		{
+			name:    "not ready while disconnected",
+			path:    "/readyz",
+			setup:   func(w *testWorld, ready *bool) { *ready = false },
+			status:  http.StatusServiceUnavailable,
+			health:  "degraded",
+			discord: "disconnected",
+		},
+		{
+			name:    "not ready while stopping",
+			path:    "/readyz",
+			setup:   func(w *testWorld, ready *bool) { w.lifecycle.Shutdown(time.Second) },
+			status:  http.StatusServiceUnavailable,
+			health:  "stopping",
+			discord: "connected",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			w, server, ready := newTestHTTPServer(t)
+			if test.setup != nil {
+				test.setup(w, ready)
+			}
+
+			recorder := httptest.NewRecorder()
+			server.Handler().ServeHTTP(recorder, httptest.NewRequest("GET", test.path, nil))
+			if recorder.Code != test.status {
+				t.Errorf("status = %d, want %d", recorder.Code, test.status)
+			}
+
+			var health HealthStatus
+			err := json.NewDecoder(recorder.Body).Decode(&health)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if health.Status != test.health || health.Discord != test.discord || health.Database != "ok" {
+				t.Errorf("health = %+v, want %s with discord %s", health, test.health, test.discord)
+			}
+		})
+	}
+}
+
+func TestHTTPMetrics(t *testing.T) {
+
+	w, server, _ := newTestHTTPServer(t)
+	server.metrics.CommandDispatched("travel")
+	server.metrics.TravelMove()
+	w.transfer.AddTransfer("1", w.lobby.ID, w.lobby.ID, testCentralGuild, "")
+
+	recorder := httptest.NewRecorder()
+	server.Handler().ServeHTTP(recorder, httptest.NewRequest("GET", "/metrics", nil))
+	if recorder.Code != http.StatusOK {
+		t.Fatalf("status = %d", recorder.Code)
+	}
+	if content := recorder.Header().Get("Content-Type"); !strings.HasPrefix(content, "text/plain") {
+		t.Errorf("content type = %q", content)
+	}
+
+	body := recorder.Body.String()
+	for _, line := range []string{
+		"aether_commands_dispatched_total{command=\"travel\"} 1",
+		"aether_travel_moves_total 1",
+		"aether_discord_api_errors_total 0",
+		"aether_pending_transfers 1",
+		"aether_discord_queue_backlog 0",
+	} {
+		if !strings.Contains(body, line+"\n") {
+			t.Errorf("metrics are missing %q", line)
+		}
+	}
+}
+
+func TestHTTPAPI(t *testing.T) {
+
+	w, server, _ := newTestHTTPServer(t)
+	hall, _ := w.rooms.rooms.GetRoomByName("hall", testCentralGuild)
+
+	tests := []struct {
+		path  string
+		into  func() interface{}
+		check func(t *testing.T, decoded interface{})
+	}{
+		{
+			path: "/api/rooms",
+			into: func() interface{} { return &[]RoomSummary{} },
+			check: func(t *testing.T, decoded interface{}) {
+				players := map[string]int{}
+				for _, room := range *decoded.(*[]RoomSummary) {
+					players[room.ID] = room.Players
+				}
+				if len(players) != 2 || players[w.lobby.ID] != 1 || players[hall.ID] != 1 {
+					t.Errorf("rooms = %+v, want the lobby and hall with one player each", decoded)
+				}
+			},
+		},
+		{
+			path: "/api/guilds",
+			into: func() interface{} { return &[]GuildSummary{} },
+			check: func(t *testing.T, decoded interface{}) {
+				guilds := *decoded.(*[]GuildSummary)
+				if len(guilds) != 1 || guilds[0].ID != testCentralGuild || guilds[0].Name != "The Aether" ||
+					guilds[0].Rooms != 2 {
+					t.Errorf("guilds = %+v, want the central guild with 2 rooms", guilds)
+				}
+			},
+		},
+		{
+			path: "/api/players",
+			into: func() interface{} { return &[]PlayerSummary{} },
+			check: func(t *testing.T, decoded interface{}) {
+				players := *decoded.(*[]PlayerSummary)
+				if len(players) != 1 || players[0].ID != "1" || players[0].RoomID != w.lobby.ID ||
+					players[0].Status != "online" {
+					t.Errorf("players = %+v, want only Alice online in the lobby", players)
+				}
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.path, func(t *testing.T) {
+			recorder := httptest.NewRecorder()
+			server.Handler().ServeHTTP(recorder, httptest.NewRequest("GET", test.path, nil))
+			if recorder.Code != http.StatusOK {
+				t.Fatalf("status = %d", recorder.Code)
+			}
+			if content := recorder.Header().Get("Content-Type"); content != "application/json" {
+				t.Errorf("content type = %q", content)
+			}
+
+			decoded := test.into()
+			err := json.NewDecoder(recorder.Body).Decode(decoded)
+			if err != nil {
+				t.Fatal(err)
+			}
+			test.check(t, decoded)
+		})
+	}
+}
+
+func TestHTTPProfiler(t *testing.T) {
+
+	tests := []struct {
+		profiler bool
+		status   int
+	}{
+		{profiler: false, status: http.StatusNotFound},
+		{profiler: true, status: http.StatusOK},
+	}
+
+	for _, test := range tests {
+		_, server, _ := newTestHTTPServer(t)
+		server.conf.MainConfig.Profiler = test.profiler
+
+		recorder := httptest.NewRecorder()
+		server.Handler().ServeHTTP(recorder, httptest.NewRequest("GET", "/debug/pprof/", nil))
+		if recorder.Code != test.status {
+			t.Errorf("profiler %v: status = %d, want %d", test.profiler, recorder.Code, test.status)
+		}
+	}
+}
@@ -0,0 +1,235 @@
+package main
+
+/*
+The lifecycle manager owns every long running background worker, and tracks in-flight work (commands, transfers)
+that has to finish before the bot can shut down.
+
+Workers are started with Start and are handed a context that is cancelled on shutdown, they should check it between
+units of work (SleepContext makes this easy) and report in with Heartbeat so their health can be reported. Shutdown
+stops new work from being accepted, cancels the workers and then waits for everything in flight to finish, up to the
+configured timeout, before storm and the discord session are closed.
+
+*/
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// WorkerFunc type
+type WorkerFunc func(ctx context.Context) error
+
+// WorkerStatus struct
+type WorkerStatus struct {
+	Name          string
+	Running       bool
+	Started       time.Time
+	Stopped       time.Time
+	LastHeartbeat time.Time
+	Error         string
+}
+
+// worker struct
+type worker struct {
+	status WorkerStatus
+	locker sync.RWMutex
+}
+
+// workerContextKey is used to find the running worker from inside its own context
+type workerContextKey struct{}
+
+// Lifecycle struct
+type Lifecycle struct {
+	logger *Logger
+
+	ctx    context.Context
+	cancel context.CancelFunc
+
+	workers      map[string]*worker
+	workerlocker sync.RWMutex
+	workergroup  sync.WaitGroup
+
+	inflight       sync.WaitGroup
+	inflightlocker sync.RWMutex
+	stopping       bool
+}
+
+// Init function
+func (h *Lifecycle) Init() {
+	h.ctx, h.cancel = context.WithCancel(context.Background())
+	h.workers = make(map[string]*worker)
+}
+
+// Context function
+func (h *Lifecycle) Context() context.Context {
+	return h.ctx
+}
+
+// Start function
+// Runs worker in its own goroutine until it returns or the lifecycle is shut down
+func (h *Lifecycle) Start(name string, run WorkerFunc) (err error) {
+	h.workerlocker.Lock()
+	defer h.workerlocker.Unlock()
+
+	if h.Stopping() {
+		return errors.New("Cannot start " + name + ", shutdown in progress")
+	}
+	if existing, ok := h.workers[name]; ok && existing.IsRunning() {
+		return errors.New("Worker already running: " + name)
+	}
+
+	record := &worker{status: WorkerStatus{Name: name, Running: true, Started: time.Now(), LastHeartbeat: time.Now()}}
+	h.workers[name] = record
+	ctx := context.WithValue(h.ctx, workerContextKey{}, record)
+
+	h.workergroup.Add(1)
+	go func() {
+		defer h.workergroup.Done()
+
+		err := h.runWorker(ctx, run)
+
+		record.locker.Lock()
+		record.status.Running = false
+		record.status.Stopped = time.Now()
+		if err != nil {
+			record.status.Error = err.Error()
+		}
+		record.locker.Unlock()
+
+		if err != nil {
+			h.logger.Error(BOTLOG, LogFields{}, "Worker "+name+" stopped with error: "+err.Error())
+		} else if ctx.Err() == nil {
+			h.logger.Warn(BOTLOG, LogFields{}, "Worker "+name+" stopped on its own")
+		}
+	}()
+
+	h.logger.Debug(BOTLOG, LogFields{}, "Started worker "+name)
+	return nil
+}
+
+// runWorker function
+// A panicking worker shouldn't take the whole bot down with it
+func (h *Lifecycle) runWorker(ctx context.Context, run WorkerFunc) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = errors.New(fmt.Sprint("panic: ", r))
+		}
+	}()
+	return run(ctx)
+}
+
+// Workers function
+// Returns a copy of every worker's status, sorted by name
+func (h *Lifecycle) Workers() (workers []WorkerStatus) {
+	h.workerlocker.RLock()
+	defer h.workerlocker.RUnlock()
+
+	for _, record := range h.workers {
+		workers = append(workers, record.Status())
+	}
+
+	sort.Slice(workers, func(i, j int) bool { return workers[i].Name < workers[j].Name })
+	return workers
+}
+
+// Status function
+func (w *worker) Status() WorkerStatus {
+	w.locker.RLock()
+	defer w.locker.RUnlock()
+	return w.status
+}
+
+// IsRunning function
+func (w *worker) IsRunning() bool {
+	return w.Status().Running
+}
+
+// Track function
+// Marks the start of work that must finish before shutdown, call the returned function when it is done. Returns
+// false once shutdown has begun, in which case the work should not be started at all.
+func (h *Lifecycle) Track() (done func(), ok bool) {
+	h.inflightlocker.RLock()
+	defer h.inflightlocker.RUnlock()
+
+	if h.stopping {
+		return func() {}, false
+	}
+
+	h.inflight.Add(1)
+	var once sync.Once
+	return func() { once.Do(h.inflight.Done) }, true
+}
+
+// Stopping function
+func (h *Lifecycle) Stopping() bool {
+	h.inflightlocker.RLock()
+	defer h.inflightlocker.RUnlock()
+	return h.stopping
+}
+
+// Shutdown function
+// Stops accepting new work, cancels every worker and waits up to timeout for them and any in-flight work to finish
+func (h *Lifecycle) Shutdown(timeout time.Duration) (err error) {
+
+	h.inflightlocker.Lock()
+	h.stopping = true
+	h.inflightlocker.Unlock()
+
+	h.cancel()
+
+	finished := make(chan bool)
+	go func() {
+		h.workergroup.Wait()
+		h.inflight.Wait()
+		close(finished)
+	}()
+
+	select {
+	case <-finished:
+		return nil
+	case <-time.After(timeout):
+	}
+
+	var running []string
+	for _, status := range h.Workers() {
+		if status.Running {
+			running = append(running, status.Name)
+		}
+	}
+	if len(running) > 0 {
+		return errors.New("Shutdown timed out waiting for workers: " + strings.Join(running, ", "))
+	}
+	return errors.New("Shutdown timed out waiting for in-flight work")
+}
+
+// Heartbeat function
+// Lets a worker report that it is still alive, does nothing outside of a worker
+func Heartbeat(ctx context.Context) {
+	record, ok := ctx.Value(workerContextKey{}).(*worker)
+	if !ok {
+		return
+	}
+
+	record.locker.Lock()
+	record.status.LastHeartbeat = time.Now()
+	record.locker.Unlock()
+}
+
+// SleepContext function
+// Sleeps for d, returns false if ctx was cancelled first
+func SleepContext(ctx context.Context, d time.Duration) bool {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+
+	select {
+	case <-ctx.Done():
+		return false
+	case <-timer.C:
+		return true
+	}
+}
@@ -1,16 +1,36 @@
 package main
 
+/*
+Structured logging for the bot.
+
+Every entry carries a level, a category (bot, permissions or bank) and optional fields, and is handed to each sink
+that wants its level. Sinks each get their own buffered queue and goroutine, so a slow sink (ie discord rate limits)
+never holds up the handler that logged. When a queue is full the entry is dropped and counted, and the sink is told
+how many entries it missed the next time it gets to write.
+
+*/
+
 import (
-	"github.com/bwmarrin/discordgo"
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
 	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
 )
 
-// Logger struct
-type Logger struct {
-	ch      *ChannelHandler
-	session *discordgo.Session
-	logchan chan string
-}
+// LogLevel type
+type LogLevel int
+
+// LogDebug const for log levels
+const (
+	LogDebug LogLevel = iota
+	LogInfo
+	LogWarn
+	LogError
+)
 
 // BOTLOG const for log types
 const (
@@ -19,77 +39,412 @@ const (
 	BANKLOG
 )
 
-// Init function
-func (h *Logger) Init(ch *ChannelHandler, channel chan string, session *discordgo.Session) {
-	h.ch = ch
-	h.logchan = channel
-	h.session = session
-	go h.ReadLog()
+// String function
+func (l LogLevel) String() string {
+	switch l {
+	case LogDebug:
+		return "DEBUG"
+	case LogInfo:
+		return "INFO"
+	case LogWarn:
+		return "WARN"
+	case LogError:
+		return "ERROR"
+	}
+	return "LEVEL(" + strconv.Itoa(int(l)) + ")"
 }
 
-// ReadLog function
-func (h *Logger) ReadLog() {
+// ParseLogLevel function
+// An empty level defaults to info
+func ParseLogLevel(level string) (LogLevel, error) {
+	switch strings.ToLower(strings.TrimSpace(level)) {
+	case "debug":
+		return LogDebug, nil
+	case "", "info":
+		return LogInfo, nil
+	case "warn", "warning":
+		return LogWarn, nil
+	case "error":
+		return LogError, nil
+	}
+	return LogInfo, errors.New("Unknown log level: " + level)
+}
 
-	message := <-h.logchan
+// LogFields struct
+type LogFields struct {
+	User    string
+	Guild   string
+	Room    string
+	Command string
+}
 
-	if strings.HasPrefix(message, "Bot") {
-		h.LogBot(message, h.session)
-		go h.ReadLog()
+// String function
+func (f LogFields) String() string {
+	var fields []string
+	if f.User != "" {
+		fields = append(fields, "user="+f.User)
 	}
-	if strings.HasPrefix(message, "Permissions") {
-		h.LogPerm(message, h.session)
-		go h.ReadLog()
+	if f.Guild != "" {
+		fields = append(fields, "guild="+f.Guild)
 	}
-	if strings.HasPrefix(message, "Bank") {
-		h.LogBank(message, h.session)
-		go h.ReadLog()
+	if f.Room != "" {
+		fields = append(fields, "room="+f.Room)
 	}
-	h.LogBot(message, h.session)
-	go h.ReadLog()
+	if f.Command != "" {
+		fields = append(fields, "command="+f.Command)
+	}
+	return strings.Join(fields, " ")
 }
 
-// LogBot function
-func (h *Logger) LogBot(message string, s *discordgo.Session) {
-	channelid, err := h.ch.GetBotLogChannel()
-	if err != nil {
-		return // Do nothing, we don't want to yell about no channel configured, just silently fail
+// LogEntry struct
+type LogEntry struct {
+	Time     time.Time
+	Level    LogLevel
+	Category int
+	Message  string
+	Fields   LogFields
+}
+
+// Format function
+func (e LogEntry) Format() string {
+	formatted := e.Time.Format("2006-01-02 15:04:05") + " " + e.Level.String() + " [" + logCategoryName(e.Category) +
+		"] " + e.Message
+	if fields := e.Fields.String(); fields != "" {
+		formatted = formatted + " " + fields
+	}
+	return formatted
+}
+
+// logCategoryName function
+func logCategoryName(category int) string {
+	switch category {
+	case PERMLOG:
+		return "permissions"
+	case BANKLOG:
+		return "bank"
 	}
+	return "bot"
+}
+
+// LogSink interface
+type LogSink interface {
+	Name() string
+	Level() LogLevel
+	Write(entry LogEntry) error
+	Close() error
+}
+
+// logQueue struct
+type logQueue struct {
+	sink    LogSink
+	entries chan LogEntry
+	dropped uint64
+	done    chan bool
+}
+
+// Logger struct
+type Logger struct {
+	buffer int
 
-	s.ChannelMessageSend(channelid, message)
+	queues      []*logQueue
+	queuelocker sync.RWMutex
 }
 
-// LogBank function
-func (h *Logger) LogBank(message string, s *discordgo.Session) {
-	channelid, err := h.ch.GetBankLogChannel()
+// SetupLogger function
+// Builds a logger with the stdout and (if configured) file sinks from our config
+func SetupLogger(conf *Config) (logger *Logger, err error) {
+
+	logger = &Logger{buffer: conf.MainConfig.LogBufferSize}
+
+	level, err := ParseLogLevel(conf.MainConfig.LogLevel)
 	if err != nil {
-		return // Do nothing, we don't want to yell about no channel configured, just silently fail
+		return logger, errors.New("Error reading log_level: " + err.Error())
+	}
+	logger.AddSink(&StdoutLogSink{level: level})
+
+	if conf.MainConfig.LogFile != "" {
+		level, err = ParseLogLevel(conf.MainConfig.LogFileLevel)
+		if err != nil {
+			return logger, errors.New("Error reading log_file_level: " + err.Error())
+		}
+
+		sink := &FileLogSink{path: conf.MainConfig.LogFile, level: level, backups: conf.MainConfig.LogFileBackups,
+			maxsize: int64(conf.MainConfig.LogFileMaxSize) * 1024 * 1024}
+		err = sink.Open()
+		if err != nil {
+			return logger, err
+		}
+		logger.AddSink(sink)
+	}
+
+	return logger, nil
+}
+
+// AddSink function
+func (h *Logger) AddSink(sink LogSink) {
+	h.queuelocker.Lock()
+	defer h.queuelocker.Unlock()
+
+	buffer := h.buffer
+	if buffer < 1 {
+		buffer = 256
 	}
 
-	s.ChannelMessageSend(channelid, message)
+	queue := &logQueue{sink: sink, entries: make(chan LogEntry, buffer), done: make(chan bool)}
+	h.queues = append(h.queues, queue)
+	go queue.run()
+}
+
+// run function
+func (q *logQueue) run() {
+	for entry := range q.entries {
+		if dropped := atomic.SwapUint64(&q.dropped, 0); dropped > 0 {
+			q.write(LogEntry{Time: time.Now(), Level: LogWarn, Category: BOTLOG,
+				Message: "Logger dropped " + strconv.FormatUint(dropped, 10) + " entries for the " + q.sink.Name() + " sink"})
+		}
+		q.write(entry)
+	}
+	q.sink.Close()
+	q.done <- true
 }
 
-// LogPerm function
-func (h *Logger) LogPerm(message string, s *discordgo.Session) {
-	channelid, err := h.ch.GetPermissionLogChannel()
+// write function
+func (q *logQueue) write(entry LogEntry) {
+	err := q.sink.Write(entry)
 	if err != nil {
-		return // Do nothing, we don't want to yell about no channel configured, just silently fail
+		// Nowhere else to put it
+		fmt.Fprintln(os.Stderr, "Error writing to "+q.sink.Name()+" log sink: "+err.Error())
 	}
-	s.ChannelMessageSend(channelid, message)
 }
 
 // Log function
-func (h *Logger) Log(message string, s *discordgo.Session, level string) {
+func (h *Logger) Log(level LogLevel, category int, fields LogFields, message string) {
+	h.queuelocker.RLock()
+	defer h.queuelocker.RUnlock()
 
-	if level == "" {
-		return
+	entry := LogEntry{Time: time.Now(), Level: level, Category: category, Message: message, Fields: fields}
+	for _, queue := range h.queues {
+		if level < queue.sink.Level() {
+			continue
+		}
+
+		select {
+		case queue.entries <- entry:
+		default:
+			atomic.AddUint64(&queue.dropped, 1)
+		}
 	}
-	if level == "bot" {
-		h.LogBot(message, s)
+}
+
+// Debug function
+func (h *Logger) Debug(category int, fields LogFields, message string) {
+	h.Log(LogDebug, category, fields, message)
+}
+
+// Info function
+func (h *Logger) Info(category int, fields LogFields, message string) {
+	h.Log(LogInfo, category, fields, message)
+}
+
+// Warn function
+func (h *Logger) Warn(category int, fields LogFields, message string) {
+	h.Log(LogWarn, category, fields, message)
+}
+
+// Error function
+func (h *Logger) Error(category int, fields LogFields, message string) {
+	h.Log(LogError, category, fields, message)
+}
+
+// Dropped function
+// Returns the number of entries each sink has dropped since it last wrote
+func (h *Logger) Dropped() map[string]uint64 {
+	h.queuelocker.RLock()
+	defer h.queuelocker.RUnlock()
+
+	dropped := make(map[string]uint64)
+	for _, queue := range h.queues {
+		dropped[queue.sink.Name()] = atomic.LoadUint64(&queue.dropped)
 	}
-	if level == "perm" || level == "permission" {
-		h.LogPerm(message, s)
+	return dropped
+}
+
+// Close function
+// Flushes whatever is queued and closes every sink
+func (h *Logger) Close() {
+	h.queuelocker.Lock()
+	defer h.queuelocker.Unlock()
+
+	for _, queue := range h.queues {
+		close(queue.entries)
+		<-queue.done
+	}
+	h.queues = nil
+}
+
+// LogWriter struct
+// Lets the stdlib log package (and anything else that wants an io.Writer) write into our logger
+type LogWriter struct {
+	logger *Logger
+	level  LogLevel
+}
+
+// Write function
+func (w *LogWriter) Write(p []byte) (n int, err error) {
+	w.logger.Log(w.level, BOTLOG, LogFields{}, strings.TrimSpace(string(p)))
+	return len(p), nil
+}
+
+// StdoutLogSink struct
+type StdoutLogSink struct {
+	level LogLevel
+}
+
+// Name function
+func (h *StdoutLogSink) Name() string { return "stdout" }
+
+// Level function
+func (h *StdoutLogSink) Level() LogLevel { return h.level }
+
+// Write function
+func (h *StdoutLogSink) Write(entry LogEntry) error {
+	_, err := fmt.Fprintln(os.Stdout, entry.Format())
+	return err
+}
+
+// Close function
+func (h *StdoutLogSink) Close() error { return nil }
+
+// FileLogSink struct
+// Writes to a local file, rotating it to path.1, path.2 ... once it grows past maxsize bytes
+type FileLogSink struct {
+	path    string
+	level   LogLevel
+	maxsize int64
+	backups int
+
+	file *os.File
+	size int64
+}
+
+// Name function
+func (h *FileLogSink) Name() string { return "file" }
+
+// Level function
+func (h *FileLogSink) Level() LogLevel { return h.level }
+
+// Open function
+func (h *FileLogSink) Open() (err error) {
+	h.file, err = os.OpenFile(h.path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0640)
+	if err != nil {
+		return err
 	}
-	if level == "bank" {
-		h.LogBank(message, s)
+
+	info, err := h.file.Stat()
+	if err != nil {
+		return err
 	}
+	h.size = info.Size()
+	return nil
 }
+
+// Write function
+func (h *FileLogSink) Write(entry LogEntry) (err error) {
+	if h.file == nil {
+		err = h.Open()
+		if err != nil {
+			return err
+		}
+	}
+
+	line := entry.Format() + "\n"
+	if h.maxsize > 0 && h.size+int64(len(line)) > h.maxsize {
+		err = h.Rotate()
+		if err != nil {
+			return err
+		}
+	}
+
+	n, err := h.file.WriteString(line)
+	h.size = h.size + int64(n)
+	return err
+}
+
+// Rotate function
+func (h *FileLogSink) Rotate() (err error) {
+	if h.file != nil {
+		h.file.Close()
+		h.file = nil
+	}
+
+	if h.backups < 1 {
+		err = os.Remove(h.path)
+	} else {
+		os.Remove(h.path + "." + strconv.Itoa(h.backups))
+		for i := h.backups - 1; i > 0; i-- {
+			os.Rename(h.path+"."+strconv.Itoa(i), h.path+"."+strconv.Itoa(i+1))
+		}
+		err = os.Rename(h.path, h.path+".1")
+	}
+	if err != nil && !os.IsNotExist(err) {
+		return err
+	}
+
+	return h.Open()
+}
+
+// Close function
+func (h *FileLogSink) Close() error {
+	if h.file == nil {
+		return nil
+	}
+	err := h.file.Close()
+	h.file = nil
+	return err
+}
+
+// DiscordLogSink struct
+// Sends entries to the bot, permissions and bank log channels configured through the channel handler
+type DiscordLogSink struct {
+	ch      *ChannelHandler
+	session DiscordSession
+	level   LogLevel
+}
+
+// Name function
+func (h *DiscordLogSink) Name() string { return "discord" }
+
+// Level function
+func (h *DiscordLogSink) Level() LogLevel { return h.level }
+
+// Write function
+func (h *DiscordLogSink) Write(entry LogEntry) error {
+
+	var channelid string
+	var err error
+	switch entry.Category {
+	case PERMLOG:
+		channelid, err = h.ch.GetPermissionLogChannel()
+	case BANKLOG:
+		channelid, err = h.ch.GetBankLogChannel()
+	default:
+		channelid, err = h.ch.GetBotLogChannel()
+	}
+	if err != nil {
+		return nil // Do nothing, we don't want to yell about no channel configured, just silently fail
+	}
+
+	message := entry.Message
+	if entry.Level != LogInfo {
+		message = "**" + entry.Level.String() + "** " + message
+	}
+	if fields := entry.Fields.String(); fields != "" {
+		message = message + " `" + fields + "`"
+	}
+
+	_, err = h.session.ChannelMessageSend(channelid, message)
+	return err
+}
+
+// Close function
+func (h *DiscordLogSink) Close() error { return nil }
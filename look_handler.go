@@ -0,0 +1,201 @@
+package main
+
+import (
+	"github.com/bwmarrin/discordgo"
+	"strings"
+)
+
+// lookEmbedColor is the sidebar color of look embeds
+const lookEmbedColor = 16744704
+
+// LookHandler struct
+type LookHandler struct {
+	registry *CommandRegistry
+	router   *CommandRouter
+	room     *RoomsHandler
+	user     *UserHandler
+	zones    *ZonesManager
+}
+
+// Init function
+func (h *LookHandler) Init() {
+	h.RegisterCommands()
+}
+
+// RegisterCommands function
+func (h *LookHandler) RegisterCommands() (err error) {
+
+	h.registry.Register("look", "Look around the room you are in, down one of its exits or at someone here",
+		"[exit | player]")
+	h.router.AddRoute("look", false, h.ParseCommand, "player")
+	return nil
+
+}
+
+// ParseCommand function
+func (h *LookHandler) ParseCommand(command []string, user User, s DiscordSession, m *discordgo.MessageCreate) {
+
+	roomID := user.RoomID
+	if roomID == "" {
+		roomID = m.ChannelID
+	}
+
+	room, err := h.room.rooms.GetRoomByID(roomID)
+	if err != nil {
+		s.ChannelMessageSend(m.ChannelID, "You can't make out anything here.")
+		return
+	}
+
+	if len(command) < 2 {
+		s.ChannelMessageSendEmbed(m.ChannelID, h.LookRoom(room, user))
+		return
+	}
+
+	target := strings.Join(command[1:], " ")
+	if exit, found := room.GetExit(target); found && user.CanSeeExit(room.ID, exit) {
+		s.ChannelMessageSendEmbed(m.ChannelID, h.LookExit(exit, user))
+		return
+	}
+
+	other, found := h.FindPlayer(target, room)
+	if !found {
+		s.ChannelMessageSend(m.ChannelID, "You don't see "+target+" here.")
+		return
+	}
+	s.ChannelMessageSendEmbed(m.ChannelID, h.LookPlayer(other))
+}
+
+// LookRoom function
+func (h *LookHandler) LookRoom(room Room, user User) (embed *discordgo.MessageEmbed) {
+
+	description := strings.TrimSpace(room.Description)
+	zone, inzone := h.zones.ZoneForRoom(room)
+	if inzone {
+		description = zone.Describe(room)
+	}
+	if description == "" {
+		description = "There is nothing remarkable about this place."
+	}
+	embed = &discordgo.MessageEmbed{Title: room.Name, Description: description, Color: lookEmbedColor}
+	if inzone {
+		embed.Footer = &discordgo.MessageEmbedFooter{Text: zone.Name + " - levels " + zone.LevelRange() +
+			", PvP " + zone.PvPText() + ", weather " + zone.WeatherText()}
+	}
+
+	var exits []string
+	for _, exit := range user.VisibleExits(room) {
+		exits = append(exits, exit.Name)
+	}
+	embed.Fields = append(embed.Fields, lookField("Exits", exits, "There is no obvious way out."))
+
+	var players []string
+	for _, userID := range room.UserIDs {
+		if userID == user.ID {
+			continue
+		}
+		players = append(players, h.playerName(userID))
+	}
+	embed.Fields = append(embed.Fields, lookField("Also here", players, "You are alone."))
+
+	if len(room.Items) > 0 {
+		embed.Fields = append(embed.Fields, lookField("Items", room.Items, ""))
+	}
+	if len(room.NPC) > 0 {
+		embed.Fields = append(embed.Fields, lookField("Characters", room.NPC, ""))
+	}
+	if room.BindPoint {
+		embed.Fields = append(embed.Fields, lookField("Bind point", nil, "You could bind yourself here."))
+	}
+	return embed
+}
+
+// LookExit function
+func (h *LookHandler) LookExit(exit Exit, user User) (embed *discordgo.MessageEmbed) {
+
+	description := "You can't tell where it leads."
+	if target, err := h.room.rooms.GetRoomByID(exit.TargetID); err == nil {
+		description = "Looking " + exit.Name + " you can make out " + target.Name + "."
+	}
+	if len(exit.CanUse(user)) > 0 {
+		description = description + "\n" + exit.LockedText()
+	}
+
+	return &discordgo.MessageEmbed{Title: exit.Name, Description: description, Color: lookEmbedColor}
+}
+
+// LookPlayer function
+// Describes a character by their profile, anything they haven't picked yet is left out
+func (h *LookHandler) LookPlayer(other User) (embed *discordgo.MessageEmbed) {
+
+	name := other.Name
+	if name == "" {
+		name = "<@" + other.ID + ">"
+	}
+
+	var traits []string
+	if other.Height != "" {
+		traits = append(traits, other.Height)
+	}
+	if other.SkinTone != "" {
+		traits = append(traits, other.SkinTone+" skinned")
+	}
+	if other.Gender != "" {
+		traits = append(traits, strings.ToLower(other.Gender))
+	}
+	race := "traveler"
+	if other.Race != "" {
+		race = strings.ToLower(other.Race)
+	}
+
+	description := name + " is a"
+	if len(traits) > 0 {
+		description = description + " " + strings.Join(traits, ", ")
+	}
+	description = description + " " + race
+	if other.Class != "" {
+		description = description + " " + strings.ToLower(other.Class)
+	}
+	description = description + "."
+
+	hair := strings.TrimSpace(other.HairColor + " " + other.HairStyle)
+	if hair != "" {
+		description = description + " They have " + strings.ToLower(hair) + " hair."
+	}
+
+	return &discordgo.MessageEmbed{Title: name, Description: description, Color: lookEmbedColor}
+}
+
+// FindPlayer function
+// Matches a mention or a character name against the players in a room
+func (h *LookHandler) FindPlayer(target string, room Room) (other User, found bool) {
+
+	targetID := CleanUserMention(target)
+	for _, userID := range room.UserIDs {
+		candidate, err := h.user.usermanager.GetUserByID(userID)
+		if err != nil {
+			continue
+		}
+		if candidate.ID == targetID || strings.EqualFold(candidate.Name, target) {
+			return candidate, true
+		}
+	}
+	return other, false
+}
+
+// playerName function
+func (h *LookHandler) playerName(userID string) string {
+	other, err := h.user.usermanager.GetUserByID(userID)
+	if err != nil || other.Name == "" {
+		return "<@" + userID + ">"
+	}
+	return other.Name
+}
+
+// lookField function
+func lookField(name string, values []string, empty string) *discordgo.MessageEmbedField {
+	value := strings.Join(values, ", ")
+	if value == "" {
+		value = empty
+	}
+	return &discordgo.MessageEmbedField{Name: name, Value: value}
+}
@@ -7,10 +7,9 @@ import (
 	"os"
 
 	"io/ioutil"
-	"net/http"
-	_ "net/http/pprof"
 	"os/signal"
 	"syscall"
+	"time"
 
 	"github.com/asdine/storm"
 	"github.com/bwmarrin/discordgo"
@@ -18,12 +17,21 @@ import (
 
 // Variables used for command line parameters
 var (
-	ConfPath string
+	ConfPath      string
+	MigrateDryRun bool
+	ImportPath    string
 )
 
 func init() {
 	// Read our command line options
 	flag.StringVar(&ConfPath, "c", "aetheral-main.conf", "Path to Config File")
+	flag.BoolVar(&MigrateDryRun, "migrate-dryrun", false, "Check pending database migrations without applying them, then exit")
+	flag.StringVar(&ImportPath, "import", "", "Rebuild the database from a json backup export, then exit")
+}
+
+func main() {
+
+	// Parsed here rather than in init so go test can load the package with its own flags
 	flag.Parse()
 
 	_, err := os.Stat(ConfPath)
@@ -32,15 +40,12 @@ func init() {
 		flag.Usage()
 		os.Exit(1)
 	}
-}
-
-func main() {
 
 	fmt.Println("\n\n|| Starting Aetheral ||\n ")
 	log.SetOutput(ioutil.Discard)
 
 	// Setup our tmp directory
-	_, err := os.Stat("tmp")
+	_, err = os.Stat("tmp")
 	if err != nil {
 		if os.IsNotExist(err) {
 			err = os.Mkdir("tmp", os.FileMode(0777))
@@ -55,9 +60,20 @@ func main() {
 	conf, err := ReadConfig(ConfPath)
 	if err != nil {
 		fmt.Println("error reading config file at: ", ConfPath)
+		fmt.Println(err.Error())
 		return
 	}
 
+	// Setup our logger, the discord sink is added once the channel handler is up
+	logger, err := SetupLogger(&conf)
+	if err != nil {
+		fmt.Println("Error setting up logger: " + err.Error())
+		return
+	}
+	defer logger.Close()
+	log.SetFlags(0)
+	log.SetOutput(&LogWriter{logger: logger, level: LogWarn})
+
 	// Create / open our embedded database
 	db, err := storm.Open(conf.MainConfig.DBFile)
 	if err != nil {
@@ -66,14 +82,32 @@ func main() {
 	}
 	defer db.Close()
 
+	// Restore from an export before the schema check, so older exports are migrated forward (rooms from before
+	// exits get theirs while restoring, see upgradeLegacyRooms)
+	dbhandler := DBHandler{conf: &conf, rawdb: db}
+	if ImportPath != "" {
+		fmt.Println("Importing Database from " + ImportPath)
+		backups := Backups{db: &dbhandler, conf: &conf}
+		backup, err := backups.ImportBackupFile(ImportPath)
+		if err != nil {
+			fmt.Println("Error importing database: " + err.Error())
+			log.Fatal(err)
+			return
+		}
+		fmt.Println("Imported backup " + backup.ID)
+	}
+
 	// Run a quick first time db configuration to verify that it is working properly
 	fmt.Println("Checking Database")
-	dbhandler := DBHandler{conf: &conf, rawdb: db}
-	err = dbhandler.FirstTimeSetup()
+	err = dbhandler.FirstTimeSetup(MigrateDryRun)
 	if err != nil {
+		fmt.Println("Error setting up database: " + err.Error())
 		log.Fatal(err)
 		return
 	}
+	if MigrateDryRun || ImportPath != "" {
+		return
+	}
 
 	// Create a new Discord session using the provided bot token.
 	dg, err := discordgo.New("Bot " + conf.MainConfig.Token)
@@ -83,95 +117,187 @@ func main() {
 	}
 	defer dg.Close()
 
-	if conf.MainConfig.Profiler {
-		http.ListenAndServe(":8080", http.DefaultServeMux)
-	}
+	// Create our lifecycle manager, every background worker is started through here
+	lifecycle := Lifecycle{logger: logger}
+	lifecycle.Init()
+	metrics := Metrics{}
 
-	logchannel := make(chan string)
-	logger := Logger{logchan: logchannel}
+	// Role and channel changes are queued per guild through here
+	discordqueue := DiscordQueue{lifecycle: &lifecycle, logger: logger, metrics: &metrics}
+	discordqueue.Init()
+
+	// Create our command router, every prefixed command is dispatched through here
+	fmt.Println("Adding Command Router")
+	router := CommandRouter{conf: &conf, lifecycle: &lifecycle, metrics: &metrics}
+	dg.AddHandler(router.Read)
 
 	// Create a callback handler and add it to our Handler Queue
 	fmt.Println("Adding Callback Handler")
-	callbackhandler := CallbackHandler{dg: dg, logger: &logger}
+	callbackhandler := CallbackHandler{conf: &conf, db: &dbhandler, dg: dg, logger: logger, router: &router}
+	err = callbackhandler.Init()
+	if err != nil {
+		fmt.Println("Error loading callbacks: " + err.Error())
+		return
+	}
 	dg.AddHandler(callbackhandler.Read)
+	lifecycle.Start("callbacks", callbackhandler.ExpireWatches)
 
 	// Create our usermanager handler
 	fmt.Println("Adding User Handler")
-	userhandler := UserHandler{conf: &conf, db: &dbhandler, logchan: logchannel}
+	userhandler := UserHandler{conf: &conf, db: &dbhandler, logger: logger, router: &router, queue: &discordqueue}
+	router.user = &userhandler
 	userhandler.Init()
-	dg.AddHandler(userhandler.Read)
 
 	// Create our permissions handler
 	fmt.Println("Adding Permissions Handler")
 	permissionshandler := PermissionsHandler{dg: dg, conf: &conf, callback: &callbackhandler, db: &dbhandler,
-		user: &userhandler, logchan: logchannel}
-	dg.AddHandler(permissionshandler.Read)
+		user: &userhandler, logger: logger, router: &router, queue: &discordqueue}
+	permissionshandler.Init()
 
 	// Create our command handler
 	fmt.Println("Adding Command Registry Handler")
 	commandhandler := CommandHandler{dg: dg, db: &dbhandler, callback: &callbackhandler,
-		user: &userhandler, conf: &conf, perm: &permissionshandler, logchan: logchannel}
+		user: &userhandler, conf: &conf, perm: &permissionshandler, logger: logger, router: &router}
 
 	// Create our permissions handler
 	fmt.Println("Adding Channel Permissions Handler")
 	channelhandler := ChannelHandler{db: &dbhandler, conf: &conf, registry: commandhandler.registry,
-		user: &userhandler, logchan: logchannel}
+		user: &userhandler, logger: logger, router: &router}
 	channelhandler.Init()
-	dg.AddHandler(channelhandler.Read)
 
 	// Don't forget to initialize the command handler -AFTER- the Channel Handler!
 	commandhandler.Init(&channelhandler)
-	dg.AddHandler(commandhandler.Read)
+	callbackhandler.registry = commandhandler.registry
+	callbackhandler.RegisterCommands()
 
 	// Initialize Guilds Manager
 	fmt.Println("Adding Guilds Manager")
 	guildsmanager := GuildsManager{db: &dbhandler}
 
+	// Initialize Zones Manager
+	fmt.Println("Adding Zones Manager")
+	zonesmanager := ZonesManager{db: &dbhandler}
+
+	// Initialize Parties Manager
+	fmt.Println("Adding Parties Manager")
+	partiesmanager := PartiesManager{db: &dbhandler}
+
 	fmt.Println("Adding Rooms Handler")
 	roomshandler := RoomsHandler{callback: &callbackhandler, conf: &conf, db: &dbhandler, perm: &permissionshandler,
-		registry: commandhandler.registry, dg: dg, user: &userhandler, ch: &channelhandler, guilds: &guildsmanager}
-	dg.AddHandler(roomshandler.Read)
+		registry: commandhandler.registry, router: &router, dg: dg, user: &userhandler, ch: &channelhandler,
+		guilds: &guildsmanager, queue: &discordqueue}
 	permissionshandler.room = &roomshandler
 	// No rooms handler init here!
 
 	fmt.Println("Adding Registration Handler")
 	registrationhandler := RegistrationHandler{callback: &callbackhandler, conf: &conf, db: &dbhandler, perm: &permissionshandler,
-		registry: commandhandler.registry, dg: dg, user: &userhandler, ch: &channelhandler, guilds: &guildsmanager}
+		registry: commandhandler.registry, router: &router, dg: dg, user: &userhandler, ch: &channelhandler,
+		guilds: &guildsmanager}
 	registrationhandler.Init()
-	dg.AddHandler(registrationhandler.Read)
 	// No rooms handler init here!
 
+	// Travel and transfers are journaled so an interrupted move can be finished or reverted on restart
+	traveljournal := TravelJournal{db: &dbhandler, conf: &conf, logger: logger, perms: &permissionshandler,
+		room: &roomshandler, user: &userhandler}
+
 	// Inititalize Transfers Handler
 	fmt.Println("Adding Transfers Handler")
-	transferhandler := TransferHandler{db: &dbhandler, conf: &conf, registry: commandhandler.registry, perms: &permissionshandler,
-		rooms: &roomshandler, user: &userhandler, dg: dg}
+	transferhandler := TransferHandler{db: &dbhandler, conf: &conf, registry: commandhandler.registry, router: &router,
+		perms: &permissionshandler, rooms: &roomshandler, user: &userhandler, dg: dg, journal: &traveljournal}
 	transferhandler.Init()
-	dg.AddHandler(transferhandler.Read)
-	go transferhandler.HandleTransfers()
+	lifecycle.Start("transfers", transferhandler.HandleTransfers)
 
 	// Initialize Travel Handler
 	fmt.Println("Adding Travel Handler")
-	travelhandler := TravelHandler{db: &dbhandler, conf: &conf, registry: commandhandler.registry, perms: &permissionshandler,
-		room: &roomshandler, user: &userhandler, transfer: &transferhandler}
+	travelhandler := TravelHandler{db: &dbhandler, conf: &conf, registry: commandhandler.registry, router: &router,
+		perms: &permissionshandler, room: &roomshandler, user: &userhandler, transfer: &transferhandler,
+		metrics: &metrics, guilds: &guildsmanager, zones: &zonesmanager, parties: &partiesmanager,
+		lifecycle: &lifecycle, journal: &traveljournal}
 	travelhandler.Init()
-	dg.AddHandler(travelhandler.Read)
+
+	// Initialize Recall Handler
+	fmt.Println("Adding Recall Handler")
+	recallhandler := RecallHandler{conf: &conf, registry: commandhandler.registry, router: &router,
+		room: &roomshandler, user: &userhandler, transfer: &transferhandler, travel: &travelhandler}
+	recallhandler.Init()
 
 	// Initialize Welcome Handler
 	fmt.Println("Adding Welcome Handler")
-	welcomehandler := WelcomeHandler{conf: &conf, user: &userhandler, db: &dbhandler}
+	welcomehandler := WelcomeHandler{conf: &conf, user: &userhandler, db: &dbhandler, router: &router}
+	welcomehandler.Init()
 	dg.AddHandler(welcomehandler.ReadNewMember)
-	dg.AddHandler(welcomehandler.Read)
 
 	// Initialize Guilds Handler
 	fmt.Println("Adding Guilds Handler")
-	guildshandler := GuildsHandler{room: &roomshandler, registry: commandhandler.registry, db: &dbhandler, conf: &conf,
-		perm: &permissionshandler, user: &userhandler, guildmanager: &guildsmanager}
+	guildshandler := GuildsHandler{room: &roomshandler, registry: commandhandler.registry, router: &router, db: &dbhandler,
+		conf: &conf, perm: &permissionshandler, user: &userhandler, guildmanager: &guildsmanager}
 	guildshandler.Init()
-	dg.AddHandler(guildshandler.Read)
 
-	// Initialize our Logger
-	fmt.Println("Initializing Logger")
-	logger.Init(&channelhandler, logchannel, dg)
+	// Initialize Backup Handler
+	fmt.Println("Adding Backup Handler")
+	backuphandler := BackupHandler{conf: &conf, db: &dbhandler, registry: commandhandler.registry, router: &router,
+		logger: logger}
+	backuphandler.Init()
+	if conf.MainConfig.BackupInterval > 0 {
+		lifecycle.Start("backups", backuphandler.ScheduledBackups)
+	}
+
+	// Initialize Queue Handler
+	fmt.Println("Adding Queue Handler")
+	queuehandler := QueueHandler{registry: commandhandler.registry, router: &router, queue: &discordqueue}
+	queuehandler.Init()
+
+	// Initialize Map Handler
+	fmt.Println("Adding Map Handler")
+	maphandler := MapHandler{registry: commandhandler.registry, router: &router, room: &roomshandler,
+		guilds: &guildsmanager}
+	maphandler.Init()
+
+	// Initialize Look Handler
+	fmt.Println("Adding Look Handler")
+	lookhandler := LookHandler{registry: commandhandler.registry, router: &router, room: &roomshandler,
+		user: &userhandler, zones: &zonesmanager}
+	lookhandler.Init()
+
+	// Initialize Search Handler
+	fmt.Println("Adding Search Handler")
+	searchhandler := SearchHandler{registry: commandhandler.registry, router: &router, room: &roomshandler,
+		user: &userhandler}
+	searchhandler.Init()
+
+	// Initialize Party Handler
+	fmt.Println("Adding Party Handler")
+	partyhandler := PartyHandler{conf: &conf, registry: commandhandler.registry, router: &router, room: &roomshandler,
+		user: &userhandler, parties: &partiesmanager}
+	partyhandler.Init()
+
+	// Initialize Zone Handler
+	fmt.Println("Adding Zone Handler")
+	zonehandler := ZoneHandler{conf: &conf, registry: commandhandler.registry, router: &router, room: &roomshandler,
+		zones: &zonesmanager}
+	zonehandler.Init()
+
+	// Initialize World Handler
+	fmt.Println("Adding World Handler")
+	worldhandler := WorldHandler{conf: &conf, registry: commandhandler.registry, router: &router, logger: logger,
+		room: &roomshandler, user: &userhandler, perm: &permissionshandler}
+	worldhandler.Init()
+
+	// Initialize Config Handler, config reload can also be triggered with a SIGHUP
+	fmt.Println("Adding Config Handler")
+	confighandler := ConfigHandler{conf: &conf, path: ConfPath, registry: commandhandler.registry, router: &router,
+		logger: logger, dg: dg}
+	confighandler.Init()
+	lifecycle.Start("config", confighandler.WatchSignals)
+
+	// Now that log channels can be looked up, send our logs to discord as well
+	fmt.Println("Adding Discord Log Sink")
+	discordlevel, err := ParseLogLevel(conf.MainConfig.DiscordLogLevel)
+	if err != nil {
+		fmt.Println("Error reading discord_log_level: " + err.Error())
+		return
+	}
+	logger.AddSink(&DiscordLogSink{ch: &channelhandler, session: dg, level: discordlevel})
 
 	// Startup setup for channels and roles
 	fmt.Println("\n|| Running Startup Setup ||\n ")
@@ -182,23 +308,30 @@ func main() {
 		return
 	}
 
+	// Finish or revert any moves that were cut short by the last shutdown
+	fmt.Println("Recovering Travel Journal")
+	traveljournal.Recover(dg)
+
+	// Now that the rooms are loaded, report anything broken in the world
+	fmt.Println("Checking World")
+	worldhandler.StartupCheck(dg)
+
 	// Setup our Events Handler now that first rooms are operational
 	fmt.Println("\n|| Standing Up Events Handler ||\n ")
-	eventshandler := EventHandler{conf: &conf, registry: commandhandler.registry, callback: &callbackhandler, db: &dbhandler,
-		user: &userhandler, dg: dg, logger: &logger}
+	eventshandler := EventHandler{conf: &conf, registry: commandhandler.registry, router: &router, callback: &callbackhandler,
+		db: &dbhandler, user: &userhandler, dg: dg, logger: logger, metrics: &metrics}
 	err = eventshandler.Init()
 	if err != nil {
 		fmt.Println("Error starting events handler: " + err.Error())
 		return
 	}
-	dg.AddHandler(eventshandler.Read)
 	dg.AddHandler(eventshandler.ReadEvents)
 
 	// Now we create and initialize our main handler
 	fmt.Println("\n|| Initializing Main Handler ||\n ")
 	primaryhandler := PrimaryHandler{db: &dbhandler, conf: &conf, dg: dg, callback: &callbackhandler, perm: &permissionshandler,
-		command: &commandhandler, logchan: logchannel, user: &userhandler, channel: &channelhandler, rooms: &roomshandler,
-		travel: &travelhandler}
+		command: &commandhandler, router: &router, logger: logger, user: &userhandler, channel: &channelhandler,
+		rooms: &roomshandler, travel: &travelhandler, lifecycle: &lifecycle}
 	err = primaryhandler.Init()
 	if err != nil {
 		fmt.Println("error in mainHandler.init", err)
@@ -206,9 +339,18 @@ func main() {
 	}
 	fmt.Println("\n|| Main Handler Initialized ||\n ")
 
-	// Setup Profiler if enabled in config
-	if conf.MainConfig.Profiler {
-		http.ListenAndServe(":8080", http.DefaultServeMux)
+	// Setup our http server (health, metrics and pprof if enabled) if configured
+	httpserver := HTTPServer{conf: &conf, db: &dbhandler, lifecycle: &lifecycle, metrics: &metrics, queue: &discordqueue,
+		rooms: roomshandler.rooms, guilds: &guildsmanager, users: userhandler.usermanager,
+		transfers: transferhandler.transferdb, state: dg.State}
+	httpserver.discordready = func() bool {
+		dg.RLock()
+		defer dg.RUnlock()
+		return dg.DataReady
+	}
+	if httpserver.ListenAddress() != "" {
+		fmt.Println("Starting HTTP Server on " + httpserver.ListenAddress())
+		lifecycle.Start("http", httpserver.Serve)
 	}
 
 	// Wait here until CTRL-C or other term signal is received.
@@ -217,4 +359,11 @@ func main() {
 	signal.Notify(sc, syscall.SIGINT, syscall.SIGTERM, os.Interrupt, os.Kill)
 	<-sc
 
+	// Let workers and in-flight commands finish before the session and database are closed
+	fmt.Println("Shutting down, waiting for workers to finish")
+	err = lifecycle.Shutdown(conf.MainConfig.ShutdownTimeout * time.Second)
+	if err != nil {
+		fmt.Println(err.Error())
+	}
+
 }
@@ -0,0 +1,83 @@
+package main
+
+import (
+	"bytes"
+	"github.com/bwmarrin/discordgo"
+	"strconv"
+)
+
+// MapHandler struct
+type MapHandler struct {
+	registry *CommandRegistry
+	router   *CommandRouter
+	room     *RoomsHandler
+	guilds   *GuildsManager
+}
+
+// Init function
+func (h *MapHandler) Init() {
+	h.RegisterCommands()
+}
+
+// RegisterCommands function
+func (h *MapHandler) RegisterCommands() (err error) {
+
+	h.registry.Register("map", "View the world map around you, builders can export the whole cluster",
+		"[radius] | dot | json")
+	h.router.AddRoute("map", false, h.ParseCommand, "player")
+	return nil
+
+}
+
+// ParseCommand function
+func (h *MapHandler) ParseCommand(command []string, user User, s DiscordSession, m *discordgo.MessageCreate) {
+
+	graph, err := BuildWorldGraph(h.room.rooms, h.guilds)
+	if err != nil {
+		s.ChannelMessageSend(m.ChannelID, "Error building map: "+err.Error())
+		return
+	}
+
+	if len(command) > 1 && (command[1] == "dot" || command[1] == "json") {
+		if !user.CheckRole("builder") {
+			s.ChannelMessageSend(m.ChannelID, "Only builders can export the world map.")
+			return
+		}
+
+		if command[1] == "dot" {
+			_, err = s.ChannelFileSend(m.ChannelID, "aether-map.dot", bytes.NewBufferString(graph.RenderDOT()))
+		} else {
+			var output []byte
+			output, err = graph.RenderJSON()
+			if err == nil {
+				_, err = s.ChannelFileSend(m.ChannelID, "aether-map.json", bytes.NewReader(output))
+			}
+		}
+		if err != nil {
+			s.ChannelMessageSend(m.ChannelID, "Error exporting map: "+err.Error())
+		}
+		return
+	}
+
+	radius := 2
+	if len(command) > 1 {
+		radius, err = strconv.Atoi(command[1])
+		if err != nil || radius < 1 || radius > 4 {
+			s.ChannelMessageSend(m.ChannelID, "Map radius must be a number from 1 to 4")
+			return
+		}
+	}
+
+	roomID := user.RoomID
+	if roomID == "" {
+		roomID = m.ChannelID
+	}
+
+	graph = graph.VisibleTo(user)
+	output, err := graph.RenderASCII(roomID, radius)
+	if err != nil {
+		s.ChannelMessageSend(m.ChannelID, "Error rendering map: "+err.Error())
+		return
+	}
+	s.ChannelMessageSend(m.ChannelID, output)
+}
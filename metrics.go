@@ -0,0 +1,116 @@
+package main
+
+import (
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+)
+
+// Metrics struct
+// Counters exposed in the prometheus text format on /metrics, safe to update from any goroutine
+type Metrics struct {
+	travelmoves      uint64
+	eventtriggers    uint64
+	discordapierrors uint64
+
+	commands       map[string]uint64
+	commandslocker sync.Mutex
+}
+
+// CommandDispatched function
+func (h *Metrics) CommandDispatched(command string) {
+	h.commandslocker.Lock()
+	defer h.commandslocker.Unlock()
+
+	if h.commands == nil {
+		h.commands = make(map[string]uint64)
+	}
+	h.commands[command]++
+}
+
+// TravelMove function
+func (h *Metrics) TravelMove() {
+	atomic.AddUint64(&h.travelmoves, 1)
+}
+
+// EventTriggered function
+func (h *Metrics) EventTriggered() {
+	atomic.AddUint64(&h.eventtriggers, 1)
+}
+
+// DiscordAPIError function
+func (h *Metrics) DiscordAPIError() {
+	atomic.AddUint64(&h.discordapierrors, 1)
+}
+
+// Commands function
+// Returns a copy of the per command dispatch counts
+func (h *Metrics) Commands() map[string]uint64 {
+	h.commandslocker.Lock()
+	defer h.commandslocker.Unlock()
+
+	commands := make(map[string]uint64)
+	for command, count := range h.commands {
+		commands[command] = count
+	}
+	return commands
+}
+
+// WritePrometheus function
+// gauges are point in time values (ie pending transfers) that are worked out by the caller at scrape time
+func (h *Metrics) WritePrometheus(w io.Writer, gauges map[string]int) (err error) {
+
+	var output strings.Builder
+
+	writeMetricHeader(&output, "aether_commands_dispatched_total", "counter", "Commands dispatched by the command router")
+	commands := h.Commands()
+	var names []string
+	for command := range commands {
+		names = append(names, command)
+	}
+	sort.Strings(names)
+	for _, command := range names {
+		output.WriteString("aether_commands_dispatched_total{command=\"" + escapeMetricLabel(command) + "\"} " +
+			strconv.FormatUint(commands[command], 10) + "\n")
+	}
+
+	writeMetricHeader(&output, "aether_travel_moves_total", "counter", "Successful player moves between rooms")
+	output.WriteString("aether_travel_moves_total " + strconv.FormatUint(atomic.LoadUint64(&h.travelmoves), 10) + "\n")
+
+	writeMetricHeader(&output, "aether_event_triggers_total", "counter", "Room events triggered by messages")
+	output.WriteString("aether_event_triggers_total " + strconv.FormatUint(atomic.LoadUint64(&h.eventtriggers), 10) + "\n")
+
+	writeMetricHeader(&output, "aether_discord_api_errors_total", "counter", "Failed discord API calls made through the queue")
+	output.WriteString("aether_discord_api_errors_total " + strconv.FormatUint(atomic.LoadUint64(&h.discordapierrors), 10) + "\n")
+
+	var gaugenames []string
+	for name := range gauges {
+		gaugenames = append(gaugenames, name)
+	}
+	sort.Strings(gaugenames)
+	for _, name := range gaugenames {
+		writeMetricHeader(&output, name, "gauge", "")
+		output.WriteString(name + " " + strconv.Itoa(gauges[name]) + "\n")
+	}
+
+	_, err = io.WriteString(w, output.String())
+	return err
+}
+
+// writeMetricHeader function
+func writeMetricHeader(output *strings.Builder, name string, metrictype string, help string) {
+	if help != "" {
+		output.WriteString("# HELP " + name + " " + help + "\n")
+	}
+	output.WriteString("# TYPE " + name + " " + metrictype + "\n")
+}
+
+// escapeMetricLabel function
+func escapeMetricLabel(value string) string {
+	value = strings.Replace(value, `\`, `\\`, -1)
+	value = strings.Replace(value, `"`, `\"`, -1)
+	return strings.Replace(value, "\n", `\n`, -1)
+}
@@ -0,0 +1,350 @@
+package main
+
+/*
+Schema migrations for our storm database.
+
+Whenever a record struct changes in a way that existing bolt data won't pick up on its own (a new index, a field
+being moved or renamed, etc.) a migration should be appended to the list below. Migrations run in order at startup,
+each one inside its own transaction, and the highest applied version is recorded in the "Migrations" bucket.
+
+Never reorder or remove an existing migration, only append new ones.
+
+*/
+
+import (
+	"errors"
+	"fmt"
+	"github.com/asdine/storm"
+	"io"
+	"os"
+	"strconv"
+	"time"
+)
+
+// Migration struct
+type Migration struct {
+	Version     int
+	Description string
+	Run         func(tx storm.Node) error
+}
+
+// MigrationRecord struct
+type MigrationRecord struct {
+	Version     int `storm:"id"`
+	Description string
+	AppliedAt   time.Time
+}
+
+// migrations is our ordered list of schema changes, versions must be sequential starting at 1
+var migrations = []Migration{
+	{Version: 1, Description: "Build indexes for room, user, event and transfer lookups", Run: migrateReIndex},
+	{Version: 2, Description: "Move room direction fields into named exits", Run: migrateRoomExits},
+	{Version: 3, Description: "Move exit item lists into exit requirements", Run: migrateExitItemRequirements},
+}
+
+// GetSchemaVersion function
+// Returns the highest migration version that has been applied, 0 for a fresh or never migrated database
+func (h *DBHandler) GetSchemaVersion() (version int, err error) {
+
+	var records []MigrationRecord
+	db := h.rawdb.From("Migrations")
+	err = db.All(&records)
+	if err != nil {
+		return 0, err
+	}
+
+	for _, record := range records {
+		if record.Version > version {
+			version = record.Version
+		}
+	}
+	return version, nil
+}
+
+// PendingMigrations function
+func (h *DBHandler) PendingMigrations() (pending []Migration, err error) {
+
+	version, err := h.GetSchemaVersion()
+	if err != nil {
+		return pending, err
+	}
+
+	for i, migration := range migrations {
+		if migration.Version != i+1 {
+			return pending, errors.New("Migration list is out of order at version " + strconv.Itoa(migration.Version))
+		}
+		if migration.Version > version {
+			pending = append(pending, migration)
+		}
+	}
+	return pending, nil
+}
+
+// Migrate function
+// Runs every pending migration in order. A dry run executes each migration inside a transaction that is always
+// rolled back, so errors are reported without anything being written.
+func (h *DBHandler) Migrate(dryrun bool) (err error) {
+
+	pending, err := h.PendingMigrations()
+	if err != nil {
+		return err
+	}
+
+	if len(pending) == 0 {
+		fmt.Println("Database schema is up to date")
+		return nil
+	}
+
+	version, err := h.GetSchemaVersion()
+	if err != nil {
+		return err
+	}
+	fmt.Println("Database schema is at version " + strconv.Itoa(version) + ", " + strconv.Itoa(len(pending)) +
+		" migration(s) pending")
+
+	if !dryrun {
+		backup, err := h.BackupDBFile("pre-migration-v" + strconv.Itoa(version))
+		if err != nil {
+			return errors.New("Could not backup database before migrating: " + err.Error())
+		}
+		fmt.Println("Database backed up to " + backup)
+	}
+
+	for _, migration := range pending {
+		err = h.runMigration(migration, dryrun)
+		if err != nil {
+			return errors.New("Migration " + strconv.Itoa(migration.Version) + " failed: " + err.Error())
+		}
+	}
+
+	if dryrun {
+		fmt.Println("Dry run complete, no changes were written")
+	}
+	return nil
+}
+
+// runMigration function
+func (h *DBHandler) runMigration(migration Migration, dryrun bool) (err error) {
+
+	fmt.Println("Running migration " + strconv.Itoa(migration.Version) + ": " + migration.Description)
+
+	tx, err := h.rawdb.Begin(true)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	err = migration.Run(tx)
+	if err != nil {
+		return err
+	}
+
+	if dryrun {
+		// The deferred rollback throws everything away
+		return nil
+	}
+
+	record := MigrationRecord{Version: migration.Version, Description: migration.Description, AppliedAt: time.Now()}
+	err = tx.From("Migrations").Save(&record)
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// BackupDBFile function
+// Copies the database file aside, this is only safe while nothing else is writing to the database (ie at startup)
+func (h *DBHandler) BackupDBFile(label string) (path string, err error) {
+
+	path = h.conf.MainConfig.DBFile + "." + label + "." + time.Now().UTC().Format("20060102-150405") + ".bak"
+
+	source, err := os.Open(h.conf.MainConfig.DBFile)
+	if err != nil {
+		return path, err
+	}
+	defer source.Close()
+
+	destination, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0600)
+	if err != nil {
+		return path, err
+	}
+	defer destination.Close()
+
+	_, err = io.Copy(destination, source)
+	if err != nil {
+		return path, err
+	}
+
+	return path, destination.Sync()
+}
+
+// migrateReIndex function
+// Version 1 - records saved before their indexes existed can't be found through them until they are reindexed
+func migrateReIndex(tx storm.Node) error {
+
+	buckets := []struct {
+		name   string
+		record interface{}
+	}{
+		{"Users", &User{}},
+		{"Rooms", &Room{}},
+		{"Events", &Event{}},
+		{"Transfers", &Transfer{}},
+		{"Guilds", &GuildRecord{}},
+	}
+
+	for _, bucket := range buckets {
+		// ReIndex doesn't check for a missing bucket, and a bucket that doesn't exist yet has nothing to index
+		count, err := tx.From(bucket.name).Count(bucket.record)
+		if err != nil {
+			return errors.New("Could not count " + bucket.name + ": " + err.Error())
+		}
+		if count == 0 {
+			continue
+		}
+
+		err = tx.From(bucket.name).ReIndex(bucket.record)
+		if err != nil {
+			return errors.New("Could not reindex " + bucket.name + ": " + err.Error())
+		}
+	}
+	return nil
+}
+
+// legacyRoomDirections struct
+// The direction fields rooms had before exits, only used to read old records and old exports
+type legacyRoomDirections struct {
+	UpID            string
+	UpItemID        []string
+	DownID          string
+	DownItemID      []string
+	NorthID         string
+	NorthItemID     []string
+	NorthEastID     string
+	NorthEastItemID []string
+	EastID          string
+	EastItemID      []string
+	SouthEastID     string
+	SouthEastItemID []string
+	SouthID         string
+	SouthItemID     []string
+	SouthWestID     string
+	SouthWestItemID []string
+	WestID          string
+	WestItemID      []string
+	NorthWestID     string
+	NorthWestItemID []string
+}
+
+// migrateRoomExits function
+// Version 2 - the ten direction fields on Room were replaced by a list of exits
+func migrateRoomExits(tx storm.Node) error {
+
+	var rooms []Room
+	db := tx.From("Rooms")
+	err := db.All(&rooms)
+	if err != nil {
+		if err == storm.ErrNotFound {
+			return nil
+		}
+		return err
+	}
+
+	for _, room := range rooms {
+		// Room no longer has the old fields, so the raw record is read again into a struct that does
+		var legacy legacyRoomDirections
+		err = db.Get("Room", room.ID, &legacy)
+		if err != nil {
+			return errors.New("Could not read room " + room.ID + ": " + err.Error())
+		}
+
+		err = addLegacyExits(&room, legacy)
+		if err != nil {
+			return err
+		}
+
+		err = db.Save(&room)
+		if err != nil {
+			return errors.New("Could not save room " + room.ID + ": " + err.Error())
+		}
+	}
+	return nil
+}
+
+// addLegacyExits function
+// Adds an exit for each old direction field that was set, used by migrateRoomExits and when restoring old exports
+func addLegacyExits(room *Room, legacy legacyRoomDirections) (err error) {
+
+	directions := []struct {
+		name    string
+		target  string
+		itemIDs []string
+	}{
+		{"north", legacy.NorthID, legacy.NorthItemID},
+		{"northeast", legacy.NorthEastID, legacy.NorthEastItemID},
+		{"east", legacy.EastID, legacy.EastItemID},
+		{"southeast", legacy.SouthEastID, legacy.SouthEastItemID},
+		{"south", legacy.SouthID, legacy.SouthItemID},
+		{"southwest", legacy.SouthWestID, legacy.SouthWestItemID},
+		{"west", legacy.WestID, legacy.WestItemID},
+		{"northwest", legacy.NorthWestID, legacy.NorthWestItemID},
+		{"up", legacy.UpID, legacy.UpItemID},
+		{"down", legacy.DownID, legacy.DownItemID},
+	}
+
+	for _, direction := range directions {
+		if direction.target == "" {
+			continue
+		}
+		if _, exists := room.GetExit(direction.name); exists {
+			continue
+		}
+
+		// Directions were always linked both ways, so every one of them has its opposite as a reverse
+		standard, _ := GetStandardDirection(direction.name)
+		exit := NewExit(direction.name, direction.target, standard.reverse, *room)
+		exit.ItemIDs = direction.itemIDs
+		err = room.AddExit(exit)
+		if err != nil {
+			return errors.New("Could not migrate room " + room.ID + ": " + err.Error())
+		}
+	}
+	return nil
+}
+
+// migrateExitItemRequirements function
+// Version 3 - the item IDs carried over from the old direction fields become item requirements
+func migrateExitItemRequirements(tx storm.Node) error {
+
+	var rooms []Room
+	db := tx.From("Rooms")
+	err := db.All(&rooms)
+	if err != nil {
+		if err == storm.ErrNotFound {
+			return nil
+		}
+		return err
+	}
+
+	for _, room := range rooms {
+		changed := false
+		for i, exit := range room.Exits {
+			for _, itemID := range exit.ItemIDs {
+				exit.Requirements = append(exit.Requirements, ExitRequirement{Type: "item", Value: itemID})
+				changed = true
+			}
+			exit.ItemIDs = nil
+			room.Exits[i] = exit
+		}
+
+		if !changed {
+			continue
+		}
+		err = db.Save(&room)
+		if err != nil {
+			return errors.New("Could not save room " + room.ID + ": " + err.Error())
+		}
+	}
+	return nil
+}
@@ -0,0 +1,54 @@
+package main
+
+import (
+	"github.com/asdine/storm"
+	"path/filepath"
+	"testing"
+)
+
+func TestMigrate(t *testing.T) {
+
+	tests := []struct {
+		name   string
+		dryrun bool
+		users  []User // Saved before migrating, every other bucket is left missing
+		want   int
+	}{
+		{name: "empty database", want: len(migrations)},
+		{name: "empty database dry run", dryrun: true, want: 0},
+		{name: "existing users", users: []User{{ID: "1", Name: "Alice"}}, want: len(migrations)},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			conf := &Config{}
+			conf.MainConfig.DBFile = filepath.Join(t.TempDir(), "aether.db")
+			rawdb, err := storm.Open(conf.MainConfig.DBFile)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer rawdb.Close()
+			db := &DBHandler{conf: conf, rawdb: rawdb}
+
+			for _, user := range test.users {
+				err = rawdb.From("Users").Save(&user)
+				if err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			err = db.Migrate(test.dryrun)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			version, err := db.GetSchemaVersion()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if version != test.want {
+				t.Errorf("schema version = %d, want %d", version, test.want)
+			}
+		})
+	}
+}
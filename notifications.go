@@ -2,7 +2,6 @@ package main
 
 import (
 	"errors"
-	"github.com/bwmarrin/discordgo"
 	"sync"
 	"time"
 )
@@ -49,7 +48,7 @@ func (h *Notifications) RemoveNotificationFromDB(notification Notification) (err
 }
 
 // RemoveNotificationFromDBByID function
-func (h *Notifications) RemoveNotificationFromDBByID(messageid string, s *discordgo.Session) (err error) {
+func (h *Notifications) RemoveNotificationFromDBByID(messageid string, s DiscordSession) (err error) {
 
 	notification, err := h.GetNotificationFromDB(messageid)
 	if err != nil {
@@ -262,7 +261,7 @@ func (h *Notifications) CreateChannelNotification(id string, notificationid stri
 }
 
 // GetNotificationLinkedChannels function
-func (h *Notifications) GetNotificationLinkedChannels(messageid string, s *discordgo.Session) (channels string, err error) {
+func (h *Notifications) GetNotificationLinkedChannels(messageid string, s DiscordSession) (channels string, err error) {
 
 	notification, err := h.GetNotificationFromDB(messageid)
 	if err != nil {
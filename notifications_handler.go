@@ -1,6 +1,7 @@
 package main
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"github.com/bwmarrin/discordgo"
@@ -13,6 +14,7 @@ import (
 type NotificationsHandler struct {
 	conf     *Config
 	registry *CommandRegistry
+	router   *CommandRouter
 	callback *CallbackHandler
 	db       *DBHandler
 }
@@ -27,47 +29,13 @@ func (h *NotificationsHandler) Init() {
 func (h *NotificationsHandler) RegisterCommands() (err error) {
 
 	h.registry.Register("notify", "Manage notifications for this channel", "enable(for)|disable(for)|add|remove|list|view|channel|messages|flush|linked")
+	h.router.AddRoute("notify", true, h.ParseCommand, "moderator")
 	return nil
 
 }
 
-// Read function
-func (h *NotificationsHandler) Read(s *discordgo.Session, m *discordgo.MessageCreate) {
-
-	cp := h.conf.MainConfig.CP
-
-	if !SafeInput(s, m, h.conf) {
-		return
-	}
-
-	user, err := h.db.GetUser(m.Author.ID)
-	if err != nil {
-		//fmt.Println("Error finding usermanager")
-		return
-	}
-
-	if strings.HasPrefix(m.Content, cp+"notify") {
-		if h.registry.CheckPermission("notify", user, s, m) {
-
-			command := strings.Fields(m.Content)
-
-			// Grab our sender ID to verify if this usermanager has permission to use this command
-			db := h.db.rawdb.From("Users")
-			var user User
-			err := db.One("ID", m.Author.ID, &user)
-			if err != nil {
-				fmt.Println("error retrieving usermanager:" + m.Author.ID)
-			}
-
-			if user.CheckRole("moderator") {
-				h.ParseCommand(command, s, m)
-			}
-		}
-	}
-}
-
 // ParseCommand function
-func (h *NotificationsHandler) ParseCommand(command []string, s *discordgo.Session, m *discordgo.MessageCreate) {
+func (h *NotificationsHandler) ParseCommand(command []string, user User, s DiscordSession, m *discordgo.MessageCreate) {
 
 	if len(command) < 2 {
 		s.ChannelMessageSend(m.ChannelID, "Expected flag for 'notifications' command, see usage for more info")
@@ -199,7 +167,7 @@ func (h *NotificationsHandler) ParseCommand(command []string, s *discordgo.Sessi
 }
 
 // AddNotification function
-func (h *NotificationsHandler) AddNotification(command []string, s *discordgo.Session, m *discordgo.MessageCreate) {
+func (h *NotificationsHandler) AddNotification(command []string, s DiscordSession, m *discordgo.MessageCreate) {
 
 	message := ""
 	for i, text := range command {
@@ -231,7 +199,7 @@ func (h *NotificationsHandler) AddNotification(command []string, s *discordgo.Se
 }
 
 // RemoveNotification fiunction
-func (h *NotificationsHandler) RemoveNotification(messageid string, s *discordgo.Session, m *discordgo.MessageCreate) {
+func (h *NotificationsHandler) RemoveNotification(messageid string, s DiscordSession, m *discordgo.MessageCreate) {
 
 	notificationsdb := Notifications{db: h.db}
 
@@ -246,7 +214,7 @@ func (h *NotificationsHandler) RemoveNotification(messageid string, s *discordgo
 }
 
 // GetAllNotifications function
-func (h *NotificationsHandler) GetAllNotifications(page string, s *discordgo.Session, m *discordgo.MessageCreate) {
+func (h *NotificationsHandler) GetAllNotifications(page string, s DiscordSession, m *discordgo.MessageCreate) {
 
 	pagenum, err := strconv.Atoi(page)
 	if err != nil {
@@ -299,7 +267,7 @@ func (h *NotificationsHandler) GetAllNotifications(page string, s *discordgo.Ses
 }
 
 // GetAllChannelNotifications function
-func (h *NotificationsHandler) GetAllChannelNotifications(page string, s *discordgo.Session, m *discordgo.MessageCreate) {
+func (h *NotificationsHandler) GetAllChannelNotifications(page string, s DiscordSession, m *discordgo.MessageCreate) {
 
 	pagenum, err := strconv.Atoi(page)
 	if err != nil {
@@ -415,7 +383,7 @@ func (h *NotificationsHandler) GetAllChannelNotifications(page string, s *discor
 }
 
 // EnableChannelNotification function
-func (h *NotificationsHandler) EnableChannelNotification(command []string, s *discordgo.Session, m *discordgo.MessageCreate) {
+func (h *NotificationsHandler) EnableChannelNotification(command []string, s DiscordSession, m *discordgo.MessageCreate) {
 
 	var parsed string
 
@@ -449,7 +417,7 @@ func (h *NotificationsHandler) EnableChannelNotification(command []string, s *di
 }
 
 // DisableChannelNotification function
-func (h *NotificationsHandler) DisableChannelNotification(notificationid string, s *discordgo.Session, m *discordgo.MessageCreate) {
+func (h *NotificationsHandler) DisableChannelNotification(notificationid string, s DiscordSession, m *discordgo.MessageCreate) {
 
 	notificationsdb := Notifications{db: h.db}
 	err := notificationsdb.RemoveChannelNotificationFromDBByID(notificationid, m.ChannelID)
@@ -465,7 +433,7 @@ func (h *NotificationsHandler) DisableChannelNotification(notificationid string,
 // Channel Specific Functions
 
 // GetAllChannelNotificationsFor function
-func (h *NotificationsHandler) GetAllChannelNotificationsFor(channelname string, page string, s *discordgo.Session, m *discordgo.MessageCreate) {
+func (h *NotificationsHandler) GetAllChannelNotificationsFor(channelname string, page string, s DiscordSession, m *discordgo.MessageCreate) {
 
 	channelname = strings.TrimPrefix(channelname, "<#")
 	channelname = strings.TrimSuffix(channelname, ">")
@@ -597,7 +565,7 @@ func (h *NotificationsHandler) GetAllChannelNotificationsFor(channelname string,
 }
 
 // EnableChannelNotificationFor function
-func (h *NotificationsHandler) EnableChannelNotificationFor(command []string, s *discordgo.Session, m *discordgo.MessageCreate) {
+func (h *NotificationsHandler) EnableChannelNotificationFor(command []string, s DiscordSession, m *discordgo.MessageCreate) {
 
 	var parsed string
 
@@ -647,7 +615,7 @@ func (h *NotificationsHandler) EnableChannelNotificationFor(command []string, s
 }
 
 // DisableChannelNotificationFor function
-func (h *NotificationsHandler) DisableChannelNotificationFor(channelname string, notificationid string, s *discordgo.Session, m *discordgo.MessageCreate) {
+func (h *NotificationsHandler) DisableChannelNotificationFor(channelname string, notificationid string, s DiscordSession, m *discordgo.MessageCreate) {
 
 	channelname = strings.TrimPrefix(channelname, "<#")
 	channelid := strings.TrimSuffix(channelname, ">")
@@ -676,7 +644,7 @@ func (h *NotificationsHandler) DisableChannelNotificationFor(channelname string,
 }
 
 // ViewNotificationMessageID function
-func (h *NotificationsHandler) ViewNotificationMessageID(notificationid string, s *discordgo.Session, m *discordgo.MessageCreate) {
+func (h *NotificationsHandler) ViewNotificationMessageID(notificationid string, s DiscordSession, m *discordgo.MessageCreate) {
 
 	notificationsdb := Notifications{db: h.db}
 	notification, err := notificationsdb.GetNotificationFromDB(notificationid)
@@ -696,7 +664,7 @@ func (h *NotificationsHandler) ViewNotificationMessageID(notificationid string,
 }
 
 // GetAllLinkedChannels function
-func (h *NotificationsHandler) GetAllLinkedChannels(notificationid string, s *discordgo.Session, m *discordgo.MessageCreate) {
+func (h *NotificationsHandler) GetAllLinkedChannels(notificationid string, s DiscordSession, m *discordgo.MessageCreate) {
 
 	notificationsdb := Notifications{db: h.db}
 	notification, err := notificationsdb.GetNotificationFromDB(notificationid)
@@ -718,11 +686,11 @@ func (h *NotificationsHandler) GetAllLinkedChannels(notificationid string, s *di
 }
 
 // CheckNotifications function
-func (h *NotificationsHandler) CheckNotifications(s *discordgo.Session) {
+func (h *NotificationsHandler) CheckNotifications(ctx context.Context, s DiscordSession) error {
 
-	for true {
-		// Only run every X minutes
-		time.Sleep(h.conf.MainConfig.Notifications * time.Minute)
+	// Only run every X minutes
+	for SleepContext(ctx, h.conf.NotificationInterval()) {
+		Heartbeat(ctx)
 
 		//fmt.Println("Running Notifications Handler")
 		notificationsdb := Notifications{db: h.db}
@@ -764,7 +732,7 @@ func (h *NotificationsHandler) CheckNotifications(s *discordgo.Session) {
 			}
 		}
 	}
-
+	return nil
 }
 
 // ParseTimeout function
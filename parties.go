@@ -0,0 +1,214 @@
+package main
+
+import (
+	"errors"
+	"github.com/asdine/storm"
+	"sync"
+)
+
+// PartiesManager struct
+type PartiesManager struct {
+	db          *DBHandler
+	querylocker sync.RWMutex
+}
+
+// Party struct
+type Party struct {
+	ID   string `storm:"id"` // primary key
+	Name string
+
+	LeaderID  string
+	MemberIDs []string // Includes the leader
+	InviteIDs []string // Users who have been invited but haven't accepted yet
+}
+
+// SavePartyToDB function
+func (h *PartiesManager) SavePartyToDB(party Party) (err error) {
+	h.querylocker.Lock()
+	defer h.querylocker.Unlock()
+
+	db := h.db.rawdb.From("Parties")
+	err = db.Save(&party)
+	return err
+}
+
+// RemovePartyFromDB function
+func (h *PartiesManager) RemovePartyFromDB(party Party) (err error) {
+	h.querylocker.Lock()
+	defer h.querylocker.Unlock()
+
+	db := h.db.rawdb.From("Parties")
+	err = db.DeleteStruct(&party)
+	return err
+}
+
+// GetPartyByID function
+func (h *PartiesManager) GetPartyByID(partyID string) (party Party, err error) {
+	h.querylocker.RLock()
+	defer h.querylocker.RUnlock()
+
+	return h.getPartyByID(partyID)
+}
+
+// getPartyByID function
+// For use with querylocker already held
+func (h *PartiesManager) getPartyByID(partyID string) (party Party, err error) {
+
+	db := h.db.rawdb.From("Parties")
+	err = db.One("ID", partyID, &party)
+	if err != nil {
+		if err == storm.ErrNotFound {
+			return party, errors.New("No party record found")
+		}
+		return party, err
+	}
+	return party, nil
+}
+
+// GetAllParties function
+func (h *PartiesManager) GetAllParties() (partylist []Party, err error) {
+	h.querylocker.RLock()
+	defer h.querylocker.RUnlock()
+
+	return h.getAllParties()
+}
+
+// getAllParties function
+// For use with querylocker already held
+func (h *PartiesManager) getAllParties() (partylist []Party, err error) {
+
+	db := h.db.rawdb.From("Parties")
+	err = db.All(&partylist)
+	if err != nil {
+		return partylist, err
+	}
+	return partylist, nil
+}
+
+// GetPartyByMember function
+func (h *PartiesManager) GetPartyByMember(userID string) (party Party, found bool) {
+
+	parties, err := h.GetAllParties()
+	if err != nil {
+		return party, false
+	}
+	return partyByMember(parties, userID)
+}
+
+// partyByMember function
+func partyByMember(parties []Party, userID string) (party Party, found bool) {
+	for _, candidate := range parties {
+		if StringInSlice(userID, candidate.MemberIDs) {
+			return candidate, true
+		}
+	}
+	return party, false
+}
+
+// GetPartiesInviting function
+func (h *PartiesManager) GetPartiesInviting(userID string) (invites []Party, err error) {
+
+	parties, err := h.GetAllParties()
+	if err != nil {
+		return invites, err
+	}
+	for _, party := range parties {
+		if StringInSlice(userID, party.InviteIDs) {
+			invites = append(invites, party)
+		}
+	}
+	return invites, nil
+}
+
+// The changes below read the party again and save it under one lock, so two changes made at the same moment (two
+// members accepting, an accept and a kick) can't overwrite each other
+
+// CreateParty function
+func (h *PartiesManager) CreateParty(leaderID string, name string) (party Party, err error) {
+	h.querylocker.Lock()
+	defer h.querylocker.Unlock()
+
+	parties, err := h.getAllParties()
+	if err != nil && err != storm.ErrNotFound {
+		return party, err
+	}
+	if _, found := partyByMember(parties, leaderID); found {
+		return party, errors.New("You are already in a party, leave it first")
+	}
+
+	partyID, err := GetUUID()
+	if err != nil {
+		return party, err
+	}
+	party = Party{ID: partyID, Name: name, LeaderID: leaderID, MemberIDs: []string{leaderID}}
+	return party, h.db.rawdb.From("Parties").Save(&party)
+}
+
+// Invite function
+func (h *PartiesManager) Invite(partyID string, userID string) (err error) {
+	h.querylocker.Lock()
+	defer h.querylocker.Unlock()
+
+	party, err := h.getPartyByID(partyID)
+	if err != nil {
+		return err
+	}
+	if StringInSlice(userID, party.MemberIDs) {
+		return errors.New("They are already in your party")
+	}
+	if StringInSlice(userID, party.InviteIDs) {
+		return errors.New("They have already been invited")
+	}
+	party.InviteIDs = append(party.InviteIDs, userID)
+	return h.db.rawdb.From("Parties").Save(&party)
+}
+
+// Accept function
+func (h *PartiesManager) Accept(partyID string, userID string) (err error) {
+	h.querylocker.Lock()
+	defer h.querylocker.Unlock()
+
+	parties, err := h.getAllParties()
+	if err != nil && err != storm.ErrNotFound {
+		return err
+	}
+	if _, found := partyByMember(parties, userID); found {
+		return errors.New("You are already in a party, leave it first")
+	}
+
+	party, err := h.getPartyByID(partyID)
+	if err != nil {
+		return err
+	}
+	if !StringInSlice(userID, party.InviteIDs) {
+		return errors.New("You haven't been invited to that party")
+	}
+	party.InviteIDs = RemoveStringFromSlice(party.InviteIDs, userID)
+	party.MemberIDs = append(party.MemberIDs, userID)
+	return h.db.rawdb.From("Parties").Save(&party)
+}
+
+// RemoveMember function
+// Hands the party to the longest standing member when the leader goes, and disbands it when nobody is left
+func (h *PartiesManager) RemoveMember(partyID string, userID string) (updated Party, disbanded bool, err error) {
+	h.querylocker.Lock()
+	defer h.querylocker.Unlock()
+
+	party, err := h.getPartyByID(partyID)
+	if err != nil {
+		return party, false, err
+	}
+	if !StringInSlice(userID, party.MemberIDs) {
+		return party, false, errors.New("They are not in the party")
+	}
+	party.MemberIDs = RemoveStringFromSlice(party.MemberIDs, userID)
+
+	db := h.db.rawdb.From("Parties")
+	if len(party.MemberIDs) == 0 {
+		return party, true, db.DeleteStruct(&party)
+	}
+	if party.LeaderID == userID {
+		party.LeaderID = party.MemberIDs[0]
+	}
+	return party, false, db.Save(&party)
+}
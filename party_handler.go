@@ -0,0 +1,268 @@
+package main
+
+import (
+	"github.com/bwmarrin/discordgo"
+	"strconv"
+	"strings"
+)
+
+// PartyHandler struct
+type PartyHandler struct {
+	conf     *Config
+	registry *CommandRegistry
+	router   *CommandRouter
+	room     *RoomsHandler
+	user     *UserHandler
+	parties  *PartiesManager
+}
+
+// Init function
+func (h *PartyHandler) Init() {
+	h.RegisterCommands()
+}
+
+// RegisterCommands function
+func (h *PartyHandler) RegisterCommands() (err error) {
+
+	h.registry.Register("party", "Travel together as a party, the leader's travel moves everyone in the same room",
+		"create [name] | invite <@user> | accept [@leader] | leave | kick <@user> | list | say <message>")
+	h.router.AddRoute("party", false, h.ParseCommand, "player")
+	return nil
+
+}
+
+// ParseCommand function
+func (h *PartyHandler) ParseCommand(command []string, user User, s DiscordSession, m *discordgo.MessageCreate) {
+
+	if len(command) < 2 {
+		s.ChannelMessageSend(m.ChannelID, "Expected flag for 'party' command, see command usage for more info")
+		return
+	}
+
+	switch command[1] {
+	case "create":
+		name := strings.TrimSpace(strings.Join(command[2:], " "))
+		if name == "" {
+			name = h.memberName(user.ID) + "'s party"
+		}
+		party, err := h.parties.CreateParty(user.ID, name)
+		if err != nil {
+			s.ChannelMessageSend(m.ChannelID, err.Error())
+			return
+		}
+		s.ChannelMessageSend(m.ChannelID, "You have formed "+party.Name+", invite others with "+
+			h.conf.CommandPrefix()+"party invite @user")
+		return
+
+	case "invite":
+		if len(command) < 3 {
+			s.ChannelMessageSend(m.ChannelID, "invite requires a user: <@user>")
+			return
+		}
+		h.InviteMember(CleanUserMention(command[2]), user, s, m)
+		return
+
+	case "accept":
+		leaderID := ""
+		if len(command) > 2 {
+			leaderID = CleanUserMention(command[2])
+		}
+		h.AcceptInvite(leaderID, user, s, m)
+		return
+
+	case "leave":
+		party, found := h.parties.GetPartyByMember(user.ID)
+		if !found {
+			s.ChannelMessageSend(m.ChannelID, "You are not in a party")
+			return
+		}
+		h.RemoveMember(party, user.ID, "has left the party", s, m)
+		return
+
+	case "kick":
+		if len(command) < 3 {
+			s.ChannelMessageSend(m.ChannelID, "kick requires a user: <@user>")
+			return
+		}
+		party, found := h.parties.GetPartyByMember(user.ID)
+		if !found || party.LeaderID != user.ID {
+			s.ChannelMessageSend(m.ChannelID, "Only a party leader can kick someone from their party")
+			return
+		}
+		memberID := CleanUserMention(command[2])
+		if memberID == user.ID {
+			s.ChannelMessageSend(m.ChannelID, "Use party leave to leave your own party")
+			return
+		}
+		h.RemoveMember(party, memberID, "has been removed from the party", s, m)
+		return
+
+	case "list":
+		h.ListParty(user, s, m)
+		return
+
+	case "say":
+		message := strings.TrimSpace(strings.Join(command[2:], " "))
+		if message == "" {
+			s.ChannelMessageSend(m.ChannelID, "say requires a message")
+			return
+		}
+		party, found := h.parties.GetPartyByMember(user.ID)
+		if !found {
+			s.ChannelMessageSend(m.ChannelID, "You are not in a party")
+			return
+		}
+		h.Relay(party, "**"+h.memberName(user.ID)+"**: "+message, s)
+		return
+	}
+
+	s.ChannelMessageSend(m.ChannelID, "Unrecognized flag for 'party' command, see command usage for more info")
+}
+
+// InviteMember function
+func (h *PartyHandler) InviteMember(inviteeID string, user User, s DiscordSession, m *discordgo.MessageCreate) {
+
+	party, found := h.parties.GetPartyByMember(user.ID)
+	if !found || party.LeaderID != user.ID {
+		s.ChannelMessageSend(m.ChannelID, "Only a party leader can invite someone, create a party first")
+		return
+	}
+	if _, err := h.user.usermanager.GetUserByID(inviteeID); err != nil {
+		s.ChannelMessageSend(m.ChannelID, "There is no traveler "+inviteeID+" to invite")
+		return
+	}
+
+	err := h.parties.Invite(party.ID, inviteeID)
+	if err != nil {
+		s.ChannelMessageSend(m.ChannelID, err.Error())
+		return
+	}
+
+	notice := h.memberName(user.ID) + " has invited you to join " + party.Name + ", reply with " +
+		h.conf.CommandPrefix() + "party accept <@" + user.ID + "> to join"
+	if channel, err := s.UserChannelCreate(inviteeID); err == nil {
+		s.ChannelMessageSend(channel.ID, notice)
+	}
+	s.ChannelMessageSend(m.ChannelID, "<@"+inviteeID+"> you have been invited to join "+party.Name)
+}
+
+// AcceptInvite function
+// The leader only needs to be named when there is more than one invite waiting
+func (h *PartyHandler) AcceptInvite(leaderID string, user User, s DiscordSession, m *discordgo.MessageCreate) {
+
+	invites, err := h.parties.GetPartiesInviting(user.ID)
+	if err != nil {
+		s.ChannelMessageSend(m.ChannelID, "Error retrieving invites: "+err.Error())
+		return
+	}
+
+	var matched []Party
+	for _, party := range invites {
+		if leaderID == "" || party.LeaderID == leaderID {
+			matched = append(matched, party)
+		}
+	}
+	if len(matched) == 0 {
+		s.ChannelMessageSend(m.ChannelID, "You don't have a party invite waiting")
+		return
+	}
+	if len(matched) > 1 {
+		var leaders []string
+		for _, party := range matched {
+			leaders = append(leaders, party.Name+" (<@"+party.LeaderID+">)")
+		}
+		s.ChannelMessageSend(m.ChannelID, "You have been invited to "+strings.Join(leaders, ", ")+
+			", name the leader of the one you want to join")
+		return
+	}
+
+	party := matched[0]
+	err = h.parties.Accept(party.ID, user.ID)
+	if err != nil {
+		s.ChannelMessageSend(m.ChannelID, err.Error())
+		return
+	}
+	s.ChannelMessageSend(m.ChannelID, "You have joined "+party.Name)
+
+	party, err = h.parties.GetPartyByID(party.ID)
+	if err == nil {
+		h.Relay(party, h.memberName(user.ID)+" has joined the party", s)
+	}
+}
+
+// RemoveMember function
+func (h *PartyHandler) RemoveMember(party Party, memberID string, reason string, s DiscordSession,
+	m *discordgo.MessageCreate) {
+
+	updated, disbanded, err := h.parties.RemoveMember(party.ID, memberID)
+	if err != nil {
+		s.ChannelMessageSend(m.ChannelID, err.Error())
+		return
+	}
+
+	name := h.memberName(memberID)
+	if disbanded {
+		s.ChannelMessageSend(m.ChannelID, party.Name+" has disbanded")
+		return
+	}
+	s.ChannelMessageSend(m.ChannelID, name+" "+reason)
+
+	notice := name + " " + reason
+	if updated.LeaderID != party.LeaderID {
+		notice = notice + ", " + h.memberName(updated.LeaderID) + " now leads the party"
+	}
+	h.Relay(updated, notice, s)
+}
+
+// ListParty function
+func (h *PartyHandler) ListParty(user User, s DiscordSession, m *discordgo.MessageCreate) {
+
+	party, found := h.parties.GetPartyByMember(user.ID)
+	if !found {
+		s.ChannelMessageSend(m.ChannelID, "You are not in a party")
+		return
+	}
+
+	output := "```\n" + party.Name + " (" + strconv.Itoa(len(party.MemberIDs)) + " members)\n\n"
+	for _, memberID := range party.MemberIDs {
+		line := h.memberName(memberID)
+		if memberID == party.LeaderID {
+			line = line + " (leader)"
+		}
+		if member, err := h.user.usermanager.GetUserByID(memberID); err == nil && member.RoomID != "" {
+			if room, err := h.room.rooms.GetRoomByID(member.RoomID); err == nil {
+				line = line + " - " + room.Name
+			}
+		}
+		output = output + line + "\n"
+	}
+	if len(party.InviteIDs) > 0 {
+		var invited []string
+		for _, inviteeID := range party.InviteIDs {
+			invited = append(invited, h.memberName(inviteeID))
+		}
+		output = output + "\nInvited: " + strings.Join(invited, ", ") + "\n"
+	}
+	s.ChannelMessageSend(m.ChannelID, output+"```\n")
+}
+
+// Relay function
+// Party chat goes to each member privately, so it follows them wherever they are in the cluster
+func (h *PartyHandler) Relay(party Party, message string, s DiscordSession) {
+	for _, memberID := range party.MemberIDs {
+		channel, err := s.UserChannelCreate(memberID)
+		if err != nil {
+			continue
+		}
+		s.ChannelMessageSend(channel.ID, ":busts_in_silhouette: ["+party.Name+"] "+message)
+	}
+}
+
+// memberName function
+func (h *PartyHandler) memberName(userID string) string {
+	member, err := h.user.usermanager.GetUserByID(userID)
+	if err != nil || member.Name == "" {
+		return "<@" + userID + ">"
+	}
+	return member.Name
+}
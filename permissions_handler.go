@@ -6,60 +6,28 @@ import (
 	"github.com/bwmarrin/discordgo"
 	"strconv"
 	"strings"
-	"time"
 )
 
 // PermissionsHandler struct
 type PermissionsHandler struct {
 	db       *DBHandler
 	conf     *Config
-	dg       *discordgo.Session
+	dg       DiscordSession
 	callback *CallbackHandler
 	user     *UserHandler
-	logchan  chan string
+	router   *CommandRouter
+	logger   *Logger
+	queue    *DiscordQueue
 	room     *RoomsHandler
 }
 
-// Read function
-func (h *PermissionsHandler) Read(s *discordgo.Session, m *discordgo.MessageCreate) {
-
-	// Ignore all messages created by the bot itself
-	// This isn't required in this specific example but it's a good practice.
-	if m.Author.ID == s.State.User.ID {
-		return
-	}
-
-	// Ignore bots
-	if m.Author.Bot {
-		return
-	}
-
-	// Verify the usermanager account exists (creates one if it doesn't exist already)
-	h.user.CheckUser(m.Author.ID, s, m.ChannelID)
-
-	/*
-		usermanager, err := h.db.GetUser(m.Author.ID)
-		if err != nil{
-			fmt.Println("Error finding usermanager")
-			return
-		}
-	*/
-
-	// Command prefix
-	cp := h.conf.MainConfig.CP
-
-	// Command from message content
-	command := strings.Fields(m.Content)
-	// We use this a bit, this is the author id formatted as a mention
-	//authormention := m.Author.Mention()
-	//mentions := m.Mentions
-
-	// We don't care about commands that aren't formatted for this handler
-	if len(command) < 1 {
-		return
-	}
+// Init function
+func (h *PermissionsHandler) Init() {
+	h.router.AddRoute("perms", false, h.ReadCommand)
+}
 
-	command[0] = strings.TrimPrefix(command[0], cp)
+// ReadCommand function
+func (h *PermissionsHandler) ReadCommand(command []string, user User, s DiscordSession, m *discordgo.MessageCreate) {
 
 	// After our command string has been trimmed down, check it against the command list
 	if command[0] == "perms" {
@@ -75,12 +43,6 @@ func (h *PermissionsHandler) Read(s *discordgo.Session, m *discordgo.MessageCrea
 		}
 
 		if command[1] == "addrole" {
-			// Get the authors user object from the database
-			user, err := h.db.GetUser(m.Author.ID)
-			if err != nil {
-				s.ChannelMessageSend(m.ChannelID, "syncserverroles error: "+err.Error())
-				return
-			}
 
 			if !user.CheckRole("moderator") {
 				s.ChannelMessageSend(m.ChannelID, "You do not have permission to use this command.")
@@ -115,12 +77,6 @@ func (h *PermissionsHandler) Read(s *discordgo.Session, m *discordgo.MessageCrea
 			return
 		}
 		if command[1] == "removerole" {
-			// Get the authors usermanager object from the database
-			user, err := h.db.GetUser(m.Author.ID)
-			if err != nil {
-				s.ChannelMessageSend(m.ChannelID, "syncserverroles error: "+err.Error())
-				return
-			}
 
 			if !user.CheckRole("moderator") {
 				s.ChannelMessageSend(m.ChannelID, "You do not have permission to use this command.")
@@ -156,13 +112,6 @@ func (h *PermissionsHandler) Read(s *discordgo.Session, m *discordgo.MessageCrea
 
 		if command[1] == "createrole" {
 
-			// Get the authors usermanager object from the database
-			user, err := h.db.GetUser(m.Author.ID)
-			if err != nil {
-				s.ChannelMessageSend(m.ChannelID, "syncserverroles error: "+err.Error())
-				return
-			}
-
 			if !user.CheckRole("admin") {
 				s.ChannelMessageSend(m.ChannelID, "You do not have permission to use this command.")
 				return
@@ -210,12 +159,6 @@ func (h *PermissionsHandler) Read(s *discordgo.Session, m *discordgo.MessageCrea
 			return
 		}
 		if command[1] == "deleterole" {
-			// Get the authors usermanager object from the database
-			user, err := h.db.GetUser(m.Author.ID)
-			if err != nil {
-				s.ChannelMessageSend(m.ChannelID, "syncserverroles error: "+err.Error())
-				return
-			}
 
 			if !user.CheckRole("admin") {
 				s.ChannelMessageSend(m.ChannelID, "You do not have permission to use this command.")
@@ -246,13 +189,6 @@ func (h *PermissionsHandler) Read(s *discordgo.Session, m *discordgo.MessageCrea
 
 		if command[1] == "viewrole" {
 
-			// Get the authors usermanager object from the database
-			user, err := h.db.GetUser(m.Author.ID)
-			if err != nil {
-				s.ChannelMessageSend(m.ChannelID, "syncserverroles error: "+err.Error())
-				return
-			}
-
 			if !user.CheckRole("moderator") {
 				s.ChannelMessageSend(m.ChannelID, "You do not have permission to use this command.")
 				return
@@ -269,13 +205,6 @@ func (h *PermissionsHandler) Read(s *discordgo.Session, m *discordgo.MessageCrea
 
 		if command[1] == "promote" {
 
-			// Get the authors usermanager object from the database
-			user, err := h.db.GetUser(m.Author.ID)
-			if err != nil {
-				s.ChannelMessageSend(m.ChannelID, "syncserverroles error: "+err.Error())
-				return
-			}
-
 			if !user.CheckRole("moderator") {
 				s.ChannelMessageSend(m.ChannelID, "You do not have permission to use this command.")
 				return
@@ -288,13 +217,6 @@ func (h *PermissionsHandler) Read(s *discordgo.Session, m *discordgo.MessageCrea
 		}
 		if command[1] == "demote" {
 
-			// Get the authors usermanager object from the database
-			user, err := h.db.GetUser(m.Author.ID)
-			if err != nil {
-				s.ChannelMessageSend(m.ChannelID, "syncserverroles error: "+err.Error())
-				return
-			}
-
 			if !user.CheckRole("moderator") {
 				s.ChannelMessageSend(m.ChannelID, "You do not have permission to use this command.")
 				return
@@ -307,12 +229,6 @@ func (h *PermissionsHandler) Read(s *discordgo.Session, m *discordgo.MessageCrea
 		}
 
 		if command[1] == "syncserverroles" {
-			// Get the authors usermanager object from the database
-			user, err := h.db.GetUser(m.Author.ID)
-			if err != nil {
-				s.ChannelMessageSend(m.ChannelID, "syncserverroles error: "+err.Error())
-				return
-			}
 
 			if !user.CheckRole("admin") {
 				s.ChannelMessageSend(m.ChannelID, "You do not have permission to use this command.")
@@ -331,12 +247,6 @@ func (h *PermissionsHandler) Read(s *discordgo.Session, m *discordgo.MessageCrea
 			return
 		}
 		if command[1] == "syncrolesdb" {
-			// Get the authors usermanager object from the database
-			user, err := h.db.GetUser(m.Author.ID)
-			if err != nil {
-				s.ChannelMessageSend(m.ChannelID, "syncserverroles error: "+err.Error())
-				return
-			}
 
 			if !user.CheckRole("admin") {
 				s.ChannelMessageSend(m.ChannelID, "You do not have permission to use this command.")
@@ -358,13 +268,6 @@ func (h *PermissionsHandler) Read(s *discordgo.Session, m *discordgo.MessageCrea
 
 		if command[1] == "translaterole" {
 
-			// Get the authors usermanager object from the database
-			user, err := h.db.GetUser(m.Author.ID)
-			if err != nil {
-				s.ChannelMessageSend(m.ChannelID, "translaterole error: "+err.Error())
-				return
-			}
-
 			if !user.CheckRole("moderator") {
 				s.ChannelMessageSend(m.ChannelID, "You do not have permission to use this command.")
 				return
@@ -388,7 +291,7 @@ func (h *PermissionsHandler) Read(s *discordgo.Session, m *discordgo.MessageCrea
 }
 
 // ViewRole function
-func (h *PermissionsHandler) ViewRole(rolename string, guildID string, s *discordgo.Session, m *discordgo.MessageCreate) {
+func (h *PermissionsHandler) ViewRole(rolename string, guildID string, s DiscordSession, m *discordgo.MessageCreate) {
 
 	roles, err := s.GuildRoles(guildID)
 	if err != nil {
@@ -414,7 +317,7 @@ func (h *PermissionsHandler) ViewRole(rolename string, guildID string, s *discor
 }
 
 // ReadPromote The promote command runs using our commands array to get the promotion settings
-func (h *PermissionsHandler) ReadPromote(commands []string, s *discordgo.Session, m *discordgo.MessageCreate) {
+func (h *PermissionsHandler) ReadPromote(commands []string, s DiscordSession, m *discordgo.MessageCreate) {
 
 	if len(commands) < 3 {
 		s.ChannelMessageSend(m.ChannelID, "Usage: promote <usermanager> <group>")
@@ -440,29 +343,29 @@ func (h *PermissionsHandler) ReadPromote(commands []string, s *discordgo.Session
 	if group == "owner" {
 		if !user.CheckRole("owner") {
 			s.ChannelMessageSend(m.ChannelID, m.Author.Mention()+" https://www.youtube.com/watch?v=fmz-K2hLwSI ")
-			h.logchan <- "Permissions " + m.Author.Mention() + " attempted to run promote to owner"
+			h.logger.Warn(PERMLOG, LogFields{User: m.Author.ID, Command: "promote"}, m.Author.Mention()+" attempted to run promote to owner")
 			return
 		}
 		s.ChannelMessageSend(m.ChannelID, "This group cannot be assigned through the promote command.")
-		h.logchan <- "Permissions " + m.Author.Mention() + " attempted to run promote to owner"
+		h.logger.Warn(PERMLOG, LogFields{User: m.Author.ID, Command: "promote"}, m.Author.Mention()+" attempted to run promote to owner")
 		return
 
 	}
 	if group == "admin" {
 		if !user.CheckRole("owner") {
 			s.ChannelMessageSend(m.ChannelID, "You do not have permission to assign this group")
-			h.logchan <- "Permissions " + m.Author.Mention() + " attempted to run promote to admin"
+			h.logger.Warn(PERMLOG, LogFields{User: m.Author.ID, Command: "promote"}, m.Author.Mention()+" attempted to run promote to admin")
 			return
 		}
 		err = h.Promote(target, group, s, m)
 		if err != nil {
 			s.ChannelMessageSend(m.ChannelID, "Error: "+err.Error())
-			h.logchan <- "Permissions " + m.Author.Mention() + " attempted to run promote to admin || " +
-				target + "||" + group + "||" + err.Error()
+			h.logger.Warn(PERMLOG, LogFields{User: m.Author.ID, Command: "promote"}, m.Author.Mention()+" attempted to run promote to admin || "+
+				target+"||"+group+"||"+err.Error())
 			return
 		}
 		s.ChannelMessageSend(m.ChannelID, m.Mentions[0].Mention()+" has been added to the "+group+" group.")
-		h.logchan <- "Permissions " + m.Mentions[0].Mention() + " has been added to the " + group + " group by " + m.Author.Mention()
+		h.logger.Info(PERMLOG, LogFields{User: m.Author.ID, Command: "promote"}, m.Mentions[0].Mention()+" has been added to the "+group+" group by "+m.Author.Mention())
 		return
 
 	}
@@ -470,18 +373,18 @@ func (h *PermissionsHandler) ReadPromote(commands []string, s *discordgo.Session
 
 		if !user.CheckRole("admin") {
 			s.ChannelMessageSend(m.ChannelID, "You do not have permission to assign this group")
-			h.logchan <- "Permissions " + m.Author.Mention() + " attempted to run promote to smoderator"
+			h.logger.Warn(PERMLOG, LogFields{User: m.Author.ID, Command: "promote"}, m.Author.Mention()+" attempted to run promote to smoderator")
 			return
 		}
 		err = h.Promote(target, group, s, m)
 		if err != nil {
 			s.ChannelMessageSend(m.ChannelID, "Error: "+err.Error())
-			h.logchan <- "Permissions " + m.Author.Mention() + " attempted to run promote to smoderator || " +
-				m.Mentions[0].Mention() + "||" + group + "||" + err.Error()
+			h.logger.Warn(PERMLOG, LogFields{User: m.Author.ID, Command: "promote"}, m.Author.Mention()+" attempted to run promote to smoderator || "+
+				m.Mentions[0].Mention()+"||"+group+"||"+err.Error())
 			return
 		}
 		s.ChannelMessageSend(m.ChannelID, m.Mentions[0].Mention()+" has been added to the "+group+" group.")
-		h.logchan <- "Permissions " + m.Mentions[0].Mention() + " has been added to the " + group + " group by " + m.Author.Mention()
+		h.logger.Info(PERMLOG, LogFields{User: m.Author.ID, Command: "promote"}, m.Mentions[0].Mention()+" has been added to the "+group+" group by "+m.Author.Mention())
 		return
 
 	}
@@ -489,19 +392,19 @@ func (h *PermissionsHandler) ReadPromote(commands []string, s *discordgo.Session
 
 		if !user.CheckRole("smoderator") {
 			s.ChannelMessageSend(m.ChannelID, "You do not have permission to assign this group")
-			h.logchan <- "Permissions " + m.Author.Mention() + " attempted to run promote to moderator"
+			h.logger.Warn(PERMLOG, LogFields{User: m.Author.ID, Command: "promote"}, m.Author.Mention()+" attempted to run promote to moderator")
 			return
 		}
 		err = h.Promote(target, group, s, m)
 		if err != nil {
 			s.ChannelMessageSend(m.ChannelID, "Error: "+err.Error())
-			h.logchan <- "Permissions " + m.Author.Mention() + " attempted to run promote to moderator || " +
-				target + "||" + group + "||" + err.Error()
+			h.logger.Warn(PERMLOG, LogFields{User: m.Author.ID, Command: "promote"}, m.Author.Mention()+" attempted to run promote to moderator || "+
+				target+"||"+group+"||"+err.Error())
 
 			return
 		}
 		s.ChannelMessageSend(m.ChannelID, m.Mentions[0].Mention()+" has been added to the "+group+" group.")
-		h.logchan <- "Permissions " + m.Mentions[0].Mention() + " has been added to the " + group + " group by " + m.Author.Mention()
+		h.logger.Info(PERMLOG, LogFields{User: m.Author.ID, Command: "promote"}, m.Mentions[0].Mention()+" has been added to the "+group+" group by "+m.Author.Mention())
 		return
 
 	}
@@ -509,18 +412,18 @@ func (h *PermissionsHandler) ReadPromote(commands []string, s *discordgo.Session
 
 		if !user.CheckRole("moderator") {
 			s.ChannelMessageSend(m.ChannelID, "You do not have permission to assign this group")
-			h.logchan <- "Permissions " + m.Author.Mention() + " attempted to run promote to editor"
+			h.logger.Warn(PERMLOG, LogFields{User: m.Author.ID, Command: "promote"}, m.Author.Mention()+" attempted to run promote to editor")
 			return
 		}
 		err = h.Promote(target, group, s, m)
 		if err != nil {
 			s.ChannelMessageSend(m.ChannelID, "Error: "+err.Error())
-			h.logchan <- "Permissions " + m.Author.Mention() + " attempted to run promote to editor || " +
-				target + "||" + group + "||" + err.Error()
+			h.logger.Warn(PERMLOG, LogFields{User: m.Author.ID, Command: "promote"}, m.Author.Mention()+" attempted to run promote to editor || "+
+				target+"||"+group+"||"+err.Error())
 			return
 		}
 		s.ChannelMessageSend(m.ChannelID, m.Mentions[0].Mention()+" has been added to the "+group+" group.")
-		h.logchan <- "Permissions " + m.Mentions[0].Mention() + " has been added to the " + group + " group by " + m.Author.Mention()
+		h.logger.Info(PERMLOG, LogFields{User: m.Author.ID, Command: "promote"}, m.Mentions[0].Mention()+" has been added to the "+group+" group by "+m.Author.Mention())
 		return
 
 	}
@@ -528,18 +431,18 @@ func (h *PermissionsHandler) ReadPromote(commands []string, s *discordgo.Session
 
 		if !user.CheckRole("moderator") {
 			s.ChannelMessageSend(m.ChannelID, "You do not have permission to assign this group")
-			h.logchan <- "Permissions " + m.Author.Mention() + " attempted to run promote to agora"
+			h.logger.Warn(PERMLOG, LogFields{User: m.Author.ID, Command: "promote"}, m.Author.Mention()+" attempted to run promote to agora")
 			return
 		}
 		err = h.Promote(target, group, s, m)
 		if err != nil {
 			s.ChannelMessageSend(m.ChannelID, "Error: "+err.Error())
-			h.logchan <- "Permissions " + m.Author.Mention() + " attempted to run promote to agora || " +
-				target + "||" + group + "||" + err.Error()
+			h.logger.Warn(PERMLOG, LogFields{User: m.Author.ID, Command: "promote"}, m.Author.Mention()+" attempted to run promote to agora || "+
+				target+"||"+group+"||"+err.Error())
 			return
 		}
 		s.ChannelMessageSend(m.ChannelID, m.Mentions[0].Mention()+" has been added to the "+group+" group.")
-		h.logchan <- "Permissions " + m.Mentions[0].Mention() + " has been added to the " + group + " group by " + m.Author.Mention()
+		h.logger.Info(PERMLOG, LogFields{User: m.Author.ID, Command: "promote"}, m.Mentions[0].Mention()+" has been added to the "+group+" group by "+m.Author.Mention())
 		return
 
 	}
@@ -547,18 +450,18 @@ func (h *PermissionsHandler) ReadPromote(commands []string, s *discordgo.Session
 
 		if !user.CheckRole("moderator") {
 			s.ChannelMessageSend(m.ChannelID, "You do not have permission to assign this group")
-			h.logchan <- "Permissions " + m.Author.Mention() + " attempted to run promote to streamer"
+			h.logger.Warn(PERMLOG, LogFields{User: m.Author.ID, Command: "promote"}, m.Author.Mention()+" attempted to run promote to streamer")
 			return
 		}
 		err = h.Promote(target, group, s, m)
 		if err != nil {
 			s.ChannelMessageSend(m.ChannelID, "Error: "+err.Error())
-			h.logchan <- "Permissions " + m.Author.Mention() + " attempted to run promote to streamer || " +
-				target + "||" + group + "||" + err.Error()
+			h.logger.Warn(PERMLOG, LogFields{User: m.Author.ID, Command: "promote"}, m.Author.Mention()+" attempted to run promote to streamer || "+
+				target+"||"+group+"||"+err.Error())
 			return
 		}
 		s.ChannelMessageSend(m.ChannelID, m.Mentions[0].Mention()+" has been added to the "+group+" group.")
-		h.logchan <- "Permissions " + m.Mentions[0].Mention() + " has been added to the " + group + " group by " + m.Author.Mention()
+		h.logger.Info(PERMLOG, LogFields{User: m.Author.ID, Command: "promote"}, m.Mentions[0].Mention()+" has been added to the "+group+" group by "+m.Author.Mention())
 		return
 
 	}
@@ -566,30 +469,30 @@ func (h *PermissionsHandler) ReadPromote(commands []string, s *discordgo.Session
 
 		if !user.CheckRole("moderator") {
 			s.ChannelMessageSend(m.ChannelID, "You do not have permission to assign this group")
-			h.logchan <- "Permissions " + m.Author.Mention() + " attempted to run promote to recruiter"
+			h.logger.Warn(PERMLOG, LogFields{User: m.Author.ID, Command: "promote"}, m.Author.Mention()+" attempted to run promote to recruiter")
 			return
 		}
 		err = h.Promote(target, group, s, m)
 		if err != nil {
 			s.ChannelMessageSend(m.ChannelID, "Error: "+err.Error())
-			h.logchan <- "Permissions " + m.Author.Mention() + " attempted to run promote to recruiter || " +
-				target + "||" + group + "||" + err.Error()
+			h.logger.Warn(PERMLOG, LogFields{User: m.Author.ID, Command: "promote"}, m.Author.Mention()+" attempted to run promote to recruiter || "+
+				target+"||"+group+"||"+err.Error())
 			return
 		}
 		s.ChannelMessageSend(m.ChannelID, m.Mentions[0].Mention()+" has been added to the "+group+" group.")
-		h.logchan <- "Permissions " + m.Mentions[0].Mention() + " has been added to the " + group + " group by " + m.Author.Mention()
+		h.logger.Info(PERMLOG, LogFields{User: m.Author.ID, Command: "promote"}, m.Mentions[0].Mention()+" has been added to the "+group+" group by "+m.Author.Mention())
 		return
 
 	}
 	s.ChannelMessageSend(m.ChannelID, group+" is not a valid group!")
-	h.logchan <- "Permissions " + m.Author.Mention() + " attempted to promote " + m.Mentions[0].Mention() +
-		" to " + group + " which does not exist"
+	h.logger.Warn(PERMLOG, LogFields{User: m.Author.ID, Command: "promote"}, m.Author.Mention()+" attempted to promote "+m.Mentions[0].Mention()+
+		" to "+group+" which does not exist")
 	return
 
 }
 
 // Promote Set the given role on a usermanager, and save the changes in the database
-func (h *PermissionsHandler) Promote(userid string, group string, s *discordgo.Session, m *discordgo.MessageCreate) (err error) {
+func (h *PermissionsHandler) Promote(userid string, group string, s DiscordSession, m *discordgo.MessageCreate) (err error) {
 
 	// Get usermanager from the database using the userid
 	user, err := h.user.GetUser(userid, s, m.ChannelID)
@@ -613,7 +516,7 @@ func (h *PermissionsHandler) Promote(userid string, group string, s *discordgo.S
 }
 
 // ReadDemote The promote command runs using our commands array to get the promotion settings
-func (h *PermissionsHandler) ReadDemote(commands []string, s *discordgo.Session, m *discordgo.MessageCreate) {
+func (h *PermissionsHandler) ReadDemote(commands []string, s DiscordSession, m *discordgo.MessageCreate) {
 
 	if len(commands) < 3 {
 		s.ChannelMessageSend(m.ChannelID, "Usage: demote <usermanager> <group>")
@@ -639,29 +542,29 @@ func (h *PermissionsHandler) ReadDemote(commands []string, s *discordgo.Session,
 	if group == "owner" {
 		if !user.CheckRole("owner") {
 			s.ChannelMessageSend(m.ChannelID, m.Author.Mention()+" https://www.youtube.com/watch?v=7qnd-hdmgfk ")
-			h.logchan <- "Permissions " + m.Author.Mention() + " attempted to run demote to owner"
+			h.logger.Warn(PERMLOG, LogFields{User: m.Author.ID, Command: "demote"}, m.Author.Mention()+" attempted to run demote to owner")
 			return
 		}
 		s.ChannelMessageSend(m.ChannelID, "This group cannot be assigned through the promote command.")
-		h.logchan <- "Permissions " + m.Author.Mention() + " attempted to run demote to owner"
+		h.logger.Warn(PERMLOG, LogFields{User: m.Author.ID, Command: "demote"}, m.Author.Mention()+" attempted to run demote to owner")
 		return
 
 	}
 	if group == "admin" {
 		if !user.CheckRole("owner") {
 			s.ChannelMessageSend(m.ChannelID, "You do not have permission to assign this group")
-			h.logchan <- "Permissions " + m.Author.Mention() + " attempted to run demote to admin"
+			h.logger.Warn(PERMLOG, LogFields{User: m.Author.ID, Command: "demote"}, m.Author.Mention()+" attempted to run demote to admin")
 			return
 		}
 		err = h.Demote(target, group)
 		if err != nil {
 			s.ChannelMessageSend(m.ChannelID, "Error: "+err.Error())
-			h.logchan <- "Permissions " + m.Author.Mention() + " attempted to run demote to admin || " +
-				target + "||" + group + "||" + err.Error()
+			h.logger.Warn(PERMLOG, LogFields{User: m.Author.ID, Command: "demote"}, m.Author.Mention()+" attempted to run demote to admin || "+
+				target+"||"+group+"||"+err.Error())
 			return
 		}
 		s.ChannelMessageSend(m.ChannelID, m.Mentions[0].Mention()+" has been set to the "+group+" group.")
-		h.logchan <- "Permissions " + m.Mentions[0].Mention() + " has been demoted to the " + group + " group by " + m.Author.Mention()
+		h.logger.Info(PERMLOG, LogFields{User: m.Author.ID, Command: "demote"}, m.Mentions[0].Mention()+" has been demoted to the "+group+" group by "+m.Author.Mention())
 		return
 
 	}
@@ -669,18 +572,18 @@ func (h *PermissionsHandler) ReadDemote(commands []string, s *discordgo.Session,
 
 		if !user.CheckRole("admin") {
 			s.ChannelMessageSend(m.ChannelID, "You do not have permission to assign this group")
-			h.logchan <- "Permissions " + m.Author.Mention() + " attempted to run demote to smoderator"
+			h.logger.Warn(PERMLOG, LogFields{User: m.Author.ID, Command: "demote"}, m.Author.Mention()+" attempted to run demote to smoderator")
 			return
 		}
 		err = h.Demote(target, group)
 		if err != nil {
 			s.ChannelMessageSend(m.ChannelID, "Error: "+err.Error())
-			h.logchan <- "Permissions " + m.Author.Mention() + " attempted to run demote to smoderator || " +
-				target + "||" + group + "||" + err.Error()
+			h.logger.Warn(PERMLOG, LogFields{User: m.Author.ID, Command: "demote"}, m.Author.Mention()+" attempted to run demote to smoderator || "+
+				target+"||"+group+"||"+err.Error())
 			return
 		}
 		s.ChannelMessageSend(m.ChannelID, m.Mentions[0].Mention()+" has been set to the "+group+" group.")
-		h.logchan <- "Permissions " + m.Mentions[0].Mention() + " has been demoted to the " + group + " group by " + m.Author.Mention()
+		h.logger.Info(PERMLOG, LogFields{User: m.Author.ID, Command: "demote"}, m.Mentions[0].Mention()+" has been demoted to the "+group+" group by "+m.Author.Mention())
 		return
 
 	}
@@ -688,18 +591,18 @@ func (h *PermissionsHandler) ReadDemote(commands []string, s *discordgo.Session,
 
 		if !user.CheckRole("smoderator") {
 			s.ChannelMessageSend(m.ChannelID, "You do not have permission to assign this group")
-			h.logchan <- "Permissions " + m.Author.Mention() + " attempted to run promote to moderator"
+			h.logger.Warn(PERMLOG, LogFields{User: m.Author.ID, Command: "demote"}, m.Author.Mention()+" attempted to run promote to moderator")
 			return
 		}
 		err = h.Demote(target, group)
 		if err != nil {
 			s.ChannelMessageSend(m.ChannelID, "Error: "+err.Error())
-			h.logchan <- "Permissions " + m.Author.Mention() + " attempted to run demote to moderator || " +
-				target + "||" + group + "||" + err.Error()
+			h.logger.Warn(PERMLOG, LogFields{User: m.Author.ID, Command: "demote"}, m.Author.Mention()+" attempted to run demote to moderator || "+
+				target+"||"+group+"||"+err.Error())
 			return
 		}
 		s.ChannelMessageSend(m.ChannelID, m.Mentions[0].Mention()+" has been set to the "+group+" group.")
-		h.logchan <- "Permissions " + m.Mentions[0].Mention() + " has been demoted to the " + group + " group by " + m.Author.Mention()
+		h.logger.Info(PERMLOG, LogFields{User: m.Author.ID, Command: "demote"}, m.Mentions[0].Mention()+" has been demoted to the "+group+" group by "+m.Author.Mention())
 		return
 
 	}
@@ -707,18 +610,18 @@ func (h *PermissionsHandler) ReadDemote(commands []string, s *discordgo.Session,
 
 		if !user.CheckRole("moderator") {
 			s.ChannelMessageSend(m.ChannelID, "You do not have permission to assign this group")
-			h.logchan <- "Permissions " + m.Author.Mention() + " attempted to run demote to editor"
+			h.logger.Warn(PERMLOG, LogFields{User: m.Author.ID, Command: "demote"}, m.Author.Mention()+" attempted to run demote to editor")
 			return
 		}
 		err = h.Demote(target, group)
 		if err != nil {
 			s.ChannelMessageSend(m.ChannelID, "Error: "+err.Error())
-			h.logchan <- "Permissions " + m.Author.Mention() + " attempted to run demote to editor || " +
-				target + "||" + group + "||" + err.Error()
+			h.logger.Warn(PERMLOG, LogFields{User: m.Author.ID, Command: "demote"}, m.Author.Mention()+" attempted to run demote to editor || "+
+				target+"||"+group+"||"+err.Error())
 			return
 		}
 		s.ChannelMessageSend(m.ChannelID, m.Mentions[0].Mention()+" has been removed from the "+group+" group.")
-		h.logchan <- "Permissions " + m.Mentions[0].Mention() + " has been removed from the " + group + " group by " + m.Author.Mention()
+		h.logger.Info(PERMLOG, LogFields{User: m.Author.ID, Command: "demote"}, m.Mentions[0].Mention()+" has been removed from the "+group+" group by "+m.Author.Mention())
 		return
 
 	}
@@ -726,18 +629,18 @@ func (h *PermissionsHandler) ReadDemote(commands []string, s *discordgo.Session,
 
 		if !user.CheckRole("moderator") {
 			s.ChannelMessageSend(m.ChannelID, "You do not have permission to assign this group")
-			h.logchan <- "Permissions " + m.Author.Mention() + " attempted to run demote to agora"
+			h.logger.Warn(PERMLOG, LogFields{User: m.Author.ID, Command: "demote"}, m.Author.Mention()+" attempted to run demote to agora")
 			return
 		}
 		err = h.Demote(target, group)
 		if err != nil {
 			s.ChannelMessageSend(m.ChannelID, "Error: "+err.Error())
-			h.logchan <- "Permissions " + m.Author.Mention() + " attempted to run demote to agora || " +
-				target + "||" + group + "||" + err.Error()
+			h.logger.Warn(PERMLOG, LogFields{User: m.Author.ID, Command: "demote"}, m.Author.Mention()+" attempted to run demote to agora || "+
+				target+"||"+group+"||"+err.Error())
 			return
 		}
 		s.ChannelMessageSend(m.ChannelID, m.Mentions[0].Mention()+" has been removed from the "+group+" group.")
-		h.logchan <- "Permissions " + m.Mentions[0].Mention() + " has been removed from the " + group + " group by " + m.Author.Mention()
+		h.logger.Info(PERMLOG, LogFields{User: m.Author.ID, Command: "demote"}, m.Mentions[0].Mention()+" has been removed from the "+group+" group by "+m.Author.Mention())
 		return
 
 	}
@@ -745,18 +648,18 @@ func (h *PermissionsHandler) ReadDemote(commands []string, s *discordgo.Session,
 
 		if !user.CheckRole("moderator") {
 			s.ChannelMessageSend(m.ChannelID, "You do not have permission to assign this group")
-			h.logchan <- "Permissions " + m.Author.Mention() + " attempted to run demote to streamer"
+			h.logger.Warn(PERMLOG, LogFields{User: m.Author.ID, Command: "demote"}, m.Author.Mention()+" attempted to run demote to streamer")
 			return
 		}
 		err = h.Demote(target, group)
 		if err != nil {
 			s.ChannelMessageSend(m.ChannelID, "Error: "+err.Error())
-			h.logchan <- "Permissions " + m.Author.Mention() + " attempted to run demote to streamer || " +
-				target + "||" + group + "||" + err.Error()
+			h.logger.Warn(PERMLOG, LogFields{User: m.Author.ID, Command: "demote"}, m.Author.Mention()+" attempted to run demote to streamer || "+
+				target+"||"+group+"||"+err.Error())
 			return
 		}
 		s.ChannelMessageSend(m.ChannelID, m.Mentions[0].Mention()+" has been removed from the "+group+" group.")
-		h.logchan <- "Permissions " + m.Mentions[0].Mention() + " has been removed from the " + group + " group by " + m.Author.Mention()
+		h.logger.Info(PERMLOG, LogFields{User: m.Author.ID, Command: "demote"}, m.Mentions[0].Mention()+" has been removed from the "+group+" group by "+m.Author.Mention())
 		return
 
 	}
@@ -764,25 +667,25 @@ func (h *PermissionsHandler) ReadDemote(commands []string, s *discordgo.Session,
 
 		if !user.CheckRole("moderator") {
 			s.ChannelMessageSend(m.ChannelID, "You do not have permission to assign this group")
-			h.logchan <- "Permissions " + m.Author.Mention() + " attempted to run demote to recruiter"
+			h.logger.Warn(PERMLOG, LogFields{User: m.Author.ID, Command: "demote"}, m.Author.Mention()+" attempted to run demote to recruiter")
 			return
 		}
 
 		err = h.Demote(target, group)
 		if err != nil {
 			s.ChannelMessageSend(m.ChannelID, "Error: "+err.Error())
-			h.logchan <- "Permissions " + m.Author.Mention() + " attempted to run demote to recruiter || " +
-				target + "||" + group + "||" + err.Error()
+			h.logger.Warn(PERMLOG, LogFields{User: m.Author.ID, Command: "demote"}, m.Author.Mention()+" attempted to run demote to recruiter || "+
+				target+"||"+group+"||"+err.Error())
 			return
 		}
 		s.ChannelMessageSend(m.ChannelID, m.Mentions[0].Mention()+" has been removed from the "+group+" group.")
-		h.logchan <- "Permissions " + m.Mentions[0].Mention() + " has been removed from the " + group + " group by " + m.Author.Mention()
+		h.logger.Info(PERMLOG, LogFields{User: m.Author.ID, Command: "demote"}, m.Mentions[0].Mention()+" has been removed from the "+group+" group by "+m.Author.Mention())
 		return
 
 	}
 	s.ChannelMessageSend(m.ChannelID, group+" is not a valid group!")
-	h.logchan <- "Permissions " + m.Author.Mention() + " attempted to demote " + m.Mentions[0].Mention() +
-		" to " + group + " which does not exist"
+	h.logger.Warn(PERMLOG, LogFields{User: m.Author.ID, Command: "demote"}, m.Author.Mention()+" attempted to demote "+m.Mentions[0].Mention()+
+		" to "+group+" which does not exist")
 	return
 
 }
@@ -940,7 +843,7 @@ func (h *PermissionsHandler) CreatePermissionInt(roleperms RolePermissions) (per
 }
 
 // CreateRole function
-func (h *PermissionsHandler) CreateRole(name string, guildID string, hoist bool, mentionable bool, color int, perm int, s *discordgo.Session) (createdrole *discordgo.Role, err error) {
+func (h *PermissionsHandler) CreateRole(name string, guildID string, hoist bool, mentionable bool, color int, perm int, s DiscordSession) (createdrole *discordgo.Role, err error) {
 
 	// Capitalize roles
 	name = strings.Title(name)
@@ -972,10 +875,11 @@ func (h *PermissionsHandler) CreateRole(name string, guildID string, hoist bool,
 }
 
 // AddRoleToUser function
-func (h *PermissionsHandler) AddRoleToUser(role string, userID string, s *discordgo.Session, m *discordgo.MessageCreate, isID bool) (err error) {
+// Waits for discord to make the change, so the user record is only updated once the role has really been added
+func (h *PermissionsHandler) AddRoleToUser(role string, userID string, s DiscordSession, m *discordgo.MessageCreate, isID bool) (err error) {
 
 	// Get usermanager from the database using the userid
-	user, err := h.user.GetUser(userID, s, m.ChannelID)
+	_, err = h.user.GetUser(userID, s, m.ChannelID)
 	if err != nil {
 		return err
 	}
@@ -985,26 +889,31 @@ func (h *PermissionsHandler) AddRoleToUser(role string, userID string, s *discor
 		return err
 	}
 
-	if isID {
-		user.JoinRoleID(role)
-		_ = s.GuildMemberRoleAdd(guildID, user.ID, role)
-
-	} else {
-
-		// Capitalize roles
-		rolename := strings.Title(role)
-
-		// Checks if a user is in a role based on the group string
-		if !user.CheckRole(rolename) {
-			// Assign the group to our target usermanager
-			user.SetRole(rolename)
-		}
-
-		roleID, err := getRoleIDByName(s, guildID, rolename)
+	// Capitalize roles
+	roleID := role
+	rolename := strings.Title(role)
+	if !isID {
+		roleID, err = getRoleIDByName(s, guildID, rolename)
 		if err != nil {
 			return err
 		}
-		_ = s.GuildMemberRoleAdd(guildID, user.ID, roleID)
+	}
+
+	err = <-h.queue.RoleAdd(s, guildID, userID, roleID, QueueHigh)
+	if err != nil {
+		return err
+	}
+
+	// Fetched again since the user may have changed while we waited on discord
+	user, err := h.user.GetUser(userID, s, m.ChannelID)
+	if err != nil {
+		return err
+	}
+	if isID {
+		user.JoinRoleID(role)
+	} else if !user.CheckRole(rolename) {
+		// Checks if a user is in a role based on the group string
+		user.SetRole(rolename)
 	}
 
 	// Open the "Users" bucket in the database
@@ -1019,10 +928,11 @@ func (h *PermissionsHandler) AddRoleToUser(role string, userID string, s *discor
 }
 
 // RemoveRoleFromUser function
-func (h *PermissionsHandler) RemoveRoleFromUser(role string, userID string, s *discordgo.Session, m *discordgo.MessageCreate, isID bool) (err error) {
+// Waits for discord to make the change, the same as AddRoleToUser
+func (h *PermissionsHandler) RemoveRoleFromUser(role string, userID string, s DiscordSession, m *discordgo.MessageCreate, isID bool) (err error) {
 
 	// Get usermanager from the database using the userid
-	user, err := h.user.GetUser(userID, s, m.ChannelID)
+	_, err = h.user.GetUser(userID, s, m.ChannelID)
 	if err != nil {
 		return err
 	}
@@ -1032,26 +942,30 @@ func (h *PermissionsHandler) RemoveRoleFromUser(role string, userID string, s *d
 		return err
 	}
 
-	if isID {
-		_ = s.GuildMemberRoleRemove(guildID, user.ID, role)
-		user.LeaveRoleID(role)
-	} else {
-
-		// Capitalize Roles
-		rolename := strings.Title(role)
-
-		// Checks if a user is in a role based on the group string
-		if user.CheckRole(rolename) {
-			// Remove role from our target user
-			user.RemoveRole(rolename)
-		}
-
-		roleID, err := getRoleIDByName(s, guildID, rolename)
+	// Capitalize Roles
+	roleID := role
+	rolename := strings.Title(role)
+	if !isID {
+		roleID, err = getRoleIDByName(s, guildID, rolename)
 		if err != nil {
 			return err
 		}
-		_ = s.GuildMemberRoleRemove(guildID, user.ID, roleID)
+	}
 
+	err = <-h.queue.RoleRemove(s, guildID, userID, roleID, QueueHigh)
+	if err != nil {
+		return err
+	}
+
+	user, err := h.user.GetUser(userID, s, m.ChannelID)
+	if err != nil {
+		return err
+	}
+	if isID {
+		user.LeaveRoleID(role)
+	} else if user.CheckRole(rolename) {
+		// Remove role from our target user
+		user.RemoveRole(rolename)
 	}
 
 	// Open the "Users" bucket in the database
@@ -1066,7 +980,7 @@ func (h *PermissionsHandler) RemoveRoleFromUser(role string, userID string, s *d
 }
 
 // SyncRolesDB function
-func (h *PermissionsHandler) SyncRolesDB(userID string, guildID string, channelID string, s *discordgo.Session) (err error) {
+func (h *PermissionsHandler) SyncRolesDB(userID string, guildID string, channelID string, s DiscordSession) (err error) {
 
 	// Get usermanager from the database using the userid
 	user, err := h.user.GetUser(userID, s, channelID)
@@ -1105,7 +1019,7 @@ func (h *PermissionsHandler) SyncRolesDB(userID string, guildID string, channelI
 }
 
 // SyncServerRoles function
-func (h *PermissionsHandler) SyncServerRoles(userID string, channelID string, s *discordgo.Session) (err error) {
+func (h *PermissionsHandler) SyncServerRoles(userID string, channelID string, s DiscordSession) (err error) {
 
 	// Get user from the database using the userid
 	user, err := h.user.GetUser(userID, s, channelID)
@@ -1114,16 +1028,15 @@ func (h *PermissionsHandler) SyncServerRoles(userID string, channelID string, s
 	}
 
 	for _, role := range user.RoleIDs {
-		time.Sleep(time.Duration(time.Second * 1))
-
-		_ = s.GuildMemberRoleAdd(user.GuildID, user.ID, role)
+		h.queue.Report(h.queue.RoleAdd(s, user.GuildID, user.ID, role, QueueHigh),
+			LogFields{User: user.ID, Guild: user.GuildID, Room: channelID}, "Could not sync role "+role)
 	}
 
 	return nil
 }
 
 // DeleteRoleOnGuild function
-func (h *PermissionsHandler) DeleteRoleOnGuild(roleID string, guildID string, s *discordgo.Session) (err error) {
+func (h *PermissionsHandler) DeleteRoleOnGuild(roleID string, guildID string, s DiscordSession) (err error) {
 
 	rooms, err := h.room.rooms.GetAllRooms()
 
@@ -1149,7 +1062,7 @@ func (h *PermissionsHandler) DeleteRoleOnGuild(roleID string, guildID string, s
 }
 
 // TranslateRoleID function
-func (h *PermissionsHandler) TranslateRoleID(roleID string, guildID string, s *discordgo.Session) (rolename string, err error) {
+func (h *PermissionsHandler) TranslateRoleID(roleID string, guildID string, s DiscordSession) (rolename string, err error) {
 
 	rolename, err = getRoleNameByID(roleID, guildID, s)
 	if err != nil {
@@ -1160,7 +1073,7 @@ func (h *PermissionsHandler) TranslateRoleID(roleID string, guildID string, s *d
 }
 
 // GuildReorderRoles function
-func (h *PermissionsHandler) GuildReorderRoles(guildID string, s *discordgo.Session) (err error) {
+func (h *PermissionsHandler) GuildReorderRoles(guildID string, s DiscordSession) (err error) {
 
 	guildroles, err := s.GuildRoles(guildID)
 
@@ -1206,7 +1119,7 @@ func (h *PermissionsHandler) GuildReorderRoles(guildID string, s *discordgo.Sess
 
 // ApplyModeratorRolePerms function
 // Default roles permissions handling
-func (h *PermissionsHandler) ApplyModeratorRolePerms(roomID string, guildID string, moderatorID string, s *discordgo.Session) (err error) {
+func (h *PermissionsHandler) ApplyModeratorRolePerms(roomID string, guildID string, moderatorID string, s DiscordSession) (err error) {
 
 	if moderatorID == "" {
 		moderatorID, err = getRoleIDByName(s, guildID, "Moderator")
@@ -1218,7 +1131,7 @@ func (h *PermissionsHandler) ApplyModeratorRolePerms(roomID string, guildID stri
 	denymoderator := h.CreatePermissionInt(RolePermissions{})
 	allowmoderator := h.CreatePermissionInt(RolePermissions{ViewChannel: true, SendMessages: true,
 		ReadMessageHistory: true, ManageMessages: true, KickMembers: true, BanMembers: true})
-	err = s.ChannelPermissionSet(roomID, moderatorID, "role", allowmoderator, denymoderator)
+	err = <-h.queue.PermissionSet(s, guildID, roomID, moderatorID, "role", allowmoderator, denymoderator, QueueLow)
 	if err != nil {
 		return err
 	}
@@ -1226,7 +1139,7 @@ func (h *PermissionsHandler) ApplyModeratorRolePerms(roomID string, guildID stri
 }
 
 // ApplyAdminRolePerms function
-func (h *PermissionsHandler) ApplyAdminRolePerms(roomID string, guildID string, adminID string, s *discordgo.Session) (err error) {
+func (h *PermissionsHandler) ApplyAdminRolePerms(roomID string, guildID string, adminID string, s DiscordSession) (err error) {
 
 	if adminID == "" {
 		adminID, err = getRoleIDByName(s, guildID, "Admin")
@@ -1238,7 +1151,7 @@ func (h *PermissionsHandler) ApplyAdminRolePerms(roomID string, guildID string,
 	denyadmin := h.CreatePermissionInt(RolePermissions{})
 	allowadmin := h.CreatePermissionInt(RolePermissions{ViewChannel: true, SendMessages: true,
 		ReadMessageHistory: true, ManageMessages: true, KickMembers: true, BanMembers: true})
-	err = s.ChannelPermissionSet(roomID, adminID, "role", allowadmin, denyadmin)
+	err = <-h.queue.PermissionSet(s, guildID, roomID, adminID, "role", allowadmin, denyadmin, QueueLow)
 	if err != nil {
 		return err
 	}
@@ -1246,7 +1159,7 @@ func (h *PermissionsHandler) ApplyAdminRolePerms(roomID string, guildID string,
 }
 
 // ApplyBuilderRolePerms function
-func (h *PermissionsHandler) ApplyBuilderRolePerms(roomID string, guildID string, builderID string, s *discordgo.Session) (err error) {
+func (h *PermissionsHandler) ApplyBuilderRolePerms(roomID string, guildID string, builderID string, s DiscordSession) (err error) {
 
 	if builderID == "" {
 		builderID, err = getRoleIDByName(s, guildID, "Builder")
@@ -1257,7 +1170,7 @@ func (h *PermissionsHandler) ApplyBuilderRolePerms(roomID string, guildID string
 
 	denybuilder := h.CreatePermissionInt(RolePermissions{})
 	allowbuilder := h.CreatePermissionInt(RolePermissions{ViewChannel: true, SendMessages: true})
-	err = s.ChannelPermissionSet(roomID, builderID, "role", allowbuilder, denybuilder)
+	err = <-h.queue.PermissionSet(s, guildID, roomID, builderID, "role", allowbuilder, denybuilder, QueueLow)
 	if err != nil {
 		return err
 	}
@@ -1266,7 +1179,7 @@ func (h *PermissionsHandler) ApplyBuilderRolePerms(roomID string, guildID string
 }
 
 // ApplyEveryoneRolePerms function
-func (h *PermissionsHandler) ApplyEveryoneRolePerms(roomID string, guildID string, everyoneID string, s *discordgo.Session) (err error) {
+func (h *PermissionsHandler) ApplyEveryoneRolePerms(roomID string, guildID string, everyoneID string, s DiscordSession) (err error) {
 
 	if everyoneID == "" {
 		// get the "everyoneID" for the guild
@@ -1278,7 +1191,7 @@ func (h *PermissionsHandler) ApplyEveryoneRolePerms(roomID string, guildID strin
 
 	denyeveryoneperms := h.CreatePermissionInt(RolePermissions{ViewChannel: true})
 	alloweveryoneperms := h.CreatePermissionInt(RolePermissions{})
-	err = s.ChannelPermissionSet(roomID, everyoneID, "role", alloweveryoneperms, denyeveryoneperms)
+	err = <-h.queue.PermissionSet(s, guildID, roomID, everyoneID, "role", alloweveryoneperms, denyeveryoneperms, QueueLow)
 	if err != nil {
 		return err
 	}
@@ -1286,7 +1199,7 @@ func (h *PermissionsHandler) ApplyEveryoneRolePerms(roomID string, guildID strin
 }
 
 // ApplyTravelRolePerms function
-func (h *PermissionsHandler) ApplyTravelRolePerms(roomID string, guildID string, s *discordgo.Session) (err error) {
+func (h *PermissionsHandler) ApplyTravelRolePerms(roomID string, guildID string, s DiscordSession) (err error) {
 
 	room, err := h.room.rooms.GetRoomByID(roomID)
 	if err != nil {
@@ -1303,7 +1216,7 @@ func (h *PermissionsHandler) ApplyTravelRolePerms(roomID string, guildID string,
 		denyperms = h.CreatePermissionInt(RolePermissions{})
 		allowperms = h.CreatePermissionInt(RolePermissions{ViewChannel: true, SendMessages: true})
 	}
-	err = s.ChannelPermissionSet(room.ID, room.TravelRoleID, "role", allowperms, denyperms)
+	err = <-h.queue.PermissionSet(s, guildID, room.ID, room.TravelRoleID, "role", allowperms, denyperms, QueueLow)
 	if err != nil {
 		return err
 	}
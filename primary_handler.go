@@ -1,39 +1,38 @@
 package main
 
 import (
+	"context"
 	"fmt"
 	"github.com/bwmarrin/discordgo"
-	"strings"
 	"time"
 )
 
 // PrimaryHandler struct
 type PrimaryHandler struct {
-	db       *DBHandler
-	conf     *Config
-	dg       *discordgo.Session
-	callback *CallbackHandler
-	perm     *PermissionsHandler
-	user     *UserHandler
-	command  *CommandHandler
-	registry *CommandRegistry
-	logchan  chan string
-	channel  *ChannelHandler
-	rooms    *RoomsHandler
-	travel   *TravelHandler
+	db        *DBHandler
+	conf      *Config
+	dg        *discordgo.Session
+	callback  *CallbackHandler
+	perm      *PermissionsHandler
+	user      *UserHandler
+	command   *CommandHandler
+	registry  *CommandRegistry
+	router    *CommandRouter
+	logger    *Logger
+	channel   *ChannelHandler
+	rooms     *RoomsHandler
+	travel    *TravelHandler
+	lifecycle *Lifecycle
 }
 
 // Init function
 func (h *PrimaryHandler) Init() (err error) {
-	// DO NOT add anything above this line!!
-	// Add our main handler -
-	h.dg.AddHandler(h.Read)
 	h.registry = h.command.registry
 
 	// Add new handlers below this line //
 	/*
 		fmt.Println("Adding Utilities Handler")
-		utilities := UtilitiesHandler{db: h.db, conf: h.conf, usermanager: h.usermanager, registry: h.command.registry, logchan: h.logchan, callback: h.callback}
+		utilities := UtilitiesHandler{db: h.db, conf: h.conf, usermanager: h.usermanager, registry: h.command.registry, logger: h.logger, callback: h.callback}
 		h.dg.AddHandler(utilities.Read)
 
 		fmt.Println("Adding Tutorial Handler")
@@ -41,10 +40,15 @@ func (h *PrimaryHandler) Init() (err error) {
 		h.dg.AddHandler(tutorials.Read)
 	*/
 	fmt.Println("Adding Notifications Handler")
-	notifications := NotificationsHandler{db: h.db, callback: h.callback, conf: h.conf, registry: h.command.registry}
+	notifications := NotificationsHandler{db: h.db, callback: h.callback, conf: h.conf, registry: h.command.registry,
+		router: h.router}
 	notifications.Init()
-	h.dg.AddHandler(notifications.Read)
-	go notifications.CheckNotifications(h.dg)
+	err = h.lifecycle.Start("notifications", func(ctx context.Context) error {
+		return notifications.CheckNotifications(ctx, h.dg)
+	})
+	if err != nil {
+		return err
+	}
 
 	// Open a websocket connection to Discord and begin listening.
 	fmt.Println("Opening Connection to Discord")
@@ -72,7 +76,7 @@ func (h *PrimaryHandler) PostInit(dg *discordgo.Session) error {
 
 	// Update our default playing status
 	fmt.Println("Updating Discord Status")
-	err := h.dg.UpdateStatus(0, h.conf.MainConfig.Playing)
+	err := h.dg.UpdateStatus(0, h.conf.NowPlaying())
 	if err != nil {
 		fmt.Println("error updating now playing,", err)
 		return err
@@ -84,63 +88,27 @@ func (h *PrimaryHandler) PostInit(dg *discordgo.Session) error {
 	return nil
 }
 
-// This function will be called (due to AddHandler above) every time a new
-// message is created on any channel that the autenticated bot has access to.
-
-// Read function
-func (h *PrimaryHandler) Read(s *discordgo.Session, m *discordgo.MessageCreate) {
-	// very important to set this first!
-	cp := h.conf.MainConfig.CP
-
-	// Ignore all messages created by the bot itself
-	// This isn't required in this specific example but it's a good practice.
-	if m.Author.ID == s.State.User.ID {
-		return
-	}
+// ReadCommand function
+func (h *PrimaryHandler) ReadCommand(command []string, user User, s DiscordSession, m *discordgo.MessageCreate) {
 
-	// Ignore bots
-	if m.Author.Bot {
+	// If the message is "ping" reply with "Pong!"
+	if command[0] == "ping" {
+		s.ChannelMessageSend(m.ChannelID, "Pong!")
 		return
 	}
 
-	user, err := h.db.GetUser(m.Author.ID)
-	if err != nil {
-		//fmt.Println("Error finding usermanager")
+	// If the message is "pong" reply with "Ping!"
+	if command[0] == "pong" {
+		s.ChannelMessageSend(m.ChannelID, "Ping!")
 		return
 	}
 
-	message := strings.Fields(m.Content)
-	if len(message) < 1 {
-		fmt.Println(m.Content)
+	if command[0] == "time" {
+		s.ChannelMessageSend(m.ChannelID, "Current UTC Time: "+time.Now().UTC().Format("2006-01-02 15:04:05"))
 		return
 	}
 
-	command := message[0]
-
-	// If the message is "ping" reply with "Pong!"
-	if command == cp+"ping" {
-		if CheckPermissions("ping", m.ChannelID, &user, s, h.command) {
-			s.ChannelMessageSend(m.ChannelID, "Pong!")
-			return
-		}
-	}
-
-	// If the message is "pong" reply with "Ping!"
-	if command == cp+"pong" {
-		if CheckPermissions("pong", m.ChannelID, &user, s, h.command) {
-			s.ChannelMessageSend(m.ChannelID, "Ping!")
-			return
-		}
-	}
-
-	if command == cp+"time" {
-		if CheckPermissions("time", m.ChannelID, &user, s, h.command) {
-			s.ChannelMessageSend(m.ChannelID, "Current UTC Time: "+time.Now().UTC().Format("2006-01-02 15:04:05"))
-			return
-		}
-	}
-
-	if command == cp+"help" {
+	if command[0] == "help" {
 		s.ChannelMessageSend(m.ChannelID, "https://github.com/yamamushi/TheAether#table-of-contents")
 		return
 	}
@@ -155,5 +123,10 @@ func (h *PrimaryHandler) RegisterCommands() (err error) {
 	h.registry.Register("time", "Display current UTC time", "time")
 	h.registry.Register("tutorial", "Begin the new player tutorial", "tutorial start")
 
+	h.router.AddRoute("ping", true, h.ReadCommand)
+	h.router.AddRoute("pong", true, h.ReadCommand)
+	h.router.AddRoute("time", true, h.ReadCommand)
+	h.router.AddRoute("help", false, h.ReadCommand)
+
 	return nil
 }
@@ -0,0 +1,76 @@
+package main
+
+import (
+	"github.com/bwmarrin/discordgo"
+	"strconv"
+	"time"
+)
+
+// QueueHandler struct
+type QueueHandler struct {
+	registry *CommandRegistry
+	router   *CommandRouter
+	queue    *DiscordQueue
+}
+
+// Init function
+func (h *QueueHandler) Init() {
+	h.RegisterCommands()
+}
+
+// RegisterCommands function
+func (h *QueueHandler) RegisterCommands() (err error) {
+
+	h.registry.Register("queue", "View the discord action backlog", "status")
+	h.router.AddRoute("queue", false, h.ParseCommand, "moderator")
+	return nil
+
+}
+
+// ParseCommand function
+func (h *QueueHandler) ParseCommand(command []string, user User, s DiscordSession, m *discordgo.MessageCreate) {
+
+	if len(command) < 2 {
+		s.ChannelMessageSend(m.ChannelID, "Expected flag for 'queue' command, see usage for more info")
+		return
+	}
+
+	if command[1] == "status" {
+		s.ChannelMessageSend(m.ChannelID, h.FormatStatus(h.queue.Status()))
+		return
+	}
+
+	s.ChannelMessageSend(m.ChannelID, "Unrecognized flag for 'queue' command: "+command[1])
+}
+
+// FormatStatus function
+func (h *QueueHandler) FormatStatus(statuses []GuildQueueStatus) (formatted string) {
+
+	if len(statuses) == 0 {
+		return "The discord queue is empty, nothing has been queued since startup."
+	}
+
+	total := 0
+	formatted = "```\n"
+	for _, status := range statuses {
+		pending := status.Pending[QueueHigh] + status.Pending[QueueNormal] + status.Pending[QueueLow]
+		total = total + pending
+
+		formatted = formatted + "Guild: " + status.GuildID + "\n" +
+			"  Pending: " + strconv.Itoa(pending) + " (high " + strconv.Itoa(status.Pending[QueueHigh]) +
+			", normal " + strconv.Itoa(status.Pending[QueueNormal]) + ", low " + strconv.Itoa(status.Pending[QueueLow]) + ")\n"
+		if status.Running != "" {
+			formatted = formatted + "  Running: " + status.Running + "\n"
+		}
+		if pending > 0 {
+			formatted = formatted + "  Oldest: " + status.Oldest.Round(time.Second).String() + "\n"
+		}
+		formatted = formatted + "  Processed: " + strconv.Itoa(status.Processed) + " Failed: " + strconv.Itoa(status.Failed) +
+			" Retried: " + strconv.Itoa(status.Retried) + " Collapsed: " + strconv.Itoa(status.Collapsed) + "\n"
+		if status.LastError != "" {
+			formatted = formatted + "  Last Error: " + status.LastError + "\n"
+		}
+	}
+	formatted = formatted + "\nTotal Backlog: " + strconv.Itoa(total) + "\n```\n"
+	return formatted
+}
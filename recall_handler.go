@@ -0,0 +1,220 @@
+package main
+
+/*
+Recall takes a player back to the bind point they last bound at, and user move lets a moderator put anyone in any
+room. Both go through Relocate, which does what travel does (roles, the room's user list and the user record) in one
+step without needing an exit.
+
+If the player is already in the target room's guild they are moved straight away. Otherwise they are sent an
+invite and a transfer is queued, so the transfer handler finishes the move once they join, the same as traveling
+through a transfer room.
+
+*/
+
+import (
+	"errors"
+	"github.com/bwmarrin/discordgo"
+	"strings"
+	"time"
+)
+
+// RecallHandler struct
+type RecallHandler struct {
+	conf     *Config
+	registry *CommandRegistry
+	router   *CommandRouter
+	room     *RoomsHandler
+	user     *UserHandler
+	transfer *TransferHandler
+	travel   *TravelHandler
+}
+
+// Init function
+func (h *RecallHandler) Init() {
+	h.RegisterCommands()
+}
+
+// RegisterCommands function
+func (h *RecallHandler) RegisterCommands() (err error) {
+
+	h.registry.Register("bind", "Bind yourself to the bind point you are in, so you can recall to it later", "")
+	h.router.AddRoute("bind", false, h.ReadBind, "player")
+
+	h.registry.Register("recall", "Return to the bind point you are bound to", "")
+	h.router.AddRoute("recall", false, h.ReadRecall, "player")
+
+	h.registry.Register("user", "Manage players", "move <@user> <#room | room name>")
+	h.registry.AddGroup("user", "moderator")
+	h.router.AddRoute("user", false, h.ParseCommand, "moderator")
+	return nil
+
+}
+
+// ReadBind function
+func (h *RecallHandler) ReadBind(command []string, user User, s DiscordSession, m *discordgo.MessageCreate) {
+
+	room, err := h.room.rooms.GetRoomByID(user.RoomID)
+	if err != nil || !room.BindPoint {
+		s.ChannelMessageSend(m.ChannelID, "There is nothing here to bind yourself to.")
+		return
+	}
+	if user.BindRoomID == room.ID {
+		s.ChannelMessageSend(m.ChannelID, "You are already bound to "+room.Name+".")
+		return
+	}
+
+	user.BindRoomID = room.ID
+	err = h.user.usermanager.SaveUserToDB(user)
+	if err != nil {
+		s.ChannelMessageSend(m.ChannelID, "Error binding: "+err.Error())
+		return
+	}
+	s.ChannelMessageSend(m.ChannelID, ":sparkles: You feel yourself bound to "+room.Name+", use "+
+		h.conf.CommandPrefix()+"recall to return here.")
+}
+
+// ReadRecall function
+func (h *RecallHandler) ReadRecall(command []string, user User, s DiscordSession, m *discordgo.MessageCreate) {
+
+	if user.BindRoomID == "" {
+		s.ChannelMessageSend(m.ChannelID, "You aren't bound anywhere, find a bind point and use "+
+			h.conf.CommandPrefix()+"bind first.")
+		return
+	}
+	target, err := h.room.rooms.GetRoomByID(user.BindRoomID)
+	if err != nil || !target.BindPoint {
+		s.ChannelMessageSend(m.ChannelID, "You reach for your bind point but the bond has faded, you will need "+
+			"to bind somewhere new.")
+		return
+	}
+	if user.RoomID == target.ID {
+		s.ChannelMessageSend(m.ChannelID, "You are already at "+target.Name+".")
+		return
+	}
+
+	wait := user.LastRecall.Add(h.conf.RecallCooldown()).Sub(time.Now())
+	if wait > 0 {
+		s.ChannelMessageSend(m.ChannelID, "You are still drained from your last recall, you can recall again in "+
+			wait.Round(time.Minute).String()+".")
+		return
+	}
+
+	// The cooldown is taken before moving so recall can't be used twice while a transfer is pending
+	lastrecall := user.LastRecall
+	user.LastRecall = time.Now()
+	err = h.user.usermanager.SaveUserToDB(user)
+	if err != nil {
+		s.ChannelMessageSend(m.ChannelID, "Error recalling: "+err.Error())
+		return
+	}
+
+	pending, err := h.Relocate(user.ID, target, s)
+	if err != nil {
+		s.ChannelMessageSend(m.ChannelID, "Your recall fizzles: "+err.Error())
+		if user, err := h.user.usermanager.GetUserByID(user.ID); err == nil {
+			user.LastRecall = lastrecall
+			h.user.usermanager.SaveUserToDB(user)
+		}
+		return
+	}
+	if pending {
+		s.ChannelMessageSend(m.ChannelID, ":sparkles: You begin to recall to "+target.Name+", follow the "+
+			"invite you have been sent to complete the journey.")
+		return
+	}
+	s.ChannelMessageSend(m.ChannelID, "<@"+user.ID+"> vanishes in a shimmer of light.")
+	s.ChannelMessageSend(target.ID, "<@"+user.ID+"> appears in a shimmer of light.")
+}
+
+// ParseCommand function
+func (h *RecallHandler) ParseCommand(command []string, user User, s DiscordSession, m *discordgo.MessageCreate) {
+
+	if len(command) < 4 || command[1] != "move" {
+		s.ChannelMessageSend(m.ChannelID, "Expected flag for 'user' command: move <@user> <#room | room name>")
+		return
+	}
+
+	target, err := h.findRoom(strings.Join(command[3:], " "), m.ChannelID, s)
+	if err != nil {
+		s.ChannelMessageSend(m.ChannelID, "Error retrieving room: "+err.Error())
+		return
+	}
+
+	userID := CleanUserMention(command[2])
+	if _, err := h.user.usermanager.GetUserByID(userID); err != nil {
+		s.ChannelMessageSend(m.ChannelID, "Error retrieving user: "+err.Error())
+		return
+	}
+
+	pending, err := h.Relocate(userID, target, s)
+	if err != nil {
+		s.ChannelMessageSend(m.ChannelID, "Error moving user: "+err.Error())
+		return
+	}
+	if pending {
+		s.ChannelMessageSend(m.ChannelID, "<@"+userID+"> is not in "+target.Name+"'s guild yet, they have been "+
+			"sent an invite and will be moved once they join.")
+		return
+	}
+	s.ChannelMessageSend(m.ChannelID, "<@"+userID+"> moved to "+target.Name)
+	s.ChannelMessageSend(target.ID, "<@"+userID+"> has arrived.")
+}
+
+// findRoom function
+// Takes a channel mention or a room name in the guild the command was used in
+func (h *RecallHandler) findRoom(search string, channelID string, s DiscordSession) (room Room, err error) {
+
+	if strings.HasPrefix(search, "<#") {
+		return h.room.rooms.GetRoomByID(CleanChannel(search))
+	}
+	guildID, err := getGuildID(s, channelID)
+	if err != nil {
+		return room, err
+	}
+	return h.room.rooms.GetRoomByName(search, guildID)
+}
+
+// Relocate function
+// Moves a user into a room wherever they are now, even if they are lost. Returns pending when the user has to join
+// the room's guild first, in which case the move is finished by the transfer handler.
+func (h *RecallHandler) Relocate(userID string, target Room, s DiscordSession) (pending bool, err error) {
+
+	user, err := h.user.usermanager.GetUserByID(userID)
+	if err != nil {
+		return false, err
+	}
+	if len(target.AdditionalRoleIDs) < 1 {
+		return false, errors.New("Target room is not configured properly: " + target.ID)
+	}
+
+	h.travel.StopJourney(userID)
+	fromroom, fromerr := h.room.rooms.GetRoomByID(user.RoomID)
+
+	if !h.transfer.IsUserInGuild(userID, target.GuildID) {
+		invite, err := s.ChannelInviteCreate(target.ID, discordgo.Invite{})
+		if err != nil {
+			return false, err
+		}
+		channel, err := s.UserChannelCreate(userID)
+		if err != nil {
+			return false, err
+		}
+		err = h.transfer.AddTransfer(userID, user.RoomID, target.ID, target.GuildID, "")
+		if err != nil {
+			return false, err
+		}
+		s.ChannelMessageSend(channel.ID, ":satellite: You are being drawn through The Aether to "+target.Name+
+			", please click the invite link below to complete your journey: https://discord.gg/"+invite.Code)
+		return true, nil
+	}
+
+	err = h.transfer.TransferToChannel(userID, target.GuildID, user.RoomID, target.ID, s)
+	if err != nil {
+		return false, err
+	}
+
+	if fromerr == nil {
+		h.travel.CrossZone(user, fromroom, target, s)
+	}
+	return false, nil
+}
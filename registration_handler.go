@@ -24,7 +24,8 @@ type RegistrationHandler struct {
 	db       *DBHandler
 	perm     *PermissionsHandler
 	registry *CommandRegistry
-	dg       *discordgo.Session
+	router   *CommandRouter
+	dg       DiscordSession
 	user     *UserHandler
 	ch       *ChannelHandler
 	rooms    *Rooms
@@ -35,6 +36,22 @@ type RegistrationHandler struct {
 func (h *RegistrationHandler) Init() {
 
 	h.RegisterCommands()
+	h.RegisterCallbacks()
+
+}
+
+// RegisterCallbacks function
+// These names are saved with pending watches, so renaming one strands anyone mid-registration
+func (h *RegistrationHandler) RegisterCallbacks() {
+
+	h.callback.RegisterCallback("registration.confirm-name", h.ConfirmName,
+		"Registration timed out waiting for your name, please ask an Admin for assistance.")
+	h.callback.RegisterCallback("registration.confirm-attributes", h.ConfirmAttributes,
+		"Your attribute roll timed out, you may re-roll with "+h.conf.CommandPrefix()+"roll-attributes.")
+	h.callback.RegisterCallback("registration.confirm-race", h.ConfirmRace,
+		"Your race choice timed out, you may choose again with "+h.conf.CommandPrefix()+"pick-race.")
+	h.callback.RegisterCallback("registration.confirm-class", h.ConfirmClass,
+		"Your class choice timed out, you may choose again with "+h.conf.CommandPrefix()+"pick-class.")
 
 }
 
@@ -45,22 +62,22 @@ func (h *RegistrationHandler) RegisterCommands() (err error) {
 	h.registry.AddGroup("register", "player")
 	h.registry.AddChannel("register", h.conf.MainConfig.LobbyChannelID)
 
+	h.router.AddRoute("register", false, h.ReadCommand)
+	h.router.AddRoute("roll-attributes", false, h.ReadCommand)
+	h.router.AddRoute("pick-race", false, h.ReadCommand)
+	h.router.AddRoute("raceinfo", false, h.ReadCommand)
+	h.router.AddRoute("pick-class", false, h.ReadCommand)
+	h.router.AddRoute("classinfo", false, h.ReadCommand)
+	h.router.AddRoute("pick-skills", false, h.ReadCommand)
+	h.router.AddRoute("pick-skill", false, h.ReadCommand)
+	h.router.AddRoute("skillinfo", false, h.ReadCommand)
+
 	return nil
 
 }
 
-// Read function
-func (h *RegistrationHandler) Read(s *discordgo.Session, m *discordgo.MessageCreate) {
-
-	cp := h.conf.MainConfig.CP
-
-	if !SafeInput(s, m, h.conf) {
-		return
-	}
-
-	// This should register all new users, presumably we want this done here because this is the first
-	// command a usermanager should have access to.
-	h.user.CheckUser(m.Author.ID, s, m.ChannelID)
+// ReadCommand function
+func (h *RegistrationHandler) ReadCommand(command []string, user User, s DiscordSession, m *discordgo.MessageCreate) {
 
 	guildID, err := getGuildID(s, m.ChannelID)
 	if err != nil {
@@ -68,12 +85,7 @@ func (h *RegistrationHandler) Read(s *discordgo.Session, m *discordgo.MessageCre
 		return
 	}
 
-	user, err := h.db.GetUser(m.Author.ID)
-	if err != nil {
-		return
-	}
-
-	if strings.HasPrefix(m.Content, cp+"register") {
+	if command[0] == "register" {
 
 		if guildID != h.conf.MainConfig.CentralGuildID {
 			// Ignore registration attempts in non-central guild
@@ -96,7 +108,7 @@ func (h *RegistrationHandler) Read(s *discordgo.Session, m *discordgo.MessageCre
 			return
 		}
 	}
-	if strings.HasPrefix(m.Content, cp+"roll-attributes") {
+	if command[0] == "roll-attributes" {
 		if user.Registered != "" {
 			s.ChannelMessageSend(m.ChannelID, "You have already been registered and cannot re-roll your attributes!")
 			return
@@ -104,7 +116,7 @@ func (h *RegistrationHandler) Read(s *discordgo.Session, m *discordgo.MessageCre
 		h.RollAttributes(s, m)
 		return
 	}
-	if strings.HasPrefix(m.Content, cp+"pick-race") {
+	if command[0] == "pick-race" {
 		if user.Registered != "" {
 			s.ChannelMessageSend(m.ChannelID, "You have already been registered and cannot change your race!")
 			return
@@ -112,11 +124,11 @@ func (h *RegistrationHandler) Read(s *discordgo.Session, m *discordgo.MessageCre
 		h.PickRace(s, m)
 		return
 	}
-	if strings.HasPrefix(m.Content, cp+"raceinfo") {
+	if command[0] == "raceinfo" {
 		h.RaceInfo(s, m)
 		return
 	}
-	if strings.HasPrefix(m.Content, cp+"pick-class") {
+	if command[0] == "pick-class" {
 		if user.Registered != "" {
 			s.ChannelMessageSend(m.ChannelID, "You have already been registered and cannot change your class!")
 			return
@@ -124,21 +136,21 @@ func (h *RegistrationHandler) Read(s *discordgo.Session, m *discordgo.MessageCre
 		h.PickClass(s, m)
 		return
 	}
-	if strings.HasPrefix(m.Content, cp+"classinfo") {
+	if command[0] == "classinfo" {
 		h.ClassInfo(s, m)
 		return
 	}
 
-	if strings.HasPrefix(m.Content, cp+"pick-skills") {
+	if command[0] == "pick-skills" {
 		h.PickSkills(s, m)
 		return
 	}
 
-	if strings.HasPrefix(m.Content, cp+"skillinfo") {
+	if command[0] == "skillinfo" {
 		h.SkillInfo(s, m)
 		return
 	}
-	if strings.HasPrefix(m.Content, cp+"pick-skill") {
+	if command[0] == "pick-skill" {
 		if user.Registered != "" {
 			s.ChannelMessageSend(m.ChannelID, "You have already been registered and cannot change your skills!")
 			return
@@ -150,7 +162,7 @@ func (h *RegistrationHandler) Read(s *discordgo.Session, m *discordgo.MessageCre
 }
 
 // StartRegistration function
-func (h *RegistrationHandler) StartRegistration(s *discordgo.Session, m *discordgo.MessageCreate) {
+func (h *RegistrationHandler) StartRegistration(s DiscordSession, m *discordgo.MessageCreate) {
 	/*
 		guildID, err := getGuildID(s, m.ChannelID)
 		if err != nil {
@@ -249,7 +261,7 @@ func (h *RegistrationHandler) SetRegistrationStep(status string, userID string)
 }
 
 // FinishRegistration function
-func (h *RegistrationHandler) FinishRegistration(s *discordgo.Session, m *discordgo.MessageCreate) {
+func (h *RegistrationHandler) FinishRegistration(s DiscordSession, m *discordgo.MessageCreate) {
 
 	user, err := h.db.GetUser(m.Author.ID)
 	if err != nil {
@@ -280,10 +292,10 @@ func (h *RegistrationHandler) FinishRegistration(s *discordgo.Session, m *discor
 }
 
 // ConfirmName Function
-func (h *RegistrationHandler) ConfirmName(name string, s *discordgo.Session, m *discordgo.MessageCreate) {
+func (h *RegistrationHandler) ConfirmName(name string, s DiscordSession, m *discordgo.MessageCreate) {
 
 	// We do this to avoid having duplicate commands overrunning each other
-	cp := h.conf.MainConfig.CP
+	cp := h.conf.CommandPrefix()
 	if strings.HasPrefix(m.Content, cp) {
 		s.ChannelMessageSend(m.ChannelID, "Invalid")
 		return
@@ -312,13 +324,13 @@ func (h *RegistrationHandler) ConfirmName(name string, s *discordgo.Session, m *
 		return
 	}
 	s.ChannelMessageSend(m.ChannelID, user.Name + " You may now proceed with your "+
-		"avatar creation by using the "+h.conf.MainConfig.CP+"pick-race command")
+		"avatar creation by using the "+h.conf.CommandPrefix()+"pick-race command")
 	return
 
 }
 
 // RollAttributes function
-func (h *RegistrationHandler) RollAttributes(s *discordgo.Session, m *discordgo.MessageCreate) {
+func (h *RegistrationHandler) RollAttributes(s DiscordSession, m *discordgo.MessageCreate) {
 
 	strengthroll := RollDiceAndAdd(6, 3)
 	dexterityroll := RollDiceAndAdd(6, 3)
@@ -344,17 +356,17 @@ func (h *RegistrationHandler) RollAttributes(s *discordgo.Session, m *discordgo.
 	attributes = attributes + "```\n"
 
 	s.ChannelMessageSend(m.ChannelID, "Roll result: Confirm? (Yes/No):\n"+attributes)
-	h.callback.Watch(h.ConfirmAttributes, GetUUIDv2(), roll, s, m)
+	h.callback.Watch("registration.confirm-attributes", GetUUIDv2(), roll, s, m)
 	return
 }
 
 // ConfirmAttributes function
-func (h *RegistrationHandler) ConfirmAttributes(command string, s *discordgo.Session, m *discordgo.MessageCreate) {
+func (h *RegistrationHandler) ConfirmAttributes(command string, s DiscordSession, m *discordgo.MessageCreate) {
 	// In this handler we don't do anything with the command string, instead we grab the response from m.Content
 
 	attributes := strings.Split(command, " ")
 	// We do this to avoid having duplicate commands overrunning each other
-	cp := h.conf.MainConfig.CP
+	cp := h.conf.CommandPrefix()
 	if strings.HasPrefix(m.Content, cp) {
 		s.ChannelMessageSend(m.ChannelID, "Roll Attributes Command Cancelled")
 		return
@@ -408,7 +420,7 @@ func (h *RegistrationHandler) ConfirmAttributes(command string, s *discordgo.Ses
 	}
 	if m.Content == "n" || m.Content == "no" {
 		s.ChannelMessageSend(m.ChannelID, "Roll discarded, you may "+
-			"re-roll with "+h.conf.MainConfig.CP+"roll-attributes.")
+			"re-roll with "+h.conf.CommandPrefix()+"roll-attributes.")
 		return
 	}
 
@@ -418,16 +430,16 @@ func (h *RegistrationHandler) ConfirmAttributes(command string, s *discordgo.Ses
 		return
 	}
 	s.ChannelMessageSend(m.ChannelID, "Attributes assigned! You may now proceed with your "+
-		//"avatar creation by using the "+h.conf.MainConfig.CP+"pick-race command")
+		//"avatar creation by using the "+h.conf.CommandPrefix()+"pick-race command")
 	"avatar creation now, what is your name? ")
-	h.callback.Watch(h.ConfirmName, GetUUIDv2(), m.Content, s, m)
+	h.callback.Watch("registration.confirm-name", GetUUIDv2(), m.Content, s, m)
 	return
 }
 
 // Race
 
 // RaceInfo function
-func (h *RegistrationHandler) RaceInfo(s *discordgo.Session, m *discordgo.MessageCreate) {
+func (h *RegistrationHandler) RaceInfo(s DiscordSession, m *discordgo.MessageCreate) {
 
 	racelist := GetRaceList()
 
@@ -455,7 +467,7 @@ func (h *RegistrationHandler) RaceInfo(s *discordgo.Session, m *discordgo.Messag
 }
 
 // PickRace function
-func (h *RegistrationHandler) PickRace(s *discordgo.Session, m *discordgo.MessageCreate) {
+func (h *RegistrationHandler) PickRace(s DiscordSession, m *discordgo.MessageCreate) {
 
 	racelist := GetRaceList()
 
@@ -483,7 +495,7 @@ func (h *RegistrationHandler) PickRace(s *discordgo.Session, m *discordgo.Messag
 		raceoption := payload[0]
 		if h.ValidateRaceChoice(raceoption) {
 			s.ChannelMessageSend(m.ChannelID, "You have chosen: "+raceoption+"\nConfirm? (Yes/No)\n")
-			h.callback.Watch(h.ConfirmRace, GetUUIDv2(), raceoption, s, m)
+			h.callback.Watch("registration.confirm-race", GetUUIDv2(), raceoption, s, m)
 			return
 		}
 		s.ChannelMessageSend(m.ChannelID, ":sparkles: Invalid Race Choice! You may pick from one of the following races: \n```"+
@@ -530,10 +542,10 @@ func (h *RegistrationHandler) ValidateRaceChoice(race string) (valid bool) {
 }
 
 // ConfirmRace function
-func (h *RegistrationHandler) ConfirmRace(race string, s *discordgo.Session, m *discordgo.MessageCreate) {
+func (h *RegistrationHandler) ConfirmRace(race string, s DiscordSession, m *discordgo.MessageCreate) {
 
 	// We do this to avoid having duplicate commands overrunning each other
-	cp := h.conf.MainConfig.CP
+	cp := h.conf.CommandPrefix()
 	if strings.HasPrefix(m.Content, cp) {
 		s.ChannelMessageSend(m.ChannelID, "Pick Race Command Cancelled")
 		return
@@ -567,13 +579,13 @@ func (h *RegistrationHandler) ConfirmRace(race string, s *discordgo.Session, m *
 		return
 	}
 	s.ChannelMessageSend(m.ChannelID, "Race assigned! You may now proceed with your "+
-		"avatar creation by using the "+h.conf.MainConfig.CP+"pick-class command")
+		"avatar creation by using the "+h.conf.CommandPrefix()+"pick-class command")
 	return
 
 }
 
 // ClassInfo function
-func (h *RegistrationHandler) ClassInfo(s *discordgo.Session, m *discordgo.MessageCreate) {
+func (h *RegistrationHandler) ClassInfo(s DiscordSession, m *discordgo.MessageCreate) {
 
 	classlist := GetClassList()
 
@@ -601,7 +613,7 @@ func (h *RegistrationHandler) ClassInfo(s *discordgo.Session, m *discordgo.Messa
 }
 
 // PickClass function
-func (h *RegistrationHandler) PickClass(s *discordgo.Session, m *discordgo.MessageCreate) {
+func (h *RegistrationHandler) PickClass(s DiscordSession, m *discordgo.MessageCreate) {
 
 	classlist := GetClassList()
 
@@ -629,7 +641,7 @@ func (h *RegistrationHandler) PickClass(s *discordgo.Session, m *discordgo.Messa
 		classoption := payload[0]
 		if h.ValidateClassChoice(classoption) {
 			s.ChannelMessageSend(m.ChannelID, "You have chosen: "+classoption+"\nConfirm? (Yes/No)\n")
-			h.callback.Watch(h.ConfirmClass, GetUUIDv2(), classoption, s, m)
+			h.callback.Watch("registration.confirm-class", GetUUIDv2(), classoption, s, m)
 			return
 		}
 		s.ChannelMessageSend(m.ChannelID, ":sparkles: Invalid Class Choice! You may pick from one of the following classes: \n```"+
@@ -639,7 +651,7 @@ func (h *RegistrationHandler) PickClass(s *discordgo.Session, m *discordgo.Messa
 }
 
 // ChooseClass function
-func (h *RegistrationHandler) ChooseClass(s *discordgo.Session, m *discordgo.MessageCreate) {
+func (h *RegistrationHandler) ChooseClass(s DiscordSession, m *discordgo.MessageCreate) {
 
 	classlist := GetClassList()
 
@@ -667,7 +679,7 @@ func (h *RegistrationHandler) ChooseClass(s *discordgo.Session, m *discordgo.Mes
 		classoption := payload[0]
 		if h.ValidateRaceChoice(classoption) {
 			s.ChannelMessageSend(m.ChannelID, "You have chosen: "+classoption+"\nConfirm? (Yes/No)\n")
-			h.callback.Watch(h.ConfirmClass, GetUUIDv2(), classoption, s, m)
+			h.callback.Watch("registration.confirm-class", GetUUIDv2(), classoption, s, m)
 			return
 		}
 		s.ChannelMessageSend(m.ChannelID, ":sparkles: Invalid Class Choice! You may pick from one of the following classes: \n```"+
@@ -726,10 +738,10 @@ func (h *RegistrationHandler) ValidateClassChoice(class string) (valid bool) {
 }
 
 // ConfirmClass function
-func (h *RegistrationHandler) ConfirmClass(class string, s *discordgo.Session, m *discordgo.MessageCreate) {
+func (h *RegistrationHandler) ConfirmClass(class string, s DiscordSession, m *discordgo.MessageCreate) {
 
 	// We do this to avoid having duplicate commands overrunning each other
-	cp := h.conf.MainConfig.CP
+	cp := h.conf.CommandPrefix()
 	if strings.HasPrefix(m.Content, cp) {
 		s.ChannelMessageSend(m.ChannelID, "Pick Class Command Cancelled")
 		return
@@ -763,13 +775,13 @@ func (h *RegistrationHandler) ConfirmClass(class string, s *discordgo.Session, m
 		return
 	}
 	s.ChannelMessageSend(m.ChannelID, "Class assigned! You may now proceed with your "+
-		"avatar creation by using the "+h.conf.MainConfig.CP+"pick-skills command")
+		"avatar creation by using the "+h.conf.CommandPrefix()+"pick-skills command")
 	return
 
 }
 
 // SkillInfo function
-func (h *RegistrationHandler) SkillInfo(s *discordgo.Session, m *discordgo.MessageCreate) {
+func (h *RegistrationHandler) SkillInfo(s DiscordSession, m *discordgo.MessageCreate) {
 	skilllist := GetSkillList()
 
 	keys := make([]string, 0, len(skilllist))
@@ -796,7 +808,7 @@ func (h *RegistrationHandler) SkillInfo(s *discordgo.Session, m *discordgo.Messa
 }
 
 // PickSkills info
-func (h *RegistrationHandler) PickSkills(s *discordgo.Session, m *discordgo.MessageCreate) {
+func (h *RegistrationHandler) PickSkills(s DiscordSession, m *discordgo.MessageCreate) {
 
 	skilllist := GetSkillList()
 
@@ -825,7 +837,7 @@ func (h *RegistrationHandler) PickSkills(s *discordgo.Session, m *discordgo.Mess
 		skilloption := payload[0]
 		if h.ValidateSkillChoice(skilloption) {
 			s.ChannelMessageSend(m.ChannelID, "You have chosen: "+skilloption+"\nConfirm? (Yes/No)\n")
-			h.callback.Watch(h.ConfirmClass, GetUUIDv2(), skilloption, s, m)
+			h.callback.Watch("registration.confirm-class", GetUUIDv2(), skilloption, s, m)
 			return
 		}
 		s.ChannelMessageSend(m.ChannelID, ":sparkles: Invalid Skill Choice! You may pick from one of the following skills: \n```"+Slist+"\n```\n")
@@ -834,7 +846,7 @@ func (h *RegistrationHandler) PickSkills(s *discordgo.Session, m *discordgo.Mess
 }
 
 // ChooseSkills function
-func (h *RegistrationHandler) ChooseSkills(s *discordgo.Session, m *discordgo.MessageCreate) {
+func (h *RegistrationHandler) ChooseSkills(s DiscordSession, m *discordgo.MessageCreate) {
 	_, payload := SplitPayload(strings.Split(m.Content, " "))
 
 	skilllist := GetSkillList()
@@ -953,33 +965,33 @@ func (h *RegistrationHandler) ValidateSkillChoice(skill string) (valid bool) {
 }
 
 // ConfirmSkills function
-func (h *RegistrationHandler) ConfirmSkills(command string, s *discordgo.Session, m *discordgo.MessageCreate) {
+func (h *RegistrationHandler) ConfirmSkills(command string, s DiscordSession, m *discordgo.MessageCreate) {
 }
 
 // ChooseFeats function
-func (h *RegistrationHandler) ChooseFeats(s *discordgo.Session, m *discordgo.MessageCreate) {}
+func (h *RegistrationHandler) ChooseFeats(s DiscordSession, m *discordgo.MessageCreate) {}
 
 // ConfirmFeats function
-func (h *RegistrationHandler) ConfirmFeats(command string, s *discordgo.Session, m *discordgo.MessageCreate) {
+func (h *RegistrationHandler) ConfirmFeats(command string, s DiscordSession, m *discordgo.MessageCreate) {
 }
 
 // ChooseStarterGear function
-func (h *RegistrationHandler) ChooseStarterGear(s *discordgo.Session, m *discordgo.MessageCreate) {}
+func (h *RegistrationHandler) ChooseStarterGear(s DiscordSession, m *discordgo.MessageCreate) {}
 
 // ConfirmStarterGear function
-func (h *RegistrationHandler) ConfirmStarterGear(command string, s *discordgo.Session, m *discordgo.MessageCreate) {
+func (h *RegistrationHandler) ConfirmStarterGear(command string, s DiscordSession, m *discordgo.MessageCreate) {
 }
 
 // ChangeMisc function
-func (h *RegistrationHandler) ChangeMisc(s *discordgo.Session, m *discordgo.MessageCreate) {}
+func (h *RegistrationHandler) ChangeMisc(s DiscordSession, m *discordgo.MessageCreate) {}
 
 // ConfirmMisc function
-func (h *RegistrationHandler) ConfirmMisc(command string, s *discordgo.Session, m *discordgo.MessageCreate) {
+func (h *RegistrationHandler) ConfirmMisc(command string, s DiscordSession, m *discordgo.MessageCreate) {
 }
 
 // ChangeBio function
-func (h *RegistrationHandler) ChangeBio(s *discordgo.Session, m *discordgo.MessageCreate) {}
+func (h *RegistrationHandler) ChangeBio(s DiscordSession, m *discordgo.MessageCreate) {}
 
 // ConfirmBio function
-func (h *RegistrationHandler) ConfirmBio(command string, s *discordgo.Session, m *discordgo.MessageCreate) {
+func (h *RegistrationHandler) ConfirmBio(command string, s DiscordSession, m *discordgo.MessageCreate) {
 }
@@ -0,0 +1,155 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestRegistrationAttributes(t *testing.T) {
+
+	tests := []struct {
+		name       string
+		registered bool
+		command    string
+		reply      string // Answer to the roll, if one is asked for
+		message    string
+		rolled     bool
+	}{
+		{
+			name:       "already registered",
+			registered: true,
+			command:    "~register",
+			message:    "You are already registered!",
+		},
+		{
+			name:       "re-roll once registered",
+			registered: true,
+			command:    "~roll-attributes",
+			message:    "You have already been registered and cannot re-roll your attributes!",
+		},
+		{
+			name:    "roll accepted",
+			command: "~roll-attributes",
+			reply:   "Yes",
+			message: "Attributes assigned!",
+			rolled:  true,
+		},
+		{
+			name:    "roll discarded",
+			command: "~roll-attributes",
+			reply:   "no",
+			message: "Roll discarded, you may re-roll with ~roll-attributes.",
+		},
+		{
+			name:    "roll interrupted by another command",
+			command: "~roll-attributes",
+			reply:   "~look",
+			message: "Roll Attributes Command Cancelled",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			w := newTestWorld(t)
+			alice := w.addPlayer(t, "1", "Alice", w.lobby)
+			if !test.registered {
+				alice.Registered = ""
+				w.user.usermanager.SaveUserToDB(alice)
+			}
+
+			w.router.Dispatch(w.s, w.s.NewMessage(alice.ID, w.lobby.ID, test.command))
+			if test.reply != "" {
+				if got := w.s.LastMessage(w.lobby.ID); !strings.HasPrefix(got, "Roll result: Confirm?") {
+					t.Fatalf("roll message = %q", got)
+				}
+				w.callback.Dispatch(w.s, w.s.NewMessage(alice.ID, w.lobby.ID, test.reply))
+			}
+
+			if got := w.s.LastMessage(w.lobby.ID); !strings.HasPrefix(got, test.message) {
+				t.Errorf("message = %q, want %q", got, test.message)
+			}
+
+			user := w.getUser(t, alice.ID)
+			for name, value := range map[string]int{"strength": user.Strength, "dexterity": user.Dexterity,
+				"constitution": user.Constitution, "intelligence": user.Intelligence, "wisdom": user.Wisdom,
+				"charisma": user.Charisma} {
+				if test.rolled && (value < 3 || value > 18) {
+					t.Errorf("%s = %d, want a 3d6 roll", name, value)
+				}
+				if !test.rolled && value != 0 {
+					t.Errorf("%s = %d, want no roll saved", name, value)
+				}
+			}
+			if test.rolled && user.RegistrationStatus != "attributes" {
+				t.Errorf("registration status = %q, want attributes", user.RegistrationStatus)
+			}
+		})
+	}
+}
+
+func TestFinishRegistration(t *testing.T) {
+
+	tests := []struct {
+		name       string
+		crossroads bool
+		message    string
+	}{
+		{
+			name:       "roles granted",
+			crossroads: true,
+			message:    "Registration complete, please enjoy your journey through *The Aether*!",
+		},
+		{
+			name:    "missing crossroads role",
+			message: "Could not complete registration: Role ID Not Found: Crossroads",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			w := newTestWorld(t)
+			crossroadsID := ""
+			if test.crossroads {
+				crossroadsID = w.addRole(t, testCentralGuild, "Crossroads")
+			}
+			alice := w.addPlayer(t, "1", "Alice", w.lobby)
+
+			w.registration.FinishRegistration(w.s, w.s.NewMessage(alice.ID, w.lobby.ID, "yes"))
+
+			if got := w.s.LastMessage(w.lobby.ID); got != test.message {
+				t.Errorf("message = %q, want %q", got, test.message)
+			}
+			registeredID, _ := getRoleIDByName(w.s, testCentralGuild, "Registered")
+			if !w.s.MemberHasRole(testCentralGuild, alice.ID, registeredID) {
+				t.Error("user is missing the registered role")
+			}
+			if test.crossroads && !w.s.MemberHasRole(testCentralGuild, alice.ID, crossroadsID) {
+				t.Error("user is missing the crossroads role")
+			}
+		})
+	}
+}
+
+func TestSetRegistrationStep(t *testing.T) {
+
+	w := newTestWorld(t)
+	alice := w.addPlayer(t, "1", "Alice", w.lobby)
+
+	for _, step := range []string{"attributes", "race", "class", "skills", "feats", "equipment", "complete"} {
+		err := w.registration.SetRegistrationStep(step, alice.ID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if status := w.getUser(t, alice.ID).RegistrationStatus; status != step {
+			t.Errorf("registration status = %q, want %q", status, step)
+		}
+	}
+
+	err := w.registration.SetRegistrationStep("dancing", alice.ID)
+	if err == nil {
+		t.Error("an unknown registration step was accepted")
+	}
+	if status := w.getUser(t, alice.ID).RegistrationStatus; status != "complete" {
+		t.Errorf("registration status = %q after a bad step, want complete", status)
+	}
+}
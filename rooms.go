@@ -2,6 +2,7 @@ package main
 
 import (
 	"errors"
+	"github.com/asdine/storm"
 	"sync"
 )
 
@@ -15,8 +16,8 @@ type Rooms struct {
 type Room struct {
 	ID string `storm:"id"` // primary key
 
-	Name    string
-	GuildID string
+	Name    string `storm:"index"`
+	GuildID string `storm:"index"`
 	Type    string
 
 	Topic string // For recording the discord room topic
@@ -24,6 +25,10 @@ type Room struct {
 	ParentID   string
 	ParentName string
 
+	ZoneID string `storm:"index"` // See zones.go
+
+	BindPoint bool // Players can bind here and recall back to it later
+
 	TravelRoleID      string
 	AdditionalRoleIDs []string
 	UserIDs           []string
@@ -40,36 +45,8 @@ type Room struct {
 	GuildTransferInvite string
 	TransferRoomID      string
 
-	// Connecting Room ID's
-	UpID     string
-	UpItemID []string
-
-	DownID     string
-	DownItemID []string
-
-	NorthID     string
-	NorthItemID []string
-
-	NorthEastID     string
-	NorthEastItemID []string
-
-	EastID     string
-	EastItemID []string
-
-	SouthEastID     string
-	SouthEastItemID []string
-
-	SouthID     string
-	SouthItemID []string
-
-	SouthWestID     string
-	SouthWestItemID []string
-
-	WestID     string
-	WestItemID []string
-
-	NorthWestID     string
-	NorthWestItemID []string
+	// Connecting rooms, see exits.go
+	Exits []Exit
 
 	Items []string
 	NPC   []string
@@ -115,31 +92,38 @@ func (h *Rooms) RemoveRoomByID(roomID string) (err error) {
 
 // GetRoomByID function
 func (h *Rooms) GetRoomByID(roomID string) (room Room, err error) {
+	h.querylocker.RLock()
+	defer h.querylocker.RUnlock()
 
-	rooms, err := h.GetAllRooms()
+	db := h.db.rawdb.From("Rooms")
+	err = db.One("ID", roomID, &room)
 	if err != nil {
-		return room, err
-	}
-
-	for _, record := range rooms {
-
-		if roomID == record.ID {
-			return record, nil
+		if err == storm.ErrNotFound {
+			return room, errors.New("No record found")
 		}
+		return room, err
 	}
-	return room, errors.New("No record found")
+	return room, nil
 }
 
 // GetRoomByName function
+// Room names are only unique within a guild, so we narrow down by the name index and then check the guild
 func (h *Rooms) GetRoomByName(roomname string, guildID string) (room Room, err error) {
+	h.querylocker.RLock()
+	defer h.querylocker.RUnlock()
 
-	rooms, err := h.GetAllRooms()
+	var rooms []Room
+	db := h.db.rawdb.From("Rooms")
+	err = db.Find("Name", roomname, &rooms)
 	if err != nil {
+		if err == storm.ErrNotFound {
+			return room, errors.New("No record found")
+		}
 		return room, err
 	}
 
 	for _, i := range rooms {
-		if i.Name == roomname && i.GuildID == guildID {
+		if i.GuildID == guildID {
 			return i, nil
 		}
 	}
@@ -147,10 +131,38 @@ func (h *Rooms) GetRoomByName(roomname string, guildID string) (room Room, err e
 	return room, errors.New("No record found")
 }
 
+// GetRoomsByGuildID function
+func (h *Rooms) GetRoomsByGuildID(guildID string) (roomlist []Room, err error) {
+	h.querylocker.RLock()
+	defer h.querylocker.RUnlock()
+
+	db := h.db.rawdb.From("Rooms")
+	err = db.Find("GuildID", guildID, &roomlist)
+	if err != nil && err != storm.ErrNotFound {
+		return roomlist, err
+	}
+
+	return roomlist, nil
+}
+
+// GetRoomsByZoneID function
+func (h *Rooms) GetRoomsByZoneID(zoneID string) (roomlist []Room, err error) {
+	h.querylocker.RLock()
+	defer h.querylocker.RUnlock()
+
+	db := h.db.rawdb.From("Rooms")
+	err = db.Find("ZoneID", zoneID, &roomlist)
+	if err != nil && err != storm.ErrNotFound {
+		return roomlist, err
+	}
+
+	return roomlist, nil
+}
+
 // GetAllRooms function
 func (h *Rooms) GetAllRooms() (roomlist []Room, err error) {
-	h.querylocker.Lock()
-	defer h.querylocker.Unlock()
+	h.querylocker.RLock()
+	defer h.querylocker.RUnlock()
 
 	db := h.db.rawdb.From("Rooms")
 	err = db.All(&roomlist)
@@ -168,35 +180,10 @@ func (h *Rooms) IsRoomLinkedTo(roomID string, checklink string) (linked bool, er
 	if err != nil {
 		return false, err
 	}
-	if room.NorthID == checklink {
-		return true, nil
-	}
-	if room.NorthEastID == checklink {
-		return true, nil
-	}
-	if room.EastID == checklink {
-		return true, nil
-	}
-	if room.SouthEastID == checklink {
-		return true, nil
-	}
-	if room.SouthID == checklink {
-		return true, nil
-	}
-	if room.SouthWestID == checklink {
-		return true, nil
-	}
-	if room.WestID == checklink {
-		return true, nil
-	}
-	if room.NorthWestID == checklink {
-		return true, nil
-	}
-	if room.UpID == checklink {
-		return true, nil
-	}
-	if room.DownID == checklink {
-		return true, nil
+	for _, exit := range room.Exits {
+		if exit.TargetID == checklink {
+			return true, nil
+		}
 	}
 	return false, nil
 }
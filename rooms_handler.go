@@ -1,13 +1,13 @@
 package main
 
 import (
+	"bytes"
 	"errors"
 	"fmt"
 	"github.com/bwmarrin/discordgo"
 	"strconv"
 	"strings"
 	"sync"
-	"time"
 )
 
 // RoomsHandler struct
@@ -17,17 +17,19 @@ type RoomsHandler struct {
 	db       *DBHandler
 	perm     *PermissionsHandler
 	registry *CommandRegistry
-	dg       *discordgo.Session
+	router   *CommandRouter
+	dg       DiscordSession
 	user     *UserHandler
 	ch       *ChannelHandler
 	rooms    *Rooms
 	guilds   *GuildsManager
+	queue    *DiscordQueue
 
 	roomsynclocker sync.RWMutex
 }
 
 // InitRooms struct
-func (h *RoomsHandler) InitRooms(s *discordgo.Session, channelID string) (err error) {
+func (h *RoomsHandler) InitRooms(s DiscordSession, channelID string) (err error) {
 
 	fmt.Println("Running Base Room Initialization")
 	guildID := h.conf.MainConfig.CentralGuildID
@@ -36,6 +38,7 @@ func (h *RoomsHandler) InitRooms(s *discordgo.Session, channelID string) (err er
 	h.rooms.db = h.db
 
 	h.RegisterCommands()
+	h.RegisterWorldCallbacks()
 
 	// Create default registered usermanager role
 	registeredperms := h.perm.CreatePermissionInt(RolePermissions{})
@@ -104,7 +107,7 @@ func (h *RoomsHandler) InitRooms(s *discordgo.Session, channelID string) (err er
 
 	// Crossroads
 	fmt.Println("Creating Crossroads Room")
-	_, err = h.AddRoom(s, "crossroads", guildID, "The Aether", "", "", 16744704, false)
+	_, _, err = h.AddRoom(s, "crossroads", guildID, "The Aether", "", "", 16744704, false)
 	if err != nil {
 		if !strings.Contains(err.Error(), "already exists") {
 			return err
@@ -146,48 +149,12 @@ func (h *RoomsHandler) InitRooms(s *discordgo.Session, channelID string) (err er
 func (h *RoomsHandler) RegisterCommands() (err error) {
 	h.registry.Register("room", "Manage rooms for this server", "")
 	h.registry.AddGroup("room", "builder")
+	h.router.AddRoute("room", true, h.ParseCommand, "builder", "moderator")
 	return nil
 }
 
-// Read function
-func (h *RoomsHandler) Read(s *discordgo.Session, m *discordgo.MessageCreate) {
-
-	cp := h.conf.MainConfig.CP
-
-	if !SafeInput(s, m, h.conf) {
-		return
-	}
-
-	user, err := h.db.GetUser(m.Author.ID)
-	if err != nil {
-		//fmt.Println("Error finding usermanager")
-		return
-	}
-	if !user.CheckRole("builder") {
-		return
-	}
-	if strings.HasPrefix(m.Content, cp+"room") {
-		if h.registry.CheckPermission("room", user, s, m) {
-
-			command := strings.Fields(m.Content)
-
-			// Grab our sender ID to verify if this usermanager has permission to use this command
-			db := h.db.rawdb.From("Users")
-			var user User
-			err := db.One("ID", m.Author.ID, &user)
-			if err != nil {
-				fmt.Println("error retrieving usermanager:" + m.Author.ID)
-			}
-
-			if user.CheckRole("moderator") {
-				h.ParseCommand(command, s, m)
-			}
-		}
-	}
-}
-
 // ParseCommand function
-func (h *RoomsHandler) ParseCommand(command []string, s *discordgo.Session, m *discordgo.MessageCreate) {
+func (h *RoomsHandler) ParseCommand(command []string, user User, s DiscordSession, m *discordgo.MessageCreate) {
 
 	guildID, err := getGuildID(s, m.ChannelID)
 	if err != nil {
@@ -246,7 +213,8 @@ func (h *RoomsHandler) ParseCommand(command []string, s *discordgo.Session, m *d
 			return
 		}
 
-		channel, err := h.AddRoom(s, command[2], guildID, parentname, transferID, transferRoomID, color, false)
+		channel, placedID, err := h.AddRoom(s, command[2], guildID, parentname, transferID, transferRoomID, color,
+			false)
 		if err != nil {
 			s.ChannelMessageSend(m.ChannelID, "Error adding channel: "+err.Error())
 			return
@@ -259,14 +227,261 @@ func (h *RoomsHandler) ParseCommand(command []string, s *discordgo.Session, m *d
 		}
 
 		s.ChannelMessageSend(m.ChannelID, "Channel Created: "+formatted)
+		if placedID != guildID {
+			s.ChannelMessageSend(m.ChannelID, "This guild is full, so the room was created in overflow guild "+
+				placedID+". Travelers reach it through the gate rooms linking the two guilds, link it to the "+
+				"gate there with room linkdirection.")
+		}
+		return
+	}
+	if command[1] == "import" {
+		h.ReadImport(guildID, s, m)
+		return
+	}
+	if command[1] == "export" {
+		if len(command) < 3 {
+			s.ChannelMessageSend(m.ChannelID, "export requires a category - <category> <yaml|json (optional)>")
+			return
+		}
+		format := "yaml"
+		args := command[2:]
+		if len(args) > 1 && (args[len(args)-1] == "json" || args[len(args)-1] == "yaml") {
+			format = args[len(args)-1]
+			args = args[:len(args)-1]
+		}
+		category := strings.Join(args, " ")
+
+		definition, err := h.ExportWorld(category, guildID, s)
+		if err != nil {
+			s.ChannelMessageSend(m.ChannelID, "Error exporting rooms: "+err.Error())
+			return
+		}
+		output, err := definition.Marshal(format)
+		if err != nil {
+			s.ChannelMessageSend(m.ChannelID, "Error exporting rooms: "+err.Error())
+			return
+		}
+		filename := strings.Replace(strings.ToLower(category), " ", "-", -1) + "." + format
+		_, err = s.ChannelFileSend(m.ChannelID, filename, bytes.NewReader(output))
+		if err != nil {
+			s.ChannelMessageSend(m.ChannelID, "Error exporting rooms: "+err.Error())
+		}
 		return
 	}
 	if command[1] == "linkdirection" {
 		if len(command) < 5 {
-			s.ChannelMessageSend(m.ChannelID, "linkdirection requires three arguments: <from> <to> <direction>")
+			s.ChannelMessageSend(m.ChannelID, "linkdirection requires three arguments: <from> <to> <exit> "+
+				"| <reverse exit (optional)>\nExits such as north get their opposite as a reverse unless one is "+
+				"given, end with a | and nothing after it for a one way exit.")
+			return
+		}
+
+		exitname, reversename, split := SplitExitArgs(command[4:])
+		if !split {
+			if direction, standard := GetStandardDirection(exitname); standard {
+				reversename = direction.reverse
+			}
+		}
+		h.LinkDirection(exitname, reversename, command[2], command[3], s, m)
+		return
+	}
+	if command[1] == "unlinkdirection" {
+		if len(command) < 4 {
+			s.ChannelMessageSend(m.ChannelID, "unlinkdirection requires two arguments: <#room> <exit>")
+			return
+		}
+		exitname := strings.Join(command[3:], " ")
+		err := h.UnlinkDirection(exitname, command[2])
+		if err != nil {
+			s.ChannelMessageSend(m.ChannelID, "Error removing exit: "+err.Error())
 			return
 		}
-		h.LinkDirection(command[4], command[2], command[3], s, m)
+		s.ChannelMessageSend(m.ChannelID, "Exit "+exitname+" removed.")
+		return
+	}
+	if command[1] == "exitalias" {
+		if len(command) < 4 {
+			s.ChannelMessageSend(m.ChannelID, "exitalias requires three arguments: <#room> <exit> | <alias>")
+			return
+		}
+		exitname, alias, split := SplitExitArgs(command[3:])
+		if !split || alias == "" {
+			s.ChannelMessageSend(m.ChannelID, "exitalias requires three arguments: <#room> <exit> | <alias>")
+			return
+		}
+		err := h.SetExitAlias(exitname, alias, command[2])
+		if err != nil {
+			s.ChannelMessageSend(m.ChannelID, "Error adding exit alias: "+err.Error())
+			return
+		}
+		s.ChannelMessageSend(m.ChannelID, "Exit "+exitname+" can now be traveled with "+alias+".")
+		return
+	}
+	if command[1] == "exitreq" {
+		usage := "exitreq requires three arguments: <#room> <exit> | <add|list|clear|message> <...>\n" +
+			"add <item|flag|attribute|race|class|role> <value>, ie: " + h.conf.CommandPrefix() +
+			"room exitreq #gate north | add attribute strength 14\n" +
+			"message <text> sets what players are told when they can't use the exit"
+		if len(command) < 4 {
+			s.ChannelMessageSend(m.ChannelID, usage)
+			return
+		}
+		exitname, action, split := SplitExitArgs(command[3:])
+		args := strings.Fields(action)
+		if !split || len(args) < 1 {
+			s.ChannelMessageSend(m.ChannelID, usage)
+			return
+		}
+
+		room, err := h.rooms.GetRoomByID(CleanChannel(command[2]))
+		if err != nil {
+			s.ChannelMessageSend(m.ChannelID, "Error retrieving room: "+err.Error())
+			return
+		}
+		exit, found := room.GetExit(exitname)
+		if !found {
+			s.ChannelMessageSend(m.ChannelID, "No exit named "+exitname+" in "+room.Name)
+			return
+		}
+
+		switch strings.ToLower(args[0]) {
+		case "add":
+			requirement, err := ParseExitRequirement(args[1:])
+			if err != nil {
+				s.ChannelMessageSend(m.ChannelID, err.Error())
+				return
+			}
+			err = h.AddExitRequirement(exit.Name, requirement, room.ID)
+			if err != nil {
+				s.ChannelMessageSend(m.ChannelID, "Error adding exit requirement: "+err.Error())
+				return
+			}
+			s.ChannelMessageSend(m.ChannelID, "Exit "+exit.Name+" now requires "+requirement.String()+".")
+		case "list":
+			if len(exit.Requirements) == 0 {
+				s.ChannelMessageSend(m.ChannelID, "Exit "+exit.Name+" has no requirements.")
+				return
+			}
+			s.ChannelMessageSend(m.ChannelID, "Exit "+exit.Name+" requires: ```\n"+
+				strings.Replace(FormatExitRequirements(exit), ", ", "\n", -1)+"\n```\nLocked message: "+exit.LockedText())
+		case "clear":
+			err = h.ClearExitRequirements(exit.Name, room.ID)
+			if err != nil {
+				s.ChannelMessageSend(m.ChannelID, "Error clearing exit requirements: "+err.Error())
+				return
+			}
+			s.ChannelMessageSend(m.ChannelID, "Exit "+exit.Name+" requirements cleared.")
+		case "message":
+			message := strings.TrimSpace(strings.TrimPrefix(action, args[0]))
+			err = h.SetExitLockedMessage(exit.Name, message, room.ID)
+			if err != nil {
+				s.ChannelMessageSend(m.ChannelID, "Error setting locked message: "+err.Error())
+				return
+			}
+			s.ChannelMessageSend(m.ChannelID, "Exit "+exit.Name+" locked message set: "+message)
+		default:
+			s.ChannelMessageSend(m.ChannelID, usage)
+		}
+		return
+	}
+	if command[1] == "exitphrase" {
+		usage := "exitphrase requires three arguments: <#room> <exit> | <departure|arrival> <text>\nie: " +
+			h.conf.CommandPrefix() + "room exitphrase #tavern north | departure through the creaking door"
+		if len(command) < 4 {
+			s.ChannelMessageSend(m.ChannelID, usage)
+			return
+		}
+		exitname, phrasing, split := SplitExitArgs(command[3:])
+		phrase := strings.Fields(phrasing)
+		if !split || len(phrase) < 2 {
+			s.ChannelMessageSend(m.ChannelID, usage)
+			return
+		}
+		text := strings.TrimSpace(strings.TrimPrefix(phrasing, phrase[0]))
+		err := h.SetExitPhrase(exitname, phrase[0], text, command[2])
+		if err != nil {
+			s.ChannelMessageSend(m.ChannelID, "Error setting exit phrasing: "+err.Error())
+			return
+		}
+		s.ChannelMessageSend(m.ChannelID, "Exit "+exitname+" "+strings.ToLower(phrase[0])+" set: "+text)
+		return
+	}
+	if command[1] == "exitcost" {
+		usage := "exitcost requires three arguments: <#room> <exit> | <stamina cost>, 0 uses the default cost\nie: " +
+			h.conf.CommandPrefix() + "room exitcost #ridge up | 5"
+		if len(command) < 4 {
+			s.ChannelMessageSend(m.ChannelID, usage)
+			return
+		}
+		exitname, value, split := SplitExitArgs(command[3:])
+		cost, err := strconv.Atoi(value)
+		if !split || err != nil || cost < 0 {
+			s.ChannelMessageSend(m.ChannelID, usage)
+			return
+		}
+		err = h.SetExitCost(exitname, cost, command[2])
+		if err != nil {
+			s.ChannelMessageSend(m.ChannelID, "Error setting exit cost: "+err.Error())
+			return
+		}
+		s.ChannelMessageSend(m.ChannelID, "Exit "+exitname+" cost set: "+value)
+		return
+	}
+	if command[1] == "exithide" {
+		usage := "exithide requires three arguments: <#room> <exit> | <dc> [perception|survival], a dc of 0 " +
+			"reveals the exit\nie: " + h.conf.CommandPrefix() + "room exithide #cellar behind the barrels | 15"
+		if len(command) < 4 {
+			s.ChannelMessageSend(m.ChannelID, usage)
+			return
+		}
+		exitname, value, split := SplitExitArgs(command[3:])
+		args := strings.Fields(value)
+		if !split || len(args) < 1 || len(args) > 2 {
+			s.ChannelMessageSend(m.ChannelID, usage)
+			return
+		}
+		dc, err := strconv.Atoi(args[0])
+		if err != nil || dc < 0 {
+			s.ChannelMessageSend(m.ChannelID, usage)
+			return
+		}
+		skill := ""
+		if len(args) == 2 {
+			skill = args[1]
+		}
+		err = h.SetExitHidden(exitname, dc, skill, command[2])
+		if err != nil {
+			s.ChannelMessageSend(m.ChannelID, "Error hiding exit: "+err.Error())
+			return
+		}
+		if dc == 0 {
+			s.ChannelMessageSend(m.ChannelID, "Exit "+exitname+" is no longer hidden")
+			return
+		}
+		s.ChannelMessageSend(m.ChannelID, "Exit "+exitname+" hidden with DC "+args[0])
+		return
+	}
+	if command[1] == "bindpoint" {
+		if len(command) < 4 || (command[3] != "on" && command[3] != "off") {
+			s.ChannelMessageSend(m.ChannelID, "bindpoint requires two arguments: <#room> <on|off>")
+			return
+		}
+		room, err := h.rooms.GetRoomByID(CleanChannel(command[2]))
+		if err != nil {
+			s.ChannelMessageSend(m.ChannelID, "Error retrieving room: "+err.Error())
+			return
+		}
+		room.BindPoint = command[3] == "on"
+		err = h.rooms.SaveRoomToDB(room)
+		if err != nil {
+			s.ChannelMessageSend(m.ChannelID, "Error updating DB: "+err.Error())
+			return
+		}
+		if room.BindPoint {
+			s.ChannelMessageSend(m.ChannelID, room.Name+" is now a bind point")
+			return
+		}
+		s.ChannelMessageSend(m.ChannelID, room.Name+" is no longer a bind point")
 		return
 	}
 	if command[1] == "remove" {
@@ -512,7 +727,7 @@ func (h *RoomsHandler) ParseCommand(command []string, s *discordgo.Session, m *d
 }
 
 // CreateManagementRooms function - Useful for creating default management roles and rooms for new guilds
-func (h *RoomsHandler) CreateManagementRooms(guildID string, s *discordgo.Session) (err error) {
+func (h *RoomsHandler) CreateManagementRooms(guildID string, s DiscordSession) (err error) {
 
 	// Create default developers role
 	developerperms := h.perm.CreatePermissionInt(RolePermissions{})
@@ -561,7 +776,7 @@ func (h *RoomsHandler) CreateManagementRooms(guildID string, s *discordgo.Sessio
 	}
 
 	// Developers
-	_, err = h.AddRoom(s, "developers", guildID, "Management", "", "", 0, true)
+	_, _, err = h.AddRoom(s, "developers", guildID, "Management", "", "", 0, true)
 	if err != nil {
 		if !strings.Contains(err.Error(), "already exists") {
 			return err
@@ -600,7 +815,7 @@ func (h *RoomsHandler) CreateManagementRooms(guildID string, s *discordgo.Sessio
 	}
 
 	// Admin
-	_, err = h.AddRoom(s, "admins", guildID, "Management", "", "", 0, true)
+	_, _, err = h.AddRoom(s, "admins", guildID, "Management", "", "", 0, true)
 	if err != nil {
 		if !strings.Contains(err.Error(), "already exists") {
 			return err
@@ -633,7 +848,7 @@ func (h *RoomsHandler) CreateManagementRooms(guildID string, s *discordgo.Sessio
 	}
 
 	// Builder
-	_, err = h.AddRoom(s, "builders", guildID, "Management", "", "", 0, true)
+	_, _, err = h.AddRoom(s, "builders", guildID, "Management", "", "", 0, true)
 	if err != nil {
 		if !strings.Contains(err.Error(), "already exists") {
 			return err
@@ -666,7 +881,7 @@ func (h *RoomsHandler) CreateManagementRooms(guildID string, s *discordgo.Sessio
 	}
 
 	// Moderator
-	_, err = h.AddRoom(s, "moderators", guildID, "Management", "", "", 0, true)
+	_, _, err = h.AddRoom(s, "moderators", guildID, "Management", "", "", 0, true)
 	if err != nil {
 		if !strings.Contains(err.Error(), "already exists") {
 			return err
@@ -699,7 +914,7 @@ func (h *RoomsHandler) CreateManagementRooms(guildID string, s *discordgo.Sessio
 	}
 
 	// Writer
-	_, err = h.AddRoom(s, "writers", guildID, "Management", "", "", 0, true)
+	_, _, err = h.AddRoom(s, "writers", guildID, "Management", "", "", 0, true)
 	if err != nil {
 		if !strings.Contains(err.Error(), "already exists") {
 			return err
@@ -735,7 +950,7 @@ func (h *RoomsHandler) CreateManagementRooms(guildID string, s *discordgo.Sessio
 }
 
 // CreateDefaultRoles function
-func (h *RoomsHandler) CreateDefaultRoles(guildID string, s *discordgo.Session) (err error) {
+func (h *RoomsHandler) CreateDefaultRoles(guildID string, s DiscordSession) (err error) {
 	// Create default registered usermanager role
 	registeredperms := h.perm.CreatePermissionInt(RolePermissions{})
 	_, err = h.perm.CreateRole("Registered", guildID, false, false, 16777215, registeredperms, s)
@@ -792,7 +1007,7 @@ func (h *RoomsHandler) CreateDefaultRoles(guildID string, s *discordgo.Session)
 }
 
 // CreateOOCChannels function
-func (h *RoomsHandler) CreateOOCChannels(guildID string, s *discordgo.Session) (err error) {
+func (h *RoomsHandler) CreateOOCChannels(guildID string, s DiscordSession) (err error) {
 
 	everyoneID, err := getGuildEveryoneRoleID(s, guildID)
 	if err != nil {
@@ -803,7 +1018,7 @@ func (h *RoomsHandler) CreateOOCChannels(guildID string, s *discordgo.Session) (
 	alloweveryoneperms := h.perm.CreatePermissionInt(RolePermissions{ViewChannel: true, ReadMessageHistory: true})
 
 	// rules
-	_, err = h.AddRoom(s, "rules", guildID, "Lobby", "", "", 0, true)
+	_, _, err = h.AddRoom(s, "rules", guildID, "Lobby", "", "", 0, true)
 	if err != nil {
 		if !strings.Contains(err.Error(), "already exists") {
 			return err
@@ -850,7 +1065,7 @@ func (h *RoomsHandler) CreateOOCChannels(guildID string, s *discordgo.Session) (
 	denymoderatorperms := h.perm.CreatePermissionInt(RolePermissions{})
 
 	// ooc
-	_, err = h.AddRoom(s, "ooc", guildID, "OOC", "", "", 0, true)
+	_, _, err = h.AddRoom(s, "ooc", guildID, "OOC", "", "", 0, true)
 	if err != nil {
 		if !strings.Contains(err.Error(), "already exists") {
 			return err
@@ -887,7 +1102,7 @@ func (h *RoomsHandler) CreateOOCChannels(guildID string, s *discordgo.Session) (
 	}
 
 	// trades
-	_, err = h.AddRoom(s, "trades", guildID, "OOC", "", "", 0, true)
+	_, _, err = h.AddRoom(s, "trades", guildID, "OOC", "", "", 0, true)
 	if err != nil {
 		if !strings.Contains(err.Error(), "already exists") {
 			return err
@@ -924,7 +1139,7 @@ func (h *RoomsHandler) CreateOOCChannels(guildID string, s *discordgo.Session) (
 	}
 
 	// help
-	_, err = h.AddRoom(s, "help", guildID, "OOC", "", "", 0, true)
+	_, _, err = h.AddRoom(s, "help", guildID, "OOC", "", "", 0, true)
 	if err != nil {
 		if !strings.Contains(err.Error(), "already exists") {
 			return err
@@ -962,7 +1177,7 @@ func (h *RoomsHandler) CreateOOCChannels(guildID string, s *discordgo.Session) (
 
 	// spoilers
 	// We want this role to be created
-	_, err = h.AddRoom(s, "spoilers", guildID, "OOC", "", "", 0, false)
+	_, _, err = h.AddRoom(s, "spoilers", guildID, "OOC", "", "", 0, false)
 	if err != nil {
 		if !strings.Contains(err.Error(), "already exists") {
 			return err
@@ -995,7 +1210,7 @@ func (h *RoomsHandler) CreateOOCChannels(guildID string, s *discordgo.Session) (
 	}
 
 	// bugs
-	_, err = h.AddRoom(s, "bugs", guildID, "OOC", "", "", 0, true)
+	_, _, err = h.AddRoom(s, "bugs", guildID, "OOC", "", "", 0, true)
 	if err != nil {
 		if !strings.Contains(err.Error(), "already exists") {
 			return err
@@ -1035,17 +1250,42 @@ func (h *RoomsHandler) CreateOOCChannels(guildID string, s *discordgo.Session) (
 }
 
 // AddRoom function
-func (h *RoomsHandler) AddRoom(s *discordgo.Session, name string, guildID string, parentname string,
-	transferInvite string, transferRoomID string, color int, overriderole bool) (createdroom *discordgo.Channel, err error) {
+// Rooms that don't fit in guildID are created in its overflow guild instead, see guild_capacity.go, placedID is the
+// guild the room ended up in. Rooms with overriderole set are part of a guild's own setup and are never moved
+// elsewhere.
+func (h *RoomsHandler) AddRoom(s DiscordSession, name string, guildID string, parentname string,
+	transferInvite string, transferRoomID string, color int, overriderole bool) (createdroom *discordgo.Channel,
+	placedID string, err error) {
 
-	rooms, err := h.rooms.GetAllRooms()
+	_, err = h.rooms.GetRoomByName(name, guildID)
+	if err == nil {
+		return createdroom, guildID, errors.New("Room already exists in database")
+	}
+
+	capacity, err := h.GetGuildCapacity(guildID, s)
 	if err != nil {
-		return createdroom, err
+		return createdroom, guildID, err
 	}
-	if len(rooms) >= 80 {
-		return createdroom, errors.New("Maximum supported rooms reached")
+	if capacity.RoomsLeft() < 1 {
+		if overriderole {
+			return createdroom, guildID, errors.New("Guild is full: " + capacity.String())
+		}
+
+		guildID, err = h.PlaceOverflowRoom(guildID, parentname, s)
+		if err != nil {
+			return createdroom, guildID, err
+		}
 	}
 
+	createdroom, err = h.createRoom(s, name, guildID, parentname, transferInvite, transferRoomID, color, overriderole)
+	return createdroom, guildID, err
+}
+
+// createRoom function
+// Creates the channel, role and record for a room without checking the guild has space for it
+func (h *RoomsHandler) createRoom(s DiscordSession, name string, guildID string, parentname string,
+	transferInvite string, transferRoomID string, color int, overriderole bool) (createdroom *discordgo.Channel, err error) {
+
 	if transferRoomID != "" {
 		_, err := s.Channel(transferRoomID)
 		if err != nil {
@@ -1186,112 +1426,13 @@ func (h *RoomsHandler) AddRoom(s *discordgo.Session, name string, guildID string
 }
 
 // RemoveRoom function
-func (h *RoomsHandler) RemoveRoom(s *discordgo.Session, name string, guildID string) (err error) {
+func (h *RoomsHandler) RemoveRoom(s DiscordSession, name string, guildID string) (err error) {
 
 	existingrecord, err := h.rooms.GetRoomByName(name, guildID)
 	if err != nil {
 		return err
 	}
 
-	if existingrecord.DownID != "" {
-		linkedroom, err := h.rooms.GetRoomByID(existingrecord.DownID)
-		if err == nil {
-			linkedroom.UpID = ""
-			err = h.rooms.SaveRoomToDB(linkedroom)
-			if err != nil {
-				return err
-			}
-		}
-	}
-
-	if existingrecord.NorthID != "" {
-		linkedroom, err := h.rooms.GetRoomByID(existingrecord.NorthID)
-		if err == nil {
-			linkedroom.SouthID = ""
-			err = h.rooms.SaveRoomToDB(linkedroom)
-			if err != nil {
-				return err
-			}
-		}
-	}
-
-	if existingrecord.NorthEastID != "" {
-		linkedroom, err := h.rooms.GetRoomByID(existingrecord.NorthEastID)
-		if err == nil {
-			linkedroom.SouthWestID = ""
-			err = h.rooms.SaveRoomToDB(linkedroom)
-			if err != nil {
-				return err
-			}
-		}
-	}
-
-	if existingrecord.EastID != "" {
-		linkedroom, err := h.rooms.GetRoomByID(existingrecord.EastID)
-		if err == nil {
-			linkedroom.WestID = ""
-			err = h.rooms.SaveRoomToDB(linkedroom)
-			if err != nil {
-				return err
-			}
-		}
-	}
-
-	if existingrecord.SouthEastID != "" {
-		linkedroom, err := h.rooms.GetRoomByID(existingrecord.SouthEastID)
-		if err == nil {
-			linkedroom.NorthWestID = ""
-			err = h.rooms.SaveRoomToDB(linkedroom)
-			if err != nil {
-				return err
-			}
-		}
-	}
-
-	if existingrecord.SouthID != "" {
-		linkedroom, err := h.rooms.GetRoomByID(existingrecord.SouthID)
-		if err == nil {
-			linkedroom.NorthID = ""
-			err = h.rooms.SaveRoomToDB(linkedroom)
-			if err != nil {
-				return err
-			}
-		}
-	}
-
-	if existingrecord.SouthWestID != "" {
-		linkedroom, err := h.rooms.GetRoomByID(existingrecord.SouthWestID)
-		if err == nil {
-			linkedroom.NorthEastID = ""
-			err = h.rooms.SaveRoomToDB(linkedroom)
-			if err != nil {
-				return err
-			}
-		}
-	}
-
-	if existingrecord.WestID != "" {
-		linkedroom, err := h.rooms.GetRoomByID(existingrecord.WestID)
-		if err == nil {
-			linkedroom.EastID = ""
-			err = h.rooms.SaveRoomToDB(linkedroom)
-			if err != nil {
-				return err
-			}
-		}
-	}
-
-	if existingrecord.NorthWestID != "" {
-		linkedroom, err := h.rooms.GetRoomByID(existingrecord.NorthWestID)
-		if err == nil {
-			linkedroom.SouthEastID = ""
-			err = h.rooms.SaveRoomToDB(linkedroom)
-			if err != nil {
-				return err
-			}
-		}
-	}
-
 	// Now clean up travel records
 	rooms, err := h.rooms.GetAllRooms()
 	if err != nil {
@@ -1299,6 +1440,14 @@ func (h *RoomsHandler) RemoveRoom(s *discordgo.Session, name string, guildID str
 	}
 
 	for _, search := range rooms {
+		// Exits into this room, including one way exits, have nowhere to go anymore
+		if search.RemoveExitsTo(existingrecord.ID) > 0 {
+			err = h.rooms.SaveRoomToDB(search)
+			if err != nil {
+				return err
+			}
+		}
+
 		if search.TransferRoomID != "" {
 			// If the search transfer room ID points to this room
 			// update the search record so that it is not anymore
@@ -1334,7 +1483,7 @@ func (h *RoomsHandler) RemoveRoom(s *discordgo.Session, name string, guildID str
 }
 
 // MoveRoom function
-func (h *RoomsHandler) MoveRoom(s *discordgo.Session, channelID string, guildID string, parentname string) (err error) {
+func (h *RoomsHandler) MoveRoom(s DiscordSession, channelID string, guildID string, parentname string) (err error) {
 
 	if parentname == "" {
 		return errors.New("No parent category supplied")
@@ -1377,7 +1526,7 @@ func (h *RoomsHandler) MoveRoom(s *discordgo.Session, channelID string, guildID
 }
 
 // AddUserIDToRoomRecord function
-func (h *RoomsHandler) AddUserIDToRoomRecord(userID string, roomID string, guildID string, s *discordgo.Session) (err error) {
+func (h *RoomsHandler) AddUserIDToRoomRecord(userID string, roomID string, guildID string, s DiscordSession) (err error) {
 
 	room, err := h.rooms.GetRoomByID(roomID)
 	if err != nil {
@@ -1425,7 +1574,7 @@ func (h *RoomsHandler) RemoveUserIDFromRoomRecord(userID string, roomID string)
 }
 
 // SetupNewServer function
-func (h *RoomsHandler) SetupNewServer(s *discordgo.Session, m *discordgo.MessageCreate) (err error) {
+func (h *RoomsHandler) SetupNewServer(s DiscordSession, m *discordgo.MessageCreate) (err error) {
 
 	guildID, err := getGuildID(s, m.ChannelID)
 	if err != nil {
@@ -1465,7 +1614,7 @@ func (h *RoomsHandler) SetupNewServer(s *discordgo.Session, m *discordgo.Message
 }
 
 // SetTravelRole function
-func (h *RoomsHandler) SetTravelRole(rolename string, roomID string, s *discordgo.Session, m *discordgo.MessageCreate) (err error) {
+func (h *RoomsHandler) SetTravelRole(rolename string, roomID string, s DiscordSession, m *discordgo.MessageCreate) (err error) {
 
 	room, err := h.rooms.GetRoomByID(roomID)
 	if err != nil {
@@ -1520,7 +1669,7 @@ func (h *RoomsHandler) SyncRolePermissions(roomID string) (err error) {
 }
 
 // LinkRole function
-func (h *RoomsHandler) LinkRole(rolename string, roomID string, s *discordgo.Session, m *discordgo.MessageCreate) {
+func (h *RoomsHandler) LinkRole(rolename string, roomID string, s DiscordSession, m *discordgo.MessageCreate) {
 
 	guildID, err := getGuildID(s, m.ChannelID)
 	if err != nil {
@@ -1576,7 +1725,7 @@ func (h *RoomsHandler) LinkRole(rolename string, roomID string, s *discordgo.Ses
 }
 
 // UnLinkRole function
-func (h *RoomsHandler) UnLinkRole(rolename string, roomID string, s *discordgo.Session, m *discordgo.MessageCreate) {
+func (h *RoomsHandler) UnLinkRole(rolename string, roomID string, s DiscordSession, m *discordgo.MessageCreate) {
 
 	guildID, err := getGuildID(s, m.ChannelID)
 	if err != nil {
@@ -1635,7 +1784,9 @@ func (h *RoomsHandler) FormatRoomInfo(roomID string) (formatted string, err erro
 	output = output + "GuildTransferInvite: " + room.GuildTransferInvite + "\n"
 	output = output + "TransferRoomID: " + room.TransferRoomID + "\n\n"
 	output = output + "ParentID: " + room.ParentID + "\n"
-	output = output + "ParentName: " + room.ParentName + "\n\n"
+	output = output + "ParentName: " + room.ParentName + "\n"
+	output = output + "ZoneID: " + room.ZoneID + "\n"
+	output = output + "BindPoint: " + strconv.FormatBool(room.BindPoint) + "\n\n"
 	output = output + "TravelRoleID: " + room.TravelRoleID + "\n"
 	output = output + "Current User Count: " + strconv.Itoa(len(room.UserIDs)) + "\n"
 	roles := ""
@@ -1649,345 +1800,360 @@ func (h *RoomsHandler) FormatRoomInfo(roomID string) (formatted string, err erro
 	output = output + "RoleID: " + roles + "\n\n"
 	output = output + "Description: " + room.Description + "\n\n"
 
-	if room.UpID != "" {
-		linkedroom, err := h.rooms.GetRoomByID(room.UpID)
+	for _, exit := range room.Exits {
+		linkedroom, err := h.rooms.GetRoomByID(exit.TargetID)
 		if err != nil {
 			return formatted, err
 		}
-		output = output + "Up Room: " + room.UpID + " - " + linkedroom.Name + " \n"
-	}
-	if len(room.UpItemID) > 0 {
-		itemoutput := ""
-		for _, item := range room.UpItemID {
-			itemoutput = itemoutput + ", " + item
+		output = output + "Exit " + exit.Name + ": " + exit.TargetID + " - " + linkedroom.Name + " \n"
+		if len(exit.Aliases) > 0 {
+			output = output + "    Aliases: " + strings.Join(exit.Aliases, ", ") + "\n"
+		}
+		if exit.ReverseName != "" {
+			output = output + "    Reverse: " + exit.ReverseName + "\n"
+		}
+		if exit.Cost > 0 {
+			output = output + "    Cost: " + strconv.Itoa(exit.Cost) + "\n"
+		}
+		if exit.IsHidden() {
+			output = output + "    Hidden: DC " + strconv.Itoa(exit.HiddenDC) + " " + exit.SearchSkill() + "\n"
+		}
+		output = output + "    Departure: " + exit.Departure + "\n"
+		output = output + "    Arrival: " + exit.Arrival + "\n"
+		if len(exit.Requirements) > 0 {
+			output = output + "    Requirements: " + FormatExitRequirements(exit) + "\n"
+			output = output + "    Locked: " + exit.LockedText() + "\n"
 		}
-		output = output + "Up Room Required Items: " + itemoutput + "\n"
 	}
 
-	if room.DownID != "" {
-		linkedroom, err := h.rooms.GetRoomByID(room.DownID)
-		if err != nil {
-			return formatted, err
-		}
-		output = output + "Down Room: " + room.DownID + " - " + linkedroom.Name + " \n"
+	output = output + "\n```\n"
+	return output, nil
+}
+
+// ViewRoom function
+func (h *RoomsHandler) ViewRoom(roomID string, s DiscordSession, m *discordgo.MessageCreate) {
+	formatted, err := h.FormatRoomInfo(roomID)
+	if err != nil {
+		s.ChannelMessageSend(m.ChannelID, "Error Retrieving Room: "+err.Error())
+		return
 	}
-	if len(room.DownItemID) > 0 {
-		itemoutput := ""
-		for _, item := range room.DownItemID {
-			itemoutput = itemoutput + ", " + item
-		}
-		output = output + "Up Room Required Items: " + itemoutput + "\n"
+
+	s.ChannelMessageSend(m.ChannelID, "Room Details: "+formatted)
+	return
+}
+
+// LinkDirection function
+// Adds an exit from one room to another, and the reverse exit back if there is one. Standard directions get their
+// opposite as a reverse unless one is given, anything else is one way unless a reverse is given.
+func (h *RoomsHandler) LinkDirection(exitname string, reversename string, fromroomID string, toroomID string,
+	s DiscordSession, m *discordgo.MessageCreate) {
+
+	guildID, err := getGuildID(s, m.ChannelID)
+	if err != nil {
+		s.ChannelMessageSend(m.ChannelID, "Could not retrieve GuildID: "+err.Error())
+		return
 	}
 
-	if room.NorthID != "" {
-		linkedroom, err := h.rooms.GetRoomByID(room.NorthID)
-		if err != nil {
-			return formatted, err
-		}
-		output = output + "North Room: " + room.NorthID + " - " + linkedroom.Name + " \n"
+	exit, err := h.LinkRooms(exitname, reversename, fromroomID, toroomID, guildID)
+	if err != nil {
+		s.ChannelMessageSend(m.ChannelID, err.Error())
+		return
 	}
-	if len(room.NorthItemID) > 0 {
-		itemoutput := ""
-		for _, item := range room.NorthItemID {
-			itemoutput = itemoutput + ", " + item
-		}
-		output = output + "Up Room Required Items: " + itemoutput + "\n"
+
+	fromroom, _ := h.rooms.GetRoomByID(CleanChannel(fromroomID))
+	toroom, _ := h.rooms.GetRoomByID(exit.TargetID)
+	if exit.ReverseName == "" {
+		s.ChannelMessageSend(m.ChannelID, "Room "+fromroom.Name+" linked to "+toroom.Name+" through "+exit.Name+
+			" (one way)")
+		return
 	}
+	s.ChannelMessageSend(m.ChannelID, "Room "+fromroom.Name+" linked to "+toroom.Name+" through "+exit.Name+
+		", and back through "+exit.ReverseName)
+	return
+}
 
-	if room.NorthEastID != "" {
-		linkedroom, err := h.rooms.GetRoomByID(room.NorthEastID)
-		if err != nil {
-			return formatted, err
-		}
-		output = output + "NorthEast Room: " + room.NorthEastID + " - " + linkedroom.Name + " \n"
+// LinkRooms function
+// Does the work for LinkDirection without reporting to a channel, both rooms must be in guildID
+func (h *RoomsHandler) LinkRooms(exitname string, reversename string, fromroomID string, toroomID string,
+	guildID string) (exit Exit, err error) {
+
+	fromroomID = CleanChannel(fromroomID)
+	fromroom, err := h.rooms.GetRoomByID(fromroomID)
+	if err != nil {
+		return exit, errors.New("Error retrieving fromroomID: " + err.Error())
 	}
-	if len(room.NorthEastItemID) > 0 {
-		itemoutput := ""
-		for _, item := range room.NorthEastItemID {
-			itemoutput = itemoutput + ", " + item
-		}
-		output = output + "Up Room Required Items: " + itemoutput + "\n"
+	if fromroom.GuildID != guildID {
+		return exit, errors.New("Guild ID's Do Not Match: " + fromroom.GuildID)
 	}
 
-	if room.EastID != "" {
-		linkedroom, err := h.rooms.GetRoomByID(room.EastID)
-		if err != nil {
-			return formatted, err
-		}
-		output = output + "East Room: " + room.EastID + " - " + linkedroom.Name + " \n"
+	toroomID = CleanChannel(toroomID)
+	toroom, err := h.rooms.GetRoomByID(toroomID)
+	if err != nil {
+		return exit, errors.New("Error retrieving toroomID: " + err.Error())
 	}
-	if len(room.EastItemID) > 0 {
-		itemoutput := ""
-		for _, item := range room.EastItemID {
-			itemoutput = itemoutput + ", " + item
-		}
-		output = output + "Up Room Required Items: " + itemoutput + "\n"
+	if toroom.GuildID != guildID {
+		return exit, errors.New("Guild ID's Do Not Match: " + toroom.GuildID)
+	}
+
+	exit = NewExit(exitname, toroom.ID, reversename, fromroom)
+	err = fromroom.AddExit(exit)
+	if err != nil {
+		return exit, errors.New("Could not add exit to " + fromroom.Name + ": " + err.Error())
 	}
 
-	if room.SouthEastID != "" {
-		linkedroom, err := h.rooms.GetRoomByID(room.SouthEastID)
+	if exit.ReverseName != "" {
+		if fromroom.ID == toroom.ID {
+			err = fromroom.AddExit(NewExit(exit.ReverseName, fromroom.ID, exit.Name, toroom))
+		} else {
+			err = toroom.AddExit(NewExit(exit.ReverseName, fromroom.ID, exit.Name, toroom))
+		}
 		if err != nil {
-			return formatted, err
+			return exit, errors.New("Could not add reverse exit to " + toroom.Name + ": " + err.Error())
 		}
-		output = output + "SouthEast Room: " + room.SouthEastID + " - " + linkedroom.Name + " \n"
 	}
-	if len(room.SouthEastItemID) > 0 {
-		itemoutput := ""
-		for _, item := range room.SouthEastItemID {
-			itemoutput = itemoutput + ", " + item
-		}
-		output = output + "Up Room Required Items: " + itemoutput + "\n"
+
+	err = h.rooms.SaveRoomToDB(fromroom)
+	if err != nil {
+		return exit, errors.New("Error updating DB: " + err.Error())
 	}
 
-	if room.SouthID != "" {
-		linkedroom, err := h.rooms.GetRoomByID(room.SouthID)
+	if exit.ReverseName != "" && fromroom.ID != toroom.ID {
+		err = h.rooms.SaveRoomToDB(toroom)
 		if err != nil {
-			return formatted, err
+			return exit, errors.New("Error updating DB: " + err.Error())
 		}
-		output = output + "South Room: " + room.SouthID + " - " + linkedroom.Name + " \n"
 	}
-	if len(room.SouthItemID) > 0 {
-		itemoutput := ""
-		for _, item := range room.SouthItemID {
-			itemoutput = itemoutput + ", " + item
-		}
-		output = output + "Up Room Required Items: " + itemoutput + "\n"
+	return exit, nil
+}
+
+// UnlinkDirection function
+// Removes an exit, and its reverse if the reverse still leads back here
+func (h *RoomsHandler) UnlinkDirection(exitname string, roomID string) (err error) {
+
+	room, err := h.rooms.GetRoomByID(CleanChannel(roomID))
+	if err != nil {
+		return err
 	}
 
-	if room.SouthWestID != "" {
-		linkedroom, err := h.rooms.GetRoomByID(room.SouthWestID)
-		if err != nil {
-			return formatted, err
-		}
-		output = output + "SouthWest Room: " + room.SouthWestID + " - " + linkedroom.Name + " \n"
+	exit, found := room.RemoveExit(exitname)
+	if !found {
+		return errors.New("No exit named " + exitname + " in " + room.Name)
 	}
-	if len(room.SouthWestItemID) > 0 {
-		itemoutput := ""
-		for _, item := range room.SouthWestItemID {
-			itemoutput = itemoutput + ", " + item
-		}
-		output = output + "Up Room Required Items: " + itemoutput + "\n"
+
+	err = h.rooms.SaveRoomToDB(room)
+	if err != nil {
+		return err
 	}
 
-	if room.WestID != "" {
-		linkedroom, err := h.rooms.GetRoomByID(room.WestID)
-		if err != nil {
-			return formatted, err
-		}
-		output = output + "West Room: " + room.WestID + " - " + linkedroom.Name + " \n"
+	if exit.ReverseName == "" {
+		return nil
 	}
-	if len(room.WestItemID) > 0 {
-		itemoutput := ""
-		for _, item := range room.WestItemID {
-			itemoutput = itemoutput + ", " + item
-		}
-		output = output + "Up Room Required Items: " + itemoutput + "\n"
+
+	target, err := h.rooms.GetRoomByID(exit.TargetID)
+	if err != nil {
+		// The target is already gone, so there's no reverse to clean up
+		return nil
 	}
+	reverse, found := target.GetExit(exit.ReverseName)
+	if !found || reverse.TargetID != room.ID {
+		return nil
+	}
+	target.RemoveExit(reverse.Name)
+	return h.rooms.SaveRoomToDB(target)
+}
 
-	if room.NorthWestID != "" {
-		linkedroom, err := h.rooms.GetRoomByID(room.NorthWestID)
-		if err != nil {
-			return formatted, err
-		}
-		output = output + "NorthWest Room: " + room.NorthWestID + " - " + linkedroom.Name + " \n"
+// SetExitAlias function
+func (h *RoomsHandler) SetExitAlias(exitname string, alias string, roomID string) (err error) {
+
+	room, err := h.rooms.GetRoomByID(CleanChannel(roomID))
+	if err != nil {
+		return err
 	}
-	if len(room.NorthWestItemID) > 0 {
-		itemoutput := ""
-		for _, item := range room.NorthWestItemID {
-			itemoutput = itemoutput + ", " + item
-		}
-		output = output + "Up Room Required Items: " + itemoutput + "\n"
+
+	exit, found := room.GetExit(exitname)
+	if !found {
+		return errors.New("No exit named " + exitname + " in " + room.Name)
 	}
 
-	output = output + "\n```\n"
-	return output, nil
+	alias = CleanExitName(alias)
+	if existing, used := room.GetExit(alias); used {
+		return errors.New(alias + " is already used by exit " + existing.Name)
+	}
+
+	exit.Aliases = append(exit.Aliases, alias)
+	err = room.UpdateExit(exit)
+	if err != nil {
+		return err
+	}
+	return h.rooms.SaveRoomToDB(room)
 }
 
-// ViewRoom function
-func (h *RoomsHandler) ViewRoom(roomID string, s *discordgo.Session, m *discordgo.MessageCreate) {
-	formatted, err := h.FormatRoomInfo(roomID)
+// SetExitPhrase function
+// phrase is either departure or arrival
+func (h *RoomsHandler) SetExitPhrase(exitname string, phrase string, text string, roomID string) (err error) {
+
+	room, err := h.rooms.GetRoomByID(CleanChannel(roomID))
 	if err != nil {
-		s.ChannelMessageSend(m.ChannelID, "Error Retrieving Room: "+err.Error())
-		return
+		return err
 	}
 
-	s.ChannelMessageSend(m.ChannelID, "Room Details: "+formatted)
-	return
-}
+	exit, found := room.GetExit(exitname)
+	if !found {
+		return errors.New("No exit named " + exitname + " in " + room.Name)
+	}
 
-// LinkDirection function
-func (h *RoomsHandler) LinkDirection(direction string, fromroomID string, toroomID string, s *discordgo.Session, m *discordgo.MessageCreate) {
+	text = strings.TrimSpace(text)
+	if strings.ToLower(phrase) == "departure" {
+		exit.Departure = text
+	} else if strings.ToLower(phrase) == "arrival" {
+		exit.Arrival = text
+	} else {
+		return errors.New("Unrecognized phrase: " + phrase + " (expected departure or arrival)")
+	}
 
-	guildID, err := getGuildID(s, m.ChannelID)
+	err = room.UpdateExit(exit)
 	if err != nil {
-		s.ChannelMessageSend(m.ChannelID, "Could not retrieve GuildID: "+err.Error())
-		return
+		return err
 	}
+	return h.rooms.SaveRoomToDB(room)
+}
 
-	fromroomID = CleanChannel(fromroomID)
-	fromroom, err := h.rooms.GetRoomByID(fromroomID)
+// SetExitCost function
+func (h *RoomsHandler) SetExitCost(exitname string, cost int, roomID string) (err error) {
+
+	room, err := h.rooms.GetRoomByID(CleanChannel(roomID))
 	if err != nil {
-		s.ChannelMessageSend(m.ChannelID, "Error retrieving fromroomID: "+err.Error())
-		return
+		return err
 	}
-	if fromroom.GuildID != guildID {
-		s.ChannelMessageSend(m.ChannelID, "Guild ID's Do Not Match: "+err.Error())
-		return
+
+	exit, found := room.GetExit(exitname)
+	if !found {
+		return errors.New("No exit named " + exitname + " in " + room.Name)
 	}
 
-	toroomID = CleanChannel(toroomID)
-	toroom, err := h.rooms.GetRoomByID(toroomID)
+	exit.Cost = cost
+	err = room.UpdateExit(exit)
 	if err != nil {
-		s.ChannelMessageSend(m.ChannelID, "Error retrieving toroomID: "+err.Error())
-		return
-	}
-	if toroom.GuildID != guildID {
-		s.ChannelMessageSend(m.ChannelID, "Guild ID's Do Not Match: "+err.Error())
-		return
+		return err
 	}
+	return h.rooms.SaveRoomToDB(room)
+}
 
-	direction = strings.ToLower(direction)
+// SetExitHidden function
+func (h *RoomsHandler) SetExitHidden(exitname string, dc int, skill string, roomID string) (err error) {
 
-	linked, err := h.rooms.IsRoomLinkedTo(fromroomID, toroomID)
+	room, err := h.rooms.GetRoomByID(CleanChannel(roomID))
 	if err != nil {
-		s.ChannelMessageSend(m.ChannelID, "Error validating room link: "+err.Error())
-		return
+		return err
 	}
-	if linked {
-		s.ChannelMessageSend(m.ChannelID, "Rooms already linked!")
-		return
+
+	exit, found := room.GetExit(exitname)
+	if !found {
+		return errors.New("No exit named " + exitname + " in " + room.Name)
 	}
 
-	if direction == "up" {
-		if toroom.DownID != "" {
-			s.ChannelMessageSend(m.ChannelID, "To room Down is already linked to: "+toroom.DownID)
-			return
-		}
-		if fromroom.UpID != "" {
-			s.ChannelMessageSend(m.ChannelID, "From from Up is already linked to: "+fromroom.UpID)
-			return
+	exit.HiddenSkill = ""
+	if dc > 0 {
+		exit.HiddenSkill, err = ParseHiddenSkill(skill)
+		if err != nil {
+			return err
 		}
-		toroom.DownID = fromroomID
-		fromroom.UpID = toroomID
-	} else if direction == "down" {
+	}
+	exit.HiddenDC = dc
+	err = room.UpdateExit(exit)
+	if err != nil {
+		return err
+	}
+	return h.rooms.SaveRoomToDB(room)
+}
 
-		if toroom.UpID != "" {
-			s.ChannelMessageSend(m.ChannelID, "To room Up is already linked to: "+toroom.UpID)
-			return
-		}
-		if fromroom.DownID != "" {
-			s.ChannelMessageSend(m.ChannelID, "From from Down is already linked to: "+fromroom.DownID)
-			return
-		}
-		toroom.UpID = fromroomID
-		fromroom.DownID = toroomID
-	} else if direction == "north" {
-		if toroom.SouthID != "" {
-			s.ChannelMessageSend(m.ChannelID, "To room South is already linked to: "+toroom.SouthID)
-			return
-		}
-		if fromroom.NorthID != "" {
-			s.ChannelMessageSend(m.ChannelID, "From from North is already linked to: "+fromroom.NorthID)
-			return
-		}
-		toroom.SouthID = fromroomID
-		fromroom.NorthID = toroomID
-	} else if direction == "northeast" {
-		if toroom.SouthWestID != "" {
-			s.ChannelMessageSend(m.ChannelID, "To room SouthWest is already linked to: "+toroom.SouthWestID)
-			return
-		}
-		if fromroom.NorthEastID != "" {
-			s.ChannelMessageSend(m.ChannelID, "From from NorthEast is already linked to: "+fromroom.NorthEastID)
-			return
-		}
-		toroom.SouthWestID = fromroomID
-		fromroom.NorthEastID = toroomID
-	} else if direction == "east" {
-		if toroom.WestID != "" {
-			s.ChannelMessageSend(m.ChannelID, "To room West is already linked to: "+toroom.WestID)
-			return
-		}
-		if fromroom.EastID != "" {
-			s.ChannelMessageSend(m.ChannelID, "From from East is already linked to: "+fromroom.EastID)
-			return
-		}
-		toroom.WestID = fromroomID
-		fromroom.EastID = toroomID
-	} else if direction == "southeast" {
-		if toroom.NorthWestID != "" {
-			s.ChannelMessageSend(m.ChannelID, "To room NorthWest is already linked to: "+toroom.NorthWestID)
-			return
-		}
-		if fromroom.SouthEastID != "" {
-			s.ChannelMessageSend(m.ChannelID, "From from SouthEast is already linked to: "+fromroom.SouthEastID)
-			return
-		}
-		toroom.NorthWestID = fromroomID
-		fromroom.SouthEastID = toroomID
-	} else if direction == "south" {
-		if toroom.NorthID != "" {
-			s.ChannelMessageSend(m.ChannelID, "To room North is already linked to: "+toroom.NorthID)
-			return
-		}
-		if fromroom.SouthID != "" {
-			s.ChannelMessageSend(m.ChannelID, "From from South is already linked to: "+fromroom.SouthID)
-			return
-		}
-		toroom.NorthID = fromroomID
-		fromroom.SouthID = toroomID
-	} else if direction == "southwest" {
-		if toroom.NorthEastID != "" {
-			s.ChannelMessageSend(m.ChannelID, "To room NorthEast is already linked to: "+toroom.NorthEastID)
-			return
-		}
-		if fromroom.SouthWestID != "" {
-			s.ChannelMessageSend(m.ChannelID, "From from SouthWest is already linked to: "+fromroom.SouthWestID)
-			return
-		}
-		toroom.NorthEastID = fromroomID
-		fromroom.SouthWestID = toroomID
-	} else if direction == "west" {
-		if toroom.EastID != "" {
-			s.ChannelMessageSend(m.ChannelID, "To room East is already linked to: "+toroom.EastID)
-			return
-		}
-		if fromroom.WestID != "" {
-			s.ChannelMessageSend(m.ChannelID, "From from WestID is already linked to: "+fromroom.WestID)
-			return
-		}
-		toroom.EastID = fromroomID
-		fromroom.WestID = toroomID
-	} else if direction == "northwest" {
-		if toroom.SouthEastID != "" {
-			s.ChannelMessageSend(m.ChannelID, "To room SouthEast is already linked to: "+toroom.SouthEastID)
-			return
-		}
-		if fromroom.NorthEastID != "" {
-			s.ChannelMessageSend(m.ChannelID, "From from NorthEast is already linked to: "+fromroom.NorthEastID)
-			return
+// AddExitRequirement function
+func (h *RoomsHandler) AddExitRequirement(exitname string, requirement ExitRequirement, roomID string) (err error) {
+
+	room, err := h.rooms.GetRoomByID(CleanChannel(roomID))
+	if err != nil {
+		return err
+	}
+
+	exit, found := room.GetExit(exitname)
+	if !found {
+		return errors.New("No exit named " + exitname + " in " + room.Name)
+	}
+
+	for _, existing := range exit.Requirements {
+		if existing.Type == requirement.Type && existing.Value == requirement.Value {
+			return errors.New("Exit " + exit.Name + " already requires " + existing.String())
 		}
-		toroom.SouthEastID = fromroomID
-		fromroom.NorthWestID = toroomID
-	} else {
-		s.ChannelMessageSend(m.ChannelID, "Unrecognized direction: "+direction)
-		return
 	}
 
-	err = h.rooms.SaveRoomToDB(fromroom)
+	exit.Requirements = append(exit.Requirements, requirement)
+	err = room.UpdateExit(exit)
 	if err != nil {
-		s.ChannelMessageSend(m.ChannelID, "Error updating DB: "+err.Error())
-		return
+		return err
 	}
+	return h.rooms.SaveRoomToDB(room)
+}
 
-	err = h.rooms.SaveRoomToDB(toroom)
+// ClearExitRequirements function
+func (h *RoomsHandler) ClearExitRequirements(exitname string, roomID string) (err error) {
+
+	room, err := h.rooms.GetRoomByID(CleanChannel(roomID))
 	if err != nil {
-		s.ChannelMessageSend(m.ChannelID, "Error updating DB: "+err.Error())
-		return
+		return err
 	}
 
-	s.ChannelMessageSend(m.ChannelID, "Room "+fromroom.Name+" linked to "+toroom.Name)
-	return
+	exit, found := room.GetExit(exitname)
+	if !found {
+		return errors.New("No exit named " + exitname + " in " + room.Name)
+	}
+
+	exit.Requirements = nil
+	err = room.UpdateExit(exit)
+	if err != nil {
+		return err
+	}
+	return h.rooms.SaveRoomToDB(room)
+}
+
+// SetExitLockedMessage function
+func (h *RoomsHandler) SetExitLockedMessage(exitname string, message string, roomID string) (err error) {
+
+	room, err := h.rooms.GetRoomByID(CleanChannel(roomID))
+	if err != nil {
+		return err
+	}
+
+	exit, found := room.GetExit(exitname)
+	if !found {
+		return errors.New("No exit named " + exitname + " in " + room.Name)
+	}
+
+	exit.LockedMessage = strings.TrimSpace(message)
+	err = room.UpdateExit(exit)
+	if err != nil {
+		return err
+	}
+	return h.rooms.SaveRoomToDB(room)
+}
+
+// FormatExitRequirements function
+func FormatExitRequirements(exit Exit) (formatted string) {
+	var requirements []string
+	for _, requirement := range exit.Requirements {
+		requirements = append(requirements, requirement.String())
+	}
+	return strings.Join(requirements, ", ")
+}
+
+// SplitExitArgs function
+// Exit names can contain spaces, so a second value is separated from them with a |
+func SplitExitArgs(args []string) (left string, right string, split bool) {
+	joined := strings.Join(args, " ")
+	index := strings.Index(joined, "|")
+	if index < 0 {
+		return strings.TrimSpace(joined), "", false
+	}
+	return strings.TrimSpace(joined[:index]), strings.TrimSpace(joined[index+1:]), true
 }
 
 // Get Set for various room details
@@ -2007,7 +2173,7 @@ func (h *RoomsHandler) GetRoomDescription(roomID string) (formatted string, err
 }
 
 // SetRoomDescription function
-func (h *RoomsHandler) SetRoomDescription(roomID string, description string, s *discordgo.Session) (err error) {
+func (h *RoomsHandler) SetRoomDescription(roomID string, description string, s DiscordSession) (err error) {
 
 	roomID = CleanChannel(roomID)
 
@@ -2041,7 +2207,7 @@ func (h *RoomsHandler) GetRoomTransferInvite(roomID string) (formatted string, e
 }
 
 // SetRoomTransferInvite function
-func (h *RoomsHandler) SetRoomTransferInvite(roomID string, invite string, s *discordgo.Session, m *discordgo.MessageCreate) (err error) {
+func (h *RoomsHandler) SetRoomTransferInvite(roomID string, invite string, s DiscordSession, m *discordgo.MessageCreate) (err error) {
 
 	roomID = CleanChannel(roomID)
 
@@ -2065,7 +2231,7 @@ func (h *RoomsHandler) SetRoomTransferInvite(roomID string, invite string, s *di
 }
 
 // GetRoomRoles function
-func (h *RoomsHandler) GetRoomRoles(roomID string, guildID string, s *discordgo.Session) (formatted string, err error) {
+func (h *RoomsHandler) GetRoomRoles(roomID string, guildID string, s DiscordSession) (formatted string, err error) {
 
 	roomID = CleanChannel(roomID)
 
@@ -2090,7 +2256,7 @@ func (h *RoomsHandler) GetRoomRoles(roomID string, guildID string, s *discordgo.
 }
 
 // SetRoomTravelRole function
-func (h *RoomsHandler) SetRoomTravelRole(rolename string, roomID string, guildID string, s *discordgo.Session) (err error) {
+func (h *RoomsHandler) SetRoomTravelRole(rolename string, roomID string, guildID string, s DiscordSession) (err error) {
 
 	roomID = CleanChannel(roomID)
 
@@ -2126,7 +2292,7 @@ func (h *RoomsHandler) RemoveRoomTravelRole(roomID string) (err error) {
 }
 
 // SetRoomTransferRoleID function
-func (h *RoomsHandler) SetRoomTransferRoleID(roleID string, roomID string, s *discordgo.Session) (err error) {
+func (h *RoomsHandler) SetRoomTransferRoleID(roleID string, roomID string, s DiscordSession) (err error) {
 
 	roomID = CleanChannel(roomID)
 
@@ -2162,7 +2328,7 @@ func (h *RoomsHandler) RemoveRoomTransferRoleID(roomID string) (err error) {
 }
 
 // SetRoomTopic function
-func (h *RoomsHandler) SetRoomTopic(roomID string, topic string, s *discordgo.Session) (err error) {
+func (h *RoomsHandler) SetRoomTopic(roomID string, topic string, s DiscordSession) (err error) {
 	roomID = CleanChannel(roomID)
 
 	room, err := h.rooms.GetRoomByID(roomID)
@@ -2197,7 +2363,7 @@ func (h *RoomsHandler) GetRoomTopic(roomID string) (topic string, err error) {
 }
 
 // RemoveRoomTopic function
-func (h *RoomsHandler) RemoveRoomTopic(roomID string, s *discordgo.Session) (err error) {
+func (h *RoomsHandler) RemoveRoomTopic(roomID string, s DiscordSession) (err error) {
 	roomID = CleanChannel(roomID)
 
 	room, err := h.rooms.GetRoomByID(roomID)
@@ -2220,7 +2386,7 @@ func (h *RoomsHandler) RemoveRoomTopic(roomID string, s *discordgo.Session) (err
 }
 
 // SyncRoomTopic function
-func (h *RoomsHandler) SyncRoomTopic(roomID string, s *discordgo.Session) (err error) {
+func (h *RoomsHandler) SyncRoomTopic(roomID string, s DiscordSession) (err error) {
 	roomID = CleanChannel(roomID)
 
 	room, err := h.rooms.GetRoomByID(roomID)
@@ -2229,7 +2395,7 @@ func (h *RoomsHandler) SyncRoomTopic(roomID string, s *discordgo.Session) (err e
 	}
 
 	editdata := discordgo.ChannelEdit{Topic: room.Topic}
-	_, err = s.ChannelEditComplex(room.ID, &editdata)
+	err = <-h.queue.ChannelEdit(s, room.GuildID, room.ID, &editdata, QueueLow)
 	if err != nil {
 		return err
 	}
@@ -2237,7 +2403,7 @@ func (h *RoomsHandler) SyncRoomTopic(roomID string, s *discordgo.Session) (err e
 }
 
 // SyncRoom function
-func (h *RoomsHandler) SyncRoom(roomID string, s *discordgo.Session) (err error) {
+func (h *RoomsHandler) SyncRoom(roomID string, s DiscordSession) (err error) {
 	h.roomsynclocker.Lock() // One room at a time!
 	defer h.roomsynclocker.Unlock()
 
@@ -2246,25 +2412,21 @@ func (h *RoomsHandler) SyncRoom(roomID string, s *discordgo.Session) (err error)
 		return err
 	}
 
-	time.Sleep(time.Duration(time.Second * 2))
 	adminID, err := h.guilds.GetGuildDiscordAdminID(room.GuildID, s)
 	if err != nil {
 		return err
 	}
 
-	time.Sleep(time.Duration(time.Second * 2))
 	builderID, err := h.guilds.GetGuildDiscordBuilderID(room.GuildID, s)
 	if err != nil {
 		return err
 	}
 
-	time.Sleep(time.Duration(time.Second * 2))
 	moderatorID, err := h.guilds.GetGuildDiscordModeratorID(room.GuildID, s)
 	if err != nil {
 		return err
 	}
 
-	time.Sleep(time.Duration(time.Second * 2))
 	everyoneID, err := h.guilds.GetGuildDiscordEveryoneID(room.GuildID, s)
 	if err != nil {
 		return err
@@ -2298,8 +2460,6 @@ func (h *RoomsHandler) SyncRoom(roomID string, s *discordgo.Session) (err error)
 	// For every room we go through its role id list
 	for _, roleID := range room.AdditionalRoleIDs {
 
-		// Wait 3 seconds for each role in the room
-		time.Sleep(time.Duration(time.Second * 3))
 		if roleID == room.TravelRoleID {
 			err = h.perm.ApplyTravelRolePerms(room.ID, room.GuildID, s)
 			if err != nil {
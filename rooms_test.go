@@ -0,0 +1,102 @@
+package main
+
+import (
+	"github.com/asdine/storm"
+	"path/filepath"
+	"strconv"
+	"testing"
+)
+
+// benchmarkSizes are the table sizes each lookup is timed against, an indexed lookup should barely change
+var benchmarkSizes = []int{100, 1000, 10000}
+
+// benchmarkGuilds is how many guilds the seeded rooms are spread over
+const benchmarkGuilds = 10
+
+// newBenchmarkDB function
+func newBenchmarkDB(b *testing.B) *DBHandler {
+	b.Helper()
+
+	rawdb, err := storm.Open(filepath.Join(b.TempDir(), "aether.db"))
+	if err != nil {
+		b.Fatal(err)
+	}
+	b.Cleanup(func() { rawdb.Close() })
+	return &DBHandler{conf: &Config{}, rawdb: rawdb}
+}
+
+// seedRooms function
+// Saves count rooms in one transaction, room i is named room-i and lives in guild i % benchmarkGuilds
+func seedRooms(b *testing.B, db *DBHandler, count int) *Rooms {
+	b.Helper()
+
+	tx, err := db.rawdb.Begin(true)
+	if err != nil {
+		b.Fatal(err)
+	}
+	defer tx.Rollback()
+
+	bucket := tx.From("Rooms")
+	for i := 0; i < count; i++ {
+		room := Room{ID: strconv.Itoa(100000 + i), Name: "room-" + strconv.Itoa(i),
+			GuildID: strconv.Itoa(i % benchmarkGuilds), Type: "room"}
+		err = bucket.Save(&room)
+		if err != nil {
+			b.Fatal(err)
+		}
+	}
+	err = tx.Commit()
+	if err != nil {
+		b.Fatal(err)
+	}
+	return &Rooms{db: db}
+}
+
+func BenchmarkGetRoomByID(b *testing.B) {
+	for _, size := range benchmarkSizes {
+		b.Run(strconv.Itoa(size), func(b *testing.B) {
+			rooms := seedRooms(b, newBenchmarkDB(b), size)
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				_, err := rooms.GetRoomByID(strconv.Itoa(100000 + i%size))
+				if err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
+
+func BenchmarkGetRoomByName(b *testing.B) {
+	for _, size := range benchmarkSizes {
+		b.Run(strconv.Itoa(size), func(b *testing.B) {
+			rooms := seedRooms(b, newBenchmarkDB(b), size)
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				room := i % size
+				_, err := rooms.GetRoomByName("room-"+strconv.Itoa(room), strconv.Itoa(room%benchmarkGuilds))
+				if err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
+
+func BenchmarkGetRoomsByGuildID(b *testing.B) {
+	for _, size := range benchmarkSizes {
+		b.Run(strconv.Itoa(size), func(b *testing.B) {
+			rooms := seedRooms(b, newBenchmarkDB(b), size)
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				roomlist, err := rooms.GetRoomsByGuildID(strconv.Itoa(i % benchmarkGuilds))
+				if err != nil {
+					b.Fatal(err)
+				}
+				if len(roomlist) != size/benchmarkGuilds {
+					b.Fatalf("found %d rooms, want %d", len(roomlist), size/benchmarkGuilds)
+				}
+			}
+		})
+	}
+}
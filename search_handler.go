@@ -0,0 +1,107 @@
+package main
+
+import (
+	"github.com/bwmarrin/discordgo"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// searchRetry is how long a player has to wait before searching the same room again
+const searchRetry = time.Minute
+
+// SearchHandler struct
+type SearchHandler struct {
+	registry *CommandRegistry
+	router   *CommandRouter
+	room     *RoomsHandler
+	user     *UserHandler
+
+	searches       map[string]time.Time // Keyed by user ID and room ID
+	searcheslocker sync.Mutex
+}
+
+// Init function
+func (h *SearchHandler) Init() {
+	h.searches = make(map[string]time.Time)
+	h.RegisterCommands()
+}
+
+// RegisterCommands function
+func (h *SearchHandler) RegisterCommands() (err error) {
+
+	h.registry.Register("search", "Search the room you are in for hidden ways out", "")
+	h.router.AddRoute("search", false, h.ParseCommand, "player")
+	return nil
+
+}
+
+// ParseCommand function
+func (h *SearchHandler) ParseCommand(command []string, user User, s DiscordSession, m *discordgo.MessageCreate) {
+
+	roomID := user.RoomID
+	if roomID == "" {
+		roomID = m.ChannelID
+	}
+
+	room, err := h.room.rooms.GetRoomByID(roomID)
+	if err != nil {
+		s.ChannelMessageSend(m.ChannelID, "There is nothing here to search.")
+		return
+	}
+
+	if wait := h.takeSearch(user.ID, room.ID); wait > 0 {
+		s.ChannelMessageSend(m.ChannelID, "You have only just searched here, give it another "+
+			strconv.Itoa(int(wait.Seconds())+1)+"s before looking again.")
+		return
+	}
+
+	roll := RollDice(20, 1)[0]
+
+	var found []string
+	for _, exit := range room.Exits {
+		if !exit.IsHidden() || user.HasDiscovered(room.ID, exit) {
+			continue
+		}
+		if roll+user.SkillBonus(exit.SearchSkill()) >= exit.HiddenDC {
+			user.Discover(room.ID, exit)
+			found = append(found, exit.Name)
+		}
+	}
+
+	if len(found) == 0 {
+		s.ChannelMessageSend(m.ChannelID, "You search around carefully but find nothing out of the ordinary. "+
+			"(rolled "+strconv.Itoa(roll)+")")
+		return
+	}
+
+	err = h.user.usermanager.SaveUserToDB(user)
+	if err != nil {
+		s.ChannelMessageSend(m.ChannelID, "Error saving your discoveries: "+err.Error())
+		return
+	}
+	s.ChannelMessageSend(m.ChannelID, "Searching carefully you discover a way "+strings.Join(found, ", ")+
+		"! (rolled "+strconv.Itoa(roll)+")")
+}
+
+// takeSearch function
+// Records a search of a room, or returns how long until the user can search it again
+func (h *SearchHandler) takeSearch(userID string, roomID string) (wait time.Duration) {
+	h.searcheslocker.Lock()
+	defer h.searcheslocker.Unlock()
+
+	now := time.Now()
+	for key, searched := range h.searches {
+		if now.Sub(searched) >= searchRetry {
+			delete(h.searches, key)
+		}
+	}
+
+	key := userID + "/" + roomID
+	if searched, ok := h.searches[key]; ok {
+		return searchRetry - now.Sub(searched)
+	}
+	h.searches[key] = now
+	return 0
+}
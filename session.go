@@ -0,0 +1,55 @@
+package main
+
+import (
+	"github.com/bwmarrin/discordgo"
+	"io"
+)
+
+// DiscordSession interface
+// This is the subset of the discordgo session that our handlers actually use, anything that talks to discord
+// outside of the websocket event callbacks should take this instead of a *discordgo.Session so that it can be
+// driven by FakeSession without a live bot token.
+type DiscordSession interface {
+	// Messages
+	ChannelMessageSend(channelID string, content string) (*discordgo.Message, error)
+	ChannelMessageSendEmbed(channelID string, embed *discordgo.MessageEmbed) (*discordgo.Message, error)
+	ChannelMessages(channelID string, limit int, beforeID, afterID, aroundID string) ([]*discordgo.Message, error)
+	ChannelMessageDelete(channelID, messageID string) error
+	ChannelMessagesBulkDelete(channelID string, messages []string) error
+	ChannelFileSend(channelID, name string, r io.Reader) (*discordgo.Message, error)
+
+	// Channels
+	Channel(channelID string) (*discordgo.Channel, error)
+	ChannelDelete(channelID string) (*discordgo.Channel, error)
+	ChannelEditComplex(channelID string, data *discordgo.ChannelEdit) (*discordgo.Channel, error)
+	ChannelPermissionSet(channelID, targetID, targetType string, allow, deny int) error
+	UserChannelCreate(recipientID string) (*discordgo.Channel, error)
+
+	// Guilds
+	Guild(guildID string) (*discordgo.Guild, error)
+	GuildChannels(guildID string) ([]*discordgo.Channel, error)
+	GuildChannelCreate(guildID, name, ctype string) (*discordgo.Channel, error)
+
+	// Members
+	User(userID string) (*discordgo.User, error)
+	GuildMember(guildID, userID string) (*discordgo.Member, error)
+	GuildMemberRoleAdd(guildID, userID, roleID string) error
+	GuildMemberRoleRemove(guildID, userID, roleID string) error
+
+	// Roles
+	GuildRoles(guildID string) ([]*discordgo.Role, error)
+	GuildRoleCreate(guildID string) (*discordgo.Role, error)
+	GuildRoleEdit(guildID, roleID, name string, color int, hoist bool, perm int, mention bool) (*discordgo.Role, error)
+	GuildRoleDelete(guildID, roleID string) error
+	GuildRoleReorder(guildID string, roles []*discordgo.Role) ([]*discordgo.Role, error)
+
+	// Invites
+	Invite(inviteID string) (*discordgo.Invite, error)
+	ChannelInviteCreate(channelID string, i discordgo.Invite) (*discordgo.Invite, error)
+
+	// Presence
+	UpdateStatus(idle int, game string) error
+}
+
+// Make sure the real session always satisfies our interface
+var _ DiscordSession = (*discordgo.Session)(nil)
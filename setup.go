@@ -3,7 +3,6 @@ package main
 import (
 	"errors"
 	"fmt"
-	"github.com/bwmarrin/discordgo"
 	"strings"
 )
 
@@ -17,7 +16,7 @@ type SetupProcess struct {
 }
 
 // Init function
-func (h *SetupProcess) Init(s *discordgo.Session, channelID string) (err error) {
+func (h *SetupProcess) Init(s DiscordSession, channelID string) (err error) {
 
 	err = h.SetupOwnerPermissions(s, channelID)
 	if err != nil {
@@ -35,7 +34,7 @@ func (h *SetupProcess) Init(s *discordgo.Session, channelID string) (err error)
 }
 
 // SetupOwnerPermissions function
-func (h *SetupProcess) SetupOwnerPermissions(s *discordgo.Session, channelID string) (err error) {
+func (h *SetupProcess) SetupOwnerPermissions(s DiscordSession, channelID string) (err error) {
 	fmt.Println("Verifying Guild Owner")
 	ownerID, err := getGuildOwnerID(s, channelID)
 	if err != nil {
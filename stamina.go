@@ -0,0 +1,80 @@
+package main
+
+/*
+Stamina is spent by moving between rooms and comes back over time.
+
+Rather than topping every user up on a timer, stamina is regenerated when it's read: StaminaUpdated records when it
+was last brought up to date and anything owed since then is added on. Users who have never had their stamina set
+(StaminaUpdated is zero) start out rested.
+
+*/
+
+import (
+	"strconv"
+	"time"
+)
+
+// StaminaSettings struct
+type StaminaSettings struct {
+	Max        int64
+	Regen      int64 // Points regained every Interval
+	Interval   time.Duration
+	TravelCost int64 // For exits without a cost of their own
+}
+
+// RegenStamina function
+func (u *User) RegenStamina(settings StaminaSettings, now time.Time) {
+
+	if u.StaminaUpdated.IsZero() {
+		u.Stamina = settings.Max
+		u.StaminaUpdated = now
+		return
+	}
+	if u.Stamina >= settings.Max {
+		u.Stamina = settings.Max
+		u.StaminaUpdated = now
+		return
+	}
+	if settings.Interval <= 0 || now.Before(u.StaminaUpdated) {
+		return
+	}
+
+	intervals := int64(now.Sub(u.StaminaUpdated) / settings.Interval)
+	u.Stamina = u.Stamina + intervals*settings.Regen
+	if u.Stamina >= settings.Max {
+		u.Stamina = settings.Max
+		u.StaminaUpdated = now
+		return
+	}
+	// Only whole intervals are used up, so partial progress towards the next point isn't lost
+	u.StaminaUpdated = u.StaminaUpdated.Add(time.Duration(intervals) * settings.Interval)
+}
+
+// SpendStamina function
+// Returns false without spending anything when there isn't enough
+func (u *User) SpendStamina(cost int64, settings StaminaSettings, now time.Time) bool {
+
+	u.RegenStamina(settings, now)
+	if u.Stamina < cost {
+		return false
+	}
+	if u.Stamina >= settings.Max {
+		// Regeneration starts from the first point spent
+		u.StaminaUpdated = now
+	}
+	u.Stamina = u.Stamina - cost
+	return true
+}
+
+// ExitCost function
+func (settings StaminaSettings) ExitCost(exit Exit) int64 {
+	if exit.Cost > 0 {
+		return int64(exit.Cost)
+	}
+	return settings.TravelCost
+}
+
+// StaminaText function
+func (u *User) StaminaText(settings StaminaSettings) string {
+	return strconv.FormatInt(u.Stamina, 10) + "/" + strconv.FormatInt(settings.Max, 10)
+}
@@ -0,0 +1,249 @@
+package main
+
+import (
+	"github.com/asdine/storm"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// Test guilds, the central guild holds the lobby and most rooms
+const (
+	testBotID        = "9000"
+	testOwnerID      = "9001"
+	testCentralGuild = "100"
+	testOtherGuild   = "200"
+)
+
+// testWorld struct
+// Every handler wired together the same way main does, over a temp database and a fake discord
+type testWorld struct {
+	s    *FakeSession
+	conf *Config
+	db   *DBHandler
+
+	lifecycle    *Lifecycle
+	queue        *DiscordQueue
+	router       *CommandRouter
+	callback     *CallbackHandler
+	user         *UserHandler
+	perms        *PermissionsHandler
+	command      *CommandHandler
+	rooms        *RoomsHandler
+	guilds       *GuildsManager
+	guildhandler *GuildsHandler
+	parties      *PartiesManager
+	registration *RegistrationHandler
+	journal      *TravelJournal
+	transfer     *TransferHandler
+	travel       *TravelHandler
+
+	lobby Room
+}
+
+// newTestWorld function
+// Builds a central guild with the Admin, Moderator, Builder and Registered roles and a lobby room
+func newTestWorld(t testing.TB) *testWorld {
+	t.Helper()
+
+	conf := &Config{}
+	conf.PresetDefaults()
+	conf.ApplyDefaults()
+	conf.MainConfig.CentralGuildID = testCentralGuild
+	conf.MainConfig.TravelCooldown = 0
+
+	conf.MainConfig.DBFile = filepath.Join(t.TempDir(), "aether.db")
+	rawdb, err := storm.Open(conf.MainConfig.DBFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	db := &DBHandler{conf: conf, rawdb: rawdb}
+
+	logger := &Logger{}
+	lifecycle := &Lifecycle{logger: logger}
+	lifecycle.Init()
+	metrics := &Metrics{}
+
+	queue := &DiscordQueue{lifecycle: lifecycle, logger: logger, metrics: metrics, Spacing: time.Millisecond}
+	queue.Init()
+
+	t.Cleanup(func() {
+		lifecycle.Shutdown(time.Second * 5)
+		rawdb.Close()
+	})
+
+	w := &testWorld{s: NewFakeSession(testBotID, "Aetheral"), conf: conf, db: db, lifecycle: lifecycle,
+		queue: queue}
+	w.s.AddGuild(testCentralGuild, "The Aether", testOwnerID)
+	w.s.AddGuild(testOtherGuild, "The Aether II", testOwnerID)
+
+	w.router = &CommandRouter{conf: conf, lifecycle: lifecycle, metrics: metrics}
+	w.callback = &CallbackHandler{conf: conf, db: db, logger: logger, router: w.router}
+	err = w.callback.Init()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	w.user = &UserHandler{conf: conf, db: db, logger: logger, router: w.router, queue: queue}
+	w.router.user = w.user
+	w.user.Init()
+
+	w.perms = &PermissionsHandler{dg: w.s, conf: conf, callback: w.callback, db: db, user: w.user, logger: logger,
+		router: w.router, queue: queue}
+	w.perms.Init()
+
+	w.command = &CommandHandler{dg: w.s, db: db, callback: w.callback, user: w.user, conf: conf, perm: w.perms,
+		logger: logger, router: w.router}
+	channel := &ChannelHandler{db: db, conf: conf, registry: w.command.registry, user: w.user, logger: logger,
+		router: w.router}
+	channel.Init()
+	w.command.Init(channel)
+	w.callback.registry = w.command.registry
+
+	w.guilds = &GuildsManager{db: db}
+	w.parties = &PartiesManager{db: db}
+	zones := &ZonesManager{db: db}
+
+	w.rooms = &RoomsHandler{callback: w.callback, conf: conf, db: db, perm: w.perms, registry: w.command.registry,
+		router: w.router, dg: w.s, user: w.user, ch: channel, guilds: w.guilds, queue: queue}
+	w.rooms.rooms = &Rooms{db: db}
+	w.perms.room = w.rooms
+
+	w.registration = &RegistrationHandler{callback: w.callback, conf: conf, db: db, perm: w.perms,
+		registry: w.command.registry, router: w.router, dg: w.s, user: w.user, ch: channel, guilds: w.guilds}
+	w.registration.Init()
+
+	w.journal = &TravelJournal{db: db, conf: conf, logger: logger, perms: w.perms, room: w.rooms, user: w.user}
+
+	w.transfer = &TransferHandler{db: db, conf: conf, registry: w.command.registry, router: w.router,
+		perms: w.perms, rooms: w.rooms, user: w.user, dg: w.s, journal: w.journal}
+	w.transfer.Init()
+
+	w.travel = &TravelHandler{db: db, conf: conf, registry: w.command.registry, router: w.router, perms: w.perms,
+		room: w.rooms, user: w.user, transfer: w.transfer, metrics: metrics, guilds: w.guilds, zones: zones,
+		parties: w.parties, lifecycle: lifecycle, journal: w.journal}
+	w.travel.Init()
+
+	w.guildhandler = &GuildsHandler{room: w.rooms, registry: w.command.registry, router: w.router, db: db,
+		conf: conf, perm: w.perms, user: w.user, guildmanager: w.guilds}
+	w.guildhandler.Init()
+
+	for _, guildID := range []string{testCentralGuild, testOtherGuild} {
+		for _, name := range []string{"Admin", "Moderator", "Builder", "Registered"} {
+			w.addRole(t, guildID, name)
+		}
+	}
+
+	w.lobby = w.addRoom(t, testCentralGuild, "lobby")
+	conf.MainConfig.LobbyChannelID = w.lobby.ID
+	w.command.registry.AddChannel("register", w.lobby.ID)
+	return w
+}
+
+// addRole function
+func (w *testWorld) addRole(t testing.TB, guildID string, name string) (roleID string) {
+	t.Helper()
+
+	role, err := w.perms.CreateRole(name, guildID, false, false, 0, 0, w.s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return role.ID
+}
+
+// addRoom function
+// Creates the channel, its travel role and the room record, and lets players travel from it
+func (w *testWorld) addRoom(t testing.TB, guildID string, name string) Room {
+	t.Helper()
+
+	channel, err := w.s.AddChannel(guildID, name, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	roleID := w.addRole(t, guildID, name)
+
+	room := Room{ID: channel.ID, Name: name, GuildID: guildID, Type: "room", TravelRoleID: roleID,
+		AdditionalRoleIDs: []string{roleID}}
+	err = w.rooms.rooms.SaveRoomToDB(room)
+	if err != nil {
+		t.Fatal(err)
+	}
+	w.command.registry.AddChannel("travel", room.ID)
+	return room
+}
+
+// link function
+func (w *testWorld) link(t testing.TB, direction string, from Room, to Room) {
+	t.Helper()
+
+	w.rooms.LinkDirection(direction, "", from.ID, to.ID, w.s, w.s.NewMessage(testOwnerID, from.ID, ""))
+	if linked, _ := w.rooms.rooms.IsRoomLinkedTo(from.ID, to.ID); !linked {
+		t.Fatalf("could not link %s to %s: %s", from.Name, to.Name, w.s.LastMessage(from.ID))
+	}
+}
+
+// addPlayer function
+// Joins a registered player to the room's guild and places them in the room, holding its travel role
+func (w *testWorld) addPlayer(t testing.TB, userID string, username string, room Room) User {
+	t.Helper()
+
+	_, err := w.s.AddMember(room.GuildID, userID, username)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = w.s.GuildMemberRoleAdd(room.GuildID, userID, room.TravelRoleID)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	user := User{ID: userID, Name: username, GuildID: room.GuildID, RoomID: room.ID, Registered: "true",
+		RoleIDs: []string{room.TravelRoleID}}
+	user.Init()
+	user.SetRole("player")
+	err = w.user.usermanager.SaveUserToDB(user)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = w.rooms.AddUserIDToRoomRecord(userID, room.ID, room.GuildID, w.s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return user
+}
+
+// getUser function
+func (w *testWorld) getUser(t testing.TB, userID string) User {
+	t.Helper()
+
+	user, err := w.user.usermanager.GetUserByID(userID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return user
+}
+
+// getRoom function
+func (w *testWorld) getRoom(t testing.TB, roomID string) Room {
+	t.Helper()
+
+	room, err := w.rooms.rooms.GetRoomByID(roomID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return room
+}
+
+// waitFor function
+// Role changes reported rather than waited on land in the background, this gives the queue a moment to catch up
+func waitFor(t testing.TB, what string, check func() bool) {
+	t.Helper()
+
+	deadline := time.Now().Add(time.Second * 5)
+	for !check() {
+		if time.Now().After(deadline) {
+			t.Fatal("Timed out waiting for " + what)
+		}
+		time.Sleep(time.Millisecond * 5)
+	}
+}
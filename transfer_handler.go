@@ -1,11 +1,11 @@
 package main
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"github.com/bwmarrin/discordgo"
 	"strconv"
-	"strings"
 	"time"
 )
 
@@ -13,15 +13,17 @@ import (
 type TransferHandler struct {
 	db         *DBHandler
 	conf       *Config
-	dg         *discordgo.Session
+	dg         DiscordSession
 	callback   *CallbackHandler
 	perms      *PermissionsHandler
 	user       *UserHandler
 	command    *CommandHandler
 	registry   *CommandRegistry
+	router     *CommandRouter
 	channel    *ChannelHandler
 	rooms      *RoomsHandler
 	transferdb *Transfers
+	journal    *TravelJournal
 }
 
 // Init function
@@ -38,45 +40,11 @@ func (h *TransferHandler) RegisterCommands() (err error) {
 
 	h.registry.Register("transfer", "Transfer Management", "-")
 	h.registry.AddGroup("transfer", "moderator")
+	h.router.AddRoute("transfer", true, h.ParseCommand, "moderator")
 	return nil
 
 }
 
-// Read function
-func (h *TransferHandler) Read(s *discordgo.Session, m *discordgo.MessageCreate) {
-
-	cp := h.conf.MainConfig.CP
-
-	if !SafeInput(s, m, h.conf) {
-		return
-	}
-
-	user, err := h.db.GetUser(m.Author.ID)
-	if err != nil {
-		//fmt.Println("Error finding usermanager")
-		return
-	}
-
-	if strings.HasPrefix(m.Content, cp+"transfer") {
-		if h.registry.CheckPermission("transfer", user, s, m) {
-
-			command := strings.Fields(m.Content)
-
-			// Grab our sender ID to verify if this usermanager has permission to use this command
-			db := h.db.rawdb.From("Users")
-			var user User
-			err := db.One("ID", m.Author.ID, &user)
-			if err != nil {
-				fmt.Println("error retrieving usermanager:" + m.Author.ID)
-			}
-
-			if user.CheckRole("moderator") {
-				h.ParseCommand(command, s, m)
-			}
-		}
-	}
-}
-
 // AddTransfer function
 func (h *TransferHandler) AddTransfer(userID string, fromChannelID string, toChannelID string, targetGuildID string, fromDirection string) (err error) {
 
@@ -100,7 +68,7 @@ func (h *TransferHandler) AddTransfer(userID string, fromChannelID string, toCha
 }
 
 // ParseCommand function
-func (h *TransferHandler) ParseCommand(input []string, s *discordgo.Session, m *discordgo.MessageCreate) {
+func (h *TransferHandler) ParseCommand(input []string, user User, s DiscordSession, m *discordgo.MessageCreate) {
 
 	_, payload := SplitPayload(input)
 
@@ -126,50 +94,59 @@ func (h *TransferHandler) ParseCommand(input []string, s *discordgo.Session, m *
 }
 
 // HandleTransfers function
-func (h *TransferHandler) HandleTransfers() {
-	// Get all transfers and parse them every 3 minutes
-	for true {
-		time.Sleep(time.Duration(time.Second * 30))
+func (h *TransferHandler) HandleTransfers(ctx context.Context) error {
+	// Get all transfers and parse them every 30 seconds
+	for SleepContext(ctx, time.Second*30) {
+		Heartbeat(ctx)
 
 		transfers, err := h.transferdb.GetAllTransfers()
 		if err != nil {
 			fmt.Print("Error retrieving transfers db: " + err.Error())
 		} else {
 			for _, transfer := range transfers {
-				time.Sleep(time.Duration(time.Second * 5))
+				// Stop between transfers, never part way through one
+				if !SleepContext(ctx, time.Second*5) {
+					return nil
+				}
 
 				// Verify the usermanager is actually in the guild before proceeding, otherwise
 				// They have not accepted the invite yet and we should skip them for now
 				if h.IsUserInGuild(transfer.UserID, transfer.TargetGuildID) {
+					h.CompleteTransfer(transfer)
+				}
+			}
+		}
+	}
+	return nil
+}
 
-					// Transfer Channel Roles
-					err = h.TransferToChannel(transfer.UserID, transfer.TargetGuildID, transfer.FromChannelID, transfer.TargetChannelID, h.dg)
-					if err != nil {
-						fmt.Println("Error transferring usermanager: " + err.Error())
-					}
-
-					// Remove record from transfers
-					h.transferdb.RemoveRoomByID(transfer.ID)
+// CompleteTransfer function
+// Moves a user who has joined the target guild into the target room. A failed move has already been rolled back, so
+// the transfer record is kept and tried again on the next pass.
+func (h *TransferHandler) CompleteTransfer(transfer Transfer) (err error) {
 
-					// Create output for channels
-					user, err := h.dg.User(transfer.UserID)
-					if err != nil {
-						fmt.Println("Error retrieving usermanager: " + err.Error())
-					}
+	// Transfer Channel Roles
+	err = h.TransferToChannel(transfer.UserID, transfer.TargetGuildID, transfer.FromChannelID, transfer.TargetChannelID, h.dg)
+	if err != nil {
+		fmt.Println("Error transferring usermanager, will retry: " + err.Error())
+		return err
+	}
 
-					if transfer.FromDirection == "below" || transfer.FromDirection == "above" {
-						h.dg.ChannelMessageSend(transfer.TargetChannelID, user.Mention()+" has materialized from "+transfer.FromDirection+".")
+	// Remove record from transfers
+	h.transferdb.RemoveRoomByID(transfer.ID)
 
-					} else {
-						h.dg.ChannelMessageSend(transfer.TargetChannelID, user.Mention()+" has materialized from the "+transfer.FromDirection+".")
-					}
+	// Create output for channels, the move itself has gone through even if we can't announce it
+	user, err := h.dg.User(transfer.UserID)
+	if err != nil {
+		fmt.Println("Error retrieving usermanager: " + err.Error())
+		return nil
+	}
 
-					h.dg.ChannelMessageSend(transfer.FromChannelID, user.Username+" has dematerialized")
+	h.dg.ChannelMessageSend(transfer.TargetChannelID, FormatArrival(user.Mention(), "materialized",
+		transfer.FromDirection))
 
-				}
-			}
-		}
-	}
+	h.dg.ChannelMessageSend(transfer.FromChannelID, user.Username+" has dematerialized")
+	return nil
 }
 
 // IsUserInGuild function
@@ -185,28 +162,8 @@ func (h *TransferHandler) IsUserInGuild(userID string, guildID string) (ispresen
 
 // TransferToChannel function
 func (h *TransferHandler) TransferToChannel(userID string, targetGuildID string, fromChannelID string,
-	targetChannelID string, s *discordgo.Session) (err error) {
-
-	// First we remove roles
-	fromRoom, err := h.rooms.rooms.GetRoomByID(fromChannelID)
-	if err != nil {
-		return err
-	}
-
-	m := new(discordgo.MessageCreate)
-	m.Message = new(discordgo.Message)
-	m.Message.ChannelID = fromChannelID
+	targetChannelID string, s DiscordSession) (err error) {
 
-	err = h.perms.RemoveRoleFromUser(fromRoom.TravelRoleID, userID, s, m, true)
-	if err != nil {
-		return err
-	}
-	h.rooms.RemoveUserIDFromRoomRecord(userID, fromChannelID)
-	if err != nil {
-		return errors.New("Error removing usermanager record from room: " + err.Error())
-	}
-
-	// Now we add roles
 	toroom, err := h.rooms.rooms.GetRoomByID(targetChannelID)
 	if err != nil {
 		return err
@@ -216,39 +173,41 @@ func (h *TransferHandler) TransferToChannel(userID string, targetGuildID string,
 		return errors.New("Target room not configured properly")
 	}
 
-	m = new(discordgo.MessageCreate)
+	m := new(discordgo.MessageCreate)
 	m.Message = new(discordgo.Message)
 	m.Message.ChannelID = targetChannelID
 
-	err = h.perms.AddRoleToUser(toroom.TravelRoleID, userID, s, m, true)
+	user, err := h.user.GetUser(userID, s, m.ChannelID)
 	if err != nil {
 		return err
 	}
-	h.rooms.AddUserIDToRoomRecord(userID, toroom.ID, toroom.GuildID, s)
-	if err != nil {
-		return errors.New("Error updating usermanager record into room: " + err.Error())
-	}
 
-	// Add registered role here
-	err = h.perms.AddRoleToUser("registered", userID, s, m, false)
-	if err != nil {
+	entry := TravelJournalEntry{Kind: TravelJournalTransfer, UserID: userID, ToRoomID: toroom.ID,
+		ToGuildID: toroom.GuildID, ToRoleID: toroom.TravelRoleID, PreviousRoomID: user.RoomID,
+		PreviousGuildID: user.GuildID}
+
+	// A user who is lost (their room was deleted) has nothing to remove
+	fromRoom, err := h.rooms.rooms.GetRoomByID(fromChannelID)
+	if err == nil {
+		entry.FromRoomID = fromRoom.ID
+		entry.FromGuildID = fromRoom.GuildID
+		entry.FromRoleID = fromRoom.TravelRoleID
+	} else if err.Error() != "No record found" {
 		return err
 	}
 
-	user, err := h.user.GetUser(userID, s, m.ChannelID)
+	err = h.journal.Run(entry, s)
 	if err != nil {
 		return err
 	}
 
-	user.RoomID = toroom.ID
-	user.GuildID = toroom.GuildID
-	db := h.db.rawdb.From("Users")
-	err = db.Update(&user)
+	// Add registered role here
+	err = h.perms.AddRoleToUser("registered", userID, s, m, false)
 	if err != nil {
-		return errors.New("Error updating usermanager record into database")
+		return err
 	}
 
-	err = h.perms.SyncServerRoles(user.ID, user.RoomID, s)
+	err = h.perms.SyncServerRoles(userID, toroom.ID, s)
 	if err != nil {
 		return err
 	}
@@ -0,0 +1,220 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestServerTransfer(t *testing.T) {
+
+	w := newTestWorld(t)
+	target := w.addRoom(t, testOtherGuild, "harbor")
+
+	// Travelling into the gate sends an invite to the other guild and queues the transfer
+	gate := w.addRoom(t, testCentralGuild, "gate")
+	gate.GuildTransferInvite = "https://discord.gg/aether"
+	gate.TransferRoomID = target.ID
+	w.rooms.rooms.SaveRoomToDB(gate)
+	w.link(t, "east", w.lobby, gate)
+
+	alice := w.addPlayer(t, "1", "Alice", w.lobby)
+	w.router.Dispatch(w.s, w.s.NewMessage(alice.ID, w.lobby.ID, "~travel east"))
+
+	dms := w.s.DirectMessages(alice.ID)
+	if len(dms) != 1 || !strings.HasSuffix(dms[0].Content, gate.GuildTransferInvite) {
+		t.Fatalf("invite was not sent, direct messages: %v", dms)
+	}
+
+	transfers, err := w.transfer.transferdb.GetAllTransfers()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(transfers) != 1 {
+		t.Fatalf("%d transfers queued, want 1", len(transfers))
+	}
+	transfer := transfers[0]
+	if transfer.UserID != alice.ID || transfer.FromChannelID != gate.ID || transfer.TargetChannelID != target.ID ||
+		transfer.TargetGuildID != testOtherGuild {
+		t.Errorf("queued transfer = %+v", transfer)
+	}
+
+	// Once they have accepted the invite they are moved into the target room
+	if w.transfer.IsUserInGuild(alice.ID, testOtherGuild) {
+		t.Fatal("user is in the target guild before accepting the invite")
+	}
+	w.s.AddMember(testOtherGuild, alice.ID, "Alice")
+
+	err = w.transfer.TransferToChannel(transfer.UserID, transfer.TargetGuildID, transfer.FromChannelID,
+		transfer.TargetChannelID, w.s)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	user := w.getUser(t, alice.ID)
+	if user.RoomID != target.ID || user.GuildID != testOtherGuild {
+		t.Errorf("user is in room %s of guild %s, want harbor in %s", user.RoomID, user.GuildID, testOtherGuild)
+	}
+	if w.s.MemberHasRole(testCentralGuild, alice.ID, gate.TravelRoleID) {
+		t.Error("user still holds the gate travel role")
+	}
+	if !w.s.MemberHasRole(testOtherGuild, alice.ID, target.TravelRoleID) {
+		t.Error("user is missing the harbor travel role")
+	}
+	registeredID, _ := getRoleIDByName(w.s, testOtherGuild, "Registered")
+	if !w.s.MemberHasRole(testOtherGuild, alice.ID, registeredID) {
+		t.Error("user is missing the registered role in the target guild")
+	}
+	if StringInSlice(alice.ID, w.getRoom(t, gate.ID).UserIDs) {
+		t.Error("user is still listed in the gate")
+	}
+	if !StringInSlice(alice.ID, w.getRoom(t, target.ID).UserIDs) {
+		t.Error("user is not listed in the harbor")
+	}
+}
+
+func TestTransferToChannel(t *testing.T) {
+
+	tests := []struct {
+		name  string
+		setup func(w *testWorld, target *Room)
+		err   string
+		moved bool
+	}{
+		{
+			name:  "from a room",
+			moved: true,
+		},
+		{
+			// A lost user has no room to leave, they are only placed in the target
+			name: "lost user",
+			setup: func(w *testWorld, target *Room) {
+				w.rooms.rooms.RemoveRoomByID(w.lobby.ID)
+			},
+			moved: true,
+		},
+		{
+			name: "misconfigured target",
+			setup: func(w *testWorld, target *Room) {
+				target.AdditionalRoleIDs = nil
+				w.rooms.rooms.SaveRoomToDB(*target)
+			},
+			err: "Target room not configured properly",
+		},
+		{
+			// add-role, remove-role, update-user and add-to-room are all undone
+			name: "rolled back when the source room can't be updated",
+			setup: func(w *testWorld, target *Room) {
+				w.s.GuildRoleDelete(testCentralGuild, w.lobby.TravelRoleID)
+			},
+			err: "Unknown Role",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			w := newTestWorld(t)
+			target := w.addRoom(t, testOtherGuild, "harbor")
+			alice := w.addPlayer(t, "1", "Alice", w.lobby)
+			w.s.AddMember(testOtherGuild, alice.ID, "Alice")
+			if test.setup != nil {
+				test.setup(w, &target)
+			}
+
+			err := w.transfer.TransferToChannel(alice.ID, testOtherGuild, w.lobby.ID, target.ID, w.s)
+			if test.err == "" && err != nil {
+				t.Fatal(err)
+			}
+			if test.err != "" && (err == nil || !strings.Contains(err.Error(), test.err)) {
+				t.Fatalf("error = %v, want %q", err, test.err)
+			}
+
+			user := w.getUser(t, alice.ID)
+			moved := user.RoomID == target.ID
+			if moved != test.moved {
+				t.Errorf("user is in room %s, moved = %v, want %v", user.RoomID, moved, test.moved)
+			}
+			if w.s.MemberHasRole(testOtherGuild, alice.ID, target.TravelRoleID) != test.moved {
+				t.Errorf("user holds the harbor travel role = %v, want %v", !test.moved, test.moved)
+			}
+			if StringInSlice(alice.ID, w.getRoom(t, target.ID).UserIDs) != test.moved {
+				t.Errorf("user listed in the harbor = %v, want %v", !test.moved, test.moved)
+			}
+			if !test.moved && user.GuildID != testCentralGuild {
+				t.Errorf("user guild = %s, want %s", user.GuildID, testCentralGuild)
+			}
+
+			entries, _ := w.journal.GetAllEntries()
+			if len(entries) > 0 {
+				t.Errorf("%d travel journal entries left behind", len(entries))
+			}
+		})
+	}
+}
+
+func TestCompleteTransfer(t *testing.T) {
+
+	tests := []struct {
+		name     string
+		setup    func(w *testWorld, target *Room)
+		kept     bool   // Whether the transfer is left queued for another try
+		arrival  string // Expected in the target room
+		departed string // Expected in the room they left
+	}{
+		{
+			name:     "moved",
+			arrival:  "<@1> has materialized.",
+			departed: "Alice has dematerialized",
+		},
+		{
+			name: "failed and kept for retry",
+			setup: func(w *testWorld, target *Room) {
+				target.AdditionalRoleIDs = nil
+				w.rooms.rooms.RemoveRoomByID(target.ID)
+				w.rooms.rooms.SaveRoomToDB(*target)
+			},
+			kept: true,
+		},
+		{
+			// The move still counts, there is just nobody to announce
+			name: "user lookup fails",
+			setup: func(w *testWorld, target *Room) {
+				w.s.Lock()
+				delete(w.s.users, "1")
+				w.s.Unlock()
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			w := newTestWorld(t)
+			target := w.addRoom(t, testOtherGuild, "harbor")
+			alice := w.addPlayer(t, "1", "Alice", w.lobby)
+			w.s.AddMember(testOtherGuild, alice.ID, "Alice")
+			err := w.transfer.AddTransfer(alice.ID, w.lobby.ID, target.ID, testOtherGuild, "")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if test.setup != nil {
+				test.setup(w, &target)
+			}
+
+			transfers, _ := w.transfer.transferdb.GetAllTransfers()
+			err = w.transfer.CompleteTransfer(transfers[0])
+			if (err != nil) != test.kept {
+				t.Errorf("error = %v, want one = %v", err, test.kept)
+			}
+
+			transfers, _ = w.transfer.transferdb.GetAllTransfers()
+			if kept := len(transfers) == 1; kept != test.kept {
+				t.Errorf("transfer kept = %v, want %v", kept, test.kept)
+			}
+			if got := w.s.LastMessage(target.ID); got != test.arrival {
+				t.Errorf("harbor message = %q, want %q", got, test.arrival)
+			}
+			if got := w.s.LastMessage(w.lobby.ID); got != test.departed {
+				t.Errorf("lobby message = %q, want %q", got, test.departed)
+			}
+		})
+	}
+}
@@ -2,6 +2,7 @@ package main
 
 import (
 	"errors"
+	"github.com/asdine/storm"
 	"sync"
 )
 
@@ -18,7 +19,7 @@ type Transfer struct {
 	TargetGuildID   string `storm:"index"`
 	FromChannelID   string `storm:"index"`
 
-	FromDirection string
+	FromDirection string // Arrival phrasing of the exit that was used, ie "from the south"
 
 	UserID string `storm:"index"`
 }
@@ -60,26 +61,40 @@ func (h *Transfers) RemoveRoomByID(transferID string) (err error) {
 }
 
 // GetTransferByID function
-func (h *Transfers) GetTransferByID(roomID string) (transfer Transfer, err error) {
+func (h *Transfers) GetTransferByID(transferID string) (transfer Transfer, err error) {
+	h.querylocker.RLock()
+	defer h.querylocker.RUnlock()
 
-	transfers, err := h.GetAllTransfers()
+	db := h.db.rawdb.From("Transfers")
+	err = db.One("ID", transferID, &transfer)
 	if err != nil {
+		if err == storm.ErrNotFound {
+			return transfer, errors.New("No record found")
+		}
 		return transfer, err
 	}
 
-	for _, i := range transfers {
-		if i.ID == roomID {
-			return i, nil
-		}
+	return transfer, nil
+}
+
+// GetTransfersByUserID function
+func (h *Transfers) GetTransfersByUserID(userID string) (transferlist []Transfer, err error) {
+	h.querylocker.RLock()
+	defer h.querylocker.RUnlock()
+
+	db := h.db.rawdb.From("Transfers")
+	err = db.Find("UserID", userID, &transferlist)
+	if err != nil && err != storm.ErrNotFound {
+		return transferlist, err
 	}
 
-	return transfer, errors.New("No record found")
+	return transferlist, nil
 }
 
 // GetAllTransfers function
 func (h *Transfers) GetAllTransfers() (transferlist []Transfer, err error) {
-	h.querylocker.Lock()
-	defer h.querylocker.Unlock()
+	h.querylocker.RLock()
+	defer h.querylocker.RUnlock()
 
 	db := h.db.rawdb.From("Transfers")
 	err = db.All(&transferlist)
@@ -0,0 +1,66 @@
+package main
+
+import (
+	"strconv"
+	"testing"
+)
+
+// seedTransfers function
+// Saves count transfers in one transaction, transfer i is queued for user i
+func seedTransfers(b *testing.B, db *DBHandler, count int) *Transfers {
+	b.Helper()
+
+	tx, err := db.rawdb.Begin(true)
+	if err != nil {
+		b.Fatal(err)
+	}
+	defer tx.Rollback()
+
+	bucket := tx.From("Transfers")
+	for i := 0; i < count; i++ {
+		transfer := Transfer{ID: strconv.Itoa(100000 + i), UserID: strconv.Itoa(100000 + i),
+			TargetGuildID: strconv.Itoa(i % benchmarkGuilds)}
+		err = bucket.Save(&transfer)
+		if err != nil {
+			b.Fatal(err)
+		}
+	}
+	err = tx.Commit()
+	if err != nil {
+		b.Fatal(err)
+	}
+	return &Transfers{db: db}
+}
+
+func BenchmarkGetTransferByID(b *testing.B) {
+	for _, size := range benchmarkSizes {
+		b.Run(strconv.Itoa(size), func(b *testing.B) {
+			transfers := seedTransfers(b, newBenchmarkDB(b), size)
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				_, err := transfers.GetTransferByID(strconv.Itoa(100000 + i%size))
+				if err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
+
+func BenchmarkGetTransfersByUserID(b *testing.B) {
+	for _, size := range benchmarkSizes {
+		b.Run(strconv.Itoa(size), func(b *testing.B) {
+			transfers := seedTransfers(b, newBenchmarkDB(b), size)
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				transferlist, err := transfers.GetTransfersByUserID(strconv.Itoa(100000 + i%size))
+				if err != nil {
+					b.Fatal(err)
+				}
+				if len(transferlist) != 1 {
+					b.Fatalf("found %d transfers, want 1", len(transferlist))
+				}
+			}
+		})
+	}
+}
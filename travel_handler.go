@@ -1,27 +1,51 @@
 package main
 
 import (
+	"context"
 	"errors"
-	"fmt"
 	"github.com/bwmarrin/discordgo"
+	"math"
+	"strconv"
 	"strings"
+	"sync"
 	"time"
 )
 
 // TravelHandler struct
 type TravelHandler struct {
-	conf     *Config
-	registry *CommandRegistry
-	callback *CallbackHandler
-	db       *DBHandler
-	perms    *PermissionsHandler
-	room     *RoomsHandler
-	user     *UserHandler
-	transfer *TransferHandler
+	conf      *Config
+	registry  *CommandRegistry
+	router    *CommandRouter
+	callback  *CallbackHandler
+	db        *DBHandler
+	perms     *PermissionsHandler
+	room      *RoomsHandler
+	user      *UserHandler
+	transfer  *TransferHandler
+	metrics   *Metrics
+	guilds    *GuildsManager
+	zones     *ZonesManager
+	parties   *PartiesManager
+	lifecycle *Lifecycle
+	journal   *TravelJournal
+
+	journeys       map[string]*journey // Keyed by user ID, see "travel to"
+	journeyslocker sync.Mutex
+
+	lastmoves       map[string]time.Time // Keyed by user ID, for the travel cooldown
+	lastmoveslocker sync.Mutex
+}
+
+// journey struct
+type journey struct {
+	destination MapNode
+	cancel      context.CancelFunc
 }
 
 // Init function
 func (h *TravelHandler) Init() {
+	h.journeys = make(map[string]*journey)
+	h.lastmoves = make(map[string]time.Time)
 	h.RegisterCommands()
 
 }
@@ -29,136 +53,431 @@ func (h *TravelHandler) Init() {
 // RegisterCommands function
 func (h *TravelHandler) RegisterCommands() (err error) {
 
-	h.registry.Register("travel", "Travel in a direction", "up|down|north|northeast|etc")
+	h.registry.Register("travel", "Travel through one of this room's exits, or all the way to another room",
+		"<exit> ie north, up or enter tavern | to <room> | stop")
 	h.registry.AddGroup("travel", "player")
+	h.router.AddRoute("travel", true, h.ParseCommand, "player")
+
+	h.registry.Register("route", "Show the exits to take to reach another room", "<room>")
+	h.router.AddRoute("route", false, h.ReadRoute, "player")
 	return nil
 
 }
 
-// Read function
-func (h *TravelHandler) Read(s *discordgo.Session, m *discordgo.MessageCreate) {
+// ParseCommand function
+func (h *TravelHandler) ParseCommand(command []string, user User, s DiscordSession, m *discordgo.MessageCreate) {
 
-	cp := h.conf.MainConfig.CP
+	if len(command) < 2 {
+		s.ChannelMessageSend(m.ChannelID, "Expected flag for 'travel' command, see command usage for more info")
+		return
+	}
 
-	if !SafeInput(s, m, h.conf) {
+	if command[1] == "stop" && len(command) == 2 {
+		destination, stopped := h.StopJourney(user.ID)
+		if !stopped {
+			s.ChannelMessageSend(m.ChannelID, "You aren't traveling anywhere.")
+			return
+		}
+		s.ChannelMessageSend(m.ChannelID, "You stop traveling to "+destination.Name+".")
 		return
 	}
 
-	user, err := h.db.GetUser(m.Author.ID)
-	if err != nil {
-		//fmt.Println("Error finding usermanager")
+	if command[1] == "to" && len(command) > 2 {
+		h.StartJourney(strings.Join(command[2:], " "), user, s, m)
 		return
 	}
 
-	if strings.HasPrefix(m.Content, cp+"travel") {
-		if h.registry.CheckPermission("travel", user, s, m) {
+	// Choosing an exit by hand takes over from any journey in progress
+	h.StopJourney(user.ID)
+
+	// Exit names can be more than one word
+	h.Move(strings.Join(command[1:], " "), s, m)
+	return
+}
+
+// Move function
+// Travels through an exit and lets both rooms know, returns the room the author ended up in. Any error has already
+// been reported to the channel. When the author leads a party, the members in the same room travel with them.
+func (h *TravelHandler) Move(exitname string, s DiscordSession, m *discordgo.MessageCreate) (room Room, err error) {
+
+	exit, err := h.Travel(exitname, s, m)
+	if err != nil {
+		s.ChannelMessageSend(m.ChannelID, err.Error())
+		return room, err
+	}
+	travelerIDs := append([]string{m.Author.ID}, h.FollowLeader(exit, s, m)...)
+
+	// Travel has moved us, so we need a fresh copy of our user record
+	user, err := h.user.GetUser(m.Author.ID, s, m.ChannelID)
+	if err != nil {
+		s.ChannelMessageSend(user.RoomID, "Error retrieving usermanager: "+err.Error())
+		return room, err
+	}
 
-			command := strings.Fields(m.Content)
+	fromroom, err := h.room.rooms.GetRoomByID(user.RoomID)
+	if err != nil {
+		s.ChannelMessageSend(user.RoomID, "Error retrieving room: "+err.Error())
+		return room, err
+	}
+
+	transferroom := Room{}
+	if fromroom.GuildTransferInvite != "" {
+		transferroom, err = h.room.rooms.GetRoomByID(fromroom.TransferRoomID)
+		if err != nil {
+			s.ChannelMessageSend(m.ChannelID, "Error retrieving transfer room: "+err.Error())
+			return fromroom, err
+		}
+	}
+
+	var travelers []User
+	var usernames, mentions []string
+	for _, travelerID := range travelerIDs {
+		h.metrics.TravelMove()
+
+		traveler, err := h.user.GetUser(travelerID, s, m.ChannelID)
+		if err != nil {
+			s.ChannelMessageSend(m.ChannelID, "Error retrieving usermanager: "+err.Error())
+			return fromroom, err
+		}
+		discorduser, err := s.User(travelerID)
+		if err != nil {
+			s.ChannelMessageSend(m.ChannelID, "Error retrieving usermanager: "+err.Error())
+			return fromroom, err
+		}
+		travelers = append(travelers, traveler)
+		usernames = append(usernames, discorduser.Username)
+		mentions = append(mentions, discorduser.Mention())
+	}
+
+	// Notify channel that usermanager has left, a party leaves and arrives as one
+	departing, arriving := usernames[0], mentions[0]
+	if len(travelers) > 1 {
+		party, _ := h.parties.GetPartyByMember(user.ID)
+		departing = party.Name + " (" + strings.Join(usernames, ", ") + ")"
+		arriving = party.Name + " (" + strings.Join(mentions, ", ") + ")"
+	}
+	s.ChannelMessageSend(m.ChannelID, exit.DepartureMessage(departing))
+
+	// If we're not leaving the server, we want to notify the channel that the usermanager has arrived
+	s.ChannelMessageSend(user.RoomID, exit.ArrivalMessage(arriving))
 
-			// Grab our sender ID to verify if this usermanager has permission to use this command
-			db := h.db.rawdb.From("Users")
-			var user User
-			err := db.One("ID", m.Author.ID, &user)
+	leftroom, err := h.room.rooms.GetRoomByID(m.ChannelID)
+	if err == nil {
+		for _, traveler := range travelers {
+			h.CrossZone(traveler, leftroom, fromroom, s)
+		}
+	}
+
+	// If we're leaving this server, we want to avoid sending an arrival message to the holding channel
+	if fromroom.GuildTransferInvite != "" {
+		for _, traveler := range travelers {
+			// m.ChannelID because this is the channel we are leaving from
+			h.HandleServerTransfer(traveler, fromroom.ID, fromroom.TransferRoomID, transferroom.GuildID, fromroom,
+				exit.Arrival, s, m)
+		}
+	}
+
+	return fromroom, nil
+}
+
+// FollowLeader function
+// Moves the author's party members who are in the room they just left through the same exit, and returns the IDs
+// of everyone who made it. Anyone who can't follow (too tired, locked out) is left behind with a note.
+func (h *TravelHandler) FollowLeader(exit Exit, s DiscordSession, m *discordgo.MessageCreate) (followerIDs []string) {
+
+	party, found := h.parties.GetPartyByMember(m.Author.ID)
+	if !found || party.LeaderID != m.Author.ID {
+		return followerIDs
+	}
+
+	for _, memberID := range party.MemberIDs {
+		if memberID == m.Author.ID {
+			continue
+		}
+		member, err := h.user.usermanager.GetUserByID(memberID)
+		if err != nil || member.RoomID != m.ChannelID {
+			continue
+		}
+
+		hidden := !member.CanSeeExit(m.ChannelID, exit)
+
+		h.StopJourney(memberID)
+		step := &discordgo.MessageCreate{Message: &discordgo.Message{ID: m.ID, ChannelID: m.ChannelID,
+			Author: &discordgo.User{ID: memberID}, Content: m.Content}}
+		if _, err := h.travel(exit.Name, true, s, step); err != nil {
+			s.ChannelMessageSend(m.ChannelID, "<@"+memberID+"> could not follow: "+err.Error())
+			continue
+		}
+		followerIDs = append(followerIDs, memberID)
+
+		// Following the leader through a hidden exit shows it to the follower, once they have made it through
+		if hidden {
+			member, err = h.user.usermanager.GetUserByID(memberID)
+			if err == nil {
+				member.Discover(m.ChannelID, exit)
+				err = h.user.usermanager.SaveUserToDB(member)
+			}
 			if err != nil {
-				fmt.Println("error retrieving usermanager:" + m.Author.ID)
+				s.ChannelMessageSend(m.ChannelID, "<@"+memberID+"> followed but could not remember the way: "+
+					err.Error())
 			}
+		}
+	}
+	return followerIDs
+}
 
-			if user.CheckRole("player") {
-				h.ParseCommand(command, s, m)
-			}
+// CooldownLeft function
+func (h *TravelHandler) CooldownLeft(userID string) time.Duration {
+	h.lastmoveslocker.Lock()
+	defer h.lastmoveslocker.Unlock()
+
+	return h.lastmoves[userID].Add(h.conf.TravelCooldown()).Sub(time.Now())
+}
+
+// TakeCooldown function
+// Starts the travel cooldown for a user if it isn't already running, otherwise returns how long is left on it
+func (h *TravelHandler) TakeCooldown(userID string) (wait time.Duration) {
+	h.lastmoveslocker.Lock()
+	defer h.lastmoveslocker.Unlock()
+
+	now := time.Now()
+	wait = h.lastmoves[userID].Add(h.conf.TravelCooldown()).Sub(now)
+	if wait > 0 {
+		return wait
+	}
+	h.lastmoves[userID] = now
+	return 0
+}
+
+// ReleaseCooldown function
+// Gives back the cooldown taken for a move that didn't happen, TakeCooldown only takes one that had run out
+func (h *TravelHandler) ReleaseCooldown(userID string) {
+	h.lastmoveslocker.Lock()
+	defer h.lastmoveslocker.Unlock()
+
+	delete(h.lastmoves, userID)
+}
+
+// CrossZone function
+// Announces the new region when travel crosses a zone boundary, and swaps the region roles over
+func (h *TravelHandler) CrossZone(user User, leftroom Room, enteredroom Room, s DiscordSession) {
+
+	if leftroom.ZoneID == enteredroom.ZoneID {
+		return
+	}
+
+	if leftzone, found := h.zones.ZoneForRoom(leftroom); found {
+		if roleID := leftzone.RegionRoleID(leftroom.GuildID); roleID != "" {
+			h.perms.queue.Report(h.perms.queue.RoleRemove(s, leftroom.GuildID, user.ID, roleID, QueueHigh),
+				LogFields{User: user.ID, Guild: leftroom.GuildID, Room: leftroom.ID},
+				"Could not remove region role for "+leftzone.Name)
 		}
 	}
+
+	enteredzone, found := h.zones.ZoneForRoom(enteredroom)
+	if !found {
+		return
+	}
+	if roleID := enteredzone.RegionRoleID(enteredroom.GuildID); roleID != "" {
+		h.perms.queue.Report(h.perms.queue.RoleAdd(s, enteredroom.GuildID, user.ID, roleID, QueueHigh),
+			LogFields{User: user.ID, Guild: enteredroom.GuildID, Room: enteredroom.ID},
+			"Could not add region role for "+enteredzone.Name)
+	}
+	s.ChannelMessageSend(enteredroom.ID, "<@"+user.ID+"> "+enteredzone.EntryMessage())
 }
 
-// ParseCommand function
-func (h *TravelHandler) ParseCommand(command []string, s *discordgo.Session, m *discordgo.MessageCreate) {
+// findDestination function
+// Builds the world graph and looks up a room the way a player would name it, preferring rooms in their own guild
+func (h *TravelHandler) findDestination(search string, user User) (graph WorldGraph, destination MapNode, err error) {
+
+	graph, err = BuildWorldGraph(h.room.rooms, h.guilds)
+	if err != nil {
+		return graph, destination, err
+	}
+
+	graph = graph.VisibleTo(user)
+	destination, err = graph.FindRoom(search, user.GuildID)
+	return graph, destination, err
+}
+
+// ReadRoute function
+func (h *TravelHandler) ReadRoute(command []string, user User, s DiscordSession, m *discordgo.MessageCreate) {
 
 	if len(command) < 2 {
-		s.ChannelMessageSend(m.ChannelID, "Expected flag for 'travel' command, see command usage for more info")
+		s.ChannelMessageSend(m.ChannelID, "Expected a room for 'route' command, see command usage for more info")
 		return
 	}
 
-	err := h.Travel(command[1], s, m)
+	graph, destination, err := h.findDestination(strings.Join(command[1:], " "), user)
 	if err != nil {
 		s.ChannelMessageSend(m.ChannelID, err.Error())
 		return
 	}
 
-	user, err := h.user.GetUser(m.Author.ID, s, m.ChannelID)
+	roomID := user.RoomID
+	if roomID == "" {
+		roomID = m.ChannelID
+	}
+
+	path, err := graph.ShortestPath(roomID, destination.ID)
 	if err != nil {
-		s.ChannelMessageSend(user.RoomID, "Error retrieving usermanager: "+err.Error())
+		s.ChannelMessageSend(m.ChannelID, err.Error())
+		return
+	}
+	if len(path) == 0 {
+		s.ChannelMessageSend(m.ChannelID, "You are already in "+destination.Name+".")
 		return
 	}
 
-	fromroom, err := h.room.rooms.GetRoomByID(user.RoomID)
+	s.ChannelMessageSend(m.ChannelID, "Route to "+destination.Name+" ("+strconv.Itoa(len(path))+" steps): "+
+		graph.FormatRoute(path))
+}
+
+// StartJourney function
+// Works out the destination and sets the author traveling towards it, replacing any journey already in progress
+func (h *TravelHandler) StartJourney(search string, user User, s DiscordSession, m *discordgo.MessageCreate) {
+
+	graph, destination, err := h.findDestination(search, user)
 	if err != nil {
-		s.ChannelMessageSend(user.RoomID, "Error retrieving room: "+err.Error())
+		s.ChannelMessageSend(m.ChannelID, err.Error())
 		return
 	}
 
-	travelfrom := ""
-	if command[1] == "north" {
-		travelfrom = "south"
-	} else if command[1] == "northeast" {
-		travelfrom = "southwest"
-	} else if command[1] == "east" {
-		travelfrom = "west"
-	} else if command[1] == "southeast" {
-		travelfrom = "northwest"
-	} else if command[1] == "south" {
-		travelfrom = "north"
-	} else if command[1] == "southwest" {
-		travelfrom = "northeast"
-	} else if command[1] == "west" {
-		travelfrom = "east"
-	} else if command[1] == "northwest" {
-		travelfrom = "southeast"
-	} else if command[1] == "up" {
-		travelfrom = "below"
-	} else if command[1] == "down" {
-		travelfrom = "above"
+	// The journey sets out from the room the user is in, which needn't be the channel they asked from
+	path, err := graph.ShortestPath(user.RoomID, destination.ID)
+	if err != nil {
+		s.ChannelMessageSend(m.ChannelID, err.Error())
+		return
 	}
-
-	transferroom := Room{}
-	if fromroom.GuildTransferInvite != "" {
-		transferroom, err = h.room.rooms.GetRoomByID(fromroom.TransferRoomID)
-		if err != nil {
-			s.ChannelMessageSend(m.ChannelID, "Error retrieving transfer room: "+err.Error())
-			return
-		}
+	if len(path) == 0 {
+		s.ChannelMessageSend(m.ChannelID, "You are already in "+destination.Name+".")
+		return
 	}
 
-	// Notify channel that usermanager has left
-	discorduser, err := s.User(user.ID)
-	if err != nil {
-		s.ChannelMessageSend(m.ChannelID, "Error retrieving usermanager: "+err.Error())
+	// Journeys count as in-flight work, so shutdown waits for the current step to finish rather than cutting a
+	// move off halfway through its role changes
+	done, ok := h.lifecycle.Track()
+	if !ok {
+		s.ChannelMessageSend(m.ChannelID, "The Aether is shutting down, try again shortly.")
 		return
 	}
-	leaveoutout := discorduser.Username + " has left traveling " + command[1]
-	s.ChannelMessageSend(m.ChannelID, leaveoutout)
 
-	// If we're not leaving the server, we want to notify the channel that the usermanager has arrived
-	if travelfrom == "below" || travelfrom == "above" {
-		s.ChannelMessageSend(user.RoomID, discorduser.Mention()+" has arrived from "+travelfrom+".")
+	h.StopJourney(user.ID)
+	ctx, cancel := context.WithCancel(h.lifecycle.Context())
+	current := &journey{destination: destination, cancel: cancel}
+	h.journeyslocker.Lock()
+	h.journeys[user.ID] = current
+	h.journeyslocker.Unlock()
+
+	s.ChannelMessageSend(m.ChannelID, "You set out for "+destination.Name+" ("+strconv.Itoa(len(path))+
+		" steps), use travel stop to stop.")
+
+	go func() {
+		defer done()
+		defer h.endJourney(user.ID, current)
+		h.Journey(ctx, destination, s, m)
+	}()
+}
+
+// StopJourney function
+// Returns the destination of the journey that was stopped, if there was one
+func (h *TravelHandler) StopJourney(userID string) (destination MapNode, stopped bool) {
+	h.journeyslocker.Lock()
+	defer h.journeyslocker.Unlock()
 
-	} else {
-		s.ChannelMessageSend(user.RoomID, discorduser.Mention()+" has arrived from the "+travelfrom+".")
+	current, ok := h.journeys[userID]
+	if !ok {
+		return destination, false
 	}
+	current.cancel()
+	delete(h.journeys, userID)
+	return current.destination, true
+}
 
-	time.Sleep(3000)
-	// If we're leaving this server, we want to avoid sending an arrival message to the holding channel
-	if fromroom.GuildTransferInvite != "" {
-		// m.ChannelID because this is the channel we are leaving from
-		h.HandleServerTransfer(user, fromroom.ID, fromroom.TransferRoomID, transferroom.GuildID, fromroom, travelfrom, s, m)
-		return
+// endJourney function
+// Only removes the journey if it hasn't already been replaced by a new one
+func (h *TravelHandler) endJourney(userID string, finished *journey) {
+	h.journeyslocker.Lock()
+	defer h.journeyslocker.Unlock()
+
+	finished.cancel()
+	if h.journeys[userID] == finished {
+		delete(h.journeys, userID)
 	}
+}
 
-	return
+// Journey function
+// Takes one step at a time through Travel, working the path out again from wherever the author is before each step
+// so rooms and exits changing along the way are picked up. Stops at the destination, at a locked exit, and at the
+// edge of the guild since the rest of the way needs the player to follow the transfer invite.
+func (h *TravelHandler) Journey(ctx context.Context, destination MapNode, s DiscordSession,
+	m *discordgo.MessageCreate) {
+
+	mention := "<@" + m.Author.ID + ">"
+	for ctx.Err() == nil {
+
+		user, err := h.user.GetUser(m.Author.ID, s, m.ChannelID)
+		if err != nil {
+			s.ChannelMessageSend(m.ChannelID, mention+" Your journey was interrupted: "+err.Error())
+			return
+		}
+
+		graph, err := BuildWorldGraph(h.room.rooms, h.guilds)
+		if err != nil {
+			s.ChannelMessageSend(user.RoomID, mention+" Your journey was interrupted: "+err.Error())
+			return
+		}
+
+		graph = graph.VisibleTo(user)
+		path, err := graph.ShortestPath(user.RoomID, destination.ID)
+		if err != nil {
+			s.ChannelMessageSend(user.RoomID, mention+" Your journey was interrupted: "+err.Error())
+			return
+		}
+		if len(path) == 0 {
+			s.ChannelMessageSend(user.RoomID, mention+" You have arrived at "+destination.Name+".")
+			return
+		}
+
+		if path[0].Kind == MapEdgeTransfer {
+			s.ChannelMessageSend(user.RoomID, mention+" The rest of the way to "+destination.Name+
+				" is through The Aether, follow your transfer invite to continue.")
+			return
+		}
+
+		if wait := h.CooldownLeft(user.ID); wait > 0 && !SleepContext(ctx, wait) {
+			return
+		}
+
+		// Each step is made as though the author had typed the exit in the room they are standing in
+		step := &discordgo.MessageCreate{Message: &discordgo.Message{ID: m.ID, ChannelID: user.RoomID,
+			Author: m.Author, Content: m.Content}}
+
+		room, err := h.Move(path[0].Name, s, step)
+		if err != nil {
+			s.ChannelMessageSend(user.RoomID, mention+" You stop traveling to "+destination.Name+".")
+			return
+		}
+
+		if room.ID == destination.ID {
+			s.ChannelMessageSend(room.ID, mention+" You have arrived at "+destination.Name+".")
+			return
+		}
+		if room.GuildTransferInvite != "" {
+			s.ChannelMessageSend(room.ID, mention+" The rest of the way to "+destination.Name+
+				" is through The Aether, follow your transfer invite to continue.")
+			return
+		}
+
+		if !SleepContext(ctx, h.conf.TravelPace()) {
+			return
+		}
+	}
 }
 
 // HandleServerTransfer function
-func (h *TravelHandler) HandleServerTransfer(user User, travelfromID string, transerToID string, targetGuildID string, fromroom Room, fromDirection string,
-	s *discordgo.Session, m *discordgo.MessageCreate) {
+func (h *TravelHandler) HandleServerTransfer(user User, travelfromID string, transerToID string, targetGuildID string, fromroom Room, arrival string,
+	s DiscordSession, m *discordgo.MessageCreate) {
 
 	// We create a private message to send to the usermanager
 
@@ -187,7 +506,7 @@ func (h *TravelHandler) HandleServerTransfer(user User, travelfromID string, tra
 	}
 
 	// We create an notification for the transfer_handler
-	err = h.transfer.AddTransfer(user.ID, travelfromID, transerToID, targetGuildID, fromDirection)
+	err = h.transfer.AddTransfer(user.ID, travelfromID, transerToID, targetGuildID, arrival)
 	if err != nil {
 		s.ChannelMessageSend(m.ChannelID, "Error creating Aether Link: "+err.Error())
 		return
@@ -197,102 +516,77 @@ func (h *TravelHandler) HandleServerTransfer(user User, travelfromID string, tra
 }
 
 // Travel function
-func (h *TravelHandler) Travel(direction string, s *discordgo.Session, m *discordgo.MessageCreate) (err error) {
+// Moves the author through the named exit of the room they are in, and returns the exit that was used
+func (h *TravelHandler) Travel(exitname string, s DiscordSession, m *discordgo.MessageCreate) (exit Exit, err error) {
+	return h.travel(exitname, false, s, m)
+}
+
+// travel function
+// following lets a party member through a hidden exit their leader took, whether or not they have found it
+func (h *TravelHandler) travel(exitname string, following bool, s DiscordSession, m *discordgo.MessageCreate) (exit Exit,
+	err error) {
 
 	user, err := h.user.GetUser(m.Author.ID, s, m.ChannelID)
 	if err != nil {
-		return err
+		return exit, err
 	}
 
 	fromroom, err := h.room.rooms.GetRoomByID(m.ChannelID)
 	if err != nil {
-		return err
-	}
-
-	toroom := ""
-	if direction == "up" {
-		toroom = fromroom.UpID
-	} else if direction == "down" {
-		toroom = fromroom.DownID
-	} else if direction == "north" {
-		toroom = fromroom.NorthID
-	} else if direction == "northeast" {
-		toroom = fromroom.NorthEastID
-	} else if direction == "east" {
-		toroom = fromroom.EastID
-	} else if direction == "southeast" {
-		toroom = fromroom.SouthEastID
-	} else if direction == "south" {
-		toroom = fromroom.SouthID
-	} else if direction == "southwest" {
-		toroom = fromroom.SouthWestID
-	} else if direction == "west" {
-		toroom = fromroom.WestID
-	} else if direction == "northwest" {
-		toroom = fromroom.NorthWestID
-	} else {
-		return errors.New("Unrecognized direction: " + direction)
-
-	}
-
-	if toroom == "" {
-		return errors.New("There is nowhere to travel in that direction")
+		return exit, err
 	}
 
+	exit, found := fromroom.GetExit(exitname)
+	if !found || (!following && !user.CanSeeExit(fromroom.ID, exit)) {
+		return exit, errors.New("There is no exit named " + exitname + " here")
+	}
+	toroom := exit.TargetID
+
 	targetroom, err := h.room.rooms.GetRoomByID(toroom)
 	if err != nil {
-		return err
+		return exit, err
 	}
 
 	if len(targetroom.AdditionalRoleIDs) < 1 {
-		return errors.New("Target room is not configured properly: " + toroom)
+		return exit, errors.New("Target room is not configured properly: " + toroom)
 	}
 
 	if len(fromroom.AdditionalRoleIDs) < 1 {
-		return errors.New("From room is not configured properly: " + toroom)
+		return exit, errors.New("From room is not configured properly: " + toroom)
 	}
 
-	if len(targetroom.Items) > 0 {
-		// This is where the logic for items validation will go
+	if len(exit.CanUse(user)) > 0 {
+		return exit, errors.New(exit.LockedText())
 	}
 
-	guildID, err := getGuildID(s, m.ChannelID)
-	if err != nil {
-		return err
+	stamina := h.conf.Stamina()
+	cost := stamina.ExitCost(exit)
+	if !user.SpendStamina(cost, stamina, time.Now()) {
+		return exit, errors.New("You are too exhausted to go " + exit.Name + ", rest a while before traveling " +
+			"on. (stamina " + user.StaminaText(stamina) + ", this way takes " + strconv.FormatInt(cost, 10) + ")")
 	}
 
-	err = h.perms.AddRoleToUser(targetroom.TravelRoleID, user.ID, s, m, true)
-	if err != nil {
-		return err
+	if wait := h.TakeCooldown(user.ID); wait > 0 {
+		return exit, errors.New("You are still catching your breath from the last stretch, wait another " +
+			strconv.Itoa(int(math.Ceil(wait.Seconds()))) + "s before moving on.")
 	}
 
-	err = h.perms.RemoveRoleFromUser(fromroom.TravelRoleID, user.ID, s, m, true)
-	if err != nil {
-		return err
-	}
-
-	user, err = h.user.GetUser(m.Author.ID, s, m.ChannelID)
-	if err != nil {
-		return err
-	}
-
-	user.RoomID = targetroom.ID
-	user.GuildID = guildID
-
-	db := h.db.rawdb.From("Users")
-	err = db.Update(&user)
-	if err != nil {
-		return errors.New("Error updating user record into database")
-	}
-
-	err = h.room.AddUserIDToRoomRecord(user.ID, targetroom.ID, guildID, s)
+	guildID, err := getGuildID(s, m.ChannelID)
 	if err != nil {
-		return errors.New("Error updating user record into room: " + err.Error())
+		h.ReleaseCooldown(user.ID)
+		return exit, err
 	}
 
-	err = h.room.RemoveUserIDFromRoomRecord(user.ID, fromroom.ID)
+	// Each step is journaled so a failure part way through puts the user back where they were, and a move that
+	// was rolled back doesn't hold up the next one
+	entry := TravelJournalEntry{Kind: TravelJournalTravel, UserID: user.ID, FromRoomID: fromroom.ID,
+		FromGuildID: fromroom.GuildID, FromRoleID: fromroom.TravelRoleID, ToRoomID: targetroom.ID,
+		ToGuildID: guildID, ToRoleID: targetroom.TravelRoleID, PreviousRoomID: user.RoomID,
+		PreviousGuildID: user.GuildID, StaminaCost: cost}
+	err = h.journal.Run(entry, s)
 	if err != nil {
-		return errors.New("Error removing user record from room: " + err.Error())
+		h.ReleaseCooldown(user.ID)
+		return exit, err
 	}
-	return nil
+	return exit, nil
 }
@@ -0,0 +1,222 @@
+package main
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestTravel(t *testing.T) {
+
+	tests := []struct {
+		name    string
+		setup   func(w *testWorld, hall Room)
+		command string
+		moved   bool
+		message string // Expected in the lobby
+	}{
+		{
+			name:    "through an exit",
+			command: "~travel north",
+			moved:   true,
+			message: "Alice has left traveling north.",
+		},
+		{
+			name:    "by alias",
+			command: "~travel n",
+			moved:   true,
+			message: "Alice has left traveling north.",
+		},
+		{
+			name:    "no such exit",
+			command: "~travel west",
+			message: "There is no exit named west here",
+		},
+		{
+			name: "too exhausted",
+			setup: func(w *testWorld, hall Room) {
+				w.rooms.SetExitCost("north", 500, w.lobby.ID)
+			},
+			command: "~travel north",
+			message: "You are too exhausted to go north",
+		},
+		{
+			name: "hidden exit",
+			setup: func(w *testWorld, hall Room) {
+				w.rooms.SetExitHidden("north", 15, HiddenSkillPerception, w.lobby.ID)
+			},
+			command: "~travel north",
+			message: "There is no exit named north here",
+		},
+		{
+			// The lobby role is gone so remove-role fails after add-role, which has to be undone
+			name: "rolled back when discord rejects a role",
+			setup: func(w *testWorld, hall Room) {
+				w.s.GuildRoleDelete(testCentralGuild, w.lobby.TravelRoleID)
+			},
+			command: "~travel north",
+			message: "Unknown Role",
+		},
+		{
+			name: "misconfigured target",
+			setup: func(w *testWorld, hall Room) {
+				hall.AdditionalRoleIDs = nil
+				w.rooms.rooms.SaveRoomToDB(hall)
+			},
+			command: "~travel north",
+			message: "Target room is not configured properly",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			w := newTestWorld(t)
+			hall := w.addRoom(t, testCentralGuild, "hall")
+			w.link(t, "north", w.lobby, hall)
+			alice := w.addPlayer(t, "1", "Alice", w.lobby)
+			if test.setup != nil {
+				test.setup(w, hall)
+			}
+
+			if !w.router.Dispatch(w.s, w.s.NewMessage(alice.ID, w.lobby.ID, test.command)) {
+				t.Fatal("travel was not dispatched")
+			}
+
+			if got := w.s.LastMessage(w.lobby.ID); !strings.Contains(got, test.message) {
+				t.Errorf("lobby message = %q, want %q", got, test.message)
+			}
+
+			from, to := w.lobby, hall
+			if !test.moved {
+				from, to = hall, w.lobby
+			}
+
+			user := w.getUser(t, alice.ID)
+			if user.RoomID != to.ID {
+				t.Errorf("user is in room %s, want %s", user.RoomID, to.Name)
+			}
+			if w.s.MemberHasRole(testCentralGuild, alice.ID, from.TravelRoleID) {
+				t.Errorf("user still holds the %s travel role", from.Name)
+			}
+			if to.ID == hall.ID && !w.s.MemberHasRole(testCentralGuild, alice.ID, to.TravelRoleID) {
+				t.Errorf("user is missing the %s travel role", to.Name)
+			}
+			if StringInSlice(alice.ID, w.getRoom(t, from.ID).UserIDs) {
+				t.Errorf("user is still listed in %s", from.Name)
+			}
+			if !StringInSlice(alice.ID, w.getRoom(t, to.ID).UserIDs) {
+				t.Errorf("user is not listed in %s", to.Name)
+			}
+
+			if test.moved {
+				if got := w.s.LastMessage(hall.ID); got != "<@1> has arrived from lobby." {
+					t.Errorf("hall message = %q", got)
+				}
+				if user.Stamina != w.conf.Stamina().Max-w.conf.Stamina().TravelCost {
+					t.Errorf("stamina = %d, want one move spent", user.Stamina)
+				}
+			}
+
+			entries, _ := w.journal.GetAllEntries()
+			if len(entries) > 0 {
+				t.Errorf("%d travel journal entries left behind", len(entries))
+			}
+		})
+	}
+}
+
+func TestTravelParty(t *testing.T) {
+
+	w := newTestWorld(t)
+	hall := w.addRoom(t, testCentralGuild, "hall")
+	w.link(t, "north", w.lobby, hall)
+	w.rooms.SetExitHidden("north", 15, HiddenSkillPerception, w.lobby.ID)
+
+	alice := w.addPlayer(t, "1", "Alice", w.lobby)
+	bob := w.addPlayer(t, "2", "Bob", w.lobby)
+	carol := w.addPlayer(t, "3", "Carol", w.lobby)
+
+	// Alice has found the hidden exit and Bob follows through it, Carol is too tired to
+	alice.Discover(w.lobby.ID, Exit{Name: "north"})
+	w.user.usermanager.SaveUserToDB(alice)
+	carol.Stamina = 0
+	carol.StaminaUpdated = time.Now()
+	w.user.usermanager.SaveUserToDB(carol)
+
+	party, err := w.parties.CreateParty(alice.ID, "Wanderers")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, member := range []User{bob, carol} {
+		err = w.parties.Invite(party.ID, member.ID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		err = w.parties.Accept(party.ID, member.ID)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	w.router.Dispatch(w.s, w.s.NewMessage(alice.ID, w.lobby.ID, "~travel north"))
+
+	tests := []struct {
+		user     User
+		room     Room
+		hasfound bool
+	}{
+		{user: alice, room: hall, hasfound: true},
+		{user: bob, room: hall, hasfound: true},
+		{user: carol, room: w.lobby, hasfound: false},
+	}
+	for _, test := range tests {
+		user := w.getUser(t, test.user.ID)
+		if user.RoomID != test.room.ID {
+			t.Errorf("%s is in room %s, want %s", test.user.Name, user.RoomID, test.room.Name)
+		}
+		if !w.s.MemberHasRole(testCentralGuild, user.ID, test.room.TravelRoleID) {
+			t.Errorf("%s is missing the %s travel role", test.user.Name, test.room.Name)
+		}
+		if found := user.HasDiscovered(w.lobby.ID, Exit{Name: "north"}); found != test.hasfound {
+			t.Errorf("%s has found the hidden exit = %v, want %v", test.user.Name, found, test.hasfound)
+		}
+	}
+
+	if got := w.s.LastMessage(hall.ID); !strings.Contains(got, "Wanderers (<@1>, <@2>) has arrived") {
+		t.Errorf("hall message = %q", got)
+	}
+	found := false
+	for _, message := range w.s.Messages(w.lobby.ID) {
+		if strings.HasPrefix(message.Content, "<@3> could not follow") {
+			found = true
+		}
+	}
+	if !found {
+		t.Error("Carol was not told about being left behind")
+	}
+}
+
+func TestTravelTo(t *testing.T) {
+
+	w := newTestWorld(t)
+	w.conf.MainConfig.TravelPace = 0
+	hall := w.addRoom(t, testCentralGuild, "hall")
+	yard := w.addRoom(t, testCentralGuild, "yard")
+	cellar := w.addRoom(t, testCentralGuild, "cellar")
+	w.link(t, "north", w.lobby, hall)
+	w.link(t, "east", hall, yard)
+	alice := w.addPlayer(t, "1", "Alice", w.lobby)
+
+	// Asked from a channel that isn't linked to anything, the route still starts in the lobby
+	w.router.Dispatch(w.s, w.s.NewMessage(alice.ID, cellar.ID, "~travel to yard"))
+	if got := w.s.LastMessage(cellar.ID); !strings.HasPrefix(got, "You set out for yard (2 steps)") {
+		t.Fatalf("cellar message = %q", got)
+	}
+
+	waitFor(t, "Alice to reach the yard", func() bool {
+		return w.getUser(t, alice.ID).RoomID == yard.ID
+	})
+	waitFor(t, "the journey to end", func() bool {
+		return strings.HasSuffix(w.s.LastMessage(yard.ID), "You have arrived at yard.")
+	})
+}
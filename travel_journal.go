@@ -0,0 +1,362 @@
+package main
+
+/*
+Moving a user between rooms takes five steps, any of which can fail:
+
+	add-role        - give the user the target room's travel role
+	remove-role     - take away the source room's travel role
+	update-user     - point the user record at the target room
+	add-to-room     - list the user in the target room
+	remove-from-room - take the user out of the source room's list
+
+Each move is written to the journal before it starts, and each step is recorded as it completes. If a step fails
+the completed steps are undone in reverse order, so the user ends up back where they started rather than holding two
+travel roles or listed in two rooms.
+
+A move that was cut short by a restart is picked up again by Recover. Moves that got as far as update-user are
+finished, since the user record already says they've arrived, anything earlier is reverted. A restart can also come
+between making a step and recording it, so reverting after a restart undoes the first unrecorded step as well, every
+undo is safe to run for a step that never happened.
+
+Role steps wait for discord to answer (after the queue's own retries), so a role change discord rejects fails the
+step and reverts the move.
+
+*/
+
+import (
+	"errors"
+	"github.com/asdine/storm"
+	"github.com/bwmarrin/discordgo"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// Travel journal entry kinds
+const (
+	TravelJournalTravel   = "travel"
+	TravelJournalTransfer = "transfer"
+)
+
+// Travel journal steps, in the order they are made
+const (
+	TravelStepAddRole        = "add-role"
+	TravelStepRemoveRole     = "remove-role"
+	TravelStepUpdateUser     = "update-user"
+	TravelStepAddToRoom      = "add-to-room"
+	TravelStepRemoveFromRoom = "remove-from-room"
+)
+
+// travelSteps lists the steps in the order they are made
+var travelSteps = []string{TravelStepAddRole, TravelStepRemoveRole, TravelStepUpdateUser, TravelStepAddToRoom,
+	TravelStepRemoveFromRoom}
+
+// TravelJournalEntry struct
+type TravelJournalEntry struct {
+	ID      string `storm:"id"` // primary key
+	Kind    string
+	UserID  string `storm:"index"`
+	Started time.Time
+
+	// The source room is empty for a user who is lost, there is nothing to take them out of
+	FromRoomID  string
+	FromGuildID string
+	FromRoleID  string
+
+	ToRoomID  string
+	ToGuildID string
+	ToRoleID  string
+
+	// What the user record said before the move, for undoing update-user
+	PreviousRoomID  string
+	PreviousGuildID string
+	StaminaCost     int64
+
+	Completed []string
+}
+
+// TravelJournal struct
+type TravelJournal struct {
+	db     *DBHandler
+	conf   *Config
+	logger *Logger
+	perms  *PermissionsHandler
+	room   *RoomsHandler
+	user   *UserHandler
+
+	querylocker sync.Mutex
+}
+
+// SaveEntryToDB function
+func (h *TravelJournal) SaveEntryToDB(entry TravelJournalEntry) (err error) {
+	h.querylocker.Lock()
+	defer h.querylocker.Unlock()
+
+	db := h.db.rawdb.From("TravelJournal")
+	err = db.Save(&entry)
+	return err
+}
+
+// RemoveEntryFromDB function
+func (h *TravelJournal) RemoveEntryFromDB(entry TravelJournalEntry) (err error) {
+	h.querylocker.Lock()
+	defer h.querylocker.Unlock()
+
+	db := h.db.rawdb.From("TravelJournal")
+	err = db.DeleteStruct(&entry)
+	return err
+}
+
+// GetAllEntries function
+func (h *TravelJournal) GetAllEntries() (entries []TravelJournalEntry, err error) {
+	h.querylocker.Lock()
+	defer h.querylocker.Unlock()
+
+	db := h.db.rawdb.From("TravelJournal")
+	err = db.All(&entries)
+	if err != nil && err != storm.ErrNotFound {
+		return entries, err
+	}
+	return entries, nil
+}
+
+// Run function
+// Journals a move and makes each step, undoing the completed steps if one fails
+func (h *TravelJournal) Run(entry TravelJournalEntry, s DiscordSession) (err error) {
+
+	entry.ID, err = GetUUID()
+	if err != nil {
+		return err
+	}
+	entry.Started = time.Now()
+	entry.Completed = nil
+
+	err = h.SaveEntryToDB(entry)
+	if err != nil {
+		return errors.New("Error journaling travel: " + err.Error())
+	}
+
+	err = h.forward(&entry, s)
+	if err != nil {
+		rollbackerr := h.rollback(&entry, s, false)
+		if rollbackerr != nil {
+			// Left in the journal so the next restart can try again
+			h.logger.Error(BOTLOG, LogFields{User: entry.UserID, Room: entry.ToRoomID},
+				"Could not undo travel "+entry.ID+": "+rollbackerr.Error())
+			return err
+		}
+	}
+
+	removeerr := h.RemoveEntryFromDB(entry)
+	if removeerr != nil {
+		h.logger.Warn(BOTLOG, LogFields{User: entry.UserID}, "Could not clear travel journal entry "+entry.ID+": "+
+			removeerr.Error())
+	}
+	return err
+}
+
+// Recover function
+// Finishes or reverts any moves that were interrupted, returns how many were found
+func (h *TravelJournal) Recover(s DiscordSession) (recovered int) {
+
+	entries, err := h.GetAllEntries()
+	if err != nil {
+		h.logger.Error(BOTLOG, LogFields{}, "Could not read travel journal: "+err.Error())
+		return 0
+	}
+
+	for _, entry := range entries {
+		entry := entry
+		fields := LogFields{User: entry.UserID, Room: entry.ToRoomID}
+
+		if StringInSlice(TravelStepUpdateUser, entry.Completed) {
+			err = h.forward(&entry, s)
+			if err == nil {
+				h.logger.Info(BOTLOG, fields, "Finished interrupted "+entry.Kind+" "+entry.ID)
+			} else {
+				h.logger.Warn(BOTLOG, fields, "Could not finish interrupted "+entry.Kind+" "+entry.ID+
+					", reverting: "+err.Error())
+			}
+		}
+		if err != nil || !StringInSlice(TravelStepUpdateUser, entry.Completed) {
+			err = h.rollback(&entry, s, true)
+			if err != nil {
+				h.logger.Error(BOTLOG, fields, "Could not revert interrupted "+entry.Kind+" "+entry.ID+": "+
+					err.Error())
+				continue
+			}
+			h.logger.Info(BOTLOG, fields, "Reverted interrupted "+entry.Kind+" "+entry.ID)
+		}
+
+		err = h.RemoveEntryFromDB(entry)
+		if err != nil {
+			h.logger.Warn(BOTLOG, fields, "Could not clear travel journal entry "+entry.ID+": "+err.Error())
+		}
+		recovered++
+	}
+
+	if recovered > 0 {
+		h.logger.Info(BOTLOG, LogFields{}, "Recovered "+strconv.Itoa(recovered)+" interrupted moves from the "+
+			"travel journal")
+	}
+	return recovered
+}
+
+// forward function
+// Makes every step that hasn't been completed yet, recording each one as it's done
+func (h *TravelJournal) forward(entry *TravelJournalEntry, s DiscordSession) (err error) {
+
+	for _, step := range travelSteps {
+		if StringInSlice(step, entry.Completed) {
+			continue
+		}
+		err = h.apply(step, entry, s)
+		if err != nil {
+			return err
+		}
+		// Recorded in memory first, so a failed save is still undone by rollback
+		entry.Completed = append(entry.Completed, step)
+		err = h.SaveEntryToDB(*entry)
+		if err != nil {
+			return errors.New("Error journaling travel: " + err.Error())
+		}
+	}
+	return nil
+}
+
+// rollback function
+// Undoes the completed steps, newest first. An interrupted move may have made its next step without recording it,
+// so that step is undone too.
+func (h *TravelJournal) rollback(entry *TravelJournalEntry, s DiscordSession, interrupted bool) (err error) {
+
+	for _, step := range travelSteps {
+		if !interrupted {
+			break
+		}
+		if StringInSlice(step, entry.Completed) {
+			continue
+		}
+		err = h.undo(step, entry, s)
+		if err != nil {
+			return errors.New("Error undoing " + step + ": " + err.Error())
+		}
+		break
+	}
+
+	for len(entry.Completed) > 0 {
+		step := entry.Completed[len(entry.Completed)-1]
+		err = h.undo(step, entry, s)
+		if err != nil {
+			return errors.New("Error undoing " + step + ": " + err.Error())
+		}
+		entry.Completed = entry.Completed[:len(entry.Completed)-1]
+		err = h.SaveEntryToDB(*entry)
+		if err != nil {
+			return errors.New("Error journaling travel: " + err.Error())
+		}
+	}
+	return nil
+}
+
+// apply function
+// Every step can be made again safely, in case a restart came between making it and recording it
+func (h *TravelJournal) apply(step string, entry *TravelJournalEntry, s DiscordSession) (err error) {
+
+	switch step {
+	case TravelStepAddRole:
+		return h.perms.AddRoleToUser(entry.ToRoleID, entry.UserID, s, journalMessage(entry.ToRoomID), true)
+
+	case TravelStepRemoveRole:
+		if entry.FromRoomID == "" {
+			return nil
+		}
+		return h.perms.RemoveRoleFromUser(entry.FromRoleID, entry.UserID, s, journalMessage(entry.FromRoomID), true)
+
+	case TravelStepUpdateUser:
+		user, err := h.user.usermanager.GetUserByID(entry.UserID)
+		if err != nil {
+			return err
+		}
+		if user.RoomID == entry.ToRoomID && user.GuildID == entry.ToGuildID {
+			return nil
+		}
+		user.RoomID = entry.ToRoomID
+		user.GuildID = entry.ToGuildID
+		if entry.StaminaCost > 0 {
+			user.SpendStamina(entry.StaminaCost, h.conf.Stamina(), time.Now())
+		}
+		err = h.user.usermanager.SaveUserToDB(user)
+		if err != nil {
+			return errors.New("Error updating user record into database")
+		}
+		return nil
+
+	case TravelStepAddToRoom:
+		err = h.room.AddUserIDToRoomRecord(entry.UserID, entry.ToRoomID, entry.ToGuildID, s)
+		if err != nil {
+			return errors.New("Error updating user record into room: " + err.Error())
+		}
+		return nil
+
+	case TravelStepRemoveFromRoom:
+		if entry.FromRoomID == "" {
+			return nil
+		}
+		err = h.room.RemoveUserIDFromRoomRecord(entry.UserID, entry.FromRoomID)
+		if err != nil {
+			return errors.New("Error removing user record from room: " + err.Error())
+		}
+		return nil
+	}
+	return errors.New("Unknown travel step " + step)
+}
+
+// undo function
+// The compensating action for each step
+func (h *TravelJournal) undo(step string, entry *TravelJournalEntry, s DiscordSession) (err error) {
+
+	switch step {
+	case TravelStepAddRole:
+		return h.perms.RemoveRoleFromUser(entry.ToRoleID, entry.UserID, s, journalMessage(entry.ToRoomID), true)
+
+	case TravelStepRemoveRole:
+		if entry.FromRoomID == "" {
+			return nil
+		}
+		return h.perms.AddRoleToUser(entry.FromRoleID, entry.UserID, s, journalMessage(entry.FromRoomID), true)
+
+	case TravelStepUpdateUser:
+		user, err := h.user.usermanager.GetUserByID(entry.UserID)
+		if err != nil {
+			return err
+		}
+		if user.RoomID != entry.ToRoomID {
+			return nil
+		}
+		user.RoomID = entry.PreviousRoomID
+		user.GuildID = entry.PreviousGuildID
+		if entry.StaminaCost > 0 {
+			user.Stamina = user.Stamina + entry.StaminaCost
+			if max := h.conf.Stamina().Max; user.Stamina > max {
+				user.Stamina = max
+			}
+		}
+		return h.user.usermanager.SaveUserToDB(user)
+
+	case TravelStepAddToRoom:
+		return h.room.RemoveUserIDFromRoomRecord(entry.UserID, entry.ToRoomID)
+
+	case TravelStepRemoveFromRoom:
+		if entry.FromRoomID == "" {
+			return nil
+		}
+		return h.room.AddUserIDToRoomRecord(entry.UserID, entry.FromRoomID, entry.FromGuildID, s)
+	}
+	return errors.New("Unknown travel step " + step)
+}
+
+// journalMessage function
+// The permissions handler works out the guild from the channel a message was sent in
+func journalMessage(channelID string) *discordgo.MessageCreate {
+	return &discordgo.MessageCreate{Message: &discordgo.Message{ChannelID: channelID}}
+}
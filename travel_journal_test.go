@@ -0,0 +1,101 @@
+package main
+
+import (
+	"testing"
+)
+
+func TestTravelJournalRecover(t *testing.T) {
+
+	tests := []struct {
+		name     string
+		made     []string // Steps that happened before the restart
+		recorded []string // Steps the journal knew about
+		moved    bool
+	}{
+		{
+			name: "nothing made",
+		},
+		{
+			name: "first step made but not recorded",
+			made: []string{TravelStepAddRole},
+		},
+		{
+			name:     "next step made but not recorded",
+			made:     []string{TravelStepAddRole, TravelStepRemoveRole},
+			recorded: []string{TravelStepAddRole},
+		},
+		{
+			name:     "reverted before update-user",
+			made:     []string{TravelStepAddRole, TravelStepRemoveRole},
+			recorded: []string{TravelStepAddRole, TravelStepRemoveRole},
+		},
+		{
+			name:     "finished after update-user",
+			made:     []string{TravelStepAddRole, TravelStepRemoveRole, TravelStepUpdateUser},
+			recorded: []string{TravelStepAddRole, TravelStepRemoveRole, TravelStepUpdateUser},
+			moved:    true,
+		},
+		{
+			name: "finished with add-to-room made but not recorded",
+			made: []string{TravelStepAddRole, TravelStepRemoveRole, TravelStepUpdateUser,
+				TravelStepAddToRoom},
+			recorded: []string{TravelStepAddRole, TravelStepRemoveRole, TravelStepUpdateUser},
+			moved:    true,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			w := newTestWorld(t)
+			hall := w.addRoom(t, testCentralGuild, "hall")
+			alice := w.addPlayer(t, "1", "Alice", w.lobby)
+
+			entry := TravelJournalEntry{ID: "journal-1", Kind: TravelJournalTravel, UserID: alice.ID,
+				FromRoomID: w.lobby.ID, FromGuildID: testCentralGuild, FromRoleID: w.lobby.TravelRoleID,
+				ToRoomID: hall.ID, ToGuildID: testCentralGuild, ToRoleID: hall.TravelRoleID,
+				PreviousRoomID: alice.RoomID, PreviousGuildID: alice.GuildID, StaminaCost: 1}
+			for _, step := range test.made {
+				err := w.journal.apply(step, &entry, w.s)
+				if err != nil {
+					t.Fatal(err)
+				}
+			}
+			entry.Completed = test.recorded
+			err := w.journal.SaveEntryToDB(entry)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if recovered := w.journal.Recover(w.s); recovered != 1 {
+				t.Errorf("recovered %d moves, want 1", recovered)
+			}
+
+			from, to := w.lobby, hall
+			if !test.moved {
+				from, to = hall, w.lobby
+			}
+
+			user := w.getUser(t, alice.ID)
+			if user.RoomID != to.ID {
+				t.Errorf("user is in room %s, want %s", user.RoomID, to.Name)
+			}
+			if w.s.MemberHasRole(testCentralGuild, alice.ID, from.TravelRoleID) {
+				t.Errorf("user still holds the %s travel role", from.Name)
+			}
+			if !w.s.MemberHasRole(testCentralGuild, alice.ID, to.TravelRoleID) {
+				t.Errorf("user is missing the %s travel role", to.Name)
+			}
+			if StringInSlice(alice.ID, w.getRoom(t, from.ID).UserIDs) {
+				t.Errorf("user is still listed in %s", from.Name)
+			}
+			if !StringInSlice(alice.ID, w.getRoom(t, to.ID).UserIDs) {
+				t.Errorf("user is not listed in %s", to.Name)
+			}
+
+			entries, _ := w.journal.GetAllEntries()
+			if len(entries) > 0 {
+				t.Errorf("%d travel journal entries left behind", len(entries))
+			}
+		})
+	}
+}
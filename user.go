@@ -2,6 +2,7 @@ package main
 
 import (
 	"errors"
+	"github.com/asdine/storm"
 	"sync"
 	"time"
 )
@@ -20,7 +21,7 @@ type User struct {
 	RoleIDs []string
 
 	// Profile stuff
-	Name           string
+	Name           string `storm:"index"`
 	SkinTone       string
 	Race           string
 	Class          string
@@ -30,13 +31,15 @@ type User struct {
 	HairStyle      string
 	Height         string
 
-	Stamina int64
-	Mana    int64
-	Sanity  int64
-	Focus   int64
+	Stamina        int64
+	StaminaUpdated time.Time // When Stamina was last regenerated, see stamina.go
+	Mana           int64
+	Sanity         int64
+	Focus          int64
 
-	QuestFlags []string
-	Statuses   []string
+	QuestFlags      []string
+	Statuses        []string
+	DiscoveredExits []string // Hidden exits this user has found, see hidden_exits.go
 
 	// Body Parts Can Have Individual States
 	REye  string
@@ -63,6 +66,9 @@ type User struct {
 	GuildID string `storm:"index"` // GuildID of the users current guild
 	RoomID  string `storm:"index"` // ChannelID of the users current room
 
+	BindRoomID string    // The bind point recall returns the user to
+	LastRecall time.Time // For the recall cooldown
+
 	ItemsMap []string // An ID pointing to the item in the database
 
 	Strength     int
@@ -171,31 +177,38 @@ func (h *UserManager) RemoveUserByID(userID string) (err error) {
 
 // GetUserByID function
 func (h *UserManager) GetUserByID(userID string) (user User, err error) {
+	h.querylocker.RLock()
+	defer h.querylocker.RUnlock()
 
-	users, err := h.GetAllUsers()
+	db := h.db.rawdb.From("Users")
+	err = db.One("ID", userID, &user)
 	if err != nil {
-		return user, err
-	}
-
-	for _, i := range users {
-		if i.ID == userID {
-			return i, nil
+		if err == storm.ErrNotFound {
+			return user, errors.New("No record found")
 		}
+		return user, err
 	}
 
-	return user, errors.New("No record found")
+	return user, nil
 }
 
 // GetUserByName function
 func (h *UserManager) GetUserByName(username string, guildID string) (user User, err error) {
+	h.querylocker.RLock()
+	defer h.querylocker.RUnlock()
 
-	users, err := h.GetAllUsers()
+	var users []User
+	db := h.db.rawdb.From("Users")
+	err = db.Find("Name", username, &users)
 	if err != nil {
+		if err == storm.ErrNotFound {
+			return user, errors.New("No record found")
+		}
 		return user, err
 	}
 
 	for _, i := range users {
-		if i.Name == username && i.GuildID == guildID {
+		if i.GuildID == guildID {
 			return i, nil
 		}
 	}
@@ -203,10 +216,24 @@ func (h *UserManager) GetUserByName(username string, guildID string) (user User,
 	return user, errors.New("No record found")
 }
 
+// GetUsersByRoomID function
+func (h *UserManager) GetUsersByRoomID(roomID string) (userlist []User, err error) {
+	h.querylocker.RLock()
+	defer h.querylocker.RUnlock()
+
+	db := h.db.rawdb.From("Users")
+	err = db.Find("RoomID", roomID, &userlist)
+	if err != nil && err != storm.ErrNotFound {
+		return userlist, err
+	}
+
+	return userlist, nil
+}
+
 // GetAllUsers function
 func (h *UserManager) GetAllUsers() (userlist []User, err error) {
-	h.querylocker.Lock()
-	defer h.querylocker.Unlock()
+	h.querylocker.RLock()
+	defer h.querylocker.RUnlock()
 
 	db := h.db.rawdb.From("Users")
 	err = db.All(&userlist)
@@ -4,8 +4,6 @@ import (
 	"fmt"
 	"github.com/bwmarrin/discordgo"
 	"strconv"
-	"strings"
-	"time"
 )
 
 // UserHandler struct
@@ -13,47 +11,33 @@ type UserHandler struct {
 	conf        *Config
 	db          *DBHandler
 	cp          string
-	logchan     chan string
+	logger      *Logger
+	queue       *DiscordQueue
 	usermanager *UserManager
+	router      *CommandRouter
 }
 
 // Init function
 func (h *UserHandler) Init() {
-	h.cp = h.conf.MainConfig.CP
+	h.cp = h.conf.CommandPrefix()
 	h.usermanager = new(UserManager)
 	h.usermanager.db = h.db
-}
-
-// Read function
-func (h *UserHandler) Read(s *discordgo.Session, m *discordgo.MessageCreate) {
-
-	cp := h.conf.MainConfig.CP
-
-	if m.Author.ID == s.State.User.ID {
-		return
-	}
-
-	// Ignore bots
-	if m.Author.Bot {
-		return
-	}
-
-	message := strings.Fields(m.Content)
 
-	if len(message) < 1 {
-		return
-	}
+	h.router.AddRoute("groups", false, h.ReadCommand)
+	h.router.AddRoute("roles", false, h.ReadCommand)
+	h.router.AddRoute("repairuser", false, h.ReadCommand)
+	h.router.AddRoute("debuguser", false, h.ReadCommand)
+	h.router.AddRoute("attributes", false, h.ReadCommand)
+	h.router.AddRoute("stats", false, h.ReadCommand)
+}
 
-	user, err := h.db.GetUser(m.Author.ID)
-	if err != nil {
-		//fmt.Println("Error finding usermanager")
-		return
-	}
+// ReadCommand function
+func (h *UserHandler) ReadCommand(message []string, user User, s DiscordSession, m *discordgo.MessageCreate) {
 
 	// We use this a bit, this is the author id formatted as a mention
 	mention := m.Author.Mention()
 
-	if message[0] == cp+"groups" {
+	if message[0] == "groups" {
 		mentions := m.Mentions
 
 		if len(mentions) == 0 {
@@ -75,7 +59,7 @@ func (h *UserHandler) Read(s *discordgo.Session, m *discordgo.MessageCreate) {
 			groups, err := h.GetGroups(mentions[0].ID, s, m.ChannelID)
 			if err != nil {
 				s.ChannelMessageSend(m.ChannelID, "Error retrieving groups: "+err.Error())
-				h.logchan <- "Bot " + mention + " || " + m.Author.Username + " || " + "groups" + "||" + err.Error()
+				h.logger.Error(BOTLOG, LogFields{User: m.Author.ID, Command: "groups"}, "Error running groups requested by "+mention+": "+err.Error())
 				return
 			}
 			s.ChannelMessageSend(m.ChannelID, h.FormatGroups(groups))
@@ -83,7 +67,7 @@ func (h *UserHandler) Read(s *discordgo.Session, m *discordgo.MessageCreate) {
 		}
 	}
 
-	if message[0] == cp+"roles" {
+	if message[0] == "roles" {
 		mentions := m.Mentions
 
 		if len(mentions) == 0 {
@@ -105,7 +89,7 @@ func (h *UserHandler) Read(s *discordgo.Session, m *discordgo.MessageCreate) {
 			roles, err := h.GetRoles(mentions[0].ID, s, m.ChannelID)
 			if err != nil {
 				s.ChannelMessageSend(m.ChannelID, "Error retrieving roles: "+err.Error())
-				h.logchan <- "Bot " + mention + " || " + m.Author.Username + " || " + "roles" + "||" + err.Error()
+				h.logger.Error(BOTLOG, LogFields{User: m.Author.ID, Command: "roles"}, "Error running roles requested by "+mention+": "+err.Error())
 				return
 			}
 			s.ChannelMessageSend(m.ChannelID, h.FormatRoles(roles))
@@ -113,7 +97,7 @@ func (h *UserHandler) Read(s *discordgo.Session, m *discordgo.MessageCreate) {
 		}
 	}
 
-	if message[0] == cp+"repairuser" {
+	if message[0] == "repairuser" {
 		if !user.CheckRole("moderator") {
 			s.ChannelMessageSend(m.ChannelID, "You do not have permission to use this command")
 			return
@@ -125,7 +109,7 @@ func (h *UserHandler) Read(s *discordgo.Session, m *discordgo.MessageCreate) {
 			err := h.RepairUser(mentions[0].ID, s, m.ChannelID, "")
 			if err != nil {
 				s.ChannelMessageSend(m.ChannelID, "Error repairing usermanager: "+err.Error())
-				h.logchan <- "Bot " + mention + " || " + m.Author.Username + " || " + "repairuser" + "||" + err.Error()
+				h.logger.Error(BOTLOG, LogFields{User: m.Author.ID, Command: "repairuser"}, "Error running repairuser requested by "+mention+": "+err.Error())
 				return
 			}
 			s.ChannelMessageSend(m.ChannelID, ":construction: User record repaired!")
@@ -136,7 +120,7 @@ func (h *UserHandler) Read(s *discordgo.Session, m *discordgo.MessageCreate) {
 			err := h.RepairUser(mentions[0].ID, s, m.ChannelID, message[2])
 			if err != nil {
 				s.ChannelMessageSend(m.ChannelID, "Error repairing usermanager: "+err.Error())
-				h.logchan <- "Bot " + mention + " || " + m.Author.Username + " || " + "repairuser" + "||" + err.Error()
+				h.logger.Error(BOTLOG, LogFields{User: m.Author.ID, Command: "repairuser"}, "Error running repairuser requested by "+mention+": "+err.Error())
 				return
 			}
 			s.ChannelMessageSend(m.ChannelID, ":construction: User record repaired!")
@@ -145,7 +129,7 @@ func (h *UserHandler) Read(s *discordgo.Session, m *discordgo.MessageCreate) {
 
 	}
 
-	if message[0] == cp+"debuguser" {
+	if message[0] == "debuguser" {
 		if !user.CheckRole("moderator") {
 			s.ChannelMessageSend(m.ChannelID, "You do not have permission to use this command")
 			return
@@ -156,14 +140,14 @@ func (h *UserHandler) Read(s *discordgo.Session, m *discordgo.MessageCreate) {
 			err := h.DebugUser(mentions[0].ID, s, m.ChannelID)
 			if err != nil {
 				s.ChannelMessageSend(m.ChannelID, "Error repairing usermanager: "+err.Error())
-				h.logchan <- "Bot " + mention + " || " + m.Author.Username + " || " + "repairuser" + "||" + err.Error()
+				h.logger.Error(BOTLOG, LogFields{User: m.Author.ID, Command: "debuguser"}, "Error running debuguser requested by "+mention+": "+err.Error())
 				return
 			}
 			return
 		}
 	}
 
-	if message[0] == cp+"attributes" {
+	if message[0] == "attributes" {
 		/*
 			if !usermanager.CheckRole("player") || !usermanager.CheckRole("Registered") {
 				s.ChannelMessageSend(m.ChannelID, "You do not have permission to use this command")
@@ -191,7 +175,7 @@ func (h *UserHandler) Read(s *discordgo.Session, m *discordgo.MessageCreate) {
 		s.ChannelMessageSend(m.ChannelID, ":large_blue_diamond: Attributes: \n"+attributes)
 		return
 	}
-	if message[0] == cp+"stats" {
+	if message[0] == "stats" {
 		/*
 			if !usermanager.CheckRole("player") || !usermanager.CheckRole("Registered") {
 				s.ChannelMessageSend(m.ChannelID, "You do not have permission to use this command")
@@ -222,7 +206,7 @@ func (h *UserHandler) Read(s *discordgo.Session, m *discordgo.MessageCreate) {
 }
 
 // AddItem function
-func (h *UserHandler) AddItem(itemid string, userid string, s *discordgo.Session, channelID string) (err error) {
+func (h *UserHandler) AddItem(itemid string, userid string, s DiscordSession, channelID string) (err error) {
 	// Make sure usermanager is in the database before we pull it out!
 	user, err := h.GetUser(userid, s, channelID)
 	if err != nil {
@@ -233,7 +217,7 @@ func (h *UserHandler) AddItem(itemid string, userid string, s *discordgo.Session
 }
 
 // RemoveItem function
-func (h *UserHandler) RemoveItem(itemid string, userid string, s *discordgo.Session, channelID string) (err error) {
+func (h *UserHandler) RemoveItem(itemid string, userid string, s DiscordSession, channelID string) (err error) {
 	// Make sure usermanager is in the database before we pull it out!
 	user, err := h.GetUser(userid, s, channelID)
 	if err != nil {
@@ -244,7 +228,7 @@ func (h *UserHandler) RemoveItem(itemid string, userid string, s *discordgo.Sess
 }
 
 // RepairUser function
-func (h *UserHandler) RepairUser(userid string, s *discordgo.Session, channelID string, guildID string) (err error) {
+func (h *UserHandler) RepairUser(userid string, s DiscordSession, channelID string, guildID string) (err error) {
 	// Make sure usermanager is in the database before we pull it out!
 	user, err := h.GetUser(userid, s, channelID)
 	if err != nil {
@@ -261,9 +245,9 @@ func (h *UserHandler) RepairUser(userid string, s *discordgo.Session, channelID
 
 	// These are ID's now!
 	for _, roleID := range user.RoleIDs {
-		// Ignore errors if role doesn't exist on the guild
-		time.Sleep(time.Duration(time.Second * 1))
-		_ = s.GuildMemberRoleAdd(guildID, user.ID, roleID)
+		// Roles that don't exist on the guild fail here, which is only logged
+		h.queue.Report(h.queue.RoleAdd(s, guildID, user.ID, roleID, QueueNormal),
+			LogFields{User: user.ID, Guild: guildID}, "Could not restore role "+roleID)
 	}
 
 	db := h.db.rawdb.From("Users")
@@ -277,7 +261,7 @@ func (h *UserHandler) RepairUser(userid string, s *discordgo.Session, channelID
 }
 
 // DebugUser function
-func (h *UserHandler) DebugUser(userid string, s *discordgo.Session, channelID string) (err error) {
+func (h *UserHandler) DebugUser(userid string, s DiscordSession, channelID string) (err error) {
 
 	user, err := h.GetUser(userid, s, channelID)
 	if err != nil {
@@ -303,7 +287,7 @@ func (h *UserHandler) DebugUser(userid string, s *discordgo.Session, channelID s
 }
 
 // GetUser function
-func (h *UserHandler) GetUser(userid string, s *discordgo.Session, channelID string) (user User, err error) {
+func (h *UserHandler) GetUser(userid string, s DiscordSession, channelID string) (user User, err error) {
 
 	// Make sure usermanager is in the database before we pull it out!
 	h.CheckUser(userid, s, channelID)
@@ -318,7 +302,7 @@ func (h *UserHandler) GetUser(userid string, s *discordgo.Session, channelID str
 }
 
 // CheckUser function
-func (h *UserHandler) CheckUser(ID string, s *discordgo.Session, channelID string) {
+func (h *UserHandler) CheckUser(ID string, s DiscordSession, channelID string) {
 
 	db := h.db.rawdb.From("Users")
 
@@ -347,7 +331,7 @@ func (h *UserHandler) CheckUser(ID string, s *discordgo.Session, channelID strin
 }
 
 // GetRoles function
-func (h *UserHandler) GetRoles(ID string, s *discordgo.Session, channelID string) (roles []string, err error) {
+func (h *UserHandler) GetRoles(ID string, s DiscordSession, channelID string) (roles []string, err error) {
 
 	h.CheckUser(ID, s, channelID)
 	user, err := h.GetUser(ID, s, channelID)
@@ -358,7 +342,7 @@ func (h *UserHandler) GetRoles(ID string, s *discordgo.Session, channelID string
 }
 
 // GetGroups function
-func (h *UserHandler) GetGroups(ID string, s *discordgo.Session, channelID string) (groups []string, err error) {
+func (h *UserHandler) GetGroups(ID string, s DiscordSession, channelID string) (groups []string, err error) {
 
 	h.CheckUser(ID, s, channelID)
 	user, err := h.GetUser(ID, s, channelID)
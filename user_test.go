@@ -0,0 +1,49 @@
+package main
+
+import (
+	"strconv"
+	"testing"
+)
+
+// seedUsers function
+// Saves count users in one transaction, spread over the same guilds as seedRooms
+func seedUsers(b *testing.B, db *DBHandler, count int) *UserManager {
+	b.Helper()
+
+	tx, err := db.rawdb.Begin(true)
+	if err != nil {
+		b.Fatal(err)
+	}
+	defer tx.Rollback()
+
+	bucket := tx.From("Users")
+	for i := 0; i < count; i++ {
+		user := User{ID: strconv.Itoa(100000 + i), Name: "user-" + strconv.Itoa(i),
+			GuildID: strconv.Itoa(i % benchmarkGuilds)}
+		user.Init()
+		err = bucket.Save(&user)
+		if err != nil {
+			b.Fatal(err)
+		}
+	}
+	err = tx.Commit()
+	if err != nil {
+		b.Fatal(err)
+	}
+	return &UserManager{db: db}
+}
+
+func BenchmarkGetUserByID(b *testing.B) {
+	for _, size := range benchmarkSizes {
+		b.Run(strconv.Itoa(size), func(b *testing.B) {
+			users := seedUsers(b, newBenchmarkDB(b), size)
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				_, err := users.GetUserByID(strconv.Itoa(100000 + i%size))
+				if err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
@@ -34,6 +34,16 @@ func RemoveStringFromSlice(s []string, r string) []string {
 	return s
 }
 
+// StringInSlice function
+func StringInSlice(r string, s []string) bool {
+	for _, v := range s {
+		if v == r {
+			return true
+		}
+	}
+	return false
+}
+
 // SafeInput function
 func SafeInput(s *discordgo.Session, m *discordgo.MessageCreate, conf *Config) bool {
 	// Ignore all messages created by the bot itself
@@ -46,7 +56,7 @@ func SafeInput(s *discordgo.Session, m *discordgo.MessageCreate, conf *Config) b
 		return false
 	}
 
-	if !strings.HasPrefix(m.Content, conf.MainConfig.CP) {
+	if !strings.HasPrefix(m.Content, conf.CommandPrefix()) {
 		return false
 	}
 
@@ -63,7 +73,7 @@ func SafeInput(s *discordgo.Session, m *discordgo.MessageCreate, conf *Config) b
 func CleanCommand(input string, conf *Config) (command string, message []string) {
 
 	// Set our command prefix to the default one within our config file
-	cp := conf.MainConfig.CP
+	cp := conf.CommandPrefix()
 	message = strings.Fields(input)
 
 	// Remove the prefix from our command
@@ -120,8 +130,18 @@ func CleanChannel(mention string) string {
 
 }
 
+// CleanUserMention function
+func CleanUserMention(mention string) string {
+
+	mention = strings.TrimPrefix(mention, "<@")
+	mention = strings.TrimPrefix(mention, "!")
+	mention = strings.TrimSuffix(mention, ">")
+	return mention
+
+}
+
 // MentionChannel function
-func MentionChannel(channelid string, s *discordgo.Session) (mention string, err error) {
+func MentionChannel(channelid string, s DiscordSession) (mention string, err error) {
 	dgchannel, err := s.Channel(channelid)
 	if err != nil {
 		return "", err
@@ -131,7 +151,7 @@ func MentionChannel(channelid string, s *discordgo.Session) (mention string, err
 }
 
 // CheckPermissions function
-func CheckPermissions(command string, channelid string, user *User, s *discordgo.Session, com *CommandHandler) bool {
+func CheckPermissions(command string, channelid string, user *User, s DiscordSession, com *CommandHandler) bool {
 
 	usergroups, err := com.user.GetGroups(user.ID, s, channelid)
 	if err != nil {
@@ -196,7 +216,7 @@ func CheckPermissions(command string, channelid string, user *User, s *discordgo
 
 /*
 // MentionOwner function
-func MentionOwner(conf *Config, s *discordgo.Session, m *discordgo.MessageCreate) (mention string, err error) {
+func MentionOwner(conf *Config, s DiscordSession, m *discordgo.MessageCreate) (mention string, err error) {
 	usermanager, err := s.User(conf.MainConfig.AdminID)
 	if err != nil {
 		return mention, err
@@ -206,7 +226,7 @@ func MentionOwner(conf *Config, s *discordgo.Session, m *discordgo.MessageCreate
 }
 
 // OwnerName function
-func OwnerName(conf *Config, s *discordgo.Session, m *discordgo.MessageCreate) (name string, err error) {
+func OwnerName(conf *Config, s DiscordSession, m *discordgo.MessageCreate) (name string, err error) {
 	usermanager, err := s.User(conf.DiscordConfig.AdminID)
 	if err != nil {
 		return name, err
@@ -218,7 +238,7 @@ func OwnerName(conf *Config, s *discordgo.Session, m *discordgo.MessageCreate) (
 */
 
 // IsVoiceChannelEmpty function
-func IsVoiceChannelEmpty(s *discordgo.Session, channelid string, botid string) bool {
+func IsVoiceChannelEmpty(s DiscordSession, channelid string, botid string) bool {
 
 	channel, err := s.Channel(channelid)
 	if err != nil {
@@ -297,7 +317,7 @@ func truncateString(str string, num int) string {
 }
 
 // GetGuildID function
-func getGuildID(s *discordgo.Session, channelID string) (guildID string, err error) {
+func getGuildID(s DiscordSession, channelID string) (guildID string, err error) {
 
 	channel, err := s.Channel(channelID)
 	if err != nil {
@@ -308,7 +328,7 @@ func getGuildID(s *discordgo.Session, channelID string) (guildID string, err err
 }
 
 // getGuildEveryoneRoleID function
-func getGuildEveryoneRoleID(s *discordgo.Session, guildID string) (everyoneid string, err error) {
+func getGuildEveryoneRoleID(s DiscordSession, guildID string) (everyoneid string, err error) {
 
 	roles, err := s.GuildRoles(guildID)
 	if err != nil {
@@ -326,7 +346,7 @@ func getGuildEveryoneRoleID(s *discordgo.Session, guildID string) (everyoneid st
 }
 
 // getGuildChannelIDByName function
-func getGuildChannelIDByName(s *discordgo.Session, guildID string, name string) (channelid string, err error) {
+func getGuildChannelIDByName(s DiscordSession, guildID string, name string) (channelid string, err error) {
 
 	channels, err := s.GuildChannels(guildID)
 	if err != nil {
@@ -343,7 +363,7 @@ func getGuildChannelIDByName(s *discordgo.Session, guildID string, name string)
 }
 
 // getRoleIDByName function
-func getRoleIDByName(s *discordgo.Session, guildID string, name string) (roleid string, err error) {
+func getRoleIDByName(s DiscordSession, guildID string, name string) (roleid string, err error) {
 
 	name = strings.Title(name)
 	roles, err := s.GuildRoles(guildID)
@@ -361,7 +381,7 @@ func getRoleIDByName(s *discordgo.Session, guildID string, name string) (roleid
 }
 
 // getRoleNameByID function
-func getRoleNameByID(roleID string, guildID string, s *discordgo.Session) (rolename string, err error) {
+func getRoleNameByID(roleID string, guildID string, s DiscordSession) (rolename string, err error) {
 
 	roles, err := s.GuildRoles(guildID)
 	if err != nil {
@@ -379,7 +399,7 @@ func getRoleNameByID(roleID string, guildID string, s *discordgo.Session) (rolen
 }
 
 // getGuildOwnerID function
-func getGuildOwnerID(s *discordgo.Session, channelID string) (ownerID string, err error) {
+func getGuildOwnerID(s DiscordSession, channelID string) (ownerID string, err error) {
 
 	guildID, err := getGuildID(s, channelID)
 	if err != nil {
@@ -396,7 +416,7 @@ func getGuildOwnerID(s *discordgo.Session, channelID string) (ownerID string, er
 }
 
 // FlushMessages function
-func FlushMessages(s *discordgo.Session, channelID string, count int) (err error) {
+func FlushMessages(s DiscordSession, channelID string, count int) (err error) {
 
 	if count <= 0 {
 		return errors.New(":rotating_light: Invalid message count supplied")
@@ -450,7 +470,7 @@ func RollDiceString(faces int, count int) (rolls []string) {
 	randomGen := rand.New(source)
 
 	for i := 0; i < count; i++ {
-		roll := randomGen.Intn(faces)
+		roll := randomGen.Intn(faces) + 1
 		rolls = append(rolls, strconv.Itoa(roll))
 	}
 
@@ -472,7 +492,7 @@ func RollDice(faces int, count int) (rolls []int) {
 	randomGen := rand.New(source)
 
 	for i := 0; i < count; i++ {
-		roll := randomGen.Intn(faces)
+		roll := randomGen.Intn(faces) + 1
 		rolls = append(rolls, roll)
 	}
 
@@ -493,7 +513,7 @@ func RollDiceAndAdd(faces int, count int) (total int) {
 	randomGen := rand.New(source)
 
 	for i := 0; i < count; i++ {
-		roll := randomGen.Intn(faces)
+		roll := randomGen.Intn(faces) + 1
 		total = total + roll
 	}
 
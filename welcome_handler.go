@@ -2,37 +2,32 @@ package main
 
 import (
 	"github.com/bwmarrin/discordgo"
-	"strings"
 )
 
 // WelcomeHandler struct
 type WelcomeHandler struct {
-	conf *Config
-	user *UserHandler
-	db   *DBHandler
+	conf   *Config
+	user   *UserHandler
+	db     *DBHandler
+	router *CommandRouter
 }
 
-// Read function
-func (h *WelcomeHandler) Read(s *discordgo.Session, m *discordgo.MessageCreate) {
-
-	cp := h.conf.MainConfig.CP
-
-	if !SafeInput(s, m, h.conf) {
-		return
-	}
-
-	if strings.HasPrefix(m.Content, cp+"about") {
+// Init function
+func (h *WelcomeHandler) Init() {
+	h.router.AddRoute("about", false, h.ReadAbout)
+}
 
-		aboutmessage := ":bulb: The Aether :bulb: ```\n"
-		aboutmessage = aboutmessage + "The Aether is a roleplaying game for Discord. Within The Aether you may take many roles.\n\n"
-		aboutmessage = aboutmessage + "Will you become a traveled adventurer or a rich king? Perhaps a ship merchantman or a shopkeeper?\n\n"
-		aboutmessage = aboutmessage + "Whatever you choose to become, The Aether welcomes you on your journey!"
-		aboutmessage = aboutmessage + "\n```\n"
+// ReadAbout function
+func (h *WelcomeHandler) ReadAbout(command []string, user User, s DiscordSession, m *discordgo.MessageCreate) {
 
-		s.ChannelMessageSend(m.ChannelID, aboutmessage)
-		return
-	}
+	aboutmessage := ":bulb: The Aether :bulb: ```\n"
+	aboutmessage = aboutmessage + "The Aether is a roleplaying game for Discord. Within The Aether you may take many roles.\n\n"
+	aboutmessage = aboutmessage + "Will you become a traveled adventurer or a rich king? Perhaps a ship merchantman or a shopkeeper?\n\n"
+	aboutmessage = aboutmessage + "Whatever you choose to become, The Aether welcomes you on your journey!"
+	aboutmessage = aboutmessage + "\n```\n"
 
+	s.ChannelMessageSend(m.ChannelID, aboutmessage)
+	return
 }
 
 // ReadNewMember function
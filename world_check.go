@@ -0,0 +1,356 @@
+package main
+
+/*
+The world checker looks for rooms, exits and users that have drifted out of line with each other.
+
+Problems are reported by severity:
+
+	error   - something players will run into, ie an exit to a deleted room or a room travel can't use
+	warning - something that is probably a mistake, ie a missing reverse exit or a room nobody can reach
+	info    - worth knowing about but usually intended, ie one way exits
+
+Some problems have a repair that can't lose anything a builder set up on purpose (removing an exit that leads
+nowhere, putting a user back in their room's user list). Those are only made when asked for with world check --fix,
+everything else is left for a builder to sort out.
+
+*/
+
+import (
+	"errors"
+	"sort"
+	"strings"
+)
+
+// World check severities, in the order they are reported
+const (
+	WorldCheckError   = "error"
+	WorldCheckWarning = "warning"
+	WorldCheckInfo    = "info"
+)
+
+// worldCheckSeverities lists the severities in the order they are reported
+var worldCheckSeverities = []string{WorldCheckError, WorldCheckWarning, WorldCheckInfo}
+
+// WorldIssue struct
+type WorldIssue struct {
+	Severity string
+	RoomID   string
+	Message  string
+
+	repair func() error // nil when there is no safe repair
+}
+
+// Fixable function
+func (i *WorldIssue) Fixable() bool {
+	return i.repair != nil
+}
+
+// WorldReport struct
+type WorldReport struct {
+	Issues []WorldIssue
+}
+
+// WorldChecker struct
+type WorldChecker struct {
+	rooms *Rooms
+	users *UserManager
+	perm  *PermissionsHandler
+	conf  *Config
+}
+
+// Check function
+func (c *WorldChecker) Check(s DiscordSession) (report WorldReport, err error) {
+
+	roomlist, err := c.rooms.GetAllRooms()
+	if err != nil {
+		return report, err
+	}
+	rooms := make(map[string]Room)
+	for _, room := range roomlist {
+		rooms[room.ID] = room
+	}
+
+	for _, room := range roomlist {
+		c.checkRoomRoles(room, &report)
+		c.checkExits(room, rooms, &report)
+		c.checkTransfer(room, rooms, s, &report)
+	}
+
+	err = c.checkUsers(rooms, &report)
+	if err != nil {
+		return report, err
+	}
+
+	c.checkReachable(roomlist, &report)
+
+	sort.SliceStable(report.Issues, func(i, j int) bool {
+		return severityRank(report.Issues[i].Severity) < severityRank(report.Issues[j].Severity)
+	})
+	return report, nil
+}
+
+// severityRank function
+func severityRank(severity string) int {
+	for i, candidate := range worldCheckSeverities {
+		if candidate == severity {
+			return i
+		}
+	}
+	return len(worldCheckSeverities)
+}
+
+// add function
+func (r *WorldReport) add(severity string, room Room, message string, repair func() error) {
+	r.Issues = append(r.Issues, WorldIssue{Severity: severity, RoomID: room.ID,
+		Message: room.Name + " (" + room.ID + "): " + message, repair: repair})
+}
+
+// checkRoomRoles function
+// Travel refuses to use a room without any AdditionalRoleIDs
+func (c *WorldChecker) checkRoomRoles(room Room, report *WorldReport) {
+
+	if len(room.AdditionalRoleIDs) > 0 {
+		return
+	}
+	if room.TravelRoleID == "" {
+		report.add(WorldCheckError, room, "has no roles, travel into or out of it will fail", nil)
+		return
+	}
+
+	report.add(WorldCheckError, room, "has no roles, travel into or out of it will fail (its travel role can be "+
+		"added back)", func() error {
+		record, err := c.rooms.GetRoomByID(room.ID)
+		if err != nil {
+			return err
+		}
+		record.AdditionalRoleIDs = append(record.AdditionalRoleIDs, record.TravelRoleID)
+		return c.rooms.SaveRoomToDB(record)
+	})
+}
+
+// checkExits function
+func (c *WorldChecker) checkExits(room Room, rooms map[string]Room, report *WorldReport) {
+
+	for _, exit := range room.Exits {
+		target, ok := rooms[exit.TargetID]
+		if !ok {
+			targetID := exit.TargetID
+			report.add(WorldCheckError, room, "exit "+exit.Name+" leads to a deleted room "+targetID, func() error {
+				record, err := c.rooms.GetRoomByID(room.ID)
+				if err != nil {
+					return err
+				}
+				record.RemoveExitsTo(targetID)
+				return c.rooms.SaveRoomToDB(record)
+			})
+			continue
+		}
+
+		linked, _ := c.rooms.IsRoomLinkedTo(target.ID, room.ID)
+		if exit.ReverseName != "" {
+			if reverse, found := target.GetExit(exit.ReverseName); !found || reverse.TargetID != room.ID {
+				report.add(WorldCheckWarning, room, "exit "+exit.Name+" expects "+target.Name+" to lead back through "+
+					exit.ReverseName+" but it has no such exit", nil)
+			}
+			continue
+		}
+		if !linked {
+			report.add(WorldCheckInfo, room, "exit "+exit.Name+" to "+target.Name+" is one way", nil)
+		}
+	}
+}
+
+// checkTransfer function
+func (c *WorldChecker) checkTransfer(room Room, rooms map[string]Room, s DiscordSession, report *WorldReport) {
+
+	if room.TransferRoomID == "" {
+		if room.GuildTransferInvite != "" {
+			report.add(WorldCheckWarning, room, "has a transfer invite but no transfer room", nil)
+		}
+		return
+	}
+
+	if _, ok := rooms[room.TransferRoomID]; !ok {
+		report.add(WorldCheckError, room, "transfers to a deleted room "+room.TransferRoomID, func() error {
+			record, err := c.rooms.GetRoomByID(room.ID)
+			if err != nil {
+				return err
+			}
+			record.TransferRoomID = ""
+			record.GuildTransferInvite = ""
+			err = c.rooms.SaveRoomToDB(record)
+			if err != nil {
+				return err
+			}
+			return c.perm.ApplyTravelRolePerms(record.ID, record.GuildID, s)
+		})
+		return
+	}
+
+	if room.GuildTransferInvite == "" {
+		report.add(WorldCheckError, room, "has a transfer room but no invite, travelers will be stranded", nil)
+		return
+	}
+
+	// Invites are stored as links, discord only wants the code on the end
+	code := room.GuildTransferInvite[strings.LastIndex(room.GuildTransferInvite, "/")+1:]
+	if _, err := s.Invite(code); err != nil {
+		report.add(WorldCheckError, room, "transfer invite "+room.GuildTransferInvite+" is invalid or expired: "+
+			err.Error(), nil)
+	}
+}
+
+// checkUsers function
+func (c *WorldChecker) checkUsers(rooms map[string]Room, report *WorldReport) (err error) {
+
+	users, err := c.users.GetAllUsers()
+	if err != nil {
+		return err
+	}
+
+	locations := make(map[string]string)
+	for _, user := range users {
+		if user.RoomID == "" {
+			continue
+		}
+		locations[user.ID] = user.RoomID
+
+		room, ok := rooms[user.RoomID]
+		if !ok {
+			report.Issues = append(report.Issues, WorldIssue{Severity: WorldCheckError, RoomID: user.RoomID,
+				Message: "user " + user.ID + " is in a deleted room " + user.RoomID})
+			continue
+		}
+		if !StringInSlice(user.ID, room.UserIDs) {
+			userID := user.ID
+			report.add(WorldCheckWarning, room, "does not list user "+userID+" who is in it", func() error {
+				record, err := c.rooms.GetRoomByID(room.ID)
+				if err != nil {
+					return err
+				}
+				if !StringInSlice(userID, record.UserIDs) {
+					record.UserIDs = append(record.UserIDs, userID)
+				}
+				return c.rooms.SaveRoomToDB(record)
+			})
+		}
+	}
+
+	for _, room := range rooms {
+		for _, userID := range room.UserIDs {
+			if locations[userID] == room.ID {
+				continue
+			}
+			roomID, staleID := room.ID, userID
+			report.add(WorldCheckWarning, room, "lists user "+staleID+" who is not in it", func() error {
+				record, err := c.rooms.GetRoomByID(roomID)
+				if err != nil {
+					return err
+				}
+				var remaining []string
+				for _, existing := range record.UserIDs {
+					if existing != staleID {
+						remaining = append(remaining, existing)
+					}
+				}
+				record.UserIDs = remaining
+				return c.rooms.SaveRoomToDB(record)
+			})
+		}
+	}
+	return nil
+}
+
+// checkReachable function
+// New players start in the central guild's crossroads, any room that can't be reached from there by exits or
+// transfers is cut off from the rest of the world
+func (c *WorldChecker) checkReachable(roomlist []Room, report *WorldReport) {
+
+	graph := WorldGraph{Rooms: make(map[string]MapNode), Adjacency: make(map[string][]MapEdge)}
+	startID := ""
+	for _, room := range roomlist {
+		graph.Rooms[room.ID] = MapNode{ID: room.ID, Name: room.Name, GuildID: room.GuildID}
+		for _, exit := range room.Exits {
+			graph.Adjacency[room.ID] = append(graph.Adjacency[room.ID], MapEdge{From: room.ID, To: exit.TargetID,
+				Name: exit.Name, Kind: MapEdgeExit})
+		}
+		if room.TransferRoomID != "" {
+			graph.Adjacency[room.ID] = append(graph.Adjacency[room.ID], MapEdge{From: room.ID,
+				To: room.TransferRoomID, Kind: MapEdgeTransfer})
+		}
+		if room.Name == "crossroads" && room.GuildID == c.conf.MainConfig.CentralGuildID {
+			startID = room.ID
+		}
+	}
+	if startID == "" {
+		report.Issues = append(report.Issues, WorldIssue{Severity: WorldCheckError,
+			Message: "the central guild has no crossroads room, reachability was not checked"})
+		return
+	}
+
+	for _, room := range roomlist {
+		if _, err := graph.ShortestPath(startID, room.ID); err != nil {
+			report.add(WorldCheckWarning, room, "cannot be reached from the crossroads", nil)
+		}
+	}
+}
+
+// Fix function
+// Makes every safe repair in the report, returns how many were made
+func (c *WorldChecker) Fix(report WorldReport) (fixed int, err error) {
+
+	var problems []string
+	for _, issue := range report.Issues {
+		if !issue.Fixable() {
+			continue
+		}
+		err = issue.repair()
+		if err != nil {
+			problems = append(problems, issue.Message+": "+err.Error())
+			continue
+		}
+		fixed++
+	}
+
+	if len(problems) > 0 {
+		return fixed, errors.New("Some repairs failed: " + strings.Join(problems, "; "))
+	}
+	return fixed, nil
+}
+
+// Count function
+func (r *WorldReport) Count(severity string) (count int) {
+	for _, issue := range r.Issues {
+		if issue.Severity == severity {
+			count++
+		}
+	}
+	return count
+}
+
+// Format function
+func (r *WorldReport) Format() (formatted string) {
+
+	if len(r.Issues) == 0 {
+		return "No problems found."
+	}
+
+	for _, severity := range worldCheckSeverities {
+		if r.Count(severity) == 0 {
+			continue
+		}
+		formatted = formatted + strings.ToUpper(severity) + "S\n"
+		for _, issue := range r.Issues {
+			if issue.Severity != severity {
+				continue
+			}
+			formatted = formatted + "  " + issue.Message
+			if issue.Fixable() {
+				formatted = formatted + " [fixable]"
+			}
+			formatted = formatted + "\n"
+		}
+		formatted = formatted + "\n"
+	}
+	return formatted
+}
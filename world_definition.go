@@ -0,0 +1,276 @@
+package main
+
+/*
+World definitions describe a region of rooms as a file instead of a string of room commands.
+
+A definition lists rooms by name along with the category they sit in, their description, topic, travel role, transfer
+link, exits and events. Exits point at other rooms by name, so a file can be imported into any guild, and each room
+lists its own exits (a two way passage appears once in each room). Fields that are left out are left alone on import,
+and importing the same file twice changes nothing the second time.
+
+	rooms:
+	- name: square
+	  category: The Aether
+	  description: A busy market square.
+	  exits:
+	  - name: enter tavern
+	    to: tavern
+	    reverse: leave tavern
+	- name: tavern
+	  category: The Aether
+	  exits:
+	  - name: leave tavern
+	    to: square
+	    reverse: enter tavern
+
+Definitions can be written as YAML or JSON, see room import and room export.
+
+*/
+
+import (
+	"encoding/json"
+	"errors"
+	"gopkg.in/yaml.v2"
+	"strings"
+)
+
+// WorldDefinition struct
+type WorldDefinition struct {
+	Rooms []RoomDefinition `json:"rooms" yaml:"rooms"`
+}
+
+// RoomDefinition struct
+type RoomDefinition struct {
+	Name        string `json:"name" yaml:"name"`
+	Category    string `json:"category" yaml:"category"`
+	Description string `json:"description,omitempty" yaml:"description,omitempty"`
+	Topic       string `json:"topic,omitempty" yaml:"topic,omitempty"`
+	TravelRole  string `json:"travelrole,omitempty" yaml:"travelrole,omitempty"` // A role name, not an ID
+	Color       int    `json:"color,omitempty" yaml:"color,omitempty"`           // Only used when the room is created
+
+	Transfer *TransferDefinition `json:"transfer,omitempty" yaml:"transfer,omitempty"`
+	Exits    []ExitDefinition    `json:"exits,omitempty" yaml:"exits,omitempty"`
+	Events   []EventDefinition   `json:"events,omitempty" yaml:"events,omitempty"`
+}
+
+// TransferDefinition struct
+type TransferDefinition struct {
+	Invite string `json:"invite" yaml:"invite"`
+	RoomID string `json:"room" yaml:"room"` // A channel ID in another guild
+}
+
+// ExitDefinition struct
+// Anything left empty gets the same defaults as room linkdirection
+type ExitDefinition struct {
+	Name          string   `json:"name" yaml:"name"`
+	To            string   `json:"to" yaml:"to"`
+	Reverse       string   `json:"reverse,omitempty" yaml:"reverse,omitempty"`
+	Aliases       []string `json:"aliases,omitempty" yaml:"aliases,omitempty"`
+	Requirements  []string `json:"requirements,omitempty" yaml:"requirements,omitempty"` // ie "attribute strength 14"
+	LockedMessage string   `json:"locked,omitempty" yaml:"locked,omitempty"`
+	Departure     string   `json:"departure,omitempty" yaml:"departure,omitempty"`
+	Arrival       string   `json:"arrival,omitempty" yaml:"arrival,omitempty"`
+	Cost          int      `json:"cost,omitempty" yaml:"cost,omitempty"`
+	Hidden        int      `json:"hidden,omitempty" yaml:"hidden,omitempty"` // The search DC, see hidden_exits.go
+	Skill         string   `json:"skill,omitempty" yaml:"skill,omitempty"`
+}
+
+// EventDefinition struct
+// The parts of an Event that describe it rather than its state, see event.go
+type EventDefinition struct {
+	Type       string   `json:"type" yaml:"type"`
+	TypeFlags  []string `json:"typeflags,omitempty" yaml:"typeflags,omitempty"`
+	Attachable bool     `json:"attachable,omitempty" yaml:"attachable,omitempty"`
+	LoadOnBoot bool     `json:"loadonboot,omitempty" yaml:"loadonboot,omitempty"`
+	Cycles     int      `json:"cycles,omitempty" yaml:"cycles,omitempty"`
+	Data       []string `json:"data,omitempty" yaml:"data,omitempty"`
+}
+
+// WorldChange struct
+// One step of an import plan
+type WorldChange struct {
+	Room   string
+	Action string
+	Detail string
+}
+
+// WorldPlan struct
+type WorldPlan struct {
+	Changes  []WorldChange
+	Warnings []string // Rooms that can't be imported, these are skipped
+}
+
+// ParseWorldDefinition function
+// Files ending in .json are read as JSON, everything else as YAML
+func ParseWorldDefinition(data []byte, filename string) (definition WorldDefinition, err error) {
+
+	if strings.HasSuffix(strings.ToLower(filename), ".json") {
+		err = json.Unmarshal(data, &definition)
+	} else {
+		err = yaml.UnmarshalStrict(data, &definition)
+	}
+	if err != nil {
+		return definition, errors.New("Could not read world definition: " + err.Error())
+	}
+
+	return definition, definition.Validate()
+}
+
+// Validate function
+func (d *WorldDefinition) Validate() (err error) {
+
+	if len(d.Rooms) == 0 {
+		return errors.New("World definition has no rooms")
+	}
+
+	var problems []string
+	names := make(map[string]bool)
+	for _, room := range d.Rooms {
+		if room.Name == "" {
+			problems = append(problems, "a room is missing its name")
+			continue
+		}
+		if names[room.Name] {
+			problems = append(problems, "room "+room.Name+" is defined more than once")
+		}
+		names[room.Name] = true
+		if room.Category == "" {
+			problems = append(problems, "room "+room.Name+" has no category")
+		}
+		if room.Transfer != nil && (room.Transfer.Invite == "" || room.Transfer.RoomID == "") {
+			problems = append(problems, "room "+room.Name+" has a transfer without an invite and room")
+		}
+		for _, exit := range room.Exits {
+			if exit.Name == "" || exit.To == "" {
+				problems = append(problems, "room "+room.Name+" has an exit without a name or destination")
+			}
+			if exit.Cost < 0 {
+				problems = append(problems, "room "+room.Name+" exit "+exit.Name+" has a negative cost")
+			}
+			if _, err := ParseHiddenSkill(exit.Skill); err != nil {
+				problems = append(problems, "room "+room.Name+" exit "+exit.Name+": "+err.Error())
+			}
+			for _, requirement := range exit.Requirements {
+				_, err := ParseExitRequirement(strings.Fields(requirement))
+				if err != nil {
+					problems = append(problems, "room "+room.Name+" exit "+exit.Name+": "+err.Error())
+				}
+			}
+		}
+	}
+
+	if len(problems) > 0 {
+		return errors.New("Invalid world definition: " + strings.Join(problems, "; "))
+	}
+	return nil
+}
+
+// Marshal function
+func (d *WorldDefinition) Marshal(format string) ([]byte, error) {
+	if format == "json" {
+		return json.MarshalIndent(d, "", "  ")
+	}
+	return yaml.Marshal(d)
+}
+
+// Exit function
+// Builds the exit a definition describes, from a room to the room with targetID
+func (e ExitDefinition) Exit(targetID string, fromroom Room) (exit Exit, err error) {
+
+	exit = NewExit(e.Name, targetID, e.Reverse, fromroom)
+	if len(e.Aliases) > 0 {
+		exit.Aliases = nil
+		for _, alias := range e.Aliases {
+			exit.Aliases = append(exit.Aliases, CleanExitName(alias))
+		}
+	}
+	for _, text := range e.Requirements {
+		requirement, err := ParseExitRequirement(strings.Fields(text))
+		if err != nil {
+			return exit, err
+		}
+		exit.Requirements = append(exit.Requirements, requirement)
+	}
+	exit.LockedMessage = e.LockedMessage
+	if e.Departure != "" {
+		exit.Departure = e.Departure
+	}
+	if e.Arrival != "" {
+		exit.Arrival = e.Arrival
+	}
+	exit.Cost = e.Cost
+	if e.Hidden > 0 {
+		exit.HiddenDC = e.Hidden
+		exit.HiddenSkill, err = ParseHiddenSkill(e.Skill)
+		if err != nil {
+			return exit, err
+		}
+	}
+	return exit, nil
+}
+
+// NewExitDefinition function
+func NewExitDefinition(exit Exit, targetname string) (definition ExitDefinition) {
+
+	definition = ExitDefinition{Name: exit.Name, To: targetname, Reverse: exit.ReverseName, Aliases: exit.Aliases,
+		LockedMessage: exit.LockedMessage, Departure: exit.Departure, Arrival: exit.Arrival, Cost: exit.Cost,
+		Hidden: exit.HiddenDC, Skill: exit.HiddenSkill}
+	for _, requirement := range exit.Requirements {
+		definition.Requirements = append(definition.Requirements, requirement.String())
+	}
+	return definition
+}
+
+// SameExit function
+// Compares everything a definition can set
+func SameExit(a Exit, b Exit) bool {
+	if a.Name != b.Name || a.TargetID != b.TargetID || a.ReverseName != b.ReverseName ||
+		a.LockedMessage != b.LockedMessage || a.Departure != b.Departure || a.Arrival != b.Arrival ||
+		a.Cost != b.Cost || a.HiddenDC != b.HiddenDC || a.HiddenSkill != b.HiddenSkill {
+		return false
+	}
+	if strings.Join(a.Aliases, "\n") != strings.Join(b.Aliases, "\n") || len(a.Requirements) != len(b.Requirements) {
+		return false
+	}
+	for i := range a.Requirements {
+		if a.Requirements[i] != b.Requirements[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// NewEventDefinition function
+func NewEventDefinition(event Event) EventDefinition {
+	return EventDefinition{Type: event.Type, TypeFlags: event.TypeFlags, Attachable: event.Attachable,
+		LoadOnBoot: event.LoadOnBoot, Cycles: event.Cycles, Data: event.Data}
+}
+
+// Matches function
+// Events have no name, so one counts as already imported when it does the same thing in the same room
+func (e EventDefinition) Matches(event Event) bool {
+	return e.Type == event.Type && strings.Join(e.TypeFlags, "\n") == strings.Join(event.TypeFlags, "\n") &&
+		strings.Join(e.Data, "\n") == strings.Join(event.Data, "\n")
+}
+
+// Format function
+func (p *WorldPlan) Format() (formatted string) {
+
+	if len(p.Changes) == 0 && len(p.Warnings) == 0 {
+		return "Nothing to change, the guild already matches this definition."
+	}
+
+	formatted = "```\n"
+	for _, change := range p.Changes {
+		formatted = formatted + change.Room + ": " + change.Action
+		if change.Detail != "" {
+			formatted = formatted + " - " + change.Detail
+		}
+		formatted = formatted + "\n"
+	}
+	for _, warning := range p.Warnings {
+		formatted = formatted + "skipped: " + warning + "\n"
+	}
+	formatted = formatted + "```\n"
+	return formatted
+}
@@ -0,0 +1,567 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"github.com/bwmarrin/discordgo"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+)
+
+// maxWorldDefinitionSize is the largest attachment room import will download
+const maxWorldDefinitionSize = 1024 * 1024
+
+// RegisterWorldCallbacks function
+func (h *RoomsHandler) RegisterWorldCallbacks() {
+
+	h.callback.RegisterCallback("rooms.confirm-import", h.ConfirmImport,
+		"The world import timed out waiting for confirmation, nothing was changed.")
+
+}
+
+// ReadImport function
+// Downloads the attached definition and previews what importing it would change
+func (h *RoomsHandler) ReadImport(guildID string, s DiscordSession, m *discordgo.MessageCreate) {
+
+	if len(m.Attachments) < 1 {
+		s.ChannelMessageSend(m.ChannelID, "import requires a world definition (.yaml or .json) attached to the message")
+		return
+	}
+	attachment := m.Attachments[0]
+
+	definition, err := DownloadWorldDefinition(attachment.URL, attachment.Filename)
+	if err != nil {
+		s.ChannelMessageSend(m.ChannelID, err.Error())
+		return
+	}
+
+	plan, err := h.ImportWorld(definition, guildID, false, m.Author.ID, s)
+	if err != nil {
+		s.ChannelMessageSend(m.ChannelID, "Error planning import: "+err.Error())
+		return
+	}
+	h.SendWorldPlan("Import plan for "+attachment.Filename+":", plan, s, m)
+	if len(plan.Changes) == 0 {
+		return
+	}
+
+	s.ChannelMessageSend(m.ChannelID, "Reply with yes to apply these changes, or "+h.conf.CommandPrefix()+
+		"cancel to leave the guild as it is.")
+	h.callback.Watch("rooms.confirm-import", GetUUIDv2(), attachment.URL+"\n"+attachment.Filename, s, m)
+}
+
+// ConfirmImport function
+// The attachment is downloaded and planned again, so anything that changed since the preview is picked up
+func (h *RoomsHandler) ConfirmImport(args string, s DiscordSession, m *discordgo.MessageCreate) {
+
+	answer := strings.ToLower(strings.TrimSpace(m.Content))
+	if answer != "yes" && answer != "y" {
+		s.ChannelMessageSend(m.ChannelID, "Import cancelled, nothing was changed.")
+		return
+	}
+
+	source := strings.SplitN(args, "\n", 2)
+	if len(source) < 2 {
+		s.ChannelMessageSend(m.ChannelID, "Error importing world: the pending import is missing its file")
+		return
+	}
+
+	definition, err := DownloadWorldDefinition(source[0], source[1])
+	if err != nil {
+		s.ChannelMessageSend(m.ChannelID, err.Error())
+		return
+	}
+
+	guildID, err := getGuildID(s, m.ChannelID)
+	if err != nil {
+		s.ChannelMessageSend(m.ChannelID, "Could not retrieve GuildID: "+err.Error())
+		return
+	}
+
+	plan, err := h.ImportWorld(definition, guildID, true, m.Author.ID, s)
+	if err != nil {
+		// Every step checks the guild before changing it, so running the import again picks up where this stopped
+		h.SendWorldPlan("Import stopped after these changes:", plan, s, m)
+		s.ChannelMessageSend(m.ChannelID, "Error importing world: "+err.Error())
+		return
+	}
+	h.SendWorldPlan("Import complete:", plan, s, m)
+}
+
+// SendWorldPlan function
+// Long plans are sent as a file rather than split over several messages
+func (h *RoomsHandler) SendWorldPlan(header string, plan WorldPlan, s DiscordSession, m *discordgo.MessageCreate) {
+
+	formatted := plan.Format()
+	if len(header)+len(formatted) < 1900 {
+		s.ChannelMessageSend(m.ChannelID, header+"\n"+formatted)
+		return
+	}
+
+	formatted = strings.TrimPrefix(formatted, "```\n")
+	formatted = strings.TrimSuffix(formatted, "```\n")
+	s.ChannelMessageSend(m.ChannelID, header)
+	s.ChannelFileSend(m.ChannelID, "import-plan.txt", bytes.NewBufferString(formatted))
+}
+
+// DownloadWorldDefinition function
+func DownloadWorldDefinition(url string, filename string) (definition WorldDefinition, err error) {
+
+	client := http.Client{Timeout: 30 * time.Second}
+	response, err := client.Get(url)
+	if err != nil {
+		return definition, errors.New("Could not download world definition: " + err.Error())
+	}
+	defer response.Body.Close()
+
+	if response.StatusCode != http.StatusOK {
+		return definition, errors.New("Could not download world definition: " + response.Status)
+	}
+
+	data, err := ioutil.ReadAll(io.LimitReader(response.Body, maxWorldDefinitionSize+1))
+	if err != nil {
+		return definition, errors.New("Could not download world definition: " + err.Error())
+	}
+	if len(data) > maxWorldDefinitionSize {
+		return definition, errors.New("World definition is too large, the limit is 1MB")
+	}
+
+	return ParseWorldDefinition(data, filename)
+}
+
+// ExportWorld function
+// Describes every room in a category, exits that lead out of the category are kept so the file round trips
+func (h *RoomsHandler) ExportWorld(category string, guildID string, s DiscordSession) (definition WorldDefinition,
+	err error) {
+
+	rooms, err := h.rooms.GetRoomsByGuildID(guildID)
+	if err != nil {
+		return definition, err
+	}
+
+	names := make(map[string]string)
+	for _, room := range rooms {
+		names[room.ID] = room.Name
+	}
+
+	eventsdb := EventsDB{db: h.db}
+	events, err := eventsdb.GetAllEvents()
+	if err != nil {
+		events = nil
+	}
+
+	for _, room := range rooms {
+		if !strings.EqualFold(room.ParentName, category) {
+			continue
+		}
+
+		roomdefinition := RoomDefinition{Name: room.Name, Category: room.ParentName, Description: room.Description,
+			Topic: room.Topic}
+
+		if room.TravelRoleID != "" {
+			rolename, err := getRoleNameByID(room.TravelRoleID, guildID, s)
+			if err == nil {
+				roomdefinition.TravelRole = rolename
+			}
+		}
+
+		if room.GuildTransferInvite != "" || room.TransferRoomID != "" {
+			roomdefinition.Transfer = &TransferDefinition{Invite: room.GuildTransferInvite, RoomID: room.TransferRoomID}
+		}
+
+		for _, exit := range room.Exits {
+			targetname, ok := names[exit.TargetID]
+			if !ok {
+				targetname = exit.TargetID
+			}
+			roomdefinition.Exits = append(roomdefinition.Exits, NewExitDefinition(exit, targetname))
+		}
+
+		for _, event := range events {
+			// Attached events are copies made for a user, the original is the one that belongs to the room
+			if event.ChannelID == room.ID && event.UserAttached == "" {
+				roomdefinition.Events = append(roomdefinition.Events, NewEventDefinition(event))
+			}
+		}
+
+		definition.Rooms = append(definition.Rooms, roomdefinition)
+	}
+
+	if len(definition.Rooms) == 0 {
+		return definition, errors.New("No rooms found in category " + category)
+	}
+
+	sort.Slice(definition.Rooms, func(i, j int) bool { return definition.Rooms[i].Name < definition.Rooms[j].Name })
+	return definition, nil
+}
+
+// ImportWorld function
+// Compares a definition with the Rooms bucket and the live guild. When apply is set each change is made as soon as it
+// is found, otherwise this only builds the plan. Rooms are created and updated before any exits are linked, so exits
+// can lead to rooms further down the file.
+func (h *RoomsHandler) ImportWorld(definition WorldDefinition, guildID string, apply bool, userID string,
+	s DiscordSession) (plan WorldPlan, err error) {
+
+	channels, err := s.GuildChannels(guildID)
+	if err != nil {
+		return plan, err
+	}
+	livechannels := make(map[string]*discordgo.Channel)
+	for _, channel := range channels {
+		livechannels[channel.ID] = channel
+	}
+
+	// Categories first, AddRoom needs them to exist
+	categories := make(map[string]bool)
+	for _, channel := range channels {
+		categories[channel.Name] = true
+	}
+	for _, roomdefinition := range definition.Rooms {
+		if categories[roomdefinition.Category] {
+			continue
+		}
+		plan.Changes = append(plan.Changes, WorldChange{Room: roomdefinition.Category, Action: "create category"})
+		if apply {
+			_, err = s.GuildChannelCreate(guildID, roomdefinition.Category, "category")
+			if err != nil {
+				return plan, err
+			}
+		}
+		categories[roomdefinition.Category] = true
+	}
+
+	// Room IDs by name, rooms that only exist once the plan is applied get a placeholder
+	roomIDs := make(map[string]string)
+	records := make(map[string]Room)
+	for _, roomdefinition := range definition.Rooms {
+
+		// Rooms that didn't fit on an earlier import live in the overflow guild
+		record, err := h.FindRoomByName(roomdefinition.Name, guildID)
+		if err != nil {
+			conflict := false
+			for _, channel := range channels {
+				// Categories have no parent, anything else with this name is a channel the Rooms bucket lost
+				if channel.Name == roomdefinition.Name && channel.ParentID != "" {
+					conflict = true
+				}
+			}
+			if conflict {
+				plan.Warnings = append(plan.Warnings, roomdefinition.Name+" already has a channel without a room "+
+					"record, run guilds sync guild first")
+				continue
+			}
+
+			plan.Changes = append(plan.Changes, WorldChange{Room: roomdefinition.Name, Action: "create room",
+				Detail: "in " + roomdefinition.Category})
+
+			invite, transferRoomID := "", ""
+			if roomdefinition.Transfer != nil {
+				invite, transferRoomID = roomdefinition.Transfer.Invite, roomdefinition.Transfer.RoomID
+			}
+			if !apply {
+				record = Room{ID: "new:" + roomdefinition.Name, Name: roomdefinition.Name,
+					ParentName: roomdefinition.Category, GuildTransferInvite: invite, TransferRoomID: transferRoomID}
+			} else {
+				_, placedID, err := h.AddRoom(s, roomdefinition.Name, guildID, roomdefinition.Category, invite,
+					transferRoomID, roomdefinition.Color, false)
+				if err != nil {
+					return plan, errors.New("Could not create room " + roomdefinition.Name + ": " + err.Error())
+				}
+				record, err = h.rooms.GetRoomByName(roomdefinition.Name, placedID)
+				if err != nil {
+					return plan, err
+				}
+				if placedID != guildID {
+					plan.Warnings = append(plan.Warnings, roomdefinition.Name+" was created in overflow guild "+
+						placedID+" because this guild is full")
+				}
+			}
+		} else if _, live := livechannels[record.ID]; !live {
+			// Overflow rooms aren't in this guild's channel list, so those are checked on their own
+			if _, err := s.Channel(record.ID); record.GuildID == guildID || err != nil {
+				plan.Warnings = append(plan.Warnings, roomdefinition.Name+" has a room record but no channel in "+
+					"its guild, run guilds sync guild first")
+				continue
+			}
+		}
+
+		record, err = h.importRoomDetails(roomdefinition, record, guildID, apply, &plan, s)
+		if err != nil {
+			return plan, err
+		}
+		roomIDs[record.Name] = record.ID
+		records[record.Name] = record
+	}
+
+	// Exits and events, now that every room has an ID
+	eventsdb := EventsDB{db: h.db}
+	events, err := eventsdb.GetAllEvents()
+	if err != nil {
+		events = nil
+	}
+	for _, roomdefinition := range definition.Rooms {
+		record, ok := records[roomdefinition.Name]
+		if !ok {
+			continue
+		}
+
+		err = h.importRoomExits(roomdefinition, record, roomIDs, guildID, apply, &plan)
+		if err != nil {
+			return plan, err
+		}
+
+		for _, eventdefinition := range roomdefinition.Events {
+			found := false
+			for _, event := range events {
+				if event.ChannelID == record.ID && event.UserAttached == "" && eventdefinition.Matches(event) {
+					found = true
+				}
+			}
+			if found {
+				continue
+			}
+
+			event := Event{ID: strings.Split(GetUUIDv2(), "-")[0], ChannelID: record.ID, Type: eventdefinition.Type,
+				TypeFlags: eventdefinition.TypeFlags, Attachable: eventdefinition.Attachable,
+				LoadOnBoot: eventdefinition.LoadOnBoot, Cycles: eventdefinition.Cycles, Data: eventdefinition.Data,
+				CreatorID: userID}
+
+			parser := EventParser{}
+			err = parser.ValidateEvent(event)
+			if err != nil {
+				plan.Warnings = append(plan.Warnings, roomdefinition.Name+" event "+eventdefinition.Type+": "+
+					err.Error())
+				continue
+			}
+
+			plan.Changes = append(plan.Changes, WorldChange{Room: roomdefinition.Name, Action: "add event",
+				Detail: eventdefinition.Type})
+			if apply {
+				err = eventsdb.SaveEventToDB(event)
+				if err != nil {
+					return plan, err
+				}
+			}
+		}
+	}
+
+	return plan, nil
+}
+
+// importRoomDetails function
+// Brings a room's category, description, topic, travel role and transfer in line with its definition
+func (h *RoomsHandler) importRoomDetails(roomdefinition RoomDefinition, record Room, guildID string, apply bool,
+	plan *WorldPlan, s DiscordSession) (updated Room, err error) {
+
+	// A room may have been placed in an overflow guild, everything here happens in the guild it is really in
+	if record.GuildID != "" {
+		guildID = record.GuildID
+	}
+
+	if record.ParentName != roomdefinition.Category {
+		plan.Changes = append(plan.Changes, WorldChange{Room: record.Name, Action: "move",
+			Detail: record.ParentName + " to " + roomdefinition.Category})
+		if apply {
+			err = h.MoveRoom(s, record.ID, guildID, roomdefinition.Category)
+			if err != nil {
+				return record, err
+			}
+		}
+	}
+
+	if roomdefinition.Description != "" && roomdefinition.Description != record.Description {
+		plan.Changes = append(plan.Changes, WorldChange{Room: record.Name, Action: "set description"})
+		if apply {
+			err = h.SetRoomDescription(record.ID, roomdefinition.Description, s)
+			if err != nil {
+				return record, err
+			}
+		}
+	}
+
+	if roomdefinition.Topic != "" && roomdefinition.Topic != record.Topic {
+		plan.Changes = append(plan.Changes, WorldChange{Room: record.Name, Action: "set topic"})
+		if apply {
+			err = h.SetRoomTopic(record.ID, roomdefinition.Topic, s)
+			if err != nil {
+				return record, err
+			}
+		}
+	}
+
+	if roomdefinition.TravelRole != "" {
+		rolename := ""
+		if record.TravelRoleID != "" {
+			rolename, _ = getRoleNameByID(record.TravelRoleID, guildID, s)
+		} else if !apply && strings.HasPrefix(record.ID, "new:") {
+			rolename = record.Name // AddRoom creates a travel role named after the room
+		}
+		if rolename != roomdefinition.TravelRole {
+			plan.Changes = append(plan.Changes, WorldChange{Room: record.Name, Action: "set travel role",
+				Detail: roomdefinition.TravelRole})
+			if apply {
+				err = h.SetRoomTravelRole(roomdefinition.TravelRole, record.ID, guildID, s)
+				if err != nil {
+					return record, err
+				}
+			}
+		}
+	}
+
+	if roomdefinition.Transfer != nil && (roomdefinition.Transfer.Invite != record.GuildTransferInvite ||
+		roomdefinition.Transfer.RoomID != record.TransferRoomID) {
+		plan.Changes = append(plan.Changes, WorldChange{Room: record.Name, Action: "set transfer",
+			Detail: roomdefinition.Transfer.RoomID})
+		if apply {
+			_, err = s.Channel(roomdefinition.Transfer.RoomID)
+			if err != nil {
+				return record, errors.New("Could not find target transfer room: " + err.Error())
+			}
+			record, err = h.rooms.GetRoomByID(record.ID)
+			if err != nil {
+				return record, err
+			}
+			record.GuildTransferInvite = roomdefinition.Transfer.Invite
+			record.TransferRoomID = roomdefinition.Transfer.RoomID
+			err = h.rooms.SaveRoomToDB(record)
+			if err != nil {
+				return record, err
+			}
+			err = h.perm.ApplyTravelRolePerms(record.ID, guildID, s)
+			if err != nil {
+				return record, err
+			}
+		}
+	}
+
+	if !apply {
+		return record, nil
+	}
+	return h.rooms.GetRoomByID(record.ID)
+}
+
+// importRoomExits function
+// A room that lists exits ends up with exactly those exits, a room that lists none keeps the ones it has
+func (h *RoomsHandler) importRoomExits(roomdefinition RoomDefinition, record Room, roomIDs map[string]string,
+	guildID string, apply bool, plan *WorldPlan) (err error) {
+
+	if len(roomdefinition.Exits) == 0 {
+		return nil
+	}
+
+	var desired []Exit
+	for _, exitdefinition := range roomdefinition.Exits {
+		targetID, ok := roomIDs[exitdefinition.To]
+		if !ok {
+			// Exits can lead to rooms that are already in the guild but not in this file
+			target, err := h.FindRoomByName(exitdefinition.To, guildID)
+			if err != nil {
+				plan.Warnings = append(plan.Warnings, record.Name+" exit "+exitdefinition.Name+" leads to unknown "+
+					"room "+exitdefinition.To)
+				continue
+			}
+			targetID = target.ID
+		}
+
+		// Rooms split across a full guild and its overflow are joined by the gate rooms, not by direct exits
+		if target, err := h.rooms.GetRoomByID(targetID); err == nil && record.GuildID != "" &&
+			target.GuildID != record.GuildID {
+			plan.Warnings = append(plan.Warnings, record.Name+" exit "+exitdefinition.Name+" leads to "+
+				exitdefinition.To+" in another guild, travelers reach it through the gate rooms instead")
+			continue
+		}
+
+		exit, err := exitdefinition.Exit(targetID, record)
+		if err != nil {
+			return err
+		}
+		desired = append(desired, exit)
+	}
+
+	var removed []string
+	for _, existing := range record.Exits {
+		wanted := false
+		for _, exit := range desired {
+			if exit.Name == existing.Name {
+				wanted = true
+			}
+		}
+		if !wanted {
+			plan.Changes = append(plan.Changes, WorldChange{Room: record.Name, Action: "remove exit",
+				Detail: existing.Name})
+			removed = append(removed, existing.Name)
+		}
+	}
+	for _, name := range removed {
+		record.RemoveExit(name)
+	}
+	changed := len(removed) > 0
+
+	var added []Exit
+	for _, exit := range desired {
+		existing, found := Exit{}, false
+		for _, candidate := range record.Exits {
+			if candidate.Name == exit.Name {
+				existing, found = candidate, true
+			}
+		}
+
+		if !found {
+			plan.Changes = append(plan.Changes, WorldChange{Room: record.Name, Action: "add exit",
+				Detail: exit.Name + " to " + exitTargetName(exit, roomIDs)})
+			added = append(added, exit)
+			continue
+		}
+		if !SameExit(existing, exit) {
+			plan.Changes = append(plan.Changes, WorldChange{Room: record.Name, Action: "update exit",
+				Detail: exit.Name})
+			record.UpdateExit(exit)
+			changed = true
+		}
+	}
+
+	if !apply {
+		return nil
+	}
+	if changed {
+		err = h.rooms.SaveRoomToDB(record)
+		if err != nil {
+			return err
+		}
+	}
+
+	// New exits go through LinkRooms as one way exits, the reverse is listed under the other room
+	for _, exit := range added {
+		_, err = h.LinkRooms(exit.Name, "", record.ID, exit.TargetID, record.GuildID)
+		if err != nil {
+			return err
+		}
+		linked, err := h.rooms.GetRoomByID(record.ID)
+		if err != nil {
+			return err
+		}
+		err = linked.UpdateExit(exit)
+		if err != nil {
+			return err
+		}
+		err = h.rooms.SaveRoomToDB(linked)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// exitTargetName function
+func exitTargetName(exit Exit, roomIDs map[string]string) string {
+	for name, id := range roomIDs {
+		if id == exit.TargetID {
+			return name
+		}
+	}
+	return exit.TargetID
+}
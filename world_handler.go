@@ -0,0 +1,107 @@
+package main
+
+import (
+	"bytes"
+	"github.com/bwmarrin/discordgo"
+	"strconv"
+)
+
+// WorldHandler struct
+type WorldHandler struct {
+	conf     *Config
+	registry *CommandRegistry
+	router   *CommandRouter
+	logger   *Logger
+	room     *RoomsHandler
+	user     *UserHandler
+	perm     *PermissionsHandler
+}
+
+// Init function
+func (h *WorldHandler) Init() {
+	h.RegisterCommands()
+}
+
+// RegisterCommands function
+func (h *WorldHandler) RegisterCommands() (err error) {
+
+	h.registry.Register("world", "Check the world for broken exits, rooms and users", "check [--fix]")
+	h.registry.AddGroup("world", "builder")
+	h.router.AddRoute("world", false, h.ParseCommand, "builder")
+	return nil
+
+}
+
+// checker function
+// The rooms record isn't created until setup has run, so this is built when it's needed
+func (h *WorldHandler) checker() *WorldChecker {
+	return &WorldChecker{rooms: h.room.rooms, users: h.user.usermanager, perm: h.perm, conf: h.conf}
+}
+
+// ParseCommand function
+func (h *WorldHandler) ParseCommand(command []string, user User, s DiscordSession, m *discordgo.MessageCreate) {
+
+	if len(command) < 2 || command[1] != "check" {
+		s.ChannelMessageSend(m.ChannelID, "Expected flag for 'world' command, see command usage for more info")
+		return
+	}
+	fix := len(command) > 2 && command[2] == "--fix"
+
+	checker := h.checker()
+	report, err := checker.Check(s)
+	if err != nil {
+		s.ChannelMessageSend(m.ChannelID, "Error checking world: "+err.Error())
+		return
+	}
+
+	summary := "World check found " + strconv.Itoa(report.Count(WorldCheckError)) + " errors, " +
+		strconv.Itoa(report.Count(WorldCheckWarning)) + " warnings and " +
+		strconv.Itoa(report.Count(WorldCheckInfo)) + " notes."
+
+	if fix {
+		fixed, err := checker.Fix(report)
+		summary = summary + " Repaired " + strconv.Itoa(fixed) + "."
+		if err != nil {
+			summary = summary + "\n" + err.Error()
+		}
+	} else {
+		for _, issue := range report.Issues {
+			if issue.Fixable() {
+				summary = summary + " Run world check --fix to make the repairs marked fixable."
+				break
+			}
+		}
+	}
+
+	formatted := report.Format()
+	if len(summary)+len(formatted) < 1900 {
+		s.ChannelMessageSend(m.ChannelID, summary+"\n```\n"+formatted+"```\n")
+		return
+	}
+	s.ChannelMessageSend(m.ChannelID, summary)
+	s.ChannelFileSend(m.ChannelID, "world-check.txt", bytes.NewBufferString(formatted))
+}
+
+// StartupCheck function
+// Logs what the checker finds without repairing anything, so problems show up before players hit them
+func (h *WorldHandler) StartupCheck(s DiscordSession) {
+
+	report, err := h.checker().Check(s)
+	if err != nil {
+		h.logger.Error(BOTLOG, LogFields{}, "World check failed: "+err.Error())
+		return
+	}
+
+	for _, issue := range report.Issues {
+		switch issue.Severity {
+		case WorldCheckError:
+			h.logger.Error(BOTLOG, LogFields{Room: issue.RoomID}, "World check: "+issue.Message)
+		case WorldCheckWarning:
+			h.logger.Warn(BOTLOG, LogFields{Room: issue.RoomID}, "World check: "+issue.Message)
+		default:
+			h.logger.Debug(BOTLOG, LogFields{Room: issue.RoomID}, "World check: "+issue.Message)
+		}
+	}
+	h.logger.Info(BOTLOG, LogFields{}, "World check found "+strconv.Itoa(report.Count(WorldCheckError))+
+		" errors and "+strconv.Itoa(report.Count(WorldCheckWarning))+" warnings, see world check for details")
+}
@@ -0,0 +1,410 @@
+package main
+
+/*
+The world map walks the room graph across every guild.
+
+Rooms are nodes, exits are edges within a guild, and a room's TransferRoomID is a separate kind of edge that leads
+to a room in another guild. The graph can be rendered as an ascii grid around a room (only the compass directions
+can be placed on a grid, everything else is listed underneath it), a Graphviz DOT file or a JSON adjacency list.
+
+*/
+
+import (
+	"encoding/json"
+	"errors"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// MapEdge kinds
+const (
+	MapEdgeExit     = "exit"
+	MapEdgeTransfer = "transfer"
+)
+
+// MapNode struct
+type MapNode struct {
+	ID        string
+	Name      string
+	GuildID   string
+	GuildName string
+}
+
+// MapEdge struct
+type MapEdge struct {
+	From   string
+	To     string
+	Name   string // The exit name, empty for transfers
+	Kind   string
+	Hidden bool // See hidden_exits.go
+}
+
+// WorldGraph struct
+type WorldGraph struct {
+	Rooms     map[string]MapNode
+	Adjacency map[string][]MapEdge // Keyed by the room the edges leave from
+}
+
+// mapOffsets are the grid offsets for the directions that can be drawn on the ascii map
+var mapOffsets = map[string][2]int{
+	"north":     {0, -1},
+	"northeast": {1, -1},
+	"east":      {1, 0},
+	"southeast": {1, 1},
+	"south":     {0, 1},
+	"southwest": {-1, 1},
+	"west":      {-1, 0},
+	"northwest": {-1, -1},
+}
+
+// BuildWorldGraph function
+func BuildWorldGraph(rooms *Rooms, guilds *GuildsManager) (graph WorldGraph, err error) {
+
+	roomlist, err := rooms.GetAllRooms()
+	if err != nil {
+		return graph, err
+	}
+
+	guildnames := make(map[string]string)
+	guildlist, err := guilds.GetAllGuilds()
+	if err == nil {
+		for _, guild := range guildlist {
+			guildnames[guild.ID] = guild.Name
+		}
+	}
+
+	graph = WorldGraph{Rooms: make(map[string]MapNode), Adjacency: make(map[string][]MapEdge)}
+	for _, room := range roomlist {
+		graph.Rooms[room.ID] = MapNode{ID: room.ID, Name: room.Name, GuildID: room.GuildID,
+			GuildName: guildnames[room.GuildID]}
+
+		for _, exit := range room.Exits {
+			graph.Adjacency[room.ID] = append(graph.Adjacency[room.ID], MapEdge{From: room.ID, To: exit.TargetID,
+				Name: exit.Name, Kind: MapEdgeExit, Hidden: exit.IsHidden()})
+		}
+		if room.TransferRoomID != "" {
+			graph.Adjacency[room.ID] = append(graph.Adjacency[room.ID], MapEdge{From: room.ID,
+				To: room.TransferRoomID, Kind: MapEdgeTransfer})
+		}
+	}
+	return graph, nil
+}
+
+// RoomIDs function
+// Sorted by guild and then room name, so exports are stable between runs
+func (g *WorldGraph) RoomIDs() (ids []string) {
+	for id := range g.Rooms {
+		ids = append(ids, id)
+	}
+	sort.Slice(ids, func(i, j int) bool {
+		a, b := g.Rooms[ids[i]], g.Rooms[ids[j]]
+		if a.GuildID != b.GuildID {
+			return a.GuildID < b.GuildID
+		}
+		if a.Name != b.Name {
+			return a.Name < b.Name
+		}
+		return a.ID < b.ID
+	})
+	return ids
+}
+
+// RenderASCII function
+// Draws the rooms within radius compass steps of roomID, with the room itself in the middle
+func (g *WorldGraph) RenderASCII(roomID string, radius int) (output string, err error) {
+
+	start, ok := g.Rooms[roomID]
+	if !ok {
+		return "", errors.New("Room is not on the map: " + roomID)
+	}
+
+	// Place rooms on the grid breadth first, a room that can't go where its exit says (the world doesn't have to
+	// be euclidean) is left off rather than drawn over another room
+	positions := map[string][2]int{start.ID: {0, 0}}
+	occupied := map[[2]int]string{{0, 0}: start.ID}
+	queue := []string{start.ID}
+	for len(queue) > 0 {
+		current := queue[0]
+		queue = queue[1:]
+		position := positions[current]
+
+		for _, edge := range g.Adjacency[current] {
+			offset, drawable := mapOffsets[edge.Name]
+			if edge.Kind != MapEdgeExit || !drawable {
+				continue
+			}
+			if _, placed := positions[edge.To]; placed {
+				continue
+			}
+			if _, known := g.Rooms[edge.To]; !known {
+				continue
+			}
+			target := [2]int{position[0] + offset[0], position[1] + offset[1]}
+			if target[0] < -radius || target[0] > radius || target[1] < -radius || target[1] > radius {
+				continue
+			}
+			if _, taken := occupied[target]; taken {
+				continue
+			}
+			positions[edge.To] = target
+			occupied[target] = edge.To
+			queue = append(queue, edge.To)
+		}
+	}
+
+	// Each room is three characters wide with a connector between it and the next room over
+	size := radius*2 + 1
+	canvas := make([][]rune, size*2-1)
+	for i := range canvas {
+		canvas[i] = []rune(strings.Repeat(" ", size*4-1))
+	}
+
+	for id, position := range positions {
+		row := (position[1] + radius) * 2
+		column := (position[0] + radius) * 4
+		copy(canvas[row][column:], []rune("["+string(g.mapMarker(id, start.ID))+"]"))
+
+		for _, edge := range g.Adjacency[id] {
+			offset, drawable := mapOffsets[edge.Name]
+			if edge.Kind != MapEdgeExit || !drawable {
+				continue
+			}
+			connectorrow := row + offset[1]
+			connectorcolumn := column + 1 + offset[0]*2
+			if connectorrow < 0 || connectorrow >= len(canvas) || connectorcolumn < 0 ||
+				connectorcolumn >= len(canvas[connectorrow]) {
+				continue
+			}
+			canvas[connectorrow][connectorcolumn] = mapConnector(canvas[connectorrow][connectorcolumn], offset)
+		}
+	}
+
+	lines := make([]string, len(canvas))
+	for i, line := range canvas {
+		lines[i] = strings.TrimRight(string(line), " ")
+	}
+	output = "```\n" + strings.Trim(strings.Join(lines, "\n"), "\n") + "\n```\n"
+	output = output + "[@] " + start.Name + "  [^] up  [v] down  [=] up and down  [T] leads to another guild\n"
+
+	// Anything that couldn't be drawn from here still needs to be listed
+	var others []string
+	for _, edge := range g.Adjacency[start.ID] {
+		if _, drawable := mapOffsets[edge.Name]; drawable && edge.Kind == MapEdgeExit {
+			continue
+		}
+		if edge.Kind == MapEdgeTransfer {
+			continue
+		}
+		others = append(others, edge.Name+" to "+g.roomName(edge.To))
+	}
+	if len(others) > 0 {
+		output = output + "Other exits: " + strings.Join(others, ", ") + "\n"
+	}
+	return output, nil
+}
+
+// mapMarker function
+func (g *WorldGraph) mapMarker(roomID string, currentID string) rune {
+	if roomID == currentID {
+		return '@'
+	}
+
+	up, down := false, false
+	for _, edge := range g.Adjacency[roomID] {
+		if edge.Kind == MapEdgeTransfer {
+			return 'T'
+		}
+		if edge.Name == "up" {
+			up = true
+		}
+		if edge.Name == "down" {
+			down = true
+		}
+	}
+	if up && down {
+		return '='
+	}
+	if up {
+		return '^'
+	}
+	if down {
+		return 'v'
+	}
+	return ' '
+}
+
+// mapConnector function
+// Two diagonals crossing in the same spot are drawn as an X
+func mapConnector(existing rune, offset [2]int) rune {
+	connector := '|'
+	if offset[1] == 0 {
+		connector = '-'
+	} else if offset[0] == offset[1] {
+		connector = '\\'
+	} else if offset[0] != 0 {
+		connector = '/'
+	}
+
+	if (existing == '/' && connector == '\\') || (existing == '\\' && connector == '/') || existing == 'X' {
+		return 'X'
+	}
+	return connector
+}
+
+// roomName function
+func (g *WorldGraph) roomName(roomID string) string {
+	if node, ok := g.Rooms[roomID]; ok {
+		return node.Name
+	}
+	return roomID
+}
+
+// RenderDOT function
+// Rooms are grouped into a cluster per guild, and transfers are drawn dashed so they stand out from exits
+func (g *WorldGraph) RenderDOT() string {
+
+	var output strings.Builder
+	output.WriteString("digraph aether {\n")
+	output.WriteString("\tnode [shape=box];\n")
+
+	ids := g.RoomIDs()
+	guildID := ""
+	for i, id := range ids {
+		node := g.Rooms[id]
+		if i == 0 || node.GuildID != guildID {
+			if i > 0 {
+				output.WriteString("\t}\n")
+			}
+			guildID = node.GuildID
+			label := node.GuildName
+			if label == "" {
+				label = node.GuildID
+			}
+			output.WriteString("\tsubgraph " + dotQuote("cluster_"+guildID) + " {\n")
+			output.WriteString("\t\tlabel=" + dotQuote(label) + ";\n")
+		}
+		output.WriteString("\t\t" + dotQuote(id) + " [label=" + dotQuote(node.Name) + "];\n")
+	}
+	if len(ids) > 0 {
+		output.WriteString("\t}\n")
+	}
+
+	for _, id := range ids {
+		for _, edge := range g.Adjacency[id] {
+			if edge.Kind == MapEdgeTransfer {
+				output.WriteString("\t" + dotQuote(edge.From) + " -> " + dotQuote(edge.To) +
+					" [label=\"transfer\", style=dashed, color=blue];\n")
+				continue
+			}
+			output.WriteString("\t" + dotQuote(edge.From) + " -> " + dotQuote(edge.To) + " [label=" +
+				dotQuote(edge.Name) + "];\n")
+		}
+	}
+
+	output.WriteString("}\n")
+	return output.String()
+}
+
+// dotQuote function
+func dotQuote(value string) string {
+	return strconv.Quote(value)
+}
+
+// RenderJSON function
+func (g *WorldGraph) RenderJSON() ([]byte, error) {
+	return json.MarshalIndent(g, "", "  ")
+}
+
+// ShortestPath function
+// Breadth first search from one room to another, following exits and transfers. Returns the edges to take in order,
+// which is empty when the rooms are the same.
+func (g *WorldGraph) ShortestPath(fromID string, toID string) (path []MapEdge, err error) {
+
+	if _, ok := g.Rooms[fromID]; !ok {
+		return path, errors.New("Room is not on the map: " + fromID)
+	}
+	if _, ok := g.Rooms[toID]; !ok {
+		return path, errors.New("Room is not on the map: " + toID)
+	}
+	if fromID == toID {
+		return path, nil
+	}
+
+	previous := map[string]MapEdge{}
+	visited := map[string]bool{fromID: true}
+	queue := []string{fromID}
+	for len(queue) > 0 && !visited[toID] {
+		current := queue[0]
+		queue = queue[1:]
+
+		for _, edge := range g.Adjacency[current] {
+			if visited[edge.To] {
+				continue
+			}
+			if _, known := g.Rooms[edge.To]; !known {
+				continue
+			}
+			visited[edge.To] = true
+			previous[edge.To] = edge
+			queue = append(queue, edge.To)
+		}
+	}
+
+	if !visited[toID] {
+		return path, errors.New("There is no way to get from " + g.roomName(fromID) + " to " + g.roomName(toID))
+	}
+
+	for current := toID; current != fromID; current = previous[current].From {
+		path = append([]MapEdge{previous[current]}, path...)
+	}
+	return path, nil
+}
+
+// FindRoom function
+// Looks a room up by ID, channel mention or name. Names are only unique within a guild, so a match in guildID wins.
+func (g *WorldGraph) FindRoom(search string, guildID string) (node MapNode, err error) {
+
+	if node, ok := g.Rooms[CleanChannel(search)]; ok {
+		return node, nil
+	}
+
+	search = strings.ToLower(strings.TrimPrefix(search, "#"))
+	found := false
+	for _, id := range g.RoomIDs() {
+		candidate := g.Rooms[id]
+		if strings.ToLower(candidate.Name) != search {
+			continue
+		}
+		if candidate.GuildID == guildID {
+			return candidate, nil
+		}
+		if !found {
+			node = candidate
+			found = true
+		}
+	}
+
+	if !found {
+		return node, errors.New("No room named " + search)
+	}
+	return node, nil
+}
+
+// FormatRoute function
+func (g *WorldGraph) FormatRoute(path []MapEdge) string {
+	var steps []string
+	for _, edge := range path {
+		if edge.Kind == MapEdgeTransfer {
+			target := g.Rooms[edge.To]
+			guild := target.GuildName
+			if guild == "" {
+				guild = target.GuildID
+			}
+			steps = append(steps, "transfer to "+target.Name+" ("+guild+")")
+			continue
+		}
+		steps = append(steps, edge.Name)
+	}
+	return strings.Join(steps, ", ")
+}
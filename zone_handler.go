@@ -0,0 +1,379 @@
+package main
+
+import (
+	"github.com/bwmarrin/discordgo"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// ZoneHandler struct
+type ZoneHandler struct {
+	conf     *Config
+	registry *CommandRegistry
+	router   *CommandRouter
+	room     *RoomsHandler
+	zones    *ZonesManager
+}
+
+// Init function
+func (h *ZoneHandler) Init() {
+	h.RegisterCommands()
+}
+
+// RegisterCommands function
+func (h *ZoneHandler) RegisterCommands() (err error) {
+
+	h.registry.Register("zone", "Group rooms into zones and manage the defaults they share",
+		"create <zone> | list | info [zone] | assign <zone|none> | <#room> ... | set <zone> | <setting> <value> | "+
+			"broadcast <zone> | <message> | remove <zone>")
+	h.registry.AddGroup("zone", "builder")
+	h.router.AddRoute("zone", false, h.ParseCommand, "builder")
+	return nil
+
+}
+
+// ParseCommand function
+func (h *ZoneHandler) ParseCommand(command []string, user User, s DiscordSession, m *discordgo.MessageCreate) {
+
+	if len(command) < 2 {
+		s.ChannelMessageSend(m.ChannelID, "Expected flag for 'zone' command, see command usage for more info")
+		return
+	}
+
+	switch command[1] {
+	case "create":
+		if len(command) < 3 {
+			s.ChannelMessageSend(m.ChannelID, "create requires a zone name")
+			return
+		}
+		zone, err := h.zones.CreateZone(strings.Join(command[2:], " "))
+		if err != nil {
+			s.ChannelMessageSend(m.ChannelID, "Error creating zone: "+err.Error())
+			return
+		}
+		s.ChannelMessageSend(m.ChannelID, "Zone "+zone.Name+" created, add rooms to it with "+
+			h.conf.CommandPrefix()+"zone assign "+zone.Name+" | #room")
+		return
+
+	case "list":
+		h.ListZones(s, m)
+		return
+
+	case "info":
+		h.ZoneInfo(strings.Join(command[2:], " "), s, m)
+		return
+
+	case "assign":
+		zonename, rooms, split := SplitExitArgs(command[2:])
+		if !split || zonename == "" || rooms == "" {
+			s.ChannelMessageSend(m.ChannelID, "assign requires a zone and at least one room: <zone|none> | <#room> ...")
+			return
+		}
+		h.AssignRooms(zonename, strings.Fields(rooms), s, m)
+		return
+
+	case "set":
+		zonename, setting, split := SplitExitArgs(command[2:])
+		if !split || zonename == "" || setting == "" {
+			s.ChannelMessageSend(m.ChannelID, "set requires a zone and a setting: <zone> | <ambient|levels|pvp|"+
+				"weather|role> <value>")
+			return
+		}
+		h.SetZone(zonename, setting, s, m)
+		return
+
+	case "broadcast":
+		zonename, message, split := SplitExitArgs(command[2:])
+		if !split || zonename == "" || message == "" {
+			s.ChannelMessageSend(m.ChannelID, "broadcast requires a zone and a message: <zone> | <message>")
+			return
+		}
+		h.Broadcast(zonename, message, s, m)
+		return
+
+	case "remove":
+		h.RemoveZone(strings.Join(command[2:], " "), s, m)
+		return
+	}
+
+	s.ChannelMessageSend(m.ChannelID, "Unrecognized flag for 'zone' command, see command usage for more info")
+}
+
+// ListZones function
+func (h *ZoneHandler) ListZones(s DiscordSession, m *discordgo.MessageCreate) {
+
+	zones, err := h.zones.GetAllZones()
+	if err != nil {
+		s.ChannelMessageSend(m.ChannelID, "Error retrieving zones: "+err.Error())
+		return
+	}
+	if len(zones) == 0 {
+		s.ChannelMessageSend(m.ChannelID, "There are no zones yet")
+		return
+	}
+	sort.Slice(zones, func(i, j int) bool { return zones[i].Name < zones[j].Name })
+
+	output := "```\n"
+	for _, zone := range zones {
+		rooms, err := h.room.rooms.GetRoomsByZoneID(zone.ID)
+		if err != nil {
+			s.ChannelMessageSend(m.ChannelID, "Error retrieving zone rooms: "+err.Error())
+			return
+		}
+		output = output + zone.Name + " - " + strconv.Itoa(len(rooms)) + " rooms\n"
+	}
+	s.ChannelMessageSend(m.ChannelID, output+"```\n")
+}
+
+// ZoneInfo function
+// Without a zone name this describes the zone of the room the command was used in
+func (h *ZoneHandler) ZoneInfo(zonename string, s DiscordSession, m *discordgo.MessageCreate) {
+
+	var zone Zone
+	var err error
+	if zonename == "" {
+		room, err := h.room.rooms.GetRoomByID(m.ChannelID)
+		if err != nil {
+			s.ChannelMessageSend(m.ChannelID, "This channel is not a room, name a zone to see its info")
+			return
+		}
+		found := false
+		zone, found = h.zones.ZoneForRoom(room)
+		if !found {
+			s.ChannelMessageSend(m.ChannelID, room.Name+" is not in a zone")
+			return
+		}
+	} else {
+		zone, err = h.zones.GetZoneByName(zonename)
+		if err != nil {
+			s.ChannelMessageSend(m.ChannelID, "Error retrieving zone: "+err.Error())
+			return
+		}
+	}
+
+	rooms, err := h.room.rooms.GetRoomsByZoneID(zone.ID)
+	if err != nil {
+		s.ChannelMessageSend(m.ChannelID, "Error retrieving zone rooms: "+err.Error())
+		return
+	}
+
+	output := "```\n"
+	output = output + "Name: " + zone.Name + "\n"
+	output = output + "ID: " + zone.ID + "\n\n"
+	output = output + "Levels: " + zone.LevelRange() + "\n"
+	output = output + "PvP: " + zone.PvPText() + "\n"
+	output = output + "Weather: " + zone.WeatherText() + "\n"
+	var guildIDs []string
+	for guildID := range zone.RegionRoleIDs {
+		guildIDs = append(guildIDs, guildID)
+	}
+	sort.Strings(guildIDs)
+	for _, guildID := range guildIDs {
+		output = output + "Region Role (" + guildID + "): " + zone.RegionRoleIDs[guildID] + "\n"
+	}
+	output = output + "\nAmbient: " + zone.Ambient + "\n\n"
+
+	output = output + "Rooms (" + strconv.Itoa(len(rooms)) + "):\n"
+	for _, room := range rooms {
+		output = output + "  " + room.Name + " (" + room.ID + ") in " + room.GuildID + "\n"
+	}
+	output = output + "```\n"
+
+	if len(output) > 1900 {
+		s.ChannelFileSend(m.ChannelID, "zone-"+zone.ID+".txt", strings.NewReader(strings.Trim(output, "`\n")))
+		return
+	}
+	s.ChannelMessageSend(m.ChannelID, output)
+}
+
+// AssignRooms function
+// A zone name of none takes the rooms out of whatever zone they are in
+func (h *ZoneHandler) AssignRooms(zonename string, roomIDs []string, s DiscordSession, m *discordgo.MessageCreate) {
+
+	zone := Zone{}
+	if !strings.EqualFold(zonename, "none") {
+		var err error
+		zone, err = h.zones.GetZoneByName(zonename)
+		if err != nil {
+			s.ChannelMessageSend(m.ChannelID, "Error retrieving zone: "+err.Error())
+			return
+		}
+	}
+
+	var assigned []string
+	for _, roomID := range roomIDs {
+		if roomID == "here" {
+			roomID = m.ChannelID
+		}
+		room, err := h.room.rooms.GetRoomByID(CleanChannel(roomID))
+		if err != nil {
+			s.ChannelMessageSend(m.ChannelID, "Error retrieving room "+roomID+": "+err.Error())
+			continue
+		}
+		room.ZoneID = zone.ID
+		err = h.room.rooms.SaveRoomToDB(room)
+		if err != nil {
+			s.ChannelMessageSend(m.ChannelID, "Error updating room "+room.Name+": "+err.Error())
+			continue
+		}
+		assigned = append(assigned, room.Name)
+	}
+
+	if len(assigned) == 0 {
+		return
+	}
+	if zone.ID == "" {
+		s.ChannelMessageSend(m.ChannelID, "Removed from their zone: "+strings.Join(assigned, ", "))
+		return
+	}
+	s.ChannelMessageSend(m.ChannelID, "Assigned to "+zone.Name+": "+strings.Join(assigned, ", "))
+}
+
+// SetZone function
+func (h *ZoneHandler) SetZone(zonename string, setting string, s DiscordSession, m *discordgo.MessageCreate) {
+
+	zone, err := h.zones.GetZoneByName(zonename)
+	if err != nil {
+		s.ChannelMessageSend(m.ChannelID, "Error retrieving zone: "+err.Error())
+		return
+	}
+
+	args := strings.Fields(setting)
+	value := strings.TrimSpace(strings.TrimPrefix(setting, args[0]))
+
+	switch strings.ToLower(args[0]) {
+	case "ambient":
+		zone.Ambient = value
+
+	case "levels":
+		if len(args) < 2 || len(args) > 3 {
+			s.ChannelMessageSend(m.ChannelID, "levels requires a minimum and optionally a maximum, 0 for none")
+			return
+		}
+		minlevel, err := strconv.Atoi(args[1])
+		if err != nil || minlevel < 0 {
+			s.ChannelMessageSend(m.ChannelID, "Invalid minimum level: "+args[1])
+			return
+		}
+		maxlevel := 0
+		if len(args) == 3 {
+			maxlevel, err = strconv.Atoi(args[2])
+			if err != nil || maxlevel < 0 || (maxlevel > 0 && maxlevel < minlevel) {
+				s.ChannelMessageSend(m.ChannelID, "Invalid maximum level: "+args[2])
+				return
+			}
+		}
+		zone.MinLevel = minlevel
+		zone.MaxLevel = maxlevel
+
+	case "pvp":
+		switch strings.ToLower(value) {
+		case "on", "true", "yes":
+			zone.PvP = true
+		case "off", "false", "no":
+			zone.PvP = false
+		default:
+			s.ChannelMessageSend(m.ChannelID, "pvp must be on or off")
+			return
+		}
+
+	case "weather":
+		if strings.EqualFold(value, "none") {
+			value = ""
+		}
+		zone.Weather = value
+
+	case "role":
+		// Roles are per guild, so this sets the region role for the guild the command was used in
+		guildID, err := getGuildID(s, m.ChannelID)
+		if err != nil {
+			s.ChannelMessageSend(m.ChannelID, "Could not retrieve GuildID: "+err.Error())
+			return
+		}
+		if zone.RegionRoleIDs == nil {
+			zone.RegionRoleIDs = make(map[string]string)
+		}
+		if value == "" || strings.EqualFold(value, "none") {
+			delete(zone.RegionRoleIDs, guildID)
+			break
+		}
+		roleID, err := getRoleIDByName(s, guildID, value)
+		if err != nil {
+			s.ChannelMessageSend(m.ChannelID, "Error retrieving role "+value+": "+err.Error())
+			return
+		}
+		zone.RegionRoleIDs[guildID] = roleID
+
+	default:
+		s.ChannelMessageSend(m.ChannelID, "Unknown zone setting "+args[0]+", expected ambient, levels, pvp, "+
+			"weather or role")
+		return
+	}
+
+	err = h.zones.SaveZoneToDB(zone)
+	if err != nil {
+		s.ChannelMessageSend(m.ChannelID, "Error updating zone: "+err.Error())
+		return
+	}
+	s.ChannelMessageSend(m.ChannelID, "Zone "+zone.Name+" updated")
+}
+
+// Broadcast function
+// Sends a message to every room in the zone, across all the guilds it spans
+func (h *ZoneHandler) Broadcast(zonename string, message string, s DiscordSession, m *discordgo.MessageCreate) {
+
+	zone, err := h.zones.GetZoneByName(zonename)
+	if err != nil {
+		s.ChannelMessageSend(m.ChannelID, "Error retrieving zone: "+err.Error())
+		return
+	}
+
+	rooms, err := h.room.rooms.GetRoomsByZoneID(zone.ID)
+	if err != nil {
+		s.ChannelMessageSend(m.ChannelID, "Error retrieving zone rooms: "+err.Error())
+		return
+	}
+
+	sent := 0
+	for _, room := range rooms {
+		_, err := s.ChannelMessageSend(room.ID, ":loudspeaker: **"+zone.Name+"**: "+message)
+		if err == nil {
+			sent++
+		}
+	}
+	s.ChannelMessageSend(m.ChannelID, "Broadcast sent to "+strconv.Itoa(sent)+" of "+strconv.Itoa(len(rooms))+
+		" rooms in "+zone.Name)
+}
+
+// RemoveZone function
+// The rooms in the zone are kept, they just aren't in a zone anymore
+func (h *ZoneHandler) RemoveZone(zonename string, s DiscordSession, m *discordgo.MessageCreate) {
+
+	zone, err := h.zones.GetZoneByName(zonename)
+	if err != nil {
+		s.ChannelMessageSend(m.ChannelID, "Error retrieving zone: "+err.Error())
+		return
+	}
+
+	rooms, err := h.room.rooms.GetRoomsByZoneID(zone.ID)
+	if err != nil {
+		s.ChannelMessageSend(m.ChannelID, "Error retrieving zone rooms: "+err.Error())
+		return
+	}
+	for _, room := range rooms {
+		room.ZoneID = ""
+		err = h.room.rooms.SaveRoomToDB(room)
+		if err != nil {
+			s.ChannelMessageSend(m.ChannelID, "Error updating room "+room.Name+": "+err.Error())
+			return
+		}
+	}
+
+	err = h.zones.RemoveZoneFromDB(zone)
+	if err != nil {
+		s.ChannelMessageSend(m.ChannelID, "Error removing zone: "+err.Error())
+		return
+	}
+	s.ChannelMessageSend(m.ChannelID, "Zone "+zone.Name+" removed")
+}
@@ -0,0 +1,214 @@
+package main
+
+/*
+A zone groups rooms into a region of the world. Zones aren't tied to a discord category or guild, so a region can
+span several guilds in the cluster.
+
+Rooms inherit the zone's defaults:
+
+	ambient  - description text shown with every room in the zone, or in place of a room's own description
+	role     - a region role, set per guild, that players hold while they are in the zone
+	levels   - the level range the zone is meant for
+	pvp      - whether players can fight each other in the zone
+	weather  - the weather profile used in the zone
+
+Each room records its zone in Room.ZoneID, a room can only be in one zone.
+
+*/
+
+import (
+	"errors"
+	"github.com/asdine/storm"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// ZonesManager struct
+type ZonesManager struct {
+	db          *DBHandler
+	querylocker sync.RWMutex
+}
+
+// Zone struct
+type Zone struct {
+	ID   string `storm:"id"` // primary key
+	Name string `storm:"unique"`
+
+	Ambient       string
+	RegionRoleIDs map[string]string // Keyed by guild ID, roles are per guild
+	MinLevel      int
+	MaxLevel      int
+	PvP           bool
+	Weather       string
+}
+
+// SaveZoneToDB function
+func (h *ZonesManager) SaveZoneToDB(zone Zone) (err error) {
+	h.querylocker.Lock()
+	defer h.querylocker.Unlock()
+
+	db := h.db.rawdb.From("Zones")
+	err = db.Save(&zone)
+	return err
+}
+
+// RemoveZoneFromDB function
+func (h *ZonesManager) RemoveZoneFromDB(zone Zone) (err error) {
+	h.querylocker.Lock()
+	defer h.querylocker.Unlock()
+
+	db := h.db.rawdb.From("Zones")
+	err = db.DeleteStruct(&zone)
+	return err
+}
+
+// GetZoneByID function
+func (h *ZonesManager) GetZoneByID(zoneID string) (zone Zone, err error) {
+	h.querylocker.RLock()
+	defer h.querylocker.RUnlock()
+
+	db := h.db.rawdb.From("Zones")
+	err = db.One("ID", zoneID, &zone)
+	if err != nil {
+		if err == storm.ErrNotFound {
+			return zone, errors.New("No zone record found")
+		}
+		return zone, err
+	}
+	return zone, nil
+}
+
+// GetZoneByName function
+// Zone names are matched without case, builders shouldn't have to remember how a zone was capitalized
+func (h *ZonesManager) GetZoneByName(zonename string) (zone Zone, err error) {
+
+	zones, err := h.GetAllZones()
+	if err != nil {
+		return zone, err
+	}
+	for _, candidate := range zones {
+		if strings.EqualFold(candidate.Name, zonename) {
+			return candidate, nil
+		}
+	}
+	return zone, errors.New("No zone named " + zonename)
+}
+
+// GetAllZones function
+func (h *ZonesManager) GetAllZones() (zonelist []Zone, err error) {
+	h.querylocker.RLock()
+	defer h.querylocker.RUnlock()
+
+	db := h.db.rawdb.From("Zones")
+	err = db.All(&zonelist)
+	if err != nil {
+		return zonelist, err
+	}
+	return zonelist, nil
+}
+
+// CreateZone function
+func (h *ZonesManager) CreateZone(zonename string) (zone Zone, err error) {
+
+	zonename = strings.TrimSpace(zonename)
+	if zonename == "" {
+		return zone, errors.New("Zone name cannot be empty")
+	}
+	if _, err := h.GetZoneByName(zonename); err == nil {
+		return zone, errors.New("A zone named " + zonename + " already exists")
+	}
+
+	zoneID, err := GetUUID()
+	if err != nil {
+		return zone, err
+	}
+	zone = Zone{ID: zoneID, Name: zonename, RegionRoleIDs: make(map[string]string)}
+	return zone, h.SaveZoneToDB(zone)
+}
+
+// ZoneForRoom function
+// Rooms without a zone, or with one that has since been removed, aren't in any zone
+func (h *ZonesManager) ZoneForRoom(room Room) (zone Zone, found bool) {
+
+	if room.ZoneID == "" {
+		return zone, false
+	}
+	zone, err := h.GetZoneByID(room.ZoneID)
+	if err != nil {
+		return zone, false
+	}
+	return zone, true
+}
+
+// Describe function
+// The room's description with the zone's ambient text
+func (z Zone) Describe(room Room) string {
+
+	description := strings.TrimSpace(room.Description)
+	ambient := strings.TrimSpace(z.Ambient)
+	if description == "" {
+		return ambient
+	}
+	if ambient == "" {
+		return description
+	}
+	return description + "\n\n" + ambient
+}
+
+// LevelRange function
+func (z Zone) LevelRange() string {
+
+	if z.MinLevel == 0 && z.MaxLevel == 0 {
+		return "any"
+	}
+	if z.MaxLevel == 0 {
+		return strconv.Itoa(z.MinLevel) + "+"
+	}
+	return strconv.Itoa(z.MinLevel) + "-" + strconv.Itoa(z.MaxLevel)
+}
+
+// PvPText function
+func (z Zone) PvPText() string {
+	if z.PvP {
+		return "enabled"
+	}
+	return "disabled"
+}
+
+// WeatherText function
+func (z Zone) WeatherText() string {
+	if z.Weather == "" {
+		return "none"
+	}
+	return z.Weather
+}
+
+// RegionRoleID function
+func (z Zone) RegionRoleID(guildID string) string {
+	if z.RegionRoleIDs == nil {
+		return ""
+	}
+	return z.RegionRoleIDs[guildID]
+}
+
+// EntryMessage function
+// The announcement made when a traveler crosses into the zone
+func (z Zone) EntryMessage() string {
+
+	message := ":map: You have entered **" + z.Name + "**"
+	var details []string
+	if z.MinLevel > 0 || z.MaxLevel > 0 {
+		details = append(details, "levels "+z.LevelRange())
+	}
+	if z.PvP {
+		details = append(details, "PvP enabled")
+	}
+	if z.Weather != "" {
+		details = append(details, z.Weather+" weather")
+	}
+	if len(details) > 0 {
+		message = message + " (" + strings.Join(details, ", ") + ")"
+	}
+	return message
+}